@@ -0,0 +1,186 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// DeletionProtectionAnnotationKey is the annotation key on a resource applied by manifestwork to protect it from
+// being deleted when it is removed from the manifestwork or the manifestwork is deleted. The value is the
+// DeletionProtectionPolicy of the resource.
+// TODO move this to the api repo
+const DeletionProtectionAnnotationKey = "work.open-cluster-management.io/deletion-protection"
+
+// ConfirmDeletionAnnotationKey is the annotation key on manifestwork to confirm the deletion of the protected
+// resources with the Confirm policy. The value is "*" to confirm the deletion of all the protected resources, or
+// a comma separated list of the keys of the protected resources in the format of
+// <resource>[.<group>]/<namespace>/<name>, e.g. persistentvolumeclaims/default/data or namespaces//prod.
+// TODO move this to the api repo
+const ConfirmDeletionAnnotationKey = "work.open-cluster-management.io/confirm-deletion"
+
+// WorkProtectedDeletionPending is the condition type of manifestwork reporting the protected resources whose
+// deletion is waiting for the confirmation.
+const WorkProtectedDeletionPending = "ProtectedDeletionPending"
+
+// DeletionProtectionPolicy is the policy to delete a protected resource
+type DeletionProtectionPolicy string
+
+const (
+	// DeletionProtectionOrphan orphans the protected resource instead of deleting it.
+	DeletionProtectionOrphan DeletionProtectionPolicy = "Orphan"
+
+	// DeletionProtectionConfirm deletes the protected resource only after its deletion is confirmed with the
+	// annotation ConfirmDeletionAnnotationKey on the manifestwork. The resource is orphaned if the manifestwork
+	// is gone, since there is no way to confirm the deletion any more.
+	DeletionProtectionConfirm DeletionProtectionPolicy = "Confirm"
+)
+
+// DeletionProtection decides the deletion protection policy of the resources applied by manifestworks. A resource
+// is protected if its kind is protected, or it has the annotation DeletionProtectionAnnotationKey which overrides
+// the policy of its kind.
+type DeletionProtection struct {
+	resources sets.Set[schema.GroupResource]
+	policy    DeletionProtectionPolicy
+}
+
+// NewDeletionProtection returns a DeletionProtection protecting the resources with the policy. Each resource is in
+// the format of <resource>[.<group>], e.g. persistentvolumeclaims or certificates.cert-manager.io.
+func NewDeletionProtection(resources []string, policy DeletionProtectionPolicy) (*DeletionProtection, error) {
+	if err := validateDeletionProtectionPolicy(policy); err != nil {
+		return nil, err
+	}
+
+	protection := &DeletionProtection{
+		resources: sets.New[schema.GroupResource](),
+		policy:    policy,
+	}
+	for _, resource := range resources {
+		resource = strings.TrimSpace(resource)
+		if len(resource) == 0 {
+			continue
+		}
+		protection.resources.Insert(schema.ParseGroupResource(resource))
+	}
+	return protection, nil
+}
+
+// PolicyOf returns the deletion protection policy of the resource, false is returned if the resource is not
+// protected. A nil DeletionProtection only honors the annotation on the resource.
+func (p *DeletionProtection) PolicyOf(gr schema.GroupResource, obj metav1.Object) (DeletionProtectionPolicy, bool) {
+	if value, ok := obj.GetAnnotations()[DeletionProtectionAnnotationKey]; ok {
+		policy := DeletionProtectionPolicy(value)
+		if validateDeletionProtectionPolicy(policy) != nil {
+			// fall back to the safest policy if the annotation is invalid
+			return DeletionProtectionOrphan, true
+		}
+		return policy, true
+	}
+
+	if p == nil || !p.resources.Has(gr) {
+		return "", false
+	}
+	return p.policy, true
+}
+
+func validateDeletionProtectionPolicy(policy DeletionProtectionPolicy) error {
+	switch policy {
+	case DeletionProtectionOrphan, DeletionProtectionConfirm:
+		return nil
+	default:
+		return fmt.Errorf("the deletion protection policy %q is invalid, it should be %s or %s",
+			policy, DeletionProtectionOrphan, DeletionProtectionConfirm)
+	}
+}
+
+// DeletionConfirmation records the protected resources whose deletion is confirmed on a manifestwork.
+type DeletionConfirmation struct {
+	all  bool
+	keys sets.Set[string]
+	// orphanUnconfirmed is true if the deletion can not be confirmed any more
+	orphanUnconfirmed bool
+}
+
+// ConfirmedDeletions returns the deletion confirmation of the manifestwork. A nil manifestwork means it is gone,
+// so the protected resources with the Confirm policy are orphaned since their deletion can not be confirmed.
+func ConfirmedDeletions(work *workapiv1.ManifestWork) DeletionConfirmation {
+	confirmation := DeletionConfirmation{keys: sets.New[string]()}
+	if work == nil {
+		confirmation.orphanUnconfirmed = true
+		return confirmation
+	}
+
+	for _, key := range strings.Split(work.Annotations[ConfirmDeletionAnnotationKey], ",") {
+		key = strings.TrimSpace(key)
+		switch {
+		case key == "*":
+			confirmation.all = true
+		case len(key) > 0:
+			confirmation.keys.Insert(key)
+		}
+	}
+	return confirmation
+}
+
+// Confirmed returns true if the deletion of the resource is confirmed
+func (c DeletionConfirmation) Confirmed(resource workapiv1.AppliedManifestResourceMeta) bool {
+	return c.all || c.keys.Has(AppliedResourceKey(resource))
+}
+
+// ValidateDeletionConfirmation validates the annotation ConfirmDeletionAnnotationKey of the manifestwork
+func ValidateDeletionConfirmation(work *workapiv1.ManifestWork) error {
+	value, ok := work.Annotations[ConfirmDeletionAnnotationKey]
+	if !ok {
+		return nil
+	}
+
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "*" {
+			continue
+		}
+		if parts := strings.Split(key, "/"); len(parts) != 3 || len(parts[0]) == 0 || len(parts[2]) == 0 {
+			return fmt.Errorf("the key %q in the annotation %s is invalid, it should be \"*\" or in the format of "+
+				"<resource>[.<group>]/<namespace>/<name>", key, ConfirmDeletionAnnotationKey)
+		}
+	}
+	return nil
+}
+
+// AppliedResourceKey returns the key of the applied resource in the format of <resource>[.<group>]/<namespace>/<name>
+func AppliedResourceKey(resource workapiv1.AppliedManifestResourceMeta) string {
+	gr := schema.GroupResource{Group: resource.Group, Resource: resource.Resource}
+	return fmt.Sprintf("%s/%s/%s", gr.String(), resource.Namespace, resource.Name)
+}
+
+// SetProtectedDeletionPendingCondition returns an UpdateManifestWorkStatusFunc which reports the protected
+// resources whose deletion is waiting for the confirmation, the condition is removed if there is none.
+func SetProtectedDeletionPendingCondition(
+	generation int64, pending []workapiv1.AppliedManifestResourceMeta) UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		if len(pending) == 0 {
+			meta.RemoveStatusCondition(&status.Conditions, WorkProtectedDeletionPending)
+			return nil
+		}
+
+		keys := sets.New[string]()
+		for _, resource := range pending {
+			keys.Insert(AppliedResourceKey(resource))
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               WorkProtectedDeletionPending,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "DeletionNotConfirmed",
+			Message: fmt.Sprintf("The deletion of the protected resources %s is pending, confirm it with the annotation %s",
+				strings.Join(sets.List(keys), ", "), ConfirmDeletionAnnotationKey),
+		})
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package helper
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestDeletionProtectionPolicyOf(t *testing.T) {
+	protection, err := NewDeletionProtection(
+		[]string{"persistentvolumeclaims", " certificates.cert-manager.io", ""}, DeletionProtectionConfirm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name            string
+		protection      *DeletionProtection
+		gr              schema.GroupResource
+		annotations     map[string]string
+		expectedPolicy  DeletionProtectionPolicy
+		expectProtected bool
+	}{
+		{
+			name:       "not protected",
+			protection: protection,
+			gr:         schema.GroupResource{Resource: "secrets"},
+		},
+		{
+			name:            "protected kind",
+			protection:      protection,
+			gr:              schema.GroupResource{Resource: "persistentvolumeclaims"},
+			expectedPolicy:  DeletionProtectionConfirm,
+			expectProtected: true,
+		},
+		{
+			name:            "protected kind with group",
+			protection:      protection,
+			gr:              schema.GroupResource{Group: "cert-manager.io", Resource: "certificates"},
+			expectedPolicy:  DeletionProtectionConfirm,
+			expectProtected: true,
+		},
+		{
+			name:            "annotation overrides the policy of the kind",
+			protection:      protection,
+			gr:              schema.GroupResource{Resource: "persistentvolumeclaims"},
+			annotations:     map[string]string{DeletionProtectionAnnotationKey: "Orphan"},
+			expectedPolicy:  DeletionProtectionOrphan,
+			expectProtected: true,
+		},
+		{
+			name:            "invalid annotation",
+			protection:      protection,
+			gr:              schema.GroupResource{Resource: "secrets"},
+			annotations:     map[string]string{DeletionProtectionAnnotationKey: "true"},
+			expectedPolicy:  DeletionProtectionOrphan,
+			expectProtected: true,
+		},
+		{
+			name:            "nil protection honors the annotation",
+			gr:              schema.GroupResource{Resource: "secrets"},
+			annotations:     map[string]string{DeletionProtectionAnnotationKey: "Confirm"},
+			expectedPolicy:  DeletionProtectionConfirm,
+			expectProtected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			obj := &metav1.ObjectMeta{Name: "test", Annotations: c.annotations}
+			policy, protected := c.protection.PolicyOf(c.gr, obj)
+			if policy != c.expectedPolicy || protected != c.expectProtected {
+				t.Errorf("expected %q %v, but got %q %v", c.expectedPolicy, c.expectProtected, policy, protected)
+			}
+		})
+	}
+
+	if _, err := NewDeletionProtection(nil, "Delete"); err == nil {
+		t.Errorf("expected error for invalid policy")
+	}
+}
+
+func TestConfirmedDeletions(t *testing.T) {
+	pvc := workapiv1.AppliedManifestResourceMeta{
+		ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "persistentvolumeclaims", Namespace: "ns1", Name: "data"},
+		Version:            "v1",
+	}
+	namespace := workapiv1.AppliedManifestResourceMeta{
+		ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "namespaces", Name: "prod"},
+		Version:            "v1",
+	}
+
+	cases := []struct {
+		name              string
+		annotations       map[string]string
+		expectedConfirmed []bool
+		expectedErr       bool
+	}{
+		{
+			name:              "no confirmation",
+			expectedConfirmed: []bool{false, false},
+		},
+		{
+			name:              "confirm all",
+			annotations:       map[string]string{ConfirmDeletionAnnotationKey: "*"},
+			expectedConfirmed: []bool{true, true},
+		},
+		{
+			name:              "confirm some",
+			annotations:       map[string]string{ConfirmDeletionAnnotationKey: "namespaces//prod, secrets/ns1/data"},
+			expectedConfirmed: []bool{false, true},
+		},
+		{
+			name:              "invalid key",
+			annotations:       map[string]string{ConfirmDeletionAnnotationKey: "persistentvolumeclaims/data"},
+			expectedConfirmed: []bool{false, false},
+			expectedErr:       true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: c.annotations}}
+			if err := ValidateDeletionConfirmation(work); (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+
+			confirmation := ConfirmedDeletions(work)
+			for i, resource := range []workapiv1.AppliedManifestResourceMeta{pvc, namespace} {
+				if confirmation.Confirmed(resource) != c.expectedConfirmed[i] {
+					t.Errorf("expected %s confirmed %v", AppliedResourceKey(resource), c.expectedConfirmed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetProtectedDeletionPendingCondition(t *testing.T) {
+	status := &workapiv1.ManifestWorkStatus{}
+	pending := []workapiv1.AppliedManifestResourceMeta{
+		{ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "persistentvolumeclaims", Namespace: "ns1", Name: "data"}},
+		{ResourceIdentifier: workapiv1.ResourceIdentifier{Group: "cert-manager.io", Resource: "certificates", Namespace: "ns1", Name: "tls"}},
+	}
+
+	if err := SetProtectedDeletionPendingCondition(2, pending)(status); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(status.Conditions, WorkProtectedDeletionPending)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != 2 {
+		t.Fatalf("unexpected condition %v", condition)
+	}
+	expectedMessage := "The deletion of the protected resources certificates.cert-manager.io/ns1/tls, " +
+		"persistentvolumeclaims/ns1/data is pending, confirm it with the annotation " + ConfirmDeletionAnnotationKey
+	if condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+
+	if err := SetProtectedDeletionPendingCondition(2, nil)(status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Conditions) != 0 {
+		t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+	}
+}
@@ -0,0 +1,162 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/utils/clock"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// WorkDeletionStuck is the condition type of manifestwork reporting the resources which are not gone in the stuck
+// threshold after they are deleted, usually because their finalizers are never removed.
+const WorkDeletionStuck = "DeletionStuck"
+
+const (
+	// maxStuckResourcesInMessage is the max number of the stuck resources listed in the condition message
+	maxStuckResourcesInMessage = 10
+
+	// stuckEventInterval is the min interval between two events of the same resource stuck in deletion
+	stuckEventInterval = 24 * time.Hour
+)
+
+// DeletionTimeoutPolicy is the policy to handle the resources whose deletion times out
+type DeletionTimeoutPolicy string
+
+const (
+	// DeletionTimeoutWait keeps waiting for the resources to be gone.
+	DeletionTimeoutWait DeletionTimeoutPolicy = "Wait"
+
+	// DeletionTimeoutOrphan stops waiting for the resources and orphans them, so the manifestwork could be
+	// deleted while the resources are still terminating.
+	DeletionTimeoutOrphan DeletionTimeoutPolicy = "Orphan"
+
+	// DeletionTimeoutRemoveFinalizers removes the finalizers of the resources, so they are gone immediately.
+	DeletionTimeoutRemoveFinalizers DeletionTimeoutPolicy = "RemoveFinalizers"
+)
+
+// StuckDeletion is a resource which is not gone in the stuck threshold after it is deleted.
+type StuckDeletion struct {
+	Resource workapiv1.AppliedManifestResourceMeta
+	// DeletionTimestamp is the time when the resource is deleted
+	DeletionTimestamp metav1.Time
+	// Finalizers is the remaining finalizers of the resource
+	Finalizers []string
+}
+
+// DeletionTimeout tracks the deletion progress of the applied resources. A resource is stuck in deletion if it is
+// not gone in the stuck threshold after it is deleted, and it is handled with the timeout policy once the timeout
+// elapses.
+type DeletionTimeout struct {
+	stuckThreshold time.Duration
+	timeout        time.Duration
+	policy         DeletionTimeoutPolicy
+	clock          clock.Clock
+	// stuckReported contains the uids of the stuck resources whose events are recorded in the stuckEventInterval
+	stuckReported *utilcache.Expiring
+}
+
+// NewDeletionTimeout returns a DeletionTimeout. The stuck resources are not reported if the stuck threshold is not
+// positive, and the timeout policy is not applied if the timeout is not positive.
+func NewDeletionTimeout(stuckThreshold, timeout time.Duration, policy DeletionTimeoutPolicy) (*DeletionTimeout, error) {
+	switch policy {
+	case DeletionTimeoutWait, DeletionTimeoutOrphan, DeletionTimeoutRemoveFinalizers:
+	default:
+		return nil, fmt.Errorf("the deletion timeout policy %q is invalid, it should be %s, %s or %s",
+			policy, DeletionTimeoutWait, DeletionTimeoutOrphan, DeletionTimeoutRemoveFinalizers)
+	}
+
+	return &DeletionTimeout{
+		stuckThreshold: stuckThreshold,
+		timeout:        timeout,
+		policy:         policy,
+		clock:          clock.RealClock{},
+		stuckReported:  utilcache.NewExpiring(),
+	}, nil
+}
+
+// WithClock sets the clock of the DeletionTimeout
+func (t *DeletionTimeout) WithClock(clock clock.Clock) *DeletionTimeout {
+	t.clock = clock
+	t.stuckReported = utilcache.NewExpiringWithClock(clock)
+	return t
+}
+
+// stuck returns true if the deleting obj is stuck in deletion
+func (t *DeletionTimeout) stuck(obj metav1.Object) bool {
+	if t == nil || t.stuckThreshold <= 0 {
+		return false
+	}
+	return t.clock.Since(obj.GetDeletionTimestamp().Time) >= t.stuckThreshold
+}
+
+// shouldReportStuck returns true if the event of the obj stuck in deletion should be recorded, the event of a
+// resource is recorded once the stuck threshold is crossed and then at most once every stuckEventInterval.
+func (t *DeletionTimeout) shouldReportStuck(obj metav1.Object) bool {
+	if _, reported := t.stuckReported.Get(obj.GetUID()); reported {
+		return false
+	}
+	t.stuckReported.Set(obj.GetUID(), struct{}{}, stuckEventInterval)
+	return true
+}
+
+// timeoutPolicy returns the timeout policy if the deletion of the deleting obj times out
+func (t *DeletionTimeout) timeoutPolicy(obj metav1.Object) (DeletionTimeoutPolicy, bool) {
+	if t == nil || t.timeout <= 0 || t.policy == DeletionTimeoutWait {
+		return "", false
+	}
+	if t.clock.Since(obj.GetDeletionTimestamp().Time) < t.timeout {
+		return "", false
+	}
+	return t.policy, true
+}
+
+// removeFinalizers removes all the finalizers of the obj, the patch fails if the obj is recreated.
+func removeFinalizers(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource,
+	obj *unstructured.Unstructured) error {
+	patch := fmt.Sprintf(`{"metadata":{"uid":%q,"finalizers":null}}`, obj.GetUID())
+	_, err := dynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Patch(
+		ctx, obj.GetName(), types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// SetDeletionStuckCondition returns an UpdateManifestWorkStatusFunc which reports the resources stuck in
+// deletion with their remaining finalizers, the condition is removed if there is none.
+func SetDeletionStuckCondition(generation int64, stuck []StuckDeletion) UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		if len(stuck) == 0 {
+			meta.RemoveStatusCondition(&status.Conditions, WorkDeletionStuck)
+			return nil
+		}
+
+		descriptions := []string{}
+		for i, deletion := range stuck {
+			if i == maxStuckResourcesInMessage {
+				descriptions = append(descriptions, fmt.Sprintf("and %d more", len(stuck)-i))
+				break
+			}
+			descriptions = append(descriptions, fmt.Sprintf("%s deleted at %s with finalizers [%s]",
+				AppliedResourceKey(deletion.Resource), deletion.DeletionTimestamp.UTC().Format(time.RFC3339),
+				strings.Join(deletion.Finalizers, ", ")))
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               WorkDeletionStuck,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "ResourcesStuckInDeletion",
+			Message: fmt.Sprintf("%d resources are stuck in deletion: %s",
+				len(stuck), strings.Join(descriptions, "; ")),
+		})
+		return nil
+	}
+}
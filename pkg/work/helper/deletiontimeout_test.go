@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestNewDeletionTimeout(t *testing.T) {
+	for _, policy := range []DeletionTimeoutPolicy{DeletionTimeoutWait, DeletionTimeoutOrphan, DeletionTimeoutRemoveFinalizers} {
+		if _, err := NewDeletionTimeout(time.Minute, time.Hour, policy); err != nil {
+			t.Errorf("unexpected error for policy %s: %v", policy, err)
+		}
+	}
+
+	if _, err := NewDeletionTimeout(time.Minute, time.Hour, "Delete"); err == nil {
+		t.Errorf("expected error for invalid policy")
+	}
+}
+
+func TestSetDeletionStuckCondition(t *testing.T) {
+	deletionTimestamp := metav1.NewTime(time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC))
+	newStuckDeletion := func(name string) StuckDeletion {
+		return StuckDeletion{
+			Resource: workapiv1.AppliedManifestResourceMeta{
+				ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: name},
+			},
+			DeletionTimestamp: deletionTimestamp,
+			Finalizers:        []string{"example.com/a", "example.com/b"},
+		}
+	}
+
+	status := &workapiv1.ManifestWorkStatus{}
+	if err := SetDeletionStuckCondition(1, []StuckDeletion{newStuckDeletion("n1")})(status); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(status.Conditions, WorkDeletionStuck)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("unexpected condition %v", condition)
+	}
+	expectedMessage := "1 resources are stuck in deletion: secrets/ns1/n1 deleted at 2023-07-01T00:00:00Z " +
+		"with finalizers [example.com/a, example.com/b]"
+	if condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+
+	stuck := []StuckDeletion{}
+	for i := 0; i < maxStuckResourcesInMessage+2; i++ {
+		stuck = append(stuck, newStuckDeletion(fmt.Sprintf("n%d", i)))
+	}
+	if err := SetDeletionStuckCondition(1, stuck)(status); err != nil {
+		t.Fatal(err)
+	}
+	condition = meta.FindStatusCondition(status.Conditions, WorkDeletionStuck)
+	if !strings.HasSuffix(condition.Message, "; and 2 more") {
+		t.Errorf("unexpected message %q", condition.Message)
+	}
+
+	if err := SetDeletionStuckCondition(1, nil)(status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Conditions) != 0 {
+		t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+	}
+}
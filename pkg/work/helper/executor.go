@@ -0,0 +1,200 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// ExecutorUserAnnotationKey is the annotation key on manifestwork to use a user of the managed cluster as the
+	// executor of the manifestwork, the value is the name of the user. The group memberships of the user are not
+	// known on the managed cluster, so the user is impersonated with only the group system:authenticated, and the
+	// permissions bound to the other groups of the user are not effective. The user executor needs the permissions
+	// bound to the user directly.
+	// TODO move this to the api repo
+	ExecutorUserAnnotationKey = "work.open-cluster-management.io/executor-user"
+
+	// ExecutorGroupAnnotationKey is the annotation key on manifestwork to use a group of the managed cluster as the
+	// executor of the manifestwork, the value is the name of the group.
+	// TODO move this to the api repo
+	ExecutorGroupAnnotationKey = "work.open-cluster-management.io/executor-group"
+)
+
+const (
+	// ExecutorSubjectTypeUser indicates that the workload resources belong to a user in the managed cluster.
+	// TODO move this to the api repo
+	ExecutorSubjectTypeUser workapiv1.ManifestWorkExecutorSubjectType = "User"
+
+	// ExecutorSubjectTypeGroup indicates that the workload resources belong to a group in the managed cluster.
+	// TODO move this to the api repo
+	ExecutorSubjectTypeGroup workapiv1.ManifestWorkExecutorSubjectType = "Group"
+
+	// groupExecutorUserPrefix is the prefix of the user impersonated by the work agent for a group executor, since
+	// a group cannot be impersonated without a user. The user is not expected to have any permission by itself.
+	groupExecutorUserPrefix = "system:open-cluster-management:executor-group:"
+
+	// reservedIdentityPrefix is the prefix of the users and groups reserved by kubernetes, e.g. system:admin and
+	// system:masters, they cannot be used as the executors so the work agent never impersonates them.
+	reservedIdentityPrefix = "system:"
+)
+
+// Executor is the executor of a manifestwork. Besides the service account executor in the manifestwork spec, it
+// also supports the user and group executors which are specified by annotations on the manifestwork.
+//
+// The resources of a service account executor are applied by the work agent itself once the permissions of the
+// service account are checked with the subject access reviews, which is how the service account executors always
+// work, and impersonating them would break the existing executors lacking the permissions the appliers need besides
+// the checked ones. The user and group executors have no such existing works, they are impersonated to apply the
+// resources so their permissions are also enforced by the api server.
+type Executor struct {
+	Subject ExecutorSubject
+}
+
+// ExecutorSubject is the subject identity used by the work agent to apply the resources.
+type ExecutorSubject struct {
+	// Type is the type of the subject identity, it could be ServiceAccount, User or Group.
+	Type workapiv1.ManifestWorkExecutorSubjectType
+	// ServiceAccount is only set if the type is ServiceAccount.
+	ServiceAccount *workapiv1.ManifestWorkSubjectServiceAccount
+	// User is only set if the type is User.
+	User *ExecutorSubjectUser
+	// Group is only set if the type is Group.
+	Group *ExecutorSubjectGroup
+}
+
+// ExecutorSubjectUser references a user in the managed cluster.
+type ExecutorSubjectUser struct {
+	Name string
+}
+
+// ExecutorSubjectGroup references a group in the managed cluster.
+type ExecutorSubjectGroup struct {
+	Name string
+}
+
+// NewExecutor converts the executor in the manifestwork spec to an Executor.
+func NewExecutor(executor *workapiv1.ManifestWorkExecutor) *Executor {
+	if executor == nil {
+		return nil
+	}
+
+	return &Executor{
+		Subject: ExecutorSubject{
+			Type:           executor.Subject.Type,
+			ServiceAccount: executor.Subject.ServiceAccount,
+		},
+	}
+}
+
+// ManifestWorkExecutor returns the executor of the manifestwork. It returns nil if no executor is specified, and
+// returns an error if the executor is specified by both the spec and the annotations, or by both the user and
+// group annotations.
+func ManifestWorkExecutor(work *workapiv1.ManifestWork) (*Executor, error) {
+	user, hasUser := work.Annotations[ExecutorUserAnnotationKey]
+	group, hasGroup := work.Annotations[ExecutorGroupAnnotationKey]
+
+	switch {
+	case !hasUser && !hasGroup:
+		return NewExecutor(work.Spec.Executor), nil
+	case hasUser && hasGroup:
+		return nil, fmt.Errorf("only one of the annotations %s and %s can be set",
+			ExecutorUserAnnotationKey, ExecutorGroupAnnotationKey)
+	case work.Spec.Executor != nil:
+		return nil, fmt.Errorf("the executor annotation cannot be set when the executor is set in the spec")
+	case hasUser:
+		if len(user) == 0 {
+			return nil, fmt.Errorf("the value of the annotation %s is empty", ExecutorUserAnnotationKey)
+		}
+		return validExecutor(&Executor{
+			Subject: ExecutorSubject{
+				Type: ExecutorSubjectTypeUser,
+				User: &ExecutorSubjectUser{Name: user},
+			},
+		})
+	default:
+		if len(group) == 0 {
+			return nil, fmt.Errorf("the value of the annotation %s is empty", ExecutorGroupAnnotationKey)
+		}
+		return validExecutor(&Executor{
+			Subject: ExecutorSubject{
+				Type:  ExecutorSubjectTypeGroup,
+				Group: &ExecutorSubjectGroup{Name: group},
+			},
+		})
+	}
+}
+
+func validExecutor(executor *Executor) (*Executor, error) {
+	if err := executor.Subject.Validate(); err != nil {
+		return nil, err
+	}
+	return executor, nil
+}
+
+// Validate checks whether the subject is well formed.
+func (s *ExecutorSubject) Validate() error {
+	switch s.Type {
+	case workapiv1.ExecutorSubjectTypeServiceAccount:
+		if s.ServiceAccount == nil {
+			return fmt.Errorf("the executor service account is nil")
+		}
+	case ExecutorSubjectTypeUser:
+		if s.User == nil || len(s.User.Name) == 0 {
+			return fmt.Errorf("the executor user is empty")
+		}
+		if strings.HasPrefix(s.User.Name, reservedIdentityPrefix) {
+			return fmt.Errorf("the executor user %s is reserved", s.User.Name)
+		}
+	case ExecutorSubjectTypeGroup:
+		if s.Group == nil || len(s.Group.Name) == 0 {
+			return fmt.Errorf("the executor group is empty")
+		}
+		if strings.HasPrefix(s.Group.Name, reservedIdentityPrefix) {
+			return fmt.Errorf("the executor group %s is reserved", s.Group.Name)
+		}
+	default:
+		return fmt.Errorf("only support %s, %s and %s type for the executor",
+			workapiv1.ExecutorSubjectTypeServiceAccount, ExecutorSubjectTypeUser, ExecutorSubjectTypeGroup)
+	}
+	return nil
+}
+
+// UserName returns the user name of the subject which is used in the subject access reviews and impersonation.
+func (s *ExecutorSubject) UserName() string {
+	switch s.Type {
+	case workapiv1.ExecutorSubjectTypeServiceAccount:
+		return fmt.Sprintf("system:serviceaccount:%s:%s", s.ServiceAccount.Namespace, s.ServiceAccount.Name)
+	case ExecutorSubjectTypeUser:
+		return s.User.Name
+	case ExecutorSubjectTypeGroup:
+		return groupExecutorUserPrefix + s.Group.Name
+	}
+	return ""
+}
+
+// Groups returns the groups of the subject which are used in the subject access reviews and impersonation. They are
+// the groups the api server sets for the impersonated subject, a user executor only has the group
+// system:authenticated since the other groups of the user are not known.
+func (s *ExecutorSubject) Groups() []string {
+	switch s.Type {
+	case workapiv1.ExecutorSubjectTypeServiceAccount:
+		return []string{"system:serviceaccounts", "system:authenticated",
+			fmt.Sprintf("system:serviceaccounts:%s", s.ServiceAccount.Namespace)}
+	case ExecutorSubjectTypeUser:
+		return []string{"system:authenticated"}
+	case ExecutorSubjectTypeGroup:
+		return []string{s.Group.Name, "system:authenticated"}
+	}
+	return nil
+}
+
+// String returns the identity name of the subject, which is the user name for service account and user executors,
+// and the group name with a "system:group:" prefix for group executors.
+func (s *ExecutorSubject) String() string {
+	if s.Type == ExecutorSubjectTypeGroup {
+		return fmt.Sprintf("system:group:%s", s.Group.Name)
+	}
+	return s.UserName()
+}
@@ -0,0 +1,152 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestManifestWorkExecutor(t *testing.T) {
+	saExecutor := &workapiv1.ManifestWorkExecutor{
+		Subject: workapiv1.ManifestWorkExecutorSubject{
+			Type: workapiv1.ExecutorSubjectTypeServiceAccount,
+			ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
+				Namespace: "ns1",
+				Name:      "sa1",
+			},
+		},
+	}
+
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		executor         *workapiv1.ManifestWorkExecutor
+		expectedExecutor *Executor
+		expectErr        bool
+	}{
+		{
+			name: "no executor",
+		},
+		{
+			name:             "service account executor",
+			executor:         saExecutor,
+			expectedExecutor: NewExecutor(saExecutor),
+		},
+		{
+			name:        "user executor",
+			annotations: map[string]string{ExecutorUserAnnotationKey: "user1"},
+			expectedExecutor: &Executor{
+				Subject: ExecutorSubject{Type: ExecutorSubjectTypeUser, User: &ExecutorSubjectUser{Name: "user1"}},
+			},
+		},
+		{
+			name:        "group executor",
+			annotations: map[string]string{ExecutorGroupAnnotationKey: "group1"},
+			expectedExecutor: &Executor{
+				Subject: ExecutorSubject{Type: ExecutorSubjectTypeGroup, Group: &ExecutorSubjectGroup{Name: "group1"}},
+			},
+		},
+		{
+			name:        "both user and group",
+			annotations: map[string]string{ExecutorUserAnnotationKey: "user1", ExecutorGroupAnnotationKey: "group1"},
+			expectErr:   true,
+		},
+		{
+			name:        "annotation with spec executor",
+			annotations: map[string]string{ExecutorUserAnnotationKey: "user1"},
+			executor:    saExecutor,
+			expectErr:   true,
+		},
+		{
+			name:        "empty user",
+			annotations: map[string]string{ExecutorUserAnnotationKey: ""},
+			expectErr:   true,
+		},
+		{
+			name:        "reserved user",
+			annotations: map[string]string{ExecutorUserAnnotationKey: "system:admin"},
+			expectErr:   true,
+		},
+		{
+			name:        "reserved group",
+			annotations: map[string]string{ExecutorGroupAnnotationKey: "system:masters"},
+			expectErr:   true,
+		},
+		{
+			name:        "reserved service account user",
+			annotations: map[string]string{ExecutorUserAnnotationKey: "system:serviceaccount:kube-system:default"},
+			expectErr:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{Name: "work1", Namespace: "cluster1", Annotations: c.annotations},
+				Spec:       workapiv1.ManifestWorkSpec{Executor: c.executor},
+			}
+			executor, err := ManifestWorkExecutor(work)
+			if c.expectErr != (err != nil) {
+				t.Fatalf("expect error %v, but got %v", c.expectErr, err)
+			}
+			if !reflect.DeepEqual(executor, c.expectedExecutor) {
+				t.Errorf("expect executor %v, but got %v", c.expectedExecutor, executor)
+			}
+		})
+	}
+}
+
+func TestExecutorSubjectIdentity(t *testing.T) {
+	cases := []struct {
+		name           string
+		subject        ExecutorSubject
+		expectedUser   string
+		expectedGroups []string
+		expectedString string
+	}{
+		{
+			name: "service account",
+			subject: ExecutorSubject{
+				Type:           workapiv1.ExecutorSubjectTypeServiceAccount,
+				ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{Namespace: "ns1", Name: "sa1"},
+			},
+			expectedUser:   "system:serviceaccount:ns1:sa1",
+			expectedGroups: []string{"system:serviceaccounts", "system:authenticated", "system:serviceaccounts:ns1"},
+			expectedString: "system:serviceaccount:ns1:sa1",
+		},
+		{
+			name:           "user",
+			subject:        ExecutorSubject{Type: ExecutorSubjectTypeUser, User: &ExecutorSubjectUser{Name: "user1"}},
+			expectedUser:   "user1",
+			expectedGroups: []string{"system:authenticated"},
+			expectedString: "user1",
+		},
+		{
+			name:           "group",
+			subject:        ExecutorSubject{Type: ExecutorSubjectTypeGroup, Group: &ExecutorSubjectGroup{Name: "group1"}},
+			expectedUser:   "system:open-cluster-management:executor-group:group1",
+			expectedGroups: []string{"group1", "system:authenticated"},
+			expectedString: "system:group:group1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.subject.Validate(); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if user := c.subject.UserName(); user != c.expectedUser {
+				t.Errorf("expect user %s, but got %s", c.expectedUser, user)
+			}
+			if groups := c.subject.Groups(); !reflect.DeepEqual(groups, c.expectedGroups) {
+				t.Errorf("expect groups %v, but got %v", c.expectedGroups, groups)
+			}
+			if s := c.subject.String(); s != c.expectedString {
+				t.Errorf("expect %s, but got %s", c.expectedString, s)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -19,10 +20,12 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -244,6 +247,20 @@ func TestMergeManifestConditions(t *testing.T) {
 				newManifestCondition(0, "resource2", newCondition("two", "True", "my-reason", "my-message", &transitionTime)),
 			},
 		},
+		{
+			name: "keep status references",
+			startingConditions: []workapiv1.ManifestCondition{
+				newManifestCondition(0, "resource1", newCondition("one", "True", "my-reason", "my-message", nil)),
+				newManifestCondition(WatchOnlyOrdinal, "resource1", newCondition("two", "True", "my-reason", "my-message", &transitionTime)),
+			},
+			newConditions: []workapiv1.ManifestCondition{
+				newManifestCondition(0, "resource2", newCondition("one", "False", "my-reason", "my-message", nil)),
+			},
+			expectedConditions: []workapiv1.ManifestCondition{
+				newManifestCondition(0, "resource2", newCondition("one", "False", "my-reason", "my-message", nil)),
+				newManifestCondition(WatchOnlyOrdinal, "resource1", newCondition("two", "True", "my-reason", "my-message", &transitionTime)),
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -444,11 +461,14 @@ func TestDeleteAppliedResourcess(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(scheme, c.existingResources...)
-			actual, err := DeleteAppliedResources(context.TODO(), c.resourcesToRemove, "testing", fakeDynamicClient, eventstesting.NewTestingEventRecorder(t), c.owner)
+			deletion, err := DeleteAppliedResources(context.TODO(), c.resourcesToRemove, "testing", fakeDynamicClient,
+				eventstesting.NewTestingEventRecorder(t), c.owner, nil, ConfirmedDeletions(nil), nil)
 			if err != nil {
 				t.Errorf("unexpected err: %v", err)
 			}
 
+			actual := deletion.PendingFinalization
+
 			if !equality.Semantic.DeepEqual(actual, c.expectedResourcesPendingFinalization) {
 				t.Errorf(cmp.Diff(actual, c.expectedResourcesPendingFinalization))
 			}
@@ -456,6 +476,220 @@ func TestDeleteAppliedResourcess(t *testing.T) {
 	}
 }
 
+func TestDeleteProtectedAppliedResources(t *testing.T) {
+	owner := metav1.OwnerReference{Name: "n1", UID: "a"}
+	secret := workapiv1.AppliedManifestResourceMeta{
+		Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"}
+	annotatedSecret := newSecret("ns1", "n1", false, "ns1-n1", owner)
+	annotatedSecret.Annotations = map[string]string{DeletionProtectionAnnotationKey: string(DeletionProtectionConfirm)}
+
+	newWork := func(confirmed string) *workapiv1.ManifestWork {
+		work, _ := spoketesting.NewManifestWork(0)
+		work.Annotations = map[string]string{ConfirmDeletionAnnotationKey: confirmed}
+		return work
+	}
+
+	cases := []struct {
+		name                                 string
+		existingResource                     runtime.Object
+		protectedResources                   []string
+		policy                               DeletionProtectionPolicy
+		work                                 *workapiv1.ManifestWork
+		expectedResourcesPendingFinalization []workapiv1.AppliedManifestResourceMeta
+		expectedResourcesPendingConfirmation []workapiv1.AppliedManifestResourceMeta
+		expectedActions                      []string
+	}{
+		{
+			name:                                 "delete resource not protected",
+			existingResource:                     newSecret("ns1", "n1", false, "ns1-n1", owner),
+			protectedResources:                   []string{"persistentvolumeclaims"},
+			policy:                               DeletionProtectionConfirm,
+			work:                                 newWork(""),
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get", "delete"},
+		},
+		{
+			name:               "orphan protected resource",
+			existingResource:   newSecret("ns1", "n1", false, "ns1-n1", owner),
+			protectedResources: []string{"secrets"},
+			policy:             DeletionProtectionOrphan,
+			work:               newWork("*"),
+			expectedActions:    []string{"get", "patch"},
+		},
+		{
+			name:                                 "wait for deletion confirmation",
+			existingResource:                     newSecret("ns1", "n1", false, "ns1-n1", owner),
+			protectedResources:                   []string{"secrets"},
+			policy:                               DeletionProtectionConfirm,
+			work:                                 newWork("secrets/ns1/n2"),
+			expectedResourcesPendingConfirmation: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get"},
+		},
+		{
+			name:                                 "delete confirmed resource",
+			existingResource:                     newSecret("ns1", "n1", false, "ns1-n1", owner),
+			protectedResources:                   []string{"secrets"},
+			policy:                               DeletionProtectionConfirm,
+			work:                                 newWork("secrets/ns1/n2, secrets/ns1/n1"),
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get", "delete"},
+		},
+		{
+			name:               "orphan unconfirmed resource if the work is gone",
+			existingResource:   newSecret("ns1", "n1", false, "ns1-n1", owner),
+			protectedResources: []string{"secrets"},
+			policy:             DeletionProtectionConfirm,
+			expectedActions:    []string{"get", "patch"},
+		},
+		{
+			name:                                 "protected by annotation",
+			existingResource:                     annotatedSecret,
+			policy:                               DeletionProtectionOrphan,
+			work:                                 newWork(""),
+			expectedResourcesPendingConfirmation: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			protection, err := NewDeletionProtection(c.protectedResources, c.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(scheme, c.existingResource)
+			deletion, errs := DeleteAppliedResources(context.TODO(),
+				[]workapiv1.AppliedManifestResourceMeta{secret}, "testing", fakeDynamicClient,
+				eventstesting.NewTestingEventRecorder(t), owner, protection, ConfirmedDeletions(c.work), nil)
+			if len(errs) != 0 {
+				t.Errorf("unexpected err: %v", errs)
+			}
+
+			pendingFinalization, pendingConfirmation := deletion.PendingFinalization, deletion.PendingConfirmation
+
+			if !equality.Semantic.DeepEqual(pendingFinalization, c.expectedResourcesPendingFinalization) {
+				t.Errorf(cmp.Diff(pendingFinalization, c.expectedResourcesPendingFinalization))
+			}
+			if !equality.Semantic.DeepEqual(pendingConfirmation, c.expectedResourcesPendingConfirmation) {
+				t.Errorf(cmp.Diff(pendingConfirmation, c.expectedResourcesPendingConfirmation))
+			}
+			testingcommon.AssertActions(t, fakeDynamicClient.Actions(), c.expectedActions...)
+		})
+	}
+}
+
+func TestDeleteStuckAppliedResources(t *testing.T) {
+	owner := metav1.OwnerReference{Name: "n1", UID: "a"}
+	secret := workapiv1.AppliedManifestResourceMeta{
+		Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"}
+	deletingSecret := newSecret("ns1", "n1", true, "ns1-n1", owner)
+	deletingSecret.Finalizers = []string{"example.com/cleanup"}
+	deletionTimestamp := time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC)
+	deletingSecret.DeletionTimestamp = &metav1.Time{Time: deletionTimestamp}
+
+	cases := []struct {
+		name                                 string
+		elapsed                              time.Duration
+		policy                               DeletionTimeoutPolicy
+		expectedResourcesPendingFinalization []workapiv1.AppliedManifestResourceMeta
+		expectedStuck                        []StuckDeletion
+		expectedActions                      []string
+	}{
+		{
+			name:                                 "wait for deletion",
+			elapsed:                              time.Minute,
+			policy:                               DeletionTimeoutOrphan,
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get"},
+		},
+		{
+			name:                                 "stuck in deletion",
+			elapsed:                              6 * time.Minute,
+			policy:                               DeletionTimeoutOrphan,
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedStuck: []StuckDeletion{
+				{Resource: secret, DeletionTimestamp: *deletingSecret.DeletionTimestamp, Finalizers: []string{"example.com/cleanup"}},
+			},
+			expectedActions: []string{"get"},
+		},
+		{
+			name:                                 "keep waiting after timeout",
+			elapsed:                              time.Hour,
+			policy:                               DeletionTimeoutWait,
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedStuck: []StuckDeletion{
+				{Resource: secret, DeletionTimestamp: *deletingSecret.DeletionTimestamp, Finalizers: []string{"example.com/cleanup"}},
+			},
+			expectedActions: []string{"get"},
+		},
+		{
+			name:            "orphan after timeout",
+			elapsed:         time.Hour,
+			policy:          DeletionTimeoutOrphan,
+			expectedActions: []string{"get", "patch"},
+		},
+		{
+			name:                                 "remove finalizers after timeout",
+			elapsed:                              time.Hour,
+			policy:                               DeletionTimeoutRemoveFinalizers,
+			expectedResourcesPendingFinalization: []workapiv1.AppliedManifestResourceMeta{secret},
+			expectedActions:                      []string{"get", "patch"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			timeout, err := NewDeletionTimeout(5*time.Minute, 30*time.Minute, c.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeout.WithClock(testingclock.NewFakeClock(deletionTimestamp.Add(c.elapsed)))
+
+			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(scheme, deletingSecret.DeepCopy())
+			recorder := events.NewInMemoryRecorder("testing")
+			deletion, errs := DeleteAppliedResources(context.TODO(),
+				[]workapiv1.AppliedManifestResourceMeta{secret}, "testing", fakeDynamicClient,
+				recorder, owner, nil, ConfirmedDeletions(nil), timeout)
+			if len(errs) != 0 {
+				t.Errorf("unexpected err: %v", errs)
+			}
+
+			if !equality.Semantic.DeepEqual(deletion.PendingFinalization, c.expectedResourcesPendingFinalization) {
+				t.Errorf(cmp.Diff(deletion.PendingFinalization, c.expectedResourcesPendingFinalization))
+			}
+			if !equality.Semantic.DeepEqual(deletion.Stuck, c.expectedStuck) {
+				t.Errorf(cmp.Diff(deletion.Stuck, c.expectedStuck))
+			}
+			testingcommon.AssertActions(t, fakeDynamicClient.Actions(), c.expectedActions...)
+
+			// the stuck deletion is only recorded once although the resource is requeued
+			_, _ = DeleteAppliedResources(context.TODO(),
+				[]workapiv1.AppliedManifestResourceMeta{secret}, "testing", fakeDynamicClient,
+				recorder, owner, nil, ConfirmedDeletions(nil), timeout)
+			stuckEvents := 0
+			for _, event := range recorder.Events() {
+				if event.Reason == "ResourceDeletionStuck" {
+					stuckEvents++
+				}
+			}
+			if expected := len(c.expectedStuck); stuckEvents != expected {
+				t.Errorf("expected %d stuck events, but got %d", expected, stuckEvents)
+			}
+		})
+	}
+}
+
 func TestRemoveFinalizer(t *testing.T) {
 	cases := []struct {
 		name               string
@@ -55,7 +55,8 @@ func init() {
 // conditions and the new manifest conditions. Rules to match ManifestCondition between two arrays:
 // 1. match the manifest condition with the whole ManifestResourceMeta;
 // 2. if not matched, try to match with properties other than ordinal in ManifestResourceMeta
-// If no existing manifest condition is matched, the new manifest condition will be used.
+// If no existing manifest condition is matched, the new manifest condition will be used. The existing manifest
+// conditions of the status references are kept at the end of the array.
 func MergeManifestConditions(conditions, newConditions []workapiv1.ManifestCondition) []workapiv1.ManifestCondition {
 	merged := []workapiv1.ManifestCondition{}
 
@@ -64,7 +65,13 @@ func MergeManifestConditions(conditions, newConditions []workapiv1.ManifestCondi
 	metaWithoutOridinalIndex := map[workapiv1.ManifestResourceMeta]workapiv1.ManifestCondition{}
 
 	duplicated := []workapiv1.ManifestResourceMeta{}
+	watchOnly := []workapiv1.ManifestCondition{}
 	for _, condition := range conditions {
+		// the conditions of the status references are not merged with the manifests, keep them as they are
+		if IsWatchOnly(condition) {
+			watchOnly = append(watchOnly, condition)
+			continue
+		}
 		metaIndex[condition.ResourceMeta] = condition
 		if metaWithoutOridinal := resetOrdinal(condition.ResourceMeta); metaWithoutOridinal != (workapiv1.ManifestResourceMeta{}) {
 			if _, exists := metaWithoutOridinalIndex[metaWithoutOridinal]; exists {
@@ -104,7 +111,7 @@ func MergeManifestConditions(conditions, newConditions []workapiv1.ManifestCondi
 		merged = append(merged, newCondition)
 	}
 
-	return merged
+	return append(merged, watchOnly...)
 }
 
 func resetOrdinal(meta workapiv1.ManifestResourceMeta) workapiv1.ManifestResourceMeta {
@@ -194,16 +201,33 @@ func updateManifestWorkStatus(
 	return &updatedManifestWork.Status, true, nil
 }
 
-// DeleteAppliedResources deletes all given applied resources and returns those pending for finalization
+// AppliedResourcesDeletion is the result of deleting the applied resources
+type AppliedResourcesDeletion struct {
+	// PendingFinalization is the resources which are deleted but not gone yet
+	PendingFinalization []workapiv1.AppliedManifestResourceMeta
+	// PendingConfirmation is the protected resources waiting for the deletion confirmation
+	PendingConfirmation []workapiv1.AppliedManifestResourceMeta
+	// Stuck is the resources pending for finalization which are stuck in deletion
+	Stuck []StuckDeletion
+}
+
+// DeleteAppliedResources deletes all given applied resources and returns those pending for finalization and those
+// pending for the deletion confirmation.
 // If the uid recorded in resources is different from what we get by client, ignore the deletion.
+// The protected resources are orphaned, or kept until their deletion is confirmed, according to their deletion
+// protection policy. The resources stuck in deletion are reported, and handled with the timeout policy once their
+// deletion times out.
 func DeleteAppliedResources(
 	ctx context.Context,
 	resources []workapiv1.AppliedManifestResourceMeta,
 	reason string,
 	dynamicClient dynamic.Interface,
 	recorder events.Recorder,
-	owner metav1.OwnerReference) ([]workapiv1.AppliedManifestResourceMeta, []error) {
-	var resourcesPendingFinalization []workapiv1.AppliedManifestResourceMeta
+	owner metav1.OwnerReference,
+	protection *DeletionProtection,
+	confirmation DeletionConfirmation,
+	timeout *DeletionTimeout) (AppliedResourcesDeletion, []error) {
+	var deletion AppliedResourcesDeletion
 	var errs []error
 
 	// set owner to be removed
@@ -258,10 +282,58 @@ func DeleteAppliedResources(
 		}
 
 		if u.GetDeletionTimestamp() != nil && !u.GetDeletionTimestamp().IsZero() {
-			resourcesPendingFinalization = append(resourcesPendingFinalization, resource)
+			if policy, timedOut := timeout.timeoutPolicy(u); timedOut {
+				err := handleDeletionTimeout(ctx, dynamicClient, recorder, gvr, u, *ownerCopy, policy)
+				switch {
+				case err != nil:
+					errs = append(errs, err)
+				case policy == DeletionTimeoutOrphan:
+					// stop waiting for the orphaned resource
+					continue
+				default:
+					// the resource is gone soon once its finalizers are removed
+					deletion.PendingFinalization = append(deletion.PendingFinalization, resource)
+					continue
+				}
+			}
+
+			if timeout.stuck(u) {
+				deletion.Stuck = append(deletion.Stuck, StuckDeletion{
+					Resource:          resource,
+					DeletionTimestamp: *u.GetDeletionTimestamp(),
+					Finalizers:        u.GetFinalizers(),
+				})
+				if timeout.shouldReportStuck(u) {
+					recorder.Warningf("ResourceDeletionStuck", "Resource %v with key %s/%s is stuck in deletion with finalizers %v.",
+						gvr, resource.Namespace, resource.Name, u.GetFinalizers())
+				}
+			}
+			deletion.PendingFinalization = append(deletion.PendingFinalization, resource)
 			continue
 		}
 
+		if policy, protected := protection.PolicyOf(gvr.GroupResource(), u); protected {
+			switch {
+			case policy == DeletionProtectionConfirm && confirmation.Confirmed(resource):
+				// the deletion is confirmed, delete it as usual
+			case policy == DeletionProtectionConfirm && !confirmation.orphanUnconfirmed:
+				deletion.PendingConfirmation = append(deletion.PendingConfirmation, resource)
+				continue
+			default:
+				// orphan the resource by removing the owner, so it is not deleted with the appliedmanifestwork either
+				err := ApplyOwnerReferences(ctx, dynamicClient, gvr, u, *ownerCopy)
+				if err != nil {
+					errs = append(errs, fmt.Errorf(
+						"failed to remove owner from resource %v with key %s/%s: %w",
+						gvr, resource.Namespace, resource.Name, err))
+					continue
+				}
+				recorder.Eventf("ResourceOrphaned", "Orphaned protected resource %v with key %s/%s because %s.",
+					gvr, resource.Namespace, resource.Name, reason)
+				continue
+			}
+		}
+
 		// delete the resource which is not deleted yet
 		uid := types.UID(resource.UID)
 		err = dynamicClient.
@@ -287,11 +359,34 @@ func DeleteAppliedResources(
 			continue
 		}
 
-		resourcesPendingFinalization = append(resourcesPendingFinalization, resource)
+		deletion.PendingFinalization = append(deletion.PendingFinalization, resource)
 		recorder.Eventf("ResourceDeleted", "Deleted resource %v with key %s/%s because %s.", gvr, resource.Namespace, resource.Name, reason)
 	}
 
-	return resourcesPendingFinalization, errs
+	return deletion, errs
+}
+
+// handleDeletionTimeout handles the resource whose deletion times out with the timeout policy
+func handleDeletionTimeout(ctx context.Context, dynamicClient dynamic.Interface, recorder events.Recorder,
+	gvr schema.GroupVersionResource, obj *unstructured.Unstructured, ownerToRemove metav1.OwnerReference,
+	policy DeletionTimeoutPolicy) error {
+	switch policy {
+	case DeletionTimeoutOrphan:
+		if err := ApplyOwnerReferences(ctx, dynamicClient, gvr, obj, ownerToRemove); err != nil {
+			return fmt.Errorf("failed to remove owner from resource %v with key %s/%s: %w",
+				gvr, obj.GetNamespace(), obj.GetName(), err)
+		}
+		recorder.Warningf("ResourceDeletionTimedOut", "Orphaned resource %v with key %s/%s because its deletion timed out with finalizers %v.",
+			gvr, obj.GetNamespace(), obj.GetName(), obj.GetFinalizers())
+	case DeletionTimeoutRemoveFinalizers:
+		if err := removeFinalizers(ctx, dynamicClient, gvr, obj); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove finalizers from resource %v with key %s/%s: %w",
+				gvr, obj.GetNamespace(), obj.GetName(), err)
+		}
+		recorder.Warningf("ResourceDeletionTimedOut", "Removed finalizers %v from resource %v with key %s/%s because its deletion timed out.",
+			obj.GetFinalizers(), gvr, obj.GetNamespace(), obj.GetName())
+	}
+	return nil
 }
 
 // existOtherAppliedManifestWorkOwners check existingOwners for other appliedManifestWork owners other than myOwner
@@ -0,0 +1,131 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// MaintenanceWindowAnnotationKey is the annotation key on manifestwork to set the maintenance window in which the
+// new generations of the manifestwork are applied, it overrides the maintenance window of the agent. The value is
+// a json of MaintenanceWindowSpec.
+// TODO move this to the api repo
+const MaintenanceWindowAnnotationKey = "work.open-cluster-management.io/maintenance-window"
+
+// ForceDeletionAnnotationKey is the annotation key on manifestwork to delete the resources of the manifestwork
+// outside the maintenance window when the manifestwork is deleted, the value is "true".
+// TODO move this to the api repo
+const ForceDeletionAnnotationKey = "work.open-cluster-management.io/force-deletion"
+
+// WorkPendingWindow is the condition type of manifestwork reporting the changes of the manifestwork are waiting for
+// the next maintenance window.
+const WorkPendingWindow = "PendingWindow"
+
+// MaintenanceWindowSpec defines the maintenance windows, each window starts at the time of the schedule and lasts
+// for the duration.
+type MaintenanceWindowSpec struct {
+	// Schedule is a standard cron expression, e.g. "0 2 * * 6" for 2:00 every Saturday.
+	Schedule string `json:"schedule"`
+	// Duration is the duration of each window, e.g. "4h".
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone is the IANA time zone of the schedule, it is UTC if not set.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// MaintenanceWindow is the parsed MaintenanceWindowSpec
+type MaintenanceWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// NewMaintenanceWindow returns a MaintenanceWindow of the spec
+func NewMaintenanceWindow(spec MaintenanceWindowSpec) (*MaintenanceWindow, error) {
+	schedule, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("the schedule %q of the maintenance window is invalid: %v", spec.Schedule, err)
+	}
+	if spec.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("the duration of the maintenance window should be positive")
+	}
+	location, err := time.LoadLocation(spec.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("the time zone %q of the maintenance window is invalid: %v", spec.TimeZone, err)
+	}
+
+	return &MaintenanceWindow{
+		schedule: schedule,
+		duration: spec.Duration.Duration,
+		location: location,
+	}, nil
+}
+
+// MaintenanceWindowOf returns the maintenance window of the manifestwork, the agentWindow is returned if the
+// manifestwork does not set its own. It returns an error if the annotation is invalid.
+func MaintenanceWindowOf(work *workapiv1.ManifestWork, agentWindow *MaintenanceWindow) (*MaintenanceWindow, error) {
+	value, ok := work.Annotations[MaintenanceWindowAnnotationKey]
+	if !ok {
+		return agentWindow, nil
+	}
+
+	spec := MaintenanceWindowSpec{}
+	if err := json.Unmarshal([]byte(value), &spec); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid json of maintenance window: %v",
+			MaintenanceWindowAnnotationKey, err)
+	}
+	return NewMaintenanceWindow(spec)
+}
+
+// Check returns whether the window is open at now. If it is open, the time when it closes is returned, otherwise
+// the time when the next window opens is returned. A nil MaintenanceWindow is always open.
+func (w *MaintenanceWindow) Check(now time.Time) (bool, time.Time) {
+	if w == nil {
+		return true, time.Time{}
+	}
+
+	// the window is open if a window starts in (now - duration, now]
+	now = now.In(w.location)
+	start := w.schedule.Next(now.Add(-w.duration))
+	if !start.After(now) {
+		return true, start.Add(w.duration)
+	}
+	return false, start
+}
+
+// IsForceDeletion returns true if the resources of the manifestwork are deleted outside the maintenance window
+func IsForceDeletion(work *workapiv1.ManifestWork) bool {
+	return work.Annotations[ForceDeletionAnnotationKey] == "true"
+}
+
+// GenerationApplied returns true if the current generation of the manifestwork has been applied
+func GenerationApplied(work *workapiv1.ManifestWork) bool {
+	condition := meta.FindStatusCondition(work.Status.Conditions, workapiv1.WorkApplied)
+	return condition != nil && condition.ObservedGeneration == work.Generation
+}
+
+// SetPendingWindowCondition returns an UpdateManifestWorkStatusFunc which reports the changes of the manifestwork
+// are waiting for the maintenance window opening at next, the condition is removed if next is zero.
+func SetPendingWindowCondition(generation int64, change string, next time.Time) UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		if next.IsZero() {
+			meta.RemoveStatusCondition(&status.Conditions, WorkPendingWindow)
+			return nil
+		}
+
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               WorkPendingWindow,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "OutsideMaintenanceWindow",
+			Message: fmt.Sprintf("The %s is pending until the next maintenance window at %s",
+				change, next.UTC().Format(time.RFC3339)),
+		})
+		return nil
+	}
+}
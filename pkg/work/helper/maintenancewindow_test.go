@@ -0,0 +1,170 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestMaintenanceWindowCheck(t *testing.T) {
+	// 2:00 - 6:00 every Saturday in Shanghai, which is 18:00 - 22:00 every Friday in UTC
+	window, err := NewMaintenanceWindow(MaintenanceWindowSpec{
+		Schedule: "0 2 * * 6",
+		Duration: metav1.Duration{Duration: 4 * time.Hour},
+		TimeZone: "Asia/Shanghai",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name               string
+		window             *MaintenanceWindow
+		now                time.Time
+		expectedOpen       bool
+		expectedTransition time.Time
+	}{
+		{
+			name:         "nil window",
+			now:          time.Date(2023, time.July, 3, 0, 0, 0, 0, time.UTC),
+			expectedOpen: true,
+		},
+		{
+			name:               "before the window",
+			window:             window,
+			now:                time.Date(2023, time.July, 7, 17, 0, 0, 0, time.UTC),
+			expectedTransition: time.Date(2023, time.July, 7, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:               "at the start of the window",
+			window:             window,
+			now:                time.Date(2023, time.July, 7, 18, 0, 0, 0, time.UTC),
+			expectedOpen:       true,
+			expectedTransition: time.Date(2023, time.July, 7, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:               "in the window",
+			window:             window,
+			now:                time.Date(2023, time.July, 7, 21, 0, 0, 0, time.UTC),
+			expectedOpen:       true,
+			expectedTransition: time.Date(2023, time.July, 7, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:               "after the window",
+			window:             window,
+			now:                time.Date(2023, time.July, 7, 22, 0, 0, 0, time.UTC),
+			expectedTransition: time.Date(2023, time.July, 14, 18, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			open, transition := c.window.Check(c.now)
+			if open != c.expectedOpen || !transition.Equal(c.expectedTransition) {
+				t.Errorf("expected %v %v, but got %v %v", c.expectedOpen, c.expectedTransition, open, transition)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowOf(t *testing.T) {
+	agentWindow, err := NewMaintenanceWindow(MaintenanceWindowSpec{
+		Schedule: "0 2 * * *",
+		Duration: metav1.Duration{Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		annotation       string
+		expectAgent      bool
+		expectErr        bool
+		expectedOpenAt   time.Time
+		expectedClosedAt time.Time
+	}{
+		{
+			name:        "agent window",
+			expectAgent: true,
+		},
+		{
+			name:             "work window overrides the agent window",
+			annotation:       `{"schedule":"0 12 * * *","duration":"2h"}`,
+			expectedOpenAt:   time.Date(2023, time.July, 1, 13, 0, 0, 0, time.UTC),
+			expectedClosedAt: time.Date(2023, time.July, 1, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "invalid json",
+			annotation: `0 12 * * *`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid schedule",
+			annotation: `{"schedule":"every day","duration":"2h"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid time zone",
+			annotation: `{"schedule":"0 12 * * *","duration":"2h","timeZone":"Mars/Olympus"}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				work.Annotations = map[string]string{MaintenanceWindowAnnotationKey: c.annotation}
+			}
+
+			window, err := MaintenanceWindowOf(work, agentWindow)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if c.expectErr {
+				return
+			}
+			if c.expectAgent {
+				if window != agentWindow {
+					t.Errorf("expected the agent window")
+				}
+				return
+			}
+			if open, _ := window.Check(c.expectedOpenAt); !open {
+				t.Errorf("expected the window is open at %v", c.expectedOpenAt)
+			}
+			if open, _ := window.Check(c.expectedClosedAt); open {
+				t.Errorf("expected the window is closed at %v", c.expectedClosedAt)
+			}
+		})
+	}
+}
+
+func TestSetPendingWindowCondition(t *testing.T) {
+	status := &workapiv1.ManifestWorkStatus{}
+	next := time.Date(2023, time.July, 7, 18, 0, 0, 0, time.UTC)
+
+	if err := SetPendingWindowCondition(3, "generation 3", next)(status); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(status.Conditions, WorkPendingWindow)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != 3 {
+		t.Fatalf("unexpected condition %v", condition)
+	}
+	expectedMessage := "The generation 3 is pending until the next maintenance window at 2023-07-07T18:00:00Z"
+	if condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+
+	if err := SetPendingWindowCondition(3, "", time.Time{})(status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Conditions) != 0 {
+		t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+	}
+}
@@ -0,0 +1,88 @@
+package helper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// WorkHubConflict is the condition type of manifestwork reporting the resources of the manifestwork are also
+// applied by the manifestworks of other hubs.
+const WorkHubConflict = "HubConflict"
+
+// HubConflict is a resource of the manifestwork which is also owned by the appliedmanifestworks of other hubs.
+type HubConflict struct {
+	ResourceMeta workapiv1.ManifestResourceMeta
+	// AppliedManifestWorks is the names of the appliedmanifestworks of other hubs owning the resource
+	AppliedManifestWorks []string
+}
+
+// HubControllerName returns the name of a controller processing the manifestworks of a hub. The controllers of
+// the additional hubs are named with the hub name as suffix, so each hub has its own queues and metrics, while the
+// controllers of the default hub, whose name is empty, keep their names.
+func HubControllerName(name, hubName string) string {
+	if len(hubName) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, hubName)
+}
+
+// DefaultHubMetricsLabel is the value of the hub label of the metrics of the default hub
+const DefaultHubMetricsLabel = "default"
+
+// HubMetricsLabel returns the value of the hub label of the metrics of the hub with the hubName, it is
+// DefaultHubMetricsLabel for the default hub whose name is empty.
+func HubMetricsLabel(hubName string) string {
+	if len(hubName) == 0 {
+		return DefaultHubMetricsLabel
+	}
+	return hubName
+}
+
+// ConflictingAppliedManifestWorks returns the names of the appliedmanifestworks of the hubs other than the hubHash
+// which own the obj.
+func ConflictingAppliedManifestWorks(obj metav1.Object, hubHash string) []string {
+	conflicts := []string{}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.APIVersion != workapiv1.GroupVersion.String() || owner.Kind != "AppliedManifestWork" {
+			continue
+		}
+		if strings.HasPrefix(owner.Name, hubHash+"-") {
+			continue
+		}
+		conflicts = append(conflicts, owner.Name)
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// SetHubConflictCondition returns an UpdateManifestWorkStatusFunc which reports the resources also applied by the
+// manifestworks of other hubs, the condition is removed if there is no conflict.
+func SetHubConflictCondition(generation int64, conflicts []HubConflict) UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		if len(conflicts) == 0 {
+			meta.RemoveStatusCondition(&status.Conditions, WorkHubConflict)
+			return nil
+		}
+
+		descriptions := []string{}
+		for _, conflict := range conflicts {
+			descriptions = append(descriptions, fmt.Sprintf("%s/%s/%s is owned by [%s]", conflict.ResourceMeta.Resource,
+				conflict.ResourceMeta.Namespace, conflict.ResourceMeta.Name, strings.Join(conflict.AppliedManifestWorks, ", ")))
+		}
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               WorkHubConflict,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "AppliedByOtherHubs",
+			Message: fmt.Sprintf("%d resources are also applied by the manifestworks of other hubs: %s",
+				len(conflicts), strings.Join(descriptions, "; ")),
+		})
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestHubMetricsLabel(t *testing.T) {
+	if label := HubMetricsLabel(""); label != DefaultHubMetricsLabel {
+		t.Errorf("expected the label of the default hub is %q, but got %q", DefaultHubMetricsLabel, label)
+	}
+	if label := HubMetricsLabel("0123abcd"); label != "0123abcd" {
+		t.Errorf("expected the label is the hub name, but got %q", label)
+	}
+}
+
+func TestHubControllerName(t *testing.T) {
+	if name := HubControllerName("ManifestWorkAgent", ""); name != "ManifestWorkAgent" {
+		t.Errorf("expected the name of the default hub is unchanged, but got %q", name)
+	}
+	if name := HubControllerName("ManifestWorkAgent", "0123abcd"); name != "ManifestWorkAgent-0123abcd" {
+		t.Errorf("expected the name is suffixed with the hub name, but got %q", name)
+	}
+}
+
+func TestConflictingAppliedManifestWorks(t *testing.T) {
+	newOwner := func(apiVersion, kind, name string) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name}
+	}
+
+	obj := &metav1.ObjectMeta{
+		Name: "test",
+		OwnerReferences: []metav1.OwnerReference{
+			newOwner("work.open-cluster-management.io/v1", "AppliedManifestWork", "hub1-work1"),
+			newOwner("work.open-cluster-management.io/v1", "AppliedManifestWork", "hub2-work2"),
+			newOwner("work.open-cluster-management.io/v1", "AppliedManifestWork", "hub3-work1"),
+			newOwner("apps/v1", "Deployment", "hub2-work3"),
+		},
+	}
+
+	conflicts := ConflictingAppliedManifestWorks(obj, "hub1")
+	if expected := []string{"hub2-work2", "hub3-work1"}; !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected conflicts %v, but got %v", expected, conflicts)
+	}
+
+	obj.OwnerReferences = obj.OwnerReferences[:1]
+	if conflicts := ConflictingAppliedManifestWorks(obj, "hub1"); len(conflicts) != 0 {
+		t.Errorf("expected no conflict, but got %v", conflicts)
+	}
+}
+
+func TestSetHubConflictCondition(t *testing.T) {
+	status := &workapiv1.ManifestWorkStatus{}
+	conflicts := []HubConflict{
+		{
+			ResourceMeta:         workapiv1.ManifestResourceMeta{Resource: "secrets", Namespace: "ns1", Name: "n1"},
+			AppliedManifestWorks: []string{"hub2-work2", "hub3-work1"},
+		},
+	}
+
+	if err := SetHubConflictCondition(1, conflicts)(status); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(status.Conditions, WorkHubConflict)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("unexpected condition %v", condition)
+	}
+	expectedMessage := "1 resources are also applied by the manifestworks of other hubs: " +
+		"secrets/ns1/n1 is owned by [hub2-work2, hub3-work1]"
+	if condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+
+	if err := SetHubConflictCondition(1, nil)(status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Conditions) != 0 {
+		t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+	}
+}
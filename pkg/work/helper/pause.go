@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// PausedAnnotationKey is the annotation key on manifestwork to pause the reconciliation of the manifestwork. When
+// the value is "true", the agent stops applying the manifests and deleting the resources of the manifestwork, while
+// the status of the resources is still reported.
+// TODO move this to the api repo
+const PausedAnnotationKey = "work.open-cluster-management.io/paused"
+
+// WorkPaused is the condition type of manifestwork reporting the reconciliation of the manifestwork is paused.
+const WorkPaused = "Paused"
+
+// IsPaused returns true if the reconciliation of the manifestwork is paused
+func IsPaused(work *workapiv1.ManifestWork) bool {
+	return work.Annotations[PausedAnnotationKey] == "true"
+}
+
+// ValidatePaused validates the annotation PausedAnnotationKey of the manifestwork
+func ValidatePaused(work *workapiv1.ManifestWork) error {
+	value, ok := work.Annotations[PausedAnnotationKey]
+	if !ok || value == "true" || value == "false" {
+		return nil
+	}
+	return fmt.Errorf("the annotation %s should be true or false, but got %q", PausedAnnotationKey, value)
+}
+
+// SetPausedCondition returns an UpdateManifestWorkStatusFunc which reports the manifestwork is paused, the
+// condition is removed once the manifestwork is resumed.
+func SetPausedCondition(generation int64, paused bool) UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		if !paused {
+			meta.RemoveStatusCondition(&status.Conditions, WorkPaused)
+			return nil
+		}
+
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               WorkPaused,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "ReconciliationPaused",
+			Message: fmt.Sprintf("The resources are not applied or deleted until the annotation %s is removed, "+
+				"their status is still reported", PausedAnnotationKey),
+		})
+		return nil
+	}
+}
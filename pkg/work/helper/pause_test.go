@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestIsPaused(t *testing.T) {
+	cases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedPaused bool
+		expectedErr    bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:           "paused",
+			annotations:    map[string]string{PausedAnnotationKey: "true"},
+			expectedPaused: true,
+		},
+		{
+			name:        "resumed",
+			annotations: map[string]string{PausedAnnotationKey: "false"},
+		},
+		{
+			name:        "invalid value",
+			annotations: map[string]string{PausedAnnotationKey: "True"},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: c.annotations}}
+			if paused := IsPaused(work); paused != c.expectedPaused {
+				t.Errorf("expected paused %v, but got %v", c.expectedPaused, paused)
+			}
+			if err := ValidatePaused(work); (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestSetPausedCondition(t *testing.T) {
+	status := &workapiv1.ManifestWorkStatus{}
+	if err := SetPausedCondition(3, true)(status); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(status.Conditions, WorkPaused)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != 3 {
+		t.Fatalf("unexpected condition %v", condition)
+	}
+
+	if err := SetPausedCondition(3, false)(status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Conditions) != 0 {
+		t.Errorf("expected the condition is removed, but got %v", status.Conditions)
+	}
+}
@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// RelatedResourcesFeedbackAnnotationKey is the annotation key on manifestwork to return the summarized status of
+// the resources related to the manifest resources as status feedback, e.g. the pods of a deployment. The value is
+// a json list of RelatedResourcesFeedbackRule. The related resources are cached by the agent for a minute, so the
+// feedback could lag behind the related resources by up to a minute.
+// TODO move this to the api repo
+const RelatedResourcesFeedbackAnnotationKey = "work.open-cluster-management.io/related-resources-feedback"
+
+// RelatedResourcesFeedbackRule defines the related resources of a manifest resource whose status is summarized
+// and returned as the status feedback of the manifest resource.
+type RelatedResourcesFeedbackRule struct {
+	// ResourceIdentifier identifies the manifest resource.
+	ResourceIdentifier workapiv1.ResourceIdentifier `json:"resourceIdentifier"`
+
+	// RelatedResources is the list of the related resources. A related resource without label selector is the
+	// resource owned by the manifest resource or the related resources before it in the list, so the pods of a
+	// deployment are selected by the related resources of replicasets and then pods.
+	RelatedResources []RelatedResource `json:"relatedResources"`
+}
+
+// RelatedResource selects the related resources in the namespace of the manifest resource.
+type RelatedResource struct {
+	// Name is the prefix of the names of the feedback values, it is the resource if not set.
+	Name string `json:"name,omitempty"`
+
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+
+	// LabelSelector selects the related resources by labels. If it is not set, the resources owned by the manifest
+	// resource or the related resources before this one are selected.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// TopN is the number of the most frequent failure reasons returned, it is 3 if not set.
+	TopN int `json:"topN,omitempty"`
+}
+
+// RelatedResourcesFeedbackRules returns the related resources feedback rules of the manifestwork, it returns an
+// error if the annotation is invalid.
+func RelatedResourcesFeedbackRules(work *workapiv1.ManifestWork) ([]RelatedResourcesFeedbackRule, error) {
+	value, ok := work.Annotations[RelatedResourcesFeedbackAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rules := []RelatedResourcesFeedbackRule{}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid json list of related resources feedback rules: %v",
+			RelatedResourcesFeedbackAnnotationKey, err)
+	}
+
+	for _, rule := range rules {
+		if len(rule.ResourceIdentifier.Resource) == 0 || len(rule.ResourceIdentifier.Name) == 0 {
+			return nil, fmt.Errorf("the resource and name of the resource identifier are required in the annotation %s",
+				RelatedResourcesFeedbackAnnotationKey)
+		}
+		for _, related := range rule.RelatedResources {
+			if len(related.Version) == 0 || len(related.Resource) == 0 {
+				return nil, fmt.Errorf("the version and resource of the related resources of %s %s/%s are required",
+					rule.ResourceIdentifier.Resource, rule.ResourceIdentifier.Namespace, rule.ResourceIdentifier.Name)
+			}
+			if _, err := labels.Parse(related.LabelSelector); err != nil {
+				return nil, fmt.Errorf("the label selector of the related resources %s is invalid: %v", related.Resource, err)
+			}
+			if related.TopN < 0 {
+				return nil, fmt.Errorf("the topN of the related resources %s should not be negative", related.Resource)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// FindRelatedResourcesFeedbackRule returns the related resources feedback rule of the manifest resource, it
+// returns nil if it is not found.
+func FindRelatedResourcesFeedbackRule(resourceMeta workapiv1.ManifestResourceMeta,
+	rules []RelatedResourcesFeedbackRule) *RelatedResourcesFeedbackRule {
+	identifier := workapiv1.ResourceIdentifier{
+		Group:     resourceMeta.Group,
+		Resource:  resourceMeta.Resource,
+		Namespace: resourceMeta.Namespace,
+		Name:      resourceMeta.Name,
+	}
+
+	for _, rule := range rules {
+		if rule.ResourceIdentifier == identifier {
+			return &rule
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestRelatedResourcesFeedbackRules(t *testing.T) {
+	deployment := workapiv1.ResourceIdentifier{Group: "apps", Resource: "deployments", Namespace: "ns1", Name: "deploy1"}
+
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedRules []RelatedResourcesFeedbackRule
+		expectErr     bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name: "valid rules",
+			annotations: map[string]string{RelatedResourcesFeedbackAnnotationKey: `[{
+				"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"deploy1"},
+				"relatedResources":[
+					{"group":"apps","version":"v1","resource":"replicasets"},
+					{"version":"v1","resource":"pods","topN":5}
+				]}]`},
+			expectedRules: []RelatedResourcesFeedbackRule{
+				{
+					ResourceIdentifier: deployment,
+					RelatedResources: []RelatedResource{
+						{Group: "apps", Version: "v1", Resource: "replicasets"},
+						{Version: "v1", Resource: "pods", TopN: 5},
+					},
+				},
+			},
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{RelatedResourcesFeedbackAnnotationKey: `{`},
+			expectErr:   true,
+		},
+		{
+			name: "no resource identifier",
+			annotations: map[string]string{RelatedResourcesFeedbackAnnotationKey: `[{
+				"relatedResources":[{"version":"v1","resource":"pods"}]}]`},
+			expectErr: true,
+		},
+		{
+			name: "no related resource version",
+			annotations: map[string]string{RelatedResourcesFeedbackAnnotationKey: `[{
+				"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"deploy1"},
+				"relatedResources":[{"resource":"pods"}]}]`},
+			expectErr: true,
+		},
+		{
+			name: "invalid label selector",
+			annotations: map[string]string{RelatedResourcesFeedbackAnnotationKey: `[{
+				"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"deploy1"},
+				"relatedResources":[{"version":"v1","resource":"pods","labelSelector":"app in ("}]}]`},
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Annotations: c.annotations}}
+			rules, err := RelatedResourcesFeedbackRules(work)
+			if c.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if !reflect.DeepEqual(rules, c.expectedRules) {
+				t.Errorf("expected rules %v, but got %v", c.expectedRules, rules)
+			}
+		})
+	}
+}
+
+func TestFindRelatedResourcesFeedbackRule(t *testing.T) {
+	rules := []RelatedResourcesFeedbackRule{
+		{
+			ResourceIdentifier: workapiv1.ResourceIdentifier{Group: "apps", Resource: "deployments", Namespace: "ns1", Name: "deploy1"},
+			RelatedResources:   []RelatedResource{{Version: "v1", Resource: "pods", LabelSelector: "app=test"}},
+		},
+	}
+
+	rule := FindRelatedResourcesFeedbackRule(workapiv1.ManifestResourceMeta{
+		Group: "apps", Version: "v1", Kind: "Deployment", Resource: "deployments", Namespace: "ns1", Name: "deploy1",
+	}, rules)
+	if rule == nil || !reflect.DeepEqual(*rule, rules[0]) {
+		t.Errorf("expected rule %v, but got %v", rules[0], rule)
+	}
+
+	rule = FindRelatedResourcesFeedbackRule(workapiv1.ManifestResourceMeta{
+		Group: "apps", Version: "v1", Kind: "Deployment", Resource: "deployments", Namespace: "ns1", Name: "deploy2",
+	}, rules)
+	if rule != nil {
+		t.Errorf("expected no rule, but got %v", rule)
+	}
+}
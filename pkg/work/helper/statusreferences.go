@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// StatusReferencesAnnotationKey is the annotation key on manifestwork to report the status of the resources on
+// the managed cluster which are not applied by the manifestwork, e.g. the nodes. The value is a json list of
+// StatusReference. The executor of the manifestwork must be allowed to get the referenced resources, and the secrets
+// can only be referenced by a manifestwork with an executor.
+// TODO move this to the api repo
+const StatusReferencesAnnotationKey = "work.open-cluster-management.io/status-references"
+
+// WatchOnlyOrdinal is the ordinal of the manifest conditions of the status references in the manifestwork status,
+// since the referenced resources are not the manifests in the spec. The work agent never applies, owns or deletes
+// the referenced resources.
+const WatchOnlyOrdinal int32 = -1
+
+// StatusReference references a resource on the managed cluster whose status is returned with the feedback rules.
+type StatusReference struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+
+	// FeedbackRules defines what status of the referenced resource is returned.
+	FeedbackRules []workapiv1.FeedbackRule `json:"feedbackRules,omitempty"`
+}
+
+// ResourceIdentifier returns the resource identifier of the referenced resource
+func (r StatusReference) ResourceIdentifier() workapiv1.ResourceIdentifier {
+	return workapiv1.ResourceIdentifier{
+		Group:     r.Group,
+		Resource:  r.Resource,
+		Namespace: r.Namespace,
+		Name:      r.Name,
+	}
+}
+
+// StatusReferences returns the status references of the manifestwork, it returns an error if the annotation is
+// invalid.
+func StatusReferences(work *workapiv1.ManifestWork) ([]StatusReference, error) {
+	value, ok := work.Annotations[StatusReferencesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	references := []StatusReference{}
+	if err := json.Unmarshal([]byte(value), &references); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid json list of status references: %v",
+			StatusReferencesAnnotationKey, err)
+	}
+
+	identifiers := map[workapiv1.ResourceIdentifier]bool{}
+	for _, reference := range references {
+		if len(reference.Version) == 0 || len(reference.Resource) == 0 || len(reference.Name) == 0 {
+			return nil, fmt.Errorf("the version, resource and name of the status references are required in the annotation %s",
+				StatusReferencesAnnotationKey)
+		}
+		identifier := reference.ResourceIdentifier()
+		if identifiers[identifier] {
+			return nil, fmt.Errorf("the status reference %s %s/%s is duplicated", reference.Resource, reference.Namespace, reference.Name)
+		}
+		identifiers[identifier] = true
+	}
+
+	return references, nil
+}
+
+// IsWatchOnly returns whether the manifest condition is of a status reference rather than a manifest in the spec.
+func IsWatchOnly(manifest workapiv1.ManifestCondition) bool {
+	return manifest.ResourceMeta.Ordinal == WatchOnlyOrdinal
+}
@@ -0,0 +1,75 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestStatusReferences(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		expectedReferences []StatusReference
+		expectErr          bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name: "valid references",
+			annotations: map[string]string{StatusReferencesAnnotationKey: `[
+				{"version":"v1","resource":"nodes","name":"node1","feedbackRules":[{"type":"JSONPaths","jsonPaths":[{"name":"ready","path":".status.conditions[?(@.type==\"Ready\")].status"}]}]},
+				{"group":"cert-manager.io","version":"v1","resource":"certificates","namespace":"ns1","name":"cert1"}
+			]`},
+			expectedReferences: []StatusReference{
+				{
+					Version:  "v1",
+					Resource: "nodes",
+					Name:     "node1",
+					FeedbackRules: []workapiv1.FeedbackRule{
+						{
+							Type:      workapiv1.JSONPathsType,
+							JsonPaths: []workapiv1.JsonPath{{Name: "ready", Path: `.status.conditions[?(@.type=="Ready")].status`}},
+						},
+					},
+				},
+				{Group: "cert-manager.io", Version: "v1", Resource: "certificates", Namespace: "ns1", Name: "cert1"},
+			},
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{StatusReferencesAnnotationKey: `[`},
+			expectErr:   true,
+		},
+		{
+			name:        "no name",
+			annotations: map[string]string{StatusReferencesAnnotationKey: `[{"version":"v1","resource":"nodes"}]`},
+			expectErr:   true,
+		},
+		{
+			name: "duplicated references",
+			annotations: map[string]string{StatusReferencesAnnotationKey: `[
+				{"version":"v1","resource":"nodes","name":"node1"},
+				{"version":"v1","resource":"nodes","name":"node1"}
+			]`},
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Annotations: c.annotations}}
+			references, err := StatusReferences(work)
+			if c.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if !reflect.DeepEqual(references, c.expectedReferences) {
+				t.Errorf("expected references %v, but got %v", c.expectedReferences, references)
+			}
+		})
+	}
+}
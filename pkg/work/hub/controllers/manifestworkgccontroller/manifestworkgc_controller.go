@@ -0,0 +1,361 @@
+package manifestworkgccontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	workinformerv1alpha1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1alpha1"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	worklisterv1alpha1 "open-cluster-management.io/api/client/work/listers/work/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/hub/controllers/manifestworkreplicasetcontroller"
+	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+)
+
+const (
+	// CompletedSinceAnnotationKey is the annotation key on the manifestwork set by the garbage collection controller,
+	// the value is the time in RFC3339 format since when the manifestwork is observed completed.
+	// TODO move this to the api repo
+	CompletedSinceAnnotationKey = "work.open-cluster-management.io/completed-since"
+
+	// JobCompleteFeedbackName is the name of the well-known status feedback of the jobs, which is the status of their
+	// condition Complete
+	JobCompleteFeedbackName = "JobComplete"
+
+	// ManifestWorkConditionGarbageCollectable is the condition type of the manifestwork set in the dry run mode
+	// which is true if the manifestwork would be deleted by a garbage collection policy.
+	// TODO move this to the api repo
+	ManifestWorkConditionGarbageCollectable = "GarbageCollectable"
+
+	ReasonOrphanedReplicaSet  = "OrphanedReplicaSet"
+	ReasonCompletedTTLExpired = "CompletedTTLExpired"
+	ReasonClusterUnreachable  = "ClusterUnreachable"
+
+	controllerName = "ManifestWorkGarbageCollectionController"
+)
+
+// Policies decides which manifestworks on the hub are garbage collected, all the policies are disabled by default.
+type Policies struct {
+	// OrphanedReplicaSet deletes the manifestworks whose manifestworkreplicaset does not exist anymore
+	OrphanedReplicaSet bool
+	// CompletedTTL deletes the manifestworks which have been completed for the duration, it is disabled if not
+	// positive. A manifestwork is completed once all its jobs are complete, which is reported by the well-known
+	// status feedback of the jobs. The manifestworks of the manifestworkreplicasets are left to the
+	// manifestworkreplicaset controller.
+	CompletedTTL time.Duration
+	// UnreachableClusterPeriod deletes the manifestworks of the clusters which have been unavailable for the
+	// duration and removes the finalizer of the work agent from them, it is disabled if not positive. The
+	// manifestworks of the manifestworkreplicasets are left to the manifestworkreplicaset controller.
+	UnreachableClusterPeriod time.Duration
+	// DryRun reports the manifestworks to delete with the events and the condition GarbageCollectable on the
+	// manifestworks rather than deleting them
+	DryRun bool
+}
+
+// Enabled returns true if any policy is enabled
+func (p Policies) Enabled() bool {
+	return p.OrphanedReplicaSet || p.CompletedTTL > 0 || p.UnreachableClusterPeriod > 0
+}
+
+// manifestWorkGarbageCollectionController deletes the manifestworks left on the hub periodically with the policies
+type manifestWorkGarbageCollectionController struct {
+	workClient                   workclientset.Interface
+	manifestWorkLister           worklisterv1.ManifestWorkLister
+	manifestWorkReplicaSetLister worklisterv1alpha1.ManifestWorkReplicaSetLister
+	clusterLister                clusterlisterv1.ManagedClusterLister
+	policies                     Policies
+	recorder                     events.Recorder
+	clock                        clock.Clock
+}
+
+// NewManifestWorkGarbageCollectionController returns the controller collecting the manifestworks with the policies
+// every interval. The controller only syncs after the informers are synced, so the manifestworkreplicasets not
+// in the cache yet are not taken as deleted.
+func NewManifestWorkGarbageCollectionController(
+	recorder events.Recorder,
+	workClient workclientset.Interface,
+	manifestWorkInformer workinformerv1.ManifestWorkInformer,
+	manifestWorkReplicaSetInformer workinformerv1alpha1.ManifestWorkReplicaSetInformer,
+	managedClusterInformer clusterinformerv1.ManagedClusterInformer,
+	policies Policies,
+	interval time.Duration) factory.Controller {
+	controller := &manifestWorkGarbageCollectionController{
+		workClient:                   workClient,
+		manifestWorkLister:           manifestWorkInformer.Lister(),
+		manifestWorkReplicaSetLister: manifestWorkReplicaSetInformer.Lister(),
+		clusterLister:                managedClusterInformer.Lister(),
+		policies:                     policies,
+		recorder:                     recorder,
+		clock:                        clock.RealClock{},
+	}
+
+	return factory.New().
+		WithBareInformers(manifestWorkInformer.Informer(), manifestWorkReplicaSetInformer.Informer(), managedClusterInformer.Informer()).
+		WithSync(controller.sync).
+		ResyncEvery(interval).
+		ToController(controllerName, recorder)
+}
+
+func (c *manifestWorkGarbageCollectionController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	manifestWorks, err := c.manifestWorkLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	now := c.clock.Now()
+	errs := []error{}
+	collected := 0
+	for _, mw := range manifestWorks {
+		if !mw.DeletionTimestamp.IsZero() {
+			if err := c.release(ctx, mw, now); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		reason, message, err := c.collectable(ctx, mw, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(reason) > 0 {
+			collected++
+		}
+		if err := c.collect(ctx, mw, reason, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	klog.V(4).Infof("%d of %d manifestworks are garbage collected with dry run %v", collected, len(manifestWorks), c.policies.DryRun)
+	return utilerrors.NewAggregate(errs)
+}
+
+// collectable returns the reason and the message if the manifestwork is collected by any policy, or empty if not
+func (c *manifestWorkGarbageCollectionController) collectable(ctx context.Context, mw *workapiv1.ManifestWork,
+	now time.Time) (string, string, error) {
+	if key, ok := mw.Labels[manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey]; ok && c.policies.OrphanedReplicaSet {
+		namespace, name, found := strings.Cut(key, ".")
+		if found {
+			_, err := c.manifestWorkReplicaSetLister.ManifestWorkReplicaSets(namespace).Get(name)
+			switch {
+			case errors.IsNotFound(err):
+				return ReasonOrphanedReplicaSet, fmt.Sprintf("the manifestworkreplicaset %s/%s does not exist", namespace, name), nil
+			case err != nil:
+				return "", "", err
+			}
+		}
+	}
+
+	if c.policies.CompletedTTL > 0 && !ownedByReplicaSet(mw) {
+		since, err := c.completedSince(ctx, mw, now)
+		if err != nil {
+			return "", "", err
+		}
+		if !since.IsZero() && !since.Add(c.policies.CompletedTTL).After(now) {
+			return ReasonCompletedTTLExpired, fmt.Sprintf("the manifestwork has been completed since %s for longer than %v",
+				since.UTC().Format(time.RFC3339), c.policies.CompletedTTL), nil
+		}
+	}
+
+	message, err := c.unreachable(mw, now)
+	if err != nil || len(message) == 0 {
+		return "", "", err
+	}
+	return ReasonClusterUnreachable, message, nil
+}
+
+// ownedByReplicaSet returns true if the manifestwork is of a manifestworkreplicaset. These manifestworks are not
+// collected by the CompletedTTL and the UnreachableClusterPeriod, since the manifestworkreplicaset controller would
+// create them again and their jobs would run again.
+func ownedByReplicaSet(mw *workapiv1.ManifestWork) bool {
+	_, ok := mw.Labels[manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey]
+	return ok
+}
+
+// completedSince returns the time since when the manifestwork is completed, or zero if it is not completed. The
+// time is recorded in the annotation of the manifestwork once it is observed completed, and the annotation is
+// removed if the manifestwork is not completed anymore, e.g. its jobs are changed.
+func (c *manifestWorkGarbageCollectionController) completedSince(ctx context.Context, mw *workapiv1.ManifestWork,
+	now time.Time) (time.Time, error) {
+	value, stamped := mw.Annotations[CompletedSinceAnnotationKey]
+	if !completed(mw) {
+		if stamped {
+			return time.Time{}, c.patchCompletedSince(ctx, mw, nil)
+		}
+		return time.Time{}, nil
+	}
+
+	if since, err := time.Parse(time.RFC3339, value); stamped && err == nil {
+		return since, nil
+	}
+	return now, c.patchCompletedSince(ctx, mw, now.UTC().Format(time.RFC3339))
+}
+
+func (c *manifestWorkGarbageCollectionController) patchCompletedSince(ctx context.Context, mw *workapiv1.ManifestWork, since interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{CompletedSinceAnnotationKey: since},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.workClient.WorkV1().ManifestWorks(mw.Namespace).Patch(ctx, mw.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// completed returns true if the manifestwork applied with its current spec has jobs and all of them are complete.
+// The jobs are only known complete with their well-known status feedback, so the manifestwork should have the
+// status feedback rule of the type WellKnownStatus for its jobs.
+func completed(mw *workapiv1.ManifestWork) bool {
+	applied := apimeta.FindStatusCondition(mw.Status.Conditions, workapiv1.WorkApplied)
+	if applied == nil || applied.ObservedGeneration != mw.Generation {
+		return false
+	}
+
+	jobs := 0
+	for _, manifest := range mw.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta.Group != "batch" || manifest.ResourceMeta.Kind != "Job" {
+			continue
+		}
+		jobs++
+		complete := false
+		for _, value := range manifest.StatusFeedbacks.Values {
+			if value.Name == JobCompleteFeedbackName && value.Value.String != nil {
+				complete = *value.Value.String == string(metav1.ConditionTrue)
+			}
+		}
+		if !complete {
+			return false
+		}
+	}
+	return jobs > 0
+}
+
+// unreachable returns the message if the cluster of the manifestwork has been unavailable for longer than the
+// UnreachableClusterPeriod, or empty if not. The manifestworks of the manifestworkreplicasets are skipped.
+func (c *manifestWorkGarbageCollectionController) unreachable(mw *workapiv1.ManifestWork, now time.Time) (string, error) {
+	if c.policies.UnreachableClusterPeriod <= 0 {
+		return "", nil
+	}
+	if ownedByReplicaSet(mw) {
+		return "", nil
+	}
+
+	cluster, err := c.clusterLister.Get(mw.Namespace)
+	switch {
+	case errors.IsNotFound(err):
+		// the namespace is not of a cluster, or the cluster is detached and its namespace is being cleaned up
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	condition := apimeta.FindStatusCondition(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
+	if condition == nil || condition.Status == metav1.ConditionTrue ||
+		condition.LastTransitionTime.Add(c.policies.UnreachableClusterPeriod).After(now) {
+		return "", nil
+	}
+	return fmt.Sprintf("the cluster %s has been unavailable since %s for longer than %v",
+		cluster.Name, condition.LastTransitionTime.UTC().Format(time.RFC3339), c.policies.UnreachableClusterPeriod), nil
+}
+
+// release removes the finalizer of the work agent from the deleting manifestwork of the unreachable cluster, since
+// the agent cannot remove it until the cluster is available again and the manifestwork would be kept deleting.
+// The resources of the manifestwork are left on the cluster, they are removed by the agent with the
+// appliedmanifestwork which is no longer on the hub once the cluster is available again.
+func (c *manifestWorkGarbageCollectionController) release(ctx context.Context, mw *workapiv1.ManifestWork, now time.Time) error {
+	if !helper.HasFinalizer(mw.Finalizers, controllers.ManifestWorkFinalizer) {
+		return nil
+	}
+	message, err := c.unreachable(mw, now)
+	if err != nil || len(message) == 0 {
+		return err
+	}
+	if c.policies.DryRun {
+		return c.collect(ctx, mw, ReasonClusterUnreachable, message)
+	}
+
+	released := mw.DeepCopy()
+	helper.RemoveFinalizer(released, controllers.ManifestWorkFinalizer)
+	_, err = c.workClient.WorkV1().ManifestWorks(mw.Namespace).Update(ctx, released, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.recorder.Eventf("ManifestWorkReleased", "the finalizer %s is removed from the deleting manifestwork %s/%s: %s",
+		controllers.ManifestWorkFinalizer, mw.Namespace, mw.Name, message)
+	return nil
+}
+
+// collect deletes the manifestwork collected with the reason, or reports it with the condition GarbageCollectable in
+// the dry run mode. The condition is removed if the manifestwork is not collected anymore.
+func (c *manifestWorkGarbageCollectionController) collect(ctx context.Context, mw *workapiv1.ManifestWork, reason, message string) error {
+	if len(reason) > 0 && !c.policies.DryRun {
+		err := c.workClient.WorkV1().ManifestWorks(mw.Namespace).Delete(ctx, mw.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &mw.UID},
+		})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.recorder.Eventf("ManifestWorkGarbageCollected", "manifestwork %s/%s is deleted: %s", mw.Namespace, mw.Name, message)
+		return nil
+	}
+
+	existing := apimeta.FindStatusCondition(mw.Status.Conditions, ManifestWorkConditionGarbageCollectable)
+	if len(reason) == 0 && existing == nil {
+		return nil
+	}
+	if len(reason) > 0 && existing != nil && existing.Reason == reason {
+		return nil
+	}
+
+	_, _, err := helper.UpdateManifestWorkStatus(ctx, c.workClient.WorkV1().ManifestWorks(mw.Namespace), mw.DeepCopy(),
+		func(status *workapiv1.ManifestWorkStatus) error {
+			if len(reason) == 0 {
+				apimeta.RemoveStatusCondition(&status.Conditions, ManifestWorkConditionGarbageCollectable)
+				return nil
+			}
+			apimeta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:               ManifestWorkConditionGarbageCollectable,
+				Status:             metav1.ConditionTrue,
+				Reason:             reason,
+				Message:            message,
+				ObservedGeneration: mw.Generation,
+			})
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	if len(reason) > 0 {
+		c.recorder.Warningf("ManifestWorkGarbageCollectable", "manifestwork %s/%s would be deleted in the dry run mode: %s",
+			mw.Namespace, mw.Name, message)
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+package manifestworkgccontroller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/hub/controllers/manifestworkreplicasetcontroller"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+)
+
+func TestSync(t *testing.T) {
+	now := time.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	dayAgo := metav1.NewTime(now.Add(-24 * time.Hour))
+
+	newWork := func(namespace string, mutate func(mw *workapiv1.ManifestWork)) *workapiv1.ManifestWork {
+		mw := &workapiv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{Name: "work", Namespace: namespace, UID: "uid"},
+		}
+		if mutate != nil {
+			mutate(mw)
+		}
+		return mw
+	}
+	ownedBy := func(key string) func(mw *workapiv1.ManifestWork) {
+		return func(mw *workapiv1.ManifestWork) {
+			mw.Labels = map[string]string{manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey: key}
+		}
+	}
+	withCondition := func(condition metav1.Condition) func(mw *workapiv1.ManifestWork) {
+		return func(mw *workapiv1.ManifestWork) {
+			mw.Status.Conditions = append(mw.Status.Conditions, condition)
+		}
+	}
+	// withJobs adds the jobs with the status feedback JobComplete to the applied work completed since the time
+	withJobs := func(since *metav1.Time, completes ...string) func(mw *workapiv1.ManifestWork) {
+		return func(mw *workapiv1.ManifestWork) {
+			mw.Generation = 1
+			if since != nil {
+				mw.Annotations = map[string]string{CompletedSinceAnnotationKey: since.UTC().Format(time.RFC3339)}
+			}
+			mw.Status.Conditions = []metav1.Condition{{Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, ObservedGeneration: 1}}
+			mw.Status.ResourceStatus.Manifests = []workapiv1.ManifestCondition{{
+				ResourceMeta: workapiv1.ManifestResourceMeta{Version: "v1", Kind: "ConfigMap", Name: "config"},
+			}}
+			for i := range completes {
+				mw.Status.ResourceStatus.Manifests = append(mw.Status.ResourceStatus.Manifests, workapiv1.ManifestCondition{
+					ResourceMeta: workapiv1.ManifestResourceMeta{Group: "batch", Version: "v1", Kind: "Job", Name: fmt.Sprintf("job%d", i)},
+					StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: []workapiv1.FeedbackValue{{
+						Name:  JobCompleteFeedbackName,
+						Value: workapiv1.FieldValue{Type: workapiv1.String, String: &completes[i]},
+					}}},
+				})
+			}
+		}
+	}
+	deletingWithFinalizers := func(finalizers ...string) func(mw *workapiv1.ManifestWork) {
+		return func(mw *workapiv1.ManifestWork) {
+			mw.DeletionTimestamp = &dayAgo
+			mw.Finalizers = finalizers
+		}
+	}
+	newCluster := func(name string, status metav1.ConditionStatus, since metav1.Time) *clusterv1.ManagedCluster {
+		return &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: clusterv1.ManagedClusterStatus{
+				Conditions: []metav1.Condition{
+					{Type: clusterv1.ManagedClusterConditionAvailable, Status: status, LastTransitionTime: since},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		policies        Policies
+		work            *workapiv1.ManifestWork
+		clusters        []runtime.Object
+		validateActions func(t *testing.T, actions []clienttesting.Action)
+	}{
+		{
+			name:     "work not collected",
+			policies: Policies{OrphanedReplicaSet: true, CompletedTTL: time.Hour, UnreachableClusterPeriod: time.Hour},
+			work:     newWork("cluster1", nil),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionTrue, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work of existing replicaset",
+			policies: Policies{OrphanedReplicaSet: true},
+			work:     newWork("cluster1", ownedBy("default.mwrs")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work of deleted replicaset",
+			policies: Policies{OrphanedReplicaSet: true},
+			work:     newWork("cluster1", ownedBy("default.deleted")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "delete")
+				testingcommon.AssertDelete(t, actions[0], "manifestworks", "cluster1", "work")
+				deleteAction := actions[0].(clienttesting.DeleteActionImpl)
+				if uid := deleteAction.DeleteOptions.Preconditions.UID; uid == nil || *uid != "uid" {
+					t.Errorf("expected the uid precondition, but got %v", deleteAction.DeleteOptions.Preconditions)
+				}
+			},
+		},
+		{
+			name:     "work of deleted replicaset with the policy disabled",
+			policies: Policies{CompletedTTL: time.Hour},
+			work:     newWork("cluster1", ownedBy("default.deleted")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work being deleted",
+			policies: Policies{OrphanedReplicaSet: true},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				ownedBy("default.deleted")(mw)
+				mw.DeletionTimestamp = &hourAgo
+			}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work completed first observed",
+			policies: Policies{CompletedTTL: 30 * time.Minute},
+			work:     newWork("cluster1", withJobs(nil, "True", "True")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "patch")
+				patch := string(actions[0].(clienttesting.PatchActionImpl).Patch)
+				if expected := now.UTC().Format(time.RFC3339); !strings.Contains(patch, expected) {
+					t.Errorf("expected the work completed since %s, but got %s", expected, patch)
+				}
+			},
+		},
+		{
+			name:     "work completed within ttl",
+			policies: Policies{CompletedTTL: 2 * time.Hour},
+			work:     newWork("cluster1", withJobs(&hourAgo, "True")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work completed over ttl",
+			policies: Policies{CompletedTTL: 30 * time.Minute},
+			work:     newWork("cluster1", withJobs(&hourAgo, "True", "True")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "delete")
+			},
+		},
+		{
+			name:     "work of replicaset completed over ttl",
+			policies: Policies{OrphanedReplicaSet: true, CompletedTTL: 30 * time.Minute},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				ownedBy("default.mwrs")(mw)
+				withJobs(&hourAgo, "True", "True")(mw)
+			}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work with a job not complete",
+			policies: Policies{CompletedTTL: 30 * time.Minute},
+			work:     newWork("cluster1", withJobs(nil, "True", "False")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "work not completed anymore",
+			policies: Policies{CompletedTTL: 30 * time.Minute},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				withJobs(&hourAgo, "True")(mw)
+				// the spec is changed and not applied yet
+				mw.Generation = 2
+			}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "patch")
+				if patch := string(actions[0].(clienttesting.PatchActionImpl).Patch); !strings.Contains(patch, "null") {
+					t.Errorf("expected the completed since annotation removed, but got %s", patch)
+				}
+			},
+		},
+		{
+			name:     "work without jobs",
+			policies: Policies{CompletedTTL: 30 * time.Minute},
+			work:     newWork("cluster1", withJobs(nil)),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "cluster unavailable within period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", nil),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, hourAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "cluster unavailable over period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", nil),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "delete")
+			},
+		},
+		{
+			name:     "work of replicaset on cluster unavailable over period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", ownedBy("default.mwrs")),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "deleting work with the agent finalizer on cluster unavailable over period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", deletingWithFinalizers(controllers.ManifestWorkFinalizer, "test")),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+				if !reflect.DeepEqual(work.Finalizers, []string{"test"}) {
+					t.Errorf("expected the agent finalizer removed, but got %v", work.Finalizers)
+				}
+			},
+		},
+		{
+			name:     "deleting work with the agent finalizer on cluster unavailable within period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", deletingWithFinalizers(controllers.ManifestWorkFinalizer)),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, hourAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "deleting work of replicaset with the agent finalizer on cluster unavailable over period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				ownedBy("default.mwrs")(mw)
+				deletingWithFinalizers(controllers.ManifestWorkFinalizer)(mw)
+			}),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "dry run with deleting work with the agent finalizer on cluster unavailable over period",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour, DryRun: true},
+			work:     newWork("cluster1", deletingWithFinalizers(controllers.ManifestWorkFinalizer)),
+			clusters: []runtime.Object{newCluster("cluster1", metav1.ConditionUnknown, dayAgo)},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+				condition := apimeta.FindStatusCondition(work.Status.Conditions, ManifestWorkConditionGarbageCollectable)
+				if condition == nil || condition.Reason != ReasonClusterUnreachable || len(work.Finalizers) != 1 {
+					t.Errorf("expected the work reported with the finalizer kept, but got %v, %v", condition, work.Finalizers)
+				}
+			},
+		},
+		{
+			name:     "cluster not found",
+			policies: Policies{UnreachableClusterPeriod: 2 * time.Hour},
+			work:     newWork("cluster1", nil),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "dry run",
+			policies: Policies{OrphanedReplicaSet: true, DryRun: true},
+			work:     newWork("cluster1", ownedBy("default.deleted")),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+				condition := apimeta.FindStatusCondition(work.Status.Conditions, ManifestWorkConditionGarbageCollectable)
+				if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonOrphanedReplicaSet {
+					t.Errorf("unexpected condition %v", condition)
+				}
+			},
+		},
+		{
+			name:     "dry run with the work reported",
+			policies: Policies{OrphanedReplicaSet: true, DryRun: true},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				ownedBy("default.deleted")(mw)
+				withCondition(metav1.Condition{Type: ManifestWorkConditionGarbageCollectable,
+					Status: metav1.ConditionTrue, Reason: ReasonOrphanedReplicaSet})(mw)
+			}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+		},
+		{
+			name:     "dry run with the work not collected anymore",
+			policies: Policies{OrphanedReplicaSet: true, DryRun: true},
+			work: newWork("cluster1", func(mw *workapiv1.ManifestWork) {
+				ownedBy("default.mwrs")(mw)
+				withCondition(metav1.Condition{Type: ManifestWorkConditionGarbageCollectable,
+					Status: metav1.ConditionTrue, Reason: ReasonOrphanedReplicaSet})(mw)
+			}),
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+				if apimeta.FindStatusCondition(work.Status.Conditions, ManifestWorkConditionGarbageCollectable) != nil {
+					t.Errorf("expected the condition removed, but got %v", work.Status.Conditions)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrs", "default", "placement")
+			workClient := workfake.NewSimpleClientset(c.work, mwrSet)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 5*time.Minute)
+			if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(c.work); err != nil {
+				t.Fatal(err)
+			}
+			if err := workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets().Informer().GetStore().Add(mwrSet); err != nil {
+				t.Fatal(err)
+			}
+
+			clusterClient := clusterfake.NewSimpleClientset(c.clusters...)
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(clusterClient, 5*time.Minute)
+			for _, cluster := range c.clusters {
+				if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			controller := &manifestWorkGarbageCollectionController{
+				workClient:                   workClient,
+				manifestWorkLister:           workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				manifestWorkReplicaSetLister: workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets().Lister(),
+				clusterLister:                clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+				policies:                     c.policies,
+				recorder:                     events.NewInMemoryRecorder("test"),
+				clock:                        testingclock.NewFakeClock(now),
+			}
+
+			workClient.ClearActions()
+			if err := controller.sync(context.TODO(), testingcommon.NewFakeSyncContext(t, "")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			c.validateActions(t, workClient.Actions())
+		})
+	}
+}
+
+func TestPoliciesEnabled(t *testing.T) {
+	if (Policies{DryRun: true}).Enabled() {
+		t.Errorf("expected the policies disabled")
+	}
+	if !(Policies{CompletedTTL: time.Minute}).Enabled() {
+		t.Errorf("expected the policies enabled")
+	}
+}
@@ -0,0 +1,285 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/utils/clock"
+
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+)
+
+const (
+	// ManifestWorkReplicaSetConditionAnalysisPassed is the condition type of ManifestWorkReplicaSet reporting the
+	// result of the analysis of the clusters rolled out to the current template revision.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionAnalysisPassed = "AnalysisPassed"
+
+	ReasonAnalysisPassed     = "Passed"
+	ReasonAnalysisBaking     = "Baking"
+	ReasonAnalysisFailed     = "Failed"
+	ReasonAnalysisRolledBack = "RolledBack"
+)
+
+// analysisReconciler analyzes the status feedback of the clusters rolled out to the current template revision
+// once their bake period passes, and rolls back the failed clusters to the last revision passing the analysis if
+// any cluster fails, the rollout of the current revision is stopped as well.
+type analysisReconciler struct {
+	workClient               workclientset.Interface
+	kubeClient               kubernetes.Interface
+	controllerRevisionLister appslisters.ControllerRevisionLister
+	manifestWorkLister       worklisterv1.ManifestWorkLister
+	recorder                 events.Recorder
+	clock                    clock.Clock
+}
+
+func (a *analysisReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	analysis, err := hubhelper.AnalysisOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	if analysis == nil {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionAnalysisPassed)
+		return mwrSet, reconcileContinue, nil
+	}
+
+	current, target, err := a.currentAndRollbackRevisions(mwrSet)
+	if err != nil || current == nil {
+		// the current revision is not synced into the cache yet
+		return mwrSet, reconcileContinue, err
+	}
+
+	rollback, err := hubhelper.RollbackOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	if rollback != nil && rollback.FailedRevision != 0 {
+		if rollback.FailedRevision == current.Revision {
+			// the analysis is stopped until the template is changed from the failed revision
+			return mwrSet, reconcileContinue, nil
+		}
+
+		// remove the automatic rollback since the template is changed
+		delete(mwrSet.Annotations, hubhelper.RollbackAnnotationKey)
+		_, err := a.workClient.WorkV1alpha1().ManifestWorkReplicaSets(mwrSet.Namespace).Update(ctx, mwrSet, metav1.UpdateOptions{})
+		return mwrSet, reconcileStop, err
+	}
+
+	manifestWorks, err := listManifestWorksByManifestWorkReplicaSet(mwrSet, a.manifestWorkLister)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	now := a.clock.Now()
+	total, passed, baking := 0, 0, 0
+	var nextCheck time.Duration
+	requeue := func(after time.Duration) {
+		if nextCheck == 0 || after < nextCheck {
+			nextCheck = after
+		}
+	}
+	failures := map[string]string{}
+	for _, mw := range manifestWorks {
+		if !mw.DeletionTimestamp.IsZero() {
+			continue
+		}
+		total++
+		if mw.Labels[hubhelper.RevisionHashLabelKey] != current.Labels[hubhelper.RevisionHashLabelKey] {
+			continue
+		}
+
+		appliedTime, err := time.Parse(time.RFC3339, mw.Annotations[hubhelper.RevisionAppliedTimeAnnotationKey])
+		if err != nil {
+			// the manifestwork is not updated by the deploy reconciler yet
+			continue
+		}
+		bakeEnd := appliedTime.Add(analysis.BakePeriod.Duration)
+		if remaining := bakeEnd.Sub(now); remaining > 0 {
+			baking++
+			requeue(remaining)
+			continue
+		}
+
+		reason, noFeedback := analyze(analysis, mw)
+		switch remaining := bakeEnd.Add(analysis.FeedbackTimeout.Duration).Sub(now); {
+		case len(reason) == 0:
+			passed++
+		case noFeedback && remaining > 0:
+			// the cluster is still baking until the status feedback is reported or the timeout
+			baking++
+			requeue(remaining)
+		default:
+			failures[mw.Namespace] = reason
+		}
+	}
+
+	if len(failures) > 0 {
+		return a.rollback(ctx, mwrSet, current, target, failures)
+	}
+
+	message := fmt.Sprintf("%d clusters of the revision %d passed the analysis", passed, current.Revision)
+	if baking > 0 {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionAnalysisPassed,
+			ReasonAnalysisBaking, fmt.Sprintf("%s, %d clusters are baking", message, baking), metav1.ConditionFalse))
+		// check the clusters again once their bake period passes
+		return mwrSet, reconcileContinue, &requeueError{after: nextCheck}
+	}
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionAnalysisPassed,
+		ReasonAnalysisPassed, message, metav1.ConditionTrue))
+
+	// the revision is a target to roll back to once all the clusters are rolled out to it and pass the analysis
+	if passed > 0 && passed == total {
+		return mwrSet, reconcileContinue, a.markPassed(ctx, current, now)
+	}
+	return mwrSet, reconcileContinue, nil
+}
+
+// rollback rolls back the failed clusters to the target revision
+func (a *analysisReconciler) rollback(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	current, target *appsv1.ControllerRevision, failures map[string]string) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	clusters := []string{}
+	for cluster := range failures {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	details := []string{}
+	for _, cluster := range clusters {
+		details = append(details, fmt.Sprintf("%s: %s", cluster, failures[cluster]))
+	}
+	message := fmt.Sprintf("%d clusters of the revision %d failed the analysis, %s",
+		len(clusters), current.Revision, strings.Join(details, "; "))
+
+	if target == nil {
+		a.recorder.Warningf("ManifestWorkReplicaSetAnalysisFailed", "%s/%s: %s, no previous revision to roll back to",
+			mwrSet.Namespace, mwrSet.Name, message)
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionAnalysisPassed,
+			ReasonAnalysisFailed, message, metav1.ConditionFalse))
+		return mwrSet, reconcileContinue, nil
+	}
+
+	rollback, err := json.Marshal(&hubhelper.Rollback{
+		Revision:       target.Revision,
+		Clusters:       clusters,
+		FailedRevision: current.Revision,
+	})
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	if mwrSet.Annotations == nil {
+		mwrSet.Annotations = map[string]string{}
+	}
+	mwrSet.Annotations[hubhelper.RollbackAnnotationKey] = string(rollback)
+	// the status is patched in the next reconcile since the manifestworkreplicaset is updated
+	if _, err := a.workClient.WorkV1alpha1().ManifestWorkReplicaSets(mwrSet.Namespace).Update(ctx, mwrSet, metav1.UpdateOptions{}); err != nil {
+		return mwrSet, reconcileStop, err
+	}
+
+	a.recorder.Warningf("ManifestWorkReplicaSetRolledBack", "%s/%s: %s, rolled back the failed clusters to the revision %d",
+		mwrSet.Namespace, mwrSet.Name, message, target.Revision)
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionAnalysisPassed,
+		ReasonAnalysisRolledBack, fmt.Sprintf("%s, rolled back the failed clusters to the revision %d", message, target.Revision),
+		metav1.ConditionFalse))
+	return mwrSet, reconcileStop, nil
+}
+
+// markPassed annotates the revision that it passed the analysis
+func (a *analysisReconciler) markPassed(ctx context.Context, revision *appsv1.ControllerRevision, now time.Time) error {
+	if _, ok := revision.Annotations[hubhelper.AnalysisPassedAnnotationKey]; ok {
+		return nil
+	}
+
+	revision = revision.DeepCopy()
+	if revision.Annotations == nil {
+		revision.Annotations = map[string]string{}
+	}
+	revision.Annotations[hubhelper.AnalysisPassedAnnotationKey] = now.UTC().Format(time.RFC3339)
+	_, err := a.kubeClient.AppsV1().ControllerRevisions(revision.Namespace).Update(ctx, revision, metav1.UpdateOptions{})
+	return err
+}
+
+// currentAndRollbackRevisions returns the revision of the current template and the revision to roll back to
+func (a *analysisReconciler) currentAndRollbackRevisions(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+) (*appsv1.ControllerRevision, *appsv1.ControllerRevision, error) {
+	hash, err := revisionHash(mwrSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions, err := listRevisions(a.controllerRevisionLister, mwrSet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, revision := range revisions {
+		if revision.Labels[hubhelper.RevisionHashLabelKey] == hash {
+			return revision, rollbackRevision(revisions, revision), nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// rollbackRevision returns the latest revision before the current one which passed the analysis, or the latest
+// revision before the current one if no revision passed the analysis, e.g. the analysis is added after them.
+// The revisions are sorted by the revision number.
+func rollbackRevision(revisions []*appsv1.ControllerRevision, current *appsv1.ControllerRevision) *appsv1.ControllerRevision {
+	var previous *appsv1.ControllerRevision
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		if revision.Revision >= current.Revision {
+			continue
+		}
+		if _, ok := revision.Annotations[hubhelper.AnalysisPassedAnnotationKey]; ok {
+			return revision
+		}
+		if previous == nil {
+			previous = revision
+		}
+	}
+	return previous
+}
+
+// analyze returns the reasons the manifestwork fails the analysis, or empty if it passes. noFeedback is true if
+// the manifestwork fails only since the status feedback is not reported.
+func analyze(analysis *hubhelper.Analysis, mw *workv1.ManifestWork) (reason string, noFeedback bool) {
+	reasons := []string{}
+	noFeedback = true
+	for i := range analysis.Rules {
+		if err := analysis.Rules[i].Evaluate(mw); err != nil {
+			reasons = append(reasons, err.Error())
+			noFeedback = noFeedback && errors.Is(err, hubhelper.ErrNoFeedback)
+		}
+	}
+	return strings.Join(reasons, ", "), noFeedback && len(reasons) > 0
+}
+
+// stampRevisionAppliedTime records the time when the revision is applied to the required manifestwork, the time
+// is kept if the existing manifestwork is of the same revision.
+func stampRevisionAppliedTime(required, existing *workv1.ManifestWork, now time.Time) {
+	appliedTime := now.UTC().Format(time.RFC3339)
+	if existing != nil && existing.Labels[hubhelper.RevisionHashLabelKey] == required.Labels[hubhelper.RevisionHashLabelKey] {
+		if existingTime, ok := existing.Annotations[hubhelper.RevisionAppliedTimeAnnotationKey]; ok {
+			appliedTime = existingTime
+		}
+	}
+
+	if required.Annotations == nil {
+		required.Annotations = map[string]string{}
+	}
+	required.Annotations[hubhelper.RevisionAppliedTimeAnnotationKey] = appliedTime
+}
@@ -0,0 +1,299 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+func TestAnalysisReconcile(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	analysis := `{"bakePeriod":"5m","rules":[{"name":"ready",` +
+		`"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},` +
+		`"expression":"feedback.ReadyReplicas == feedback.Replicas"}]}`
+
+	newMWRSet := func(annotations map[string]string) *workapiv1alpha1.ManifestWorkReplicaSet {
+		mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+		mwrSet.Annotations = map[string]string{hubhelper.AnalysisAnnotationKey: analysis}
+		for key, value := range annotations {
+			mwrSet.Annotations[key] = value
+		}
+		return mwrSet
+	}
+	// revisionsOf returns the revisions of the manifestworkreplicaset, the current revision is the latest one after
+	// the previous revisions, and the first previous revision passed the analysis if withPassed is true
+	revisionsOf := func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, previous int, withPassed bool) []*appsv1.ControllerRevision {
+		revisions := []*appsv1.ControllerRevision{}
+		for i := 0; i < previous; i++ {
+			previousMWRSet := mwrSet.DeepCopy()
+			previousMWRSet.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+			// the previous revisions are different from each other
+			previousMWRSet.Spec.ManifestWorkTemplate.ManifestConfigs = make([]workapiv1.ManifestConfigOption, i)
+			previousHash, _ := revisionHash(previousMWRSet)
+			revision, _ := newControllerRevision(previousMWRSet, previousHash, int64(i+1))
+			if i == 0 && withPassed {
+				revision.Annotations = map[string]string{hubhelper.AnalysisPassedAnnotationKey: "2023-05-01T00:00:00Z"}
+			}
+			revisions = append(revisions, revision)
+		}
+		hash, _ := revisionHash(mwrSet)
+		current, _ := newControllerRevision(mwrSet, hash, int64(previous+1))
+		return append(revisions, current)
+	}
+	workOf := func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, cluster string, applied time.Time, ready int64) *workapiv1.ManifestWork {
+		mw, _ := CreateManifestWork(mwrSet, cluster)
+		mw.Annotations = map[string]string{hubhelper.RevisionAppliedTimeAnnotationKey: applied.Format(time.RFC3339)}
+		mw.Status.ResourceStatus.Manifests = []workapiv1.ManifestCondition{{
+			ResourceMeta: workapiv1.ManifestResourceMeta{Group: "apps", Resource: "deployments", Namespace: "default", Name: "test"},
+			StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: []workapiv1.FeedbackValue{
+				{Name: "ReadyReplicas", Value: workapiv1.FieldValue{Type: workapiv1.Integer, Integer: pointer.Int64(ready)}},
+				{Name: "Replicas", Value: workapiv1.FieldValue{Type: workapiv1.Integer, Integer: pointer.Int64(3)}},
+			}},
+		}}
+		return mw
+	}
+	noFeedback := func(mw *workapiv1.ManifestWork) *workapiv1.ManifestWork {
+		mw.Status.ResourceStatus.Manifests = nil
+		return mw
+	}
+
+	cases := []struct {
+		name             string
+		mwrSet           *workapiv1alpha1.ManifestWorkReplicaSet
+		previous         int
+		withPassed       bool
+		works            func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork
+		expectedRequeue  bool
+		expectedReason   string
+		expectedRollback *hubhelper.Rollback
+		expectedUpdated  bool
+		expectedPassed   bool
+	}{
+		{
+			name:   "clusters are baking",
+			mwrSet: newMWRSet(nil),
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), workOf(mwrSet, "cls2", now.Add(-time.Minute), 0),
+				}
+			},
+			expectedRequeue: true,
+			expectedReason:  ReasonAnalysisBaking,
+		},
+		{
+			name:   "clusters passed the analysis",
+			mwrSet: newMWRSet(nil),
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), workOf(mwrSet, "cls2", now.Add(-10*time.Minute), 3),
+				}
+			},
+			expectedReason: ReasonAnalysisPassed,
+			expectedPassed: true,
+		},
+		{
+			name:   "the revision is not passed until all clusters are rolled out to it",
+			mwrSet: newMWRSet(nil),
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				previous := workOf(mwrSet, "cls2", now.Add(-10*time.Minute), 3)
+				previous.Labels[hubhelper.RevisionHashLabelKey] = "previous"
+				return []*workapiv1.ManifestWork{workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), previous}
+			},
+			expectedReason: ReasonAnalysisPassed,
+		},
+		{
+			name:     "roll back the failed clusters to the previous revision",
+			mwrSet:   newMWRSet(nil),
+			previous: 1,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), workOf(mwrSet, "cls2", now.Add(-10*time.Minute), 1),
+				}
+			},
+			expectedReason:   ReasonAnalysisRolledBack,
+			expectedRollback: &hubhelper.Rollback{Revision: 1, Clusters: []string{"cls2"}, FailedRevision: 2},
+			expectedUpdated:  true,
+		},
+		{
+			name:       "roll back the failed clusters to the last passed revision",
+			mwrSet:     newMWRSet(nil),
+			previous:   2,
+			withPassed: true,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 1), workOf(mwrSet, "cls2", now.Add(-10*time.Minute), 1),
+				}
+			},
+			expectedReason:   ReasonAnalysisRolledBack,
+			expectedRollback: &hubhelper.Rollback{Revision: 1, Clusters: []string{"cls1", "cls2"}, FailedRevision: 3},
+			expectedUpdated:  true,
+		},
+		{
+			name:     "the cluster without status feedback is baking until the timeout",
+			mwrSet:   newMWRSet(nil),
+			previous: 1,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), noFeedback(workOf(mwrSet, "cls2", now.Add(-10*time.Minute), 3)),
+				}
+			},
+			expectedRequeue: true,
+			expectedReason:  ReasonAnalysisBaking,
+		},
+		{
+			name:     "the cluster without status feedback fails after the timeout",
+			mwrSet:   newMWRSet(nil),
+			previous: 1,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{
+					workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 3), noFeedback(workOf(mwrSet, "cls2", now.Add(-20*time.Minute), 3)),
+				}
+			},
+			expectedReason:   ReasonAnalysisRolledBack,
+			expectedRollback: &hubhelper.Rollback{Revision: 1, Clusters: []string{"cls2"}, FailedRevision: 2},
+			expectedUpdated:  true,
+		},
+		{
+			name:   "no previous revision to roll back to",
+			mwrSet: newMWRSet(nil),
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 1)}
+			},
+			expectedReason: ReasonAnalysisFailed,
+		},
+		{
+			name:     "the analysis is stopped while rolled back",
+			mwrSet:   newMWRSet(map[string]string{hubhelper.RollbackAnnotationKey: `{"revision":1,"failedRevision":2}`}),
+			previous: 1,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return []*workapiv1.ManifestWork{workOf(mwrSet, "cls1", now.Add(-10*time.Minute), 1)}
+			},
+			expectedRollback: &hubhelper.Rollback{Revision: 1, FailedRevision: 2},
+		},
+		{
+			name:     "remove the rollback once the template is changed",
+			mwrSet:   newMWRSet(map[string]string{hubhelper.RollbackAnnotationKey: `{"revision":1,"failedRevision":1}`}),
+			previous: 1,
+			works: func(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) []*workapiv1.ManifestWork {
+				return nil
+			},
+			expectedUpdated: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			revisions := revisionsOf(c.mwrSet, c.previous, c.withPassed)
+			objects := []runtime.Object{}
+			for _, revision := range revisions {
+				objects = append(objects, revision)
+			}
+			fKubeClient := fakekube.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fKubeClient, 1*time.Minute)
+			for _, revision := range revisions {
+				if err := kubeInformerFactory.Apps().V1().ControllerRevisions().Informer().GetStore().Add(revision); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fWorkClient := fakeworkclient.NewSimpleClientset(c.mwrSet)
+			workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+			for _, mw := range c.works(c.mwrSet) {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reconciler := analysisReconciler{
+				workClient:               fWorkClient,
+				kubeClient:               fKubeClient,
+				controllerRevisionLister: kubeInformerFactory.Apps().V1().ControllerRevisions().Lister(),
+				manifestWorkLister:       workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				recorder:                 events.NewInMemoryRecorder("test"),
+				clock:                    testingclock.NewFakeClock(now),
+			}
+			mwrSet, _, err := reconciler.reconcile(context.TODO(), c.mwrSet.DeepCopy())
+			if _, ok := err.(*requeueError); ok != c.expectedRequeue {
+				t.Errorf("expected requeue %v, but got %v", c.expectedRequeue, err)
+			} else if !ok && err != nil {
+				t.Fatal(err)
+			}
+
+			condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionAnalysisPassed)
+			if len(c.expectedReason) > 0 && (condition == nil || condition.Reason != c.expectedReason) {
+				t.Errorf("expected condition reason %s, but got %v", c.expectedReason, condition)
+			}
+
+			updated := false
+			for _, action := range fWorkClient.Actions() {
+				if action.GetVerb() == "update" {
+					updated = true
+					mwrSet = action.(clienttesting.UpdateAction).GetObject().(*workapiv1alpha1.ManifestWorkReplicaSet)
+				}
+			}
+			if updated != c.expectedUpdated {
+				t.Errorf("expected updated %v, but got %v", c.expectedUpdated, updated)
+			}
+
+			passed := false
+			for _, action := range fKubeClient.Actions() {
+				if action.GetVerb() != "update" {
+					continue
+				}
+				revision := action.(clienttesting.UpdateAction).GetObject().(*appsv1.ControllerRevision)
+				_, passed = revision.Annotations[hubhelper.AnalysisPassedAnnotationKey]
+			}
+			if passed != c.expectedPassed {
+				t.Errorf("expected the revision passed %v, but got %v", c.expectedPassed, passed)
+			}
+
+			rollback, err := hubhelper.RollbackOf(mwrSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := json.Marshal(c.expectedRollback)
+			actual, _ := json.Marshal(rollback)
+			if string(expected) != string(actual) {
+				t.Errorf("expected rollback %s, but got %s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestStampRevisionAppliedTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	required, _ := CreateManifestWork(mwrSet, "cls1")
+
+	existing := required.DeepCopy()
+	existing.Annotations = map[string]string{hubhelper.RevisionAppliedTimeAnnotationKey: "2023-05-01T00:00:00Z"}
+	stampRevisionAppliedTime(required, existing, now)
+	if applied := required.Annotations[hubhelper.RevisionAppliedTimeAnnotationKey]; applied != "2023-05-01T00:00:00Z" {
+		t.Errorf("expected the applied time of the existing manifestwork is kept, but got %s", applied)
+	}
+
+	existing.Labels[hubhelper.RevisionHashLabelKey] = "previous"
+	stampRevisionAppliedTime(required, existing, now)
+	if applied := required.Annotations[hubhelper.RevisionAppliedTimeAnnotationKey]; applied != now.Format(time.RFC3339) {
+		t.Errorf("expected the applied time is updated for the new revision, but got %s", applied)
+	}
+}
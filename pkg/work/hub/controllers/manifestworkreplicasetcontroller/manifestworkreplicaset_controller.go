@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -19,9 +20,13 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
 	clusterinformerv1beta1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1beta1"
 	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
 	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
@@ -48,6 +53,10 @@ type ManifestWorkReplicaSetController struct {
 	workClient                    workclientset.Interface
 	manifestWorkReplicaSetLister  worklisterv1alpha1.ManifestWorkReplicaSetLister
 	manifestWorkReplicaSetIndexer cache.Indexer
+	manifestWorkLister            worklisterv1.ManifestWorkLister
+	// deploys tracks the manifestWorkReplicaSets to deploy, the deployReconciler is skipped for the status changes
+	// of the manifestworks once the rollout is completed
+	deploys deployTracker
 
 	reconcilers []ManifestWorkReplicaSetReconcile
 }
@@ -60,6 +69,15 @@ type ManifestWorkReplicaSetReconcile interface {
 
 type reconcileState int64
 
+// requeueError is returned by a reconciler to reconcile the manifestWorkReplicaSet again after a while
+type requeueError struct {
+	after time.Duration
+}
+
+func (e *requeueError) Error() string {
+	return fmt.Sprintf("requeue after %v", e.after)
+}
+
 const (
 	reconcileStop reconcileState = iota
 	reconcileContinue
@@ -68,69 +86,121 @@ const (
 func NewManifestWorkReplicaSetController(
 	recorder events.Recorder,
 	workClient workclientset.Interface,
+	kubeClient kubernetes.Interface,
 	manifestWorkReplicaSetInformer workinformerv1alpha1.ManifestWorkReplicaSetInformer,
 	manifestWorkInformer workinformerv1.ManifestWorkInformer,
+	orphanedWorkInformer workinformerv1.ManifestWorkInformer,
 	placementInformer clusterinformerv1beta1.PlacementInformer,
-	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer) factory.Controller {
+	placeDecisionInformer clusterinformerv1beta1.PlacementDecisionInformer,
+	managedClusterInformer clusterinformerv1.ManagedClusterInformer,
+	controllerRevisionInformer appsinformers.ControllerRevisionInformer) factory.Controller {
 
+	err := manifestWorkReplicaSetInformer.Informer().AddIndexers(
+		cache.Indexers{
+			manifestWorkReplicaSetByPlacement: indexManifestWorkReplicaSetByPlacement,
+		})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	err = manifestWorkInformer.Informer().AddIndexers(
+		cache.Indexers{
+			manifestWorkByManifestWorkReplicaSet: indexManifestWorkByManifestWorkReplicaSet,
+		})
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	manifestWorkLister := &manifestWorkIndexLister{
+		ManifestWorkLister: manifestWorkInformer.Lister(),
+		indexer:            manifestWorkInformer.Informer().GetIndexer(),
+	}
+
+	deployer := &deployReconciler{workClient: workClient, workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkLister),
+		manifestWorkLister: manifestWorkLister, orphanedWorkLister: orphanedWorkInformer.Lister(),
+		placementLister: placementInformer.Lister(), placeDecisionLister: placeDecisionInformer.Lister(),
+		clusterLister: managedClusterInformer.Lister(), controllerRevisionLister: controllerRevisionInformer.Lister()}
 	controller := &ManifestWorkReplicaSetController{
 		workClient:                    workClient,
+		manifestWorkLister:            manifestWorkLister,
 		manifestWorkReplicaSetLister:  manifestWorkReplicaSetInformer.Lister(),
 		manifestWorkReplicaSetIndexer: manifestWorkReplicaSetInformer.Informer().GetIndexer(),
 
 		reconcilers: []ManifestWorkReplicaSetReconcile{
-			&finalizeReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkInformer.Lister()),
-				workClient: workClient, manifestWorkLister: manifestWorkInformer.Lister()},
+			&finalizeReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkLister),
+				workClient: workClient, manifestWorkLister: manifestWorkLister, appliedWorks: &deployer.appliedWorks},
 			&addFinalizerReconciler{workClient: workClient},
-			&deployReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(workClient, manifestWorkInformer.Lister()),
-				manifestWorkLister: manifestWorkInformer.Lister(), placementLister: placementInformer.Lister(), placeDecisionLister: placeDecisionInformer.Lister()},
-			&statusReconciler{manifestWorkLister: manifestWorkInformer.Lister()},
+			deployer,
+			&revisionReconciler{kubeClient: kubeClient, controllerRevisionLister: controllerRevisionInformer.Lister(),
+				manifestWorkLister: manifestWorkLister},
+			&analysisReconciler{workClient: workClient, kubeClient: kubeClient, controllerRevisionLister: controllerRevisionInformer.Lister(),
+				manifestWorkLister: manifestWorkLister, recorder: recorder, clock: clock.RealClock{}},
+			&statusReconciler{manifestWorkLister: manifestWorkLister},
 		},
 	}
 
-	err := manifestWorkReplicaSetInformer.Informer().AddIndexers(
-		cache.Indexers{
-			manifestWorkReplicaSetByPlacement: indexManifestWorkReplicaSetByPlacement,
-		})
-	if err != nil {
-		utilruntime.HandleError(err)
+	labelFilter := func(obj interface{}) bool {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		if _, ok := accessor.GetLabels()[ManifestWorkReplicaSetControllerNameLabelKey]; ok {
+			return true
+		}
+		return false
 	}
 
 	return factory.New().
-		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
-			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
-			if err != nil {
-				utilruntime.HandleError(err)
-				return ""
-			}
-			return key
-		}, manifestWorkReplicaSetInformer.Informer()).
+		WithInformersQueueKeyFunc(controller.manifestWorkReplicaSetQueueKeyFunc, manifestWorkReplicaSetInformer.Informer()).
+		WithFilteredEventsInformersQueueKeyFunc(controller.manifestWorkDeployQueueKeyFunc, labelFilter, manifestWorkInformer.Informer()).
 		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
-			accessor, _ := meta.Accessor(obj)
-			labelValue, ok := accessor.GetLabels()[ManifestWorkReplicaSetControllerNameLabelKey]
-			if !ok {
-				return ""
-			}
-			keys := strings.Split(labelValue, ".")
-			if len(keys) != 2 {
-				return ""
-			}
-			return fmt.Sprintf("%s/%s", keys[0], keys[1])
-		}, func(obj interface{}) bool {
-			accessor, err := meta.Accessor(obj)
-			if err != nil {
-				return false
-			}
-			if _, ok := accessor.GetLabels()[ManifestWorkReplicaSetControllerNameLabelKey]; ok {
-				return true
-			}
-			return false
-		}, manifestWorkInformer.Informer()).
-		WithInformersQueueKeysFunc(controller.placementDecisionQueueKeysFunc, placeDecisionInformer.Informer()).
-		WithInformersQueueKeysFunc(controller.placementQueueKeysFunc, placementInformer.Informer()).
+			key := labelQueueKeyFunc(obj)
+			controller.deploys.markDeploy(key)
+			return key
+		}, labelFilter, controllerRevisionInformer.Informer()).
+		WithInformersQueueKeysFunc(controller.deploys.deployKeysFunc(controller.placementDecisionQueueKeysFunc), placeDecisionInformer.Informer()).
+		WithInformersQueueKeysFunc(controller.deploys.deployKeysFunc(controller.placementQueueKeysFunc), placementInformer.Informer()).
+		WithInformersQueueKeysFunc(controller.deploys.deployKeysFunc(controller.managedClusterQueueKeysFunc), managedClusterInformer.Informer()).
+		WithBareInformers(orphanedWorkInformer.Informer()).
 		WithSync(controller.sync).ToController("ManifestWorkReplicaSetController", recorder)
 }
 
+// manifestWorkReplicaSetQueueKeyFunc returns the queue key of the manifestWorkReplicaSet, and marks it to deploy
+// unless only its status is changed.
+func (m *ManifestWorkReplicaSetController) manifestWorkReplicaSetQueueKeyFunc(obj runtime.Object) string {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return ""
+	}
+	if m.deploys.observeManifestWorkReplicaSet(obj, m.manifestWorkReplicaSetLister) {
+		m.deploys.markDeploy(key)
+	}
+	return key
+}
+
+// manifestWorkDeployQueueKeyFunc returns the queue key of the manifestWorkReplicaSet of the manifestwork, and marks it to
+// deploy unless only the status of the manifestwork is changed.
+func (m *ManifestWorkReplicaSetController) manifestWorkDeployQueueKeyFunc(obj runtime.Object) string {
+	key := labelQueueKeyFunc(obj)
+	if m.deploys.observeManifestWork(obj, m.manifestWorkLister) {
+		m.deploys.markDeploy(key)
+	}
+	return key
+}
+
+// labelQueueKeyFunc returns the queue key of the manifestWorkReplicaSet in the label of the object
+func labelQueueKeyFunc(obj runtime.Object) string {
+	accessor, _ := meta.Accessor(obj)
+	labelValue, ok := accessor.GetLabels()[ManifestWorkReplicaSetControllerNameLabelKey]
+	if !ok {
+		return ""
+	}
+	keys := strings.Split(labelValue, ".")
+	if len(keys) != 2 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", keys[0], keys[1])
+}
+
 // sync is the main reconcile loop for placeManifest work. It is triggered every 15sec
 func (m *ManifestWorkReplicaSetController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
 	key := controllerContext.QueueKey()
@@ -154,11 +224,20 @@ func (m *ManifestWorkReplicaSetController) sync(ctx context.Context, controllerC
 	oldManifestWorkReplicaSet := manifestWorkReplicaSet
 	manifestWorkReplicaSet = manifestWorkReplicaSet.DeepCopy()
 
+	// the manifestworks are only deployed if the rollout proceeds or anything other than their status changes
+	deploy := m.deploys.popDeploy(key) || !rolloutCompleted(manifestWorkReplicaSet)
+
 	var state reconcileState
 	var errs []error
 	for _, reconciler := range m.reconcilers {
+		if _, ok := reconciler.(*deployReconciler); ok && !deploy {
+			continue
+		}
 		manifestWorkReplicaSet, state, err = reconciler.reconcile(ctx, manifestWorkReplicaSet)
-		if err != nil {
+		if requeue, ok := err.(*requeueError); ok {
+			m.deploys.markDeploy(key)
+			controllerContext.Queue().AddAfter(key, requeue.after)
+		} else if err != nil {
 			errs = append(errs, err)
 		}
 		if state == reconcileStop {
@@ -171,6 +250,10 @@ func (m *ManifestWorkReplicaSetController) sync(ctx context.Context, controllerC
 		errs = append(errs, err)
 	}
 
+	if len(errs) > 0 {
+		// deploy again when the manifestWorkReplicaSet is requeued for the errors
+		m.deploys.markDeploy(key)
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -206,8 +289,14 @@ func (m *ManifestWorkReplicaSetController) patchPlaceManifestStatus(ctx context.
 	return err
 }
 
+// listManifestWorksByManifestWorkReplicaSet lists the manifestworks of the manifestWorkReplicaSet, by the index if
+// the lister supports it.
 func listManifestWorksByManifestWorkReplicaSet(mwrs *workapiv1alpha1.ManifestWorkReplicaSet,
 	manifestWorkLister worklisterv1.ManifestWorkLister) ([]*workapiv1.ManifestWork, error) {
+	if indexLister, ok := manifestWorkLister.(*manifestWorkIndexLister); ok {
+		return indexLister.listByManifestWorkReplicaSet(manifestWorkReplicaSetKey(mwrs))
+	}
+
 	req, err := labels.NewRequirement(ManifestWorkReplicaSetControllerNameLabelKey, selection.Equals, []string{manifestWorkReplicaSetKey(mwrs)})
 	if err != nil {
 		return nil, err
@@ -5,14 +5,19 @@ import (
 	"testing"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
 )
 
@@ -56,7 +61,7 @@ func TestManifestWorkReplicaSetControllerPatchStatus(t *testing.T) {
 				workClient: fWorkClient, manifestWorkLister: mwLister},
 			&addFinalizerReconciler{workClient: fWorkClient},
 			&deployReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
-				manifestWorkLister: mwLister, placementLister: placementLister, placeDecisionLister: placementDecisionLister},
+				manifestWorkLister: mwLister, orphanedWorkLister: mwLister, placementLister: placementLister, placeDecisionLister: placementDecisionLister},
 			&statusReconciler{manifestWorkLister: mwLister},
 		},
 	}
@@ -80,3 +85,138 @@ func TestManifestWorkReplicaSetControllerPatchStatus(t *testing.T) {
 		t.Fatal("PlaceMW patch action not match ", actions[0])
 	}
 }
+
+func TestManifestWorkReplicaSetControllerSkipDeployOnStatusChanges(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	mwrSet.Generation = 1
+	mw, _ := CreateManifestWork(mwrSet, "cls1")
+	mw.Generation = 1
+	mw.Status.Conditions = []metav1.Condition{
+		{Type: workv1.WorkApplied, Status: metav1.ConditionTrue, ObservedGeneration: 1},
+		{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue, ObservedGeneration: 1},
+	}
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet, mw)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Minute)
+	mwrSetInformer := workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets().Informer()
+	if err := mwrSetInformer.AddIndexers(cache.Indexers{
+		manifestWorkReplicaSetByPlacement: indexManifestWorkReplicaSetByPlacement,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mwrSetInformer.GetStore().Add(mwrSet); err != nil {
+		t.Fatal(err)
+	}
+	mwStore := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore()
+	if err := mwStore.Add(mw); err != nil {
+		t.Fatal(err)
+	}
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1")
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(
+		fakeclusterclient.NewSimpleClientset(placement, placementDecision), 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	decisionStore := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore()
+	if err := decisionStore.Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+	controller := &ManifestWorkReplicaSetController{
+		workClient:                    fWorkClient,
+		manifestWorkLister:            mwLister,
+		manifestWorkReplicaSetLister:  workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets().Lister(),
+		manifestWorkReplicaSetIndexer: mwrSetInformer.GetIndexer(),
+		reconcilers: []ManifestWorkReplicaSetReconcile{
+			&deployReconciler{workClient: fWorkClient, workApplier: workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+				manifestWorkLister: mwLister, orphanedWorkLister: mwLister, placementLister: clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+				placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister()},
+			&statusReconciler{manifestWorkLister: mwLister},
+		},
+	}
+
+	// workActions returns the actions writing the manifestworks since the last call
+	workActions := func() []clienttesting.Action {
+		var actions []clienttesting.Action
+		for _, action := range fWorkClient.Actions() {
+			if action.GetResource().Resource == "manifestworks" && action.GetVerb() != "get" &&
+				action.GetVerb() != "list" && action.GetVerb() != "watch" {
+				actions = append(actions, action)
+			}
+		}
+		fWorkClient.ClearActions()
+		return actions
+	}
+	sync := func() {
+		if err := controller.sync(context.TODO(), testingcommon.NewFakeSyncContext(t, "default/mwrSet-test")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// patchedEvent syncs the manifestWorkReplicaSet patched by the controller into the cache, and returns the queue key
+	// of its update event
+	patchedEvent := func() string {
+		patched, err := fWorkClient.WorkV1alpha1().ManifestWorkReplicaSets("default").Get(context.TODO(), "mwrSet-test", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mwrSetInformer.GetStore().Update(patched); err != nil {
+			t.Fatal(err)
+		}
+		return controller.manifestWorkReplicaSetQueueKeyFunc(patched)
+	}
+
+	// the manifestWorkReplicaSet and its manifestwork are observed and deployed at the start
+	controller.manifestWorkReplicaSetQueueKeyFunc(mwrSet)
+	controller.manifestWorkDeployQueueKeyFunc(mw)
+	sync()
+	patchedEvent()
+	sync()
+	workActions()
+
+	// cls2 is added to the placement decision whose event is not handled yet, so its manifestwork is only created if
+	// the manifestWorkReplicaSet is deployed
+	_, placementDecision = helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2")
+	if err := decisionStore.Update(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	// the status of the manifestwork is updated by the agent
+	updated := mw.DeepCopy()
+	updated.Status.Conditions = append(updated.Status.Conditions,
+		metav1.Condition{Type: workv1.WorkDegraded, Status: metav1.ConditionTrue, ObservedGeneration: 1})
+	if err := mwStore.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	if key := controller.manifestWorkDeployQueueKeyFunc(updated); key != "default/mwrSet-test" {
+		t.Fatalf("expected the manifestWorkReplicaSet is enqueued, but got %q", key)
+	}
+	sync()
+	if actions := workActions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork is written for the status change of the manifestwork, but got %v", actions)
+	}
+
+	// the status of the manifestWorkReplicaSet patched by the controller triggers another sync
+	if key := patchedEvent(); key != "default/mwrSet-test" {
+		t.Fatalf("expected the manifestWorkReplicaSet is enqueued, but got %q", key)
+	}
+	if degraded := mwrSetInformer.GetStore().List()[0].(*workv1alpha1.ManifestWorkReplicaSet).Status.Summary.Degraded; degraded != 1 {
+		t.Fatalf("expected the status of the manifestWorkReplicaSet is patched with the degraded manifestwork, but got %d", degraded)
+	}
+	sync()
+	if actions := workActions(); len(actions) != 0 {
+		t.Errorf("expected no manifestwork is written for the status change of the manifestWorkReplicaSet, but got %v", actions)
+	}
+
+	// the event of the placement decision deploys the manifestWorkReplicaSet
+	keys := controller.deploys.deployKeysFunc(controller.placementDecisionQueueKeysFunc)(placementDecision)
+	if len(keys) != 1 || keys[0] != "default/mwrSet-test" {
+		t.Fatalf("expected the manifestWorkReplicaSet is enqueued, but got %v", keys)
+	}
+	sync()
+	actions := workActions()
+	if len(actions) != 1 || actions[0].GetVerb() != "create" || actions[0].GetNamespace() != "cls2" {
+		t.Errorf("expected the manifestwork of cls2 is created for the change of the placement decision, but got %v", actions)
+	}
+}
@@ -3,33 +3,93 @@ package manifestworkreplicasetcontroller
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	appslisters "k8s.io/client-go/listers/apps/v1"
 
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
 	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
 	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	"open-cluster-management.io/api/utils/work/v1/workapplier"
 	workv1 "open-cluster-management.io/api/work/v1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 )
 
 // deployReconciler is to manage ManifestWork based on the placement.
 type deployReconciler struct {
+	workClient          workclientset.Interface
 	workApplier         *workapplier.WorkApplier
 	manifestWorkLister  worklisterv1.ManifestWorkLister
 	placeDecisionLister clusterlister.PlacementDecisionLister
 	placementLister     clusterlister.PlacementLister
+	clusterLister       clusterlisterv1.ManagedClusterLister
+	// orphanedWorkLister lists the manifestworks orphaned by the manifestworkreplicasets to adopt them again
+	orphanedWorkLister worklisterv1.ManifestWorkLister
+	// controllerRevisionLister lists the template revisions to roll back to
+	controllerRevisionLister appslisters.ControllerRevisionLister
+	// removals counts the placement decisions changes of the manifestworks pending removal
+	removals removalTracker
+	// appliedWorks tracks the applied manifestworks to skip those not changed
+	appliedWorks workTracker
 }
 
 func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
 ) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	strategy, err := hubhelper.RolloutStrategyOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	current, err := newWorkSource(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	analysis, err := hubhelper.AnalysisOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	removalPolicy, err := hubhelper.RemovalPolicyOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	// the clusters selected by the rollback are deployed with the template and the overrides of the revision. If
+	// the current revision failed the analysis, the clusters not rolled out to it yet are rolled back as well.
+	rollback, err := hubhelper.RollbackOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	var rollbackSource *workSource
+	failedHash := ""
+	if rollback != nil {
+		if rollback.FailedRevision != 0 {
+			failedHash, err = hashOfRevision(d.controllerRevisionLister, mwrSet, rollback.FailedRevision)
+			if err != nil {
+				return mwrSet, reconcileContinue, err
+			}
+		}
+		rollbackMWRSet, err := mwrSetOfRevision(d.controllerRevisionLister, mwrSet, rollback.Revision)
+		if err != nil {
+			return mwrSet, reconcileContinue, err
+		}
+		rollbackSource, err = newWorkSource(rollbackMWRSet)
+		if err != nil {
+			return mwrSet, reconcileContinue, err
+		}
+	}
+
 	// Manifestwork create/update/delete logic.
 	var placements []*clusterv1beta1.Placement
 	for _, placementRef := range mwrSet.Spec.PlacementRefs {
@@ -49,11 +109,15 @@ func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 	}
 
 	errs := []error{}
-	addedClusters, deletedClusters, existingClusters := sets.New[string](), sets.New[string](), sets.New[string]()
+	existingClusters := sets.New[string]()
+	existingWorks := map[string]*workv1.ManifestWork{}
 	for _, mw := range manifestWorks {
 		existingClusters.Insert(mw.Namespace)
+		existingWorks[mw.Namespace] = mw
 	}
 
+	// a cluster selected by several placements belongs to the first of them in the placementRefs
+	clusterPlacements := map[string]string{}
 	for _, placement := range placements {
 		added, deleted, err := helper.GetClusters(d.placeDecisionLister, placement, existingClusters)
 		if err != nil {
@@ -62,56 +126,126 @@ func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 			return mwrSet, reconcileContinue, utilerrors.NewAggregate(errs)
 		}
 
-		addedClusters = addedClusters.Union(added)
-		deletedClusters = deletedClusters.Union(deleted)
+		for cls := range existingClusters.Union(added).Difference(deleted) {
+			if _, ok := clusterPlacements[cls]; !ok {
+				clusterPlacements[cls] = placement.Name
+			}
+		}
 	}
+	selectedClusters := sets.KeySet(clusterPlacements)
+	deletedClusters := existingClusters.Difference(selectedClusters)
 
-	// Create manifestWork for added clusters
-	for cls := range addedClusters {
-		mw, err := CreateManifestWork(mwrSet, cls)
+	// Remove manifestWork for deleted clusters with the removal policy, and cancel the pending removals of the
+	// clusters selected again.
+	decisions := ""
+	if removalPolicy.ConsecutiveDecisions > 1 && deletedClusters.Len() > 0 {
+		decisions, err = decisionsVersion(d.placeDecisionLister, placements)
 		if err != nil {
-			errs = append(errs, err)
+			return mwrSet, reconcileContinue, err
+		}
+	}
+	now := time.Now()
+	pendings := []*pendingRemoval{}
+	var requeueAfter time.Duration
+	for cls := range existingClusters {
+		if !deletedClusters.Has(cls) {
+			if err := d.cancelRemoval(ctx, mwrSet, existingWorks[cls]); err != nil {
+				errs = append(errs, err)
+			}
 			continue
 		}
-
-		_, err = d.workApplier.Apply(ctx, mw)
+		pending, err := d.removeManifestWork(ctx, mwrSet, removalPolicy, existingWorks[cls], decisions, now)
 		if err != nil {
 			errs = append(errs, err)
 		}
+		if pending != nil {
+			pendings = append(pendings, pending)
+			if pending.after > 0 && (requeueAfter == 0 || pending.after < requeueAfter) {
+				requeueAfter = pending.after
+			}
+		}
 	}
+	setRemovalPendingCondition(mwrSet, removalPolicy, pendings)
 
-	// Update manifestWorks in case there are changes at ManifestWork or ManifestWorkReplicaSet
-	for cls := range existingClusters {
-		// Delete manifestWork for deleted clusters
-		if deletedClusters.Has(cls) {
-			err = d.workApplier.Delete(ctx, cls, mwrSet.Name)
-			if err != nil {
-				errs = append(errs, err)
-			}
+	groups := map[string]int{}
+	if strategy.Type == hubhelper.RolloutProgressivePerGroup {
+		groups, err = decisionGroups(d.placeDecisionLister, placements)
+		if err != nil {
+			return mwrSet, reconcileContinue, err
+		}
+	}
+
+	// Create manifestWork for added clusters, and update manifestWorks in case there are changes at ManifestWork
+	// or ManifestWorkReplicaSet, the clusters are rolled out with the rollout strategy. The manifestworks not
+	// changed since they are applied are skipped.
+	mwrSetKey := manifestWorkReplicaSetKey(mwrSet)
+	d.appliedWorks.retain(mwrSetKey, selectedClusters)
+	requiredWorks, workKeys := map[string]*workv1.ManifestWork{}, map[string]string{}
+	clusters := []rolloutCluster{}
+	for cls := range selectedClusters {
+		source := current
+		if rollback != nil && (rollback.Selects(cls) || (failedHash == current.hash && !onRevision(existingWorks[cls], failedHash))) {
+			source = rollbackSource
+		}
+		key, err := d.workKey(source, cls, clusterPlacements[cls], analysis != nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if d.appliedWorks.upToDate(mwrSetKey, cls, key, existingWorks[cls]) {
+			clusters = append(clusters, rolloutCluster{name: cls, group: groups[cls], status: observedRolloutStatus(existingWorks[cls])})
 			continue
 		}
 
-		mw, err := CreateManifestWork(mwrSet, cls)
+		mw, err := d.createManifestWork(source, cls, clusterPlacements[cls])
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
+		if analysis != nil {
+			stampRevisionAppliedTime(mw, existingWorks[cls], time.Now())
+		}
+		requiredWorks[cls], workKeys[cls] = mw, key
+		clusters = append(clusters, rolloutCluster{name: cls, group: groups[cls], status: rolloutStatusOf(existingWorks[cls], mw)})
+	}
 
-		_, err = d.workApplier.Apply(ctx, mw)
+	plan, err := planRollout(strategy, clusters)
+	if err != nil {
+		return mwrSet, reconcileContinue, utilerrors.NewAggregate(append(errs, err))
+	}
+	for _, cls := range plan.toApply {
+		required, ok := requiredWorks[cls]
+		if !ok {
+			continue
+		}
+		// the manifestwork orphaned before is adopted rather than created since the cluster is selected again
+		if existingWorks[cls] == nil {
+			orphaned, err := d.orphanedWorkLister.ManifestWorks(cls).Get(required.Name)
+			switch {
+			case err == nil:
+				if err := d.adoptManifestWork(ctx, mwrSet, orphaned, required); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			case !errors.IsNotFound(err):
+				errs = append(errs, err)
+				continue
+			}
+		}
+		applied, err := d.workApplier.Apply(ctx, required)
 		if err != nil {
 			errs = append(errs, err)
+			continue
 		}
+		d.appliedWorks.record(mwrSetKey, cls, workKeys[cls], required, applied)
 	}
+	setRolloutCondition(mwrSet, plan)
 
 	// Set the Summary
 	if mwrSet.Status.Summary == (workapiv1alpha1.ManifestWorkReplicaSetSummary{}) {
 		mwrSet.Status.Summary = workapiv1alpha1.ManifestWorkReplicaSetSummary{}
 	}
-	total := len(existingClusters) - len(deletedClusters) + len(addedClusters)
-	if total < 0 {
-		total = 0
-	}
-
+	total := len(selectedClusters)
 	mwrSet.Status.Summary.Total = total
 	if total == 0 {
 		mwrSet.Status.Summary.Applied = 0
@@ -123,9 +257,98 @@ func (d *deployReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetPlacementDecisionVerified(workapiv1alpha1.ReasonAsExpected, ""))
 	}
 
+	if len(errs) == 0 && requeueAfter > 0 {
+		// check the pending removals again
+		return mwrSet, reconcileContinue, &requeueError{after: requeueAfter}
+	}
 	return mwrSet, reconcileContinue, utilerrors.NewAggregate(errs)
 }
 
+// workSource is everything to generate the manifestworks of a revision of the manifestWorkReplicaSet
+type workSource struct {
+	mwrSet    *workapiv1alpha1.ManifestWorkReplicaSet
+	hash      string
+	overrides []hubhelper.OverrideRule
+}
+
+func newWorkSource(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*workSource, error) {
+	hash, err := revisionHash(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := hubhelper.OverridesOf(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+	return &workSource{mwrSet: mwrSet, hash: hash, overrides: overrides}, nil
+}
+
+// workKey returns the key identifying everything to generate the manifestwork of the cluster, which is the
+// revision hash of the source, the placement, the names of the override rules selecting the cluster and whether
+// the revision applied time is stamped. The key is much cheaper than generating the manifestwork.
+func (d *deployReconciler) workKey(source *workSource, clusterName, placement string, stamped bool) (string, error) {
+	selected := []string{}
+	if len(source.overrides) > 0 {
+		cluster, err := d.getCluster(clusterName)
+		if err != nil {
+			return "", err
+		}
+		for _, rule := range source.overrides {
+			ok, err := rule.Selects(cluster, placement)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				selected = append(selected, rule.Name)
+			}
+		}
+	}
+	if len(source.mwrSet.Spec.PlacementRefs) < 2 {
+		// the placement is not recorded in the manifestwork
+		placement = ""
+	}
+	return fmt.Sprintf("%s/%s/%t/%s", source.hash, placement, stamped, strings.Join(selected, ",")), nil
+}
+
+// createManifestWork returns the manifestwork of the cluster with the override rules selecting the cluster and its
+// placement applied, the names of the applied rules are recorded in the annotation of the
+// manifestwork, so is the placement if there are several placements to summarize the status per placement.
+func (d *deployReconciler) createManifestWork(source *workSource, clusterName, placement string) (*workv1.ManifestWork, error) {
+	mw, err := newManifestWork(source.mwrSet, clusterName, source.hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(source.mwrSet.Spec.PlacementRefs) > 1 {
+		mw.Annotations = map[string]string{hubhelper.PlacementAnnotationKey: placement}
+	}
+	if len(source.overrides) == 0 {
+		return mw, nil
+	}
+
+	cluster, err := d.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := hubhelper.ApplyOverrides(&mw.Spec, source.overrides, cluster, placement)
+	if err != nil {
+		return nil, err
+	}
+	if mw.Annotations == nil {
+		mw.Annotations = map[string]string{}
+	}
+	mw.Annotations[hubhelper.AppliedOverridesAnnotationKey] = strings.Join(applied, ",")
+	return mw, nil
+}
+
+func (d *deployReconciler) getCluster(clusterName string) (*clusterv1.ManagedCluster, error) {
+	cluster, err := d.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		// the cluster could still be selected by its name
+		return &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}, nil
+	}
+	return cluster, err
+}
+
 // Return only True status if there all clusters have manifests applied as expected
 func GetManifestworkApplied(reason string, message string) metav1.Condition {
 	if reason == workapiv1alpha1.ReasonAsExpected {
@@ -156,6 +379,15 @@ func getCondition(conditionType string, reason string, message string, status me
 }
 
 func CreateManifestWork(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, clusterNS string) (*workv1.ManifestWork, error) {
+	hash, err := revisionHash(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+	return newManifestWork(mwrSet, clusterNS, hash)
+}
+
+// newManifestWork returns the manifestwork of the manifestWorkReplicaSet with the revision hash computed already
+func newManifestWork(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, clusterNS, hash string) (*workv1.ManifestWork, error) {
 	if clusterNS == "" {
 		return nil, fmt.Errorf("Invalid cluster namespace")
 	}
@@ -164,7 +396,15 @@ func CreateManifestWork(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, clusterN
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      mwrSet.Name,
 			Namespace: clusterNS,
-			Labels:    map[string]string{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)},
+			Labels: map[string]string{
+				ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet),
+				hubhelper.RevisionHashLabelKey:               hash,
+			},
 		},
 		Spec: mwrSet.Spec.ManifestWorkTemplate}, nil
 }
+
+// onRevision returns true if the manifestwork is generated from the revision
+func onRevision(mw *workv1.ManifestWork, hash string) bool {
+	return mw != nil && mw.Labels[hubhelper.RevisionHashLabelKey] == hash
+}
@@ -2,6 +2,7 @@ package manifestworkreplicasetcontroller
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,9 +13,12 @@ import (
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
 	"open-cluster-management.io/api/utils/work/v1/workapplier"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
 )
 
@@ -50,6 +54,7 @@ func TestDeployReconcileAsExpected(t *testing.T) {
 	pmwDeployController := deployReconciler{
 		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
 		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
 		placeDecisionLister: placementDecisionLister,
 		placementLister:     placementLister,
 	}
@@ -114,6 +119,7 @@ func TestDeployReconcileAsPlacementDecisionEmpty(t *testing.T) {
 	pmwDeployController := deployReconciler{
 		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
 		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
 		placeDecisionLister: placementDecisionLister,
 		placementLister:     placementLister,
 	}
@@ -174,6 +180,7 @@ func TestDeployReconcileAsPlacementNotExist(t *testing.T) {
 	pmwDeployController := deployReconciler{
 		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
 		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
 		placeDecisionLister: placementDecisionLister,
 		placementLister:     placementLister,
 	}
@@ -200,3 +207,119 @@ func TestDeployReconcileAsPlacementNotExist(t *testing.T) {
 		t.Fatal("Placement condition Reason not match PlacementDecisionEmpty ", placeCondition)
 	}
 }
+
+func TestDeployReconcileWithOverrides(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	mwrSet.Annotations = map[string]string{hubhelper.OverridesAnnotationKey: `[{"name":"prod","clusterSelector":{"matchLabels":{"env":"prod"}},` +
+		`"patches":[{"kind":"kind","namespace":"test-ns","name":"test-name","type":"MergePatch","patch":{"data":{"env":"prod"}}}]}]`}
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Minute)
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2")
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cls1", Labels: map[string]string{"env": "prod"}}}
+	fClusterClient := fakeclusterclient.NewSimpleClientset(placement, placementDecision, cluster)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(fClusterClient, 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
+		placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		clusterLister:       clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+	}
+
+	if _, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet); err != nil {
+		t.Fatal(err)
+	}
+
+	for cls, expectedOverrides := range map[string]string{"cls1": "prod", "cls2": ""} {
+		mw, err := fWorkClient.WorkV1().ManifestWorks(cls).Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mw.Annotations[hubhelper.AppliedOverridesAnnotationKey] != expectedOverrides {
+			t.Errorf("expected overrides %q applied to %s, but got %q",
+				expectedOverrides, cls, mw.Annotations[hubhelper.AppliedOverridesAnnotationKey])
+		}
+		patched := strings.Contains(string(mw.Spec.Workload.Manifests[0].Raw), `"env":"prod"`)
+		if patched != (len(expectedOverrides) > 0) {
+			t.Errorf("expected the manifest of %s patched %v, but got %s", cls, !patched, mw.Spec.Workload.Manifests[0].Raw)
+		}
+	}
+}
+
+func TestDeployReconcileWithPlacementOverrides(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "canary")
+	mwrSet.Spec.PlacementRefs = append(mwrSet.Spec.PlacementRefs, workapiv1alpha1.LocalPlacementReference{Name: "prod"})
+	mwrSet.Annotations = map[string]string{hubhelper.OverridesAnnotationKey: `[{"name":"prod","placements":["prod"],` +
+		`"patches":[{"kind":"kind","namespace":"test-ns","name":"test-name","type":"MergePatch","patch":{"data":{"env":"prod"}}}]}]`}
+
+	// the manifestwork of cls3 selected by the placement prod only is kept
+	existing, _ := CreateManifestWork(mwrSet, "cls3")
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet, existing)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Minute)
+	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(existing); err != nil {
+		t.Fatal(err)
+	}
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	canary, canaryDecision := helpertest.CreateTestPlacement("canary", "default", "cls1", "cls2")
+	prod, prodDecision := helpertest.CreateTestPlacement("prod", "default", "cls2", "cls3")
+	fClusterClient := fakeclusterclient.NewSimpleClientset(canary, canaryDecision, prod, prodDecision)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(fClusterClient, 1*time.Minute)
+	for _, placement := range []*clusterv1beta1.Placement{canary, prod} {
+		if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, decision := range []*clusterv1beta1.PlacementDecision{canaryDecision, prodDecision} {
+		if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(decision); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
+		placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		clusterLister:       clusterInformerFactory.Cluster().V1().ManagedClusters().Lister(),
+	}
+
+	mwrSet, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mwrSet.Status.Summary.Total != 3 {
+		t.Errorf("expected 3 clusters, but got %d", mwrSet.Status.Summary.Total)
+	}
+
+	// cls2 selected by both placements belongs to the first placement canary
+	for cls, expectedPlacement := range map[string]string{"cls1": "canary", "cls2": "canary", "cls3": "prod"} {
+		mw, err := fWorkClient.WorkV1().ManifestWorks(cls).Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mw.Annotations[hubhelper.PlacementAnnotationKey] != expectedPlacement {
+			t.Errorf("expected %s belongs to the placement %s, but got %q",
+				cls, expectedPlacement, mw.Annotations[hubhelper.PlacementAnnotationKey])
+		}
+		patched := strings.Contains(string(mw.Spec.Workload.Manifests[0].Raw), `"env":"prod"`)
+		if patched != (expectedPlacement == "prod") {
+			t.Errorf("expected the manifest of %s in the placement %s patched %v, but got %s",
+				cls, expectedPlacement, !patched, mw.Spec.Workload.Manifests[0].Raw)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	worklisterv1alpha1 "open-cluster-management.io/api/client/work/listers/work/v1alpha1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+// observedObject is the metadata of a manifestwork or manifestWorkReplicaSet last observed by the deployTracker
+type observedObject struct {
+	uid         types.UID
+	generation  int64
+	deleting    bool
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// deployTracker tracks the manifestWorkReplicaSets to deploy. A manifestWorkReplicaSet is deployed once any event
+// other than the status change of itself or its manifestworks happens, so the manifestworks are not generated and
+// compared again for every status update reported by the clusters, nor for the status patched by the controller
+// itself. The status changes still deploy the manifestWorkReplicaSet whose rollout is not completed since the rollout
+// proceeds with the status of the manifestworks.
+type deployTracker struct {
+	lock sync.Mutex
+	// pending is the keys of the manifestWorkReplicaSets to deploy
+	pending sets.Set[string]
+	// works is the manifestworks last observed by their namespace/name
+	works map[string]*observedObject
+	// replicaSets is the manifestWorkReplicaSets last observed by their namespace/name
+	replicaSets map[string]*observedObject
+}
+
+// markDeploy marks the manifestWorkReplicaSets of the queue keys to deploy
+func (t *deployTracker) markDeploy(keys ...string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.pending == nil {
+		t.pending = sets.New[string]()
+	}
+	for _, key := range keys {
+		if len(key) > 0 {
+			t.pending.Insert(key)
+		}
+	}
+}
+
+// popDeploy returns true if the manifestWorkReplicaSet of the queue key is marked to deploy, and unmarks it
+func (t *deployTracker) popDeploy(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.pending.Has(key) {
+		return false
+	}
+	t.pending.Delete(key)
+	return true
+}
+
+// observeManifestWork returns true if the manifestwork is added, deleted or changed other than its status since it
+// was observed last time. The manifestwork deleted is no longer in the lister when its event is handled.
+func (t *deployTracker) observeManifestWork(obj runtime.Object, lister worklisterv1.ManifestWorkLister) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.works == nil {
+		t.works = map[string]*observedObject{}
+	}
+	return observe(t.works, obj, func(namespace, name string) error {
+		_, err := lister.ManifestWorks(namespace).Get(name)
+		return err
+	})
+}
+
+// observeManifestWorkReplicaSet returns true if the manifestWorkReplicaSet is added, deleted or changed other than its
+// status since it was observed last time. The status of the manifestWorkReplicaSet is patched by the controller itself
+// for the status changes of the manifestworks, which must not deploy the manifestWorkReplicaSet again.
+func (t *deployTracker) observeManifestWorkReplicaSet(obj runtime.Object, lister worklisterv1alpha1.ManifestWorkReplicaSetLister) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.replicaSets == nil {
+		t.replicaSets = map[string]*observedObject{}
+	}
+	return observe(t.replicaSets, obj, func(namespace, name string) error {
+		_, err := lister.ManifestWorkReplicaSets(namespace).Get(name)
+		return err
+	})
+}
+
+// observe records the metadata of the object in the observed objects, and returns true if the object is added,
+// deleted or changed other than its status. The object is deleted if it is not found by the get func.
+func observe(observed map[string]*observedObject, obj runtime.Object, get func(namespace, name string) error) bool {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return true
+	}
+	accessor, err := apimeta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+
+	if err := get(accessor.GetNamespace(), accessor.GetName()); errors.IsNotFound(err) {
+		delete(observed, key)
+		return true
+	}
+
+	current := &observedObject{
+		uid:         accessor.GetUID(),
+		generation:  accessor.GetGeneration(),
+		deleting:    accessor.GetDeletionTimestamp() != nil,
+		labels:      accessor.GetLabels(),
+		annotations: accessor.GetAnnotations(),
+	}
+	last, ok := observed[key]
+	observed[key] = current
+	return !ok || !reflect.DeepEqual(last, current)
+}
+
+// deployKeysFunc returns the queue keys func marking the manifestWorkReplicaSets of the keys to deploy
+func (t *deployTracker) deployKeysFunc(keysFunc func(obj runtime.Object) []string) func(obj runtime.Object) []string {
+	return func(obj runtime.Object) []string {
+		keys := keysFunc(obj)
+		t.markDeploy(keys...)
+		return keys
+	}
+}
+
+// rolloutCompleted returns true if the manifestwork is rolled out to all the clusters
+func rolloutCompleted(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) bool {
+	condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+	return condition != nil && condition.Reason == ReasonRolloutCompleted
+}
@@ -19,6 +19,8 @@ type finalizeReconciler struct {
 	workApplier        *workapplier.WorkApplier
 	workClient         workclientset.Interface
 	manifestWorkLister worklisterv1.ManifestWorkLister
+	// appliedWorks is the tracker of the deployReconciler to forget the manifestworks once they are deleted
+	appliedWorks *workTracker
 }
 
 func (f *finalizeReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
@@ -43,6 +45,10 @@ func (f *finalizeReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alp
 		return mwrSet, reconcileContinue, err
 	}
 
+	if f.appliedWorks != nil {
+		f.appliedWorks.forget(manifestWorkReplicaSetKey(mwrSet))
+	}
+
 	// Remove finalizer after delete all created Manifestworks
 	if helper.RemoveFinalizer(mwrSet, ManifestWorkReplicaSetFinalizer) {
 		_, err := f.workClient.WorkV1alpha1().ManifestWorkReplicaSets(mwrSet.Namespace).Update(ctx, mwrSet, metav1.UpdateOptions{})
@@ -2,21 +2,48 @@ package manifestworkreplicasetcontroller
 
 import (
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
 	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 )
 
 const (
-	manifestWorkReplicaSetByPlacement = "manifestWorkReplicaSetByPlacement"
+	manifestWorkReplicaSetByPlacement    = "manifestWorkReplicaSetByPlacement"
+	manifestWorkByManifestWorkReplicaSet = "manifestWorkByManifestWorkReplicaSet"
 )
 
+// manifestWorkIndexLister is the manifestwork lister which lists the manifestworks of a manifestWorkReplicaSet
+// by the index rather than iterating all the manifestworks on the hub.
+type manifestWorkIndexLister struct {
+	worklisterv1.ManifestWorkLister
+	indexer cache.Indexer
+}
+
+func (l *manifestWorkIndexLister) listByManifestWorkReplicaSet(mwrSetKey string) ([]*workapiv1.ManifestWork, error) {
+	objs, err := l.indexer.ByIndex(manifestWorkByManifestWorkReplicaSet, mwrSetKey)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestWorks := make([]*workapiv1.ManifestWork, 0, len(objs))
+	for _, obj := range objs {
+		manifestWorks = append(manifestWorks, obj.(*workapiv1.ManifestWork))
+	}
+	return manifestWorks, nil
+}
+
 func (m *ManifestWorkReplicaSetController) placementQueueKeysFunc(obj runtime.Object) []string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -64,6 +91,36 @@ func (m *ManifestWorkReplicaSetController) placementDecisionQueueKeysFunc(obj ru
 	return keys
 }
 
+// managedClusterQueueKeysFunc enqueues the manifestWorkReplicaSets with override rules which have manifestworks
+// in the cluster namespace, since the overrides applied to the cluster depend on its labels and claims.
+func (m *ManifestWorkReplicaSetController) managedClusterQueueKeysFunc(obj runtime.Object) []string {
+	accessor, _ := meta.Accessor(obj)
+	manifestWorks, err := m.manifestWorkLister.ManifestWorks(accessor.GetName()).List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return []string{}
+	}
+
+	var keys []string
+	for _, mw := range manifestWorks {
+		namespace, name, found := strings.Cut(mw.Labels[ManifestWorkReplicaSetControllerNameLabelKey], ".")
+		if !found {
+			continue
+		}
+		manifestWorkReplicaSet, err := m.manifestWorkReplicaSetLister.ManifestWorkReplicaSets(namespace).Get(name)
+		if err != nil {
+			continue
+		}
+		if _, ok := manifestWorkReplicaSet.Annotations[hubhelper.OverridesAnnotationKey]; !ok {
+			continue
+		}
+		klog.V(4).Infof("enqueue manifestWorkReplicaSet %s/%s, because of managedCluster %s", namespace, name, accessor.GetName())
+		keys = append(keys, fmt.Sprintf("%s/%s", namespace, name))
+	}
+
+	return keys
+}
+
 // we will generate manifestwork with a label
 func (m *ManifestWorkReplicaSetController) manifestWorkQueueKeyFunc(obj runtime.Object) string {
 	accessor, _ := meta.Accessor(obj)
@@ -90,6 +147,20 @@ func indexManifestWorkReplicaSetByPlacement(obj interface{}) ([]string, error) {
 	return keys, nil
 }
 
+// indexManifestWorkByManifestWorkReplicaSet indexes the manifestworks by the key of their manifestWorkReplicaSet
+func indexManifestWorkByManifestWorkReplicaSet(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, err
+	}
+
+	key, ok := accessor.GetLabels()[ManifestWorkReplicaSetControllerNameLabelKey]
+	if !ok {
+		return []string{}, nil
+	}
+	return []string{key}, nil
+}
+
 // manifestWorkReplicaSetKey return the value of the key of manifestworkreplicaset, and comply with
 // label value format.
 func manifestWorkReplicaSetKey(mwrs *workapiv1alpha1.ManifestWorkReplicaSet) string {
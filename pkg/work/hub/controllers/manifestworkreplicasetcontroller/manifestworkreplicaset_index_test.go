@@ -62,7 +62,7 @@ func TestPlaceMWControllerIndex(t *testing.T) {
 				workClient: fWorkClient, manifestWorkLister: mwLister},
 			&addFinalizerReconciler{workClient: fWorkClient},
 			&deployReconciler{workApplier: workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
-				manifestWorkLister: mwLister, placementLister: placementLister, placeDecisionLister: placementDecisionLister},
+				manifestWorkLister: mwLister, orphanedWorkLister: mwLister, placementLister: placementLister, placeDecisionLister: placementDecisionLister},
 			&statusReconciler{manifestWorkLister: mwLister},
 		},
 	}
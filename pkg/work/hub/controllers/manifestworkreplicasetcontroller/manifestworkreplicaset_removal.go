@@ -0,0 +1,232 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+)
+
+const (
+	// ManifestWorkReplicaSetConditionRemovalPending is the condition type of ManifestWorkReplicaSet reporting the
+	// clusters no longer selected by the placements whose manifestworks are pending removal.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionRemovalPending = "RemovalPending"
+
+	ReasonRemovalPending = "RemovalPending"
+
+	// maxPendingRemovalsInCondition is the max number of the clusters listed in the condition RemovalPending
+	maxPendingRemovalsInCondition = 20
+)
+
+// removalTracker counts the consecutive changes of the placement decisions from which the clusters are absent. A
+// change is identified by the version of the placement decisions, so the reconciles triggered by other events are
+// not counted. The counts are kept in memory since updating them on the manifestworks would trigger the
+// reconciles, and they start over once the controller restarts, which only delays the removal.
+type removalTracker struct {
+	lock   sync.Mutex
+	counts map[string]*removalCount
+}
+
+type removalCount struct {
+	decisions string
+	count     int
+}
+
+// observe counts the placement decisions of the version from which the cluster of the key is absent, and returns
+// the number of the consecutive changes of the placement decisions counted.
+func (t *removalTracker) observe(key, decisions string) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.counts == nil {
+		t.counts = map[string]*removalCount{}
+	}
+	count, ok := t.counts[key]
+	if !ok {
+		count = &removalCount{}
+		t.counts[key] = count
+	}
+	if count.count == 0 || count.decisions != decisions {
+		count.decisions = decisions
+		count.count++
+	}
+	return count.count
+}
+
+func (t *removalTracker) forget(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.counts, key)
+}
+
+// pendingRemoval is a manifestwork pending removal
+type pendingRemoval struct {
+	cluster string
+	since   time.Time
+	// after is the duration to reconcile again to check the removal, it is zero if the removal is only checked
+	// again once the placement decisions change
+	after time.Duration
+}
+
+// removeManifestWork removes the manifestwork of a cluster which is no longer selected by the placements with the
+// removal policy, it returns the pending removal if the manifestwork is not removed yet. The decisions is the
+// version of the placement decisions of the manifestWorkReplicaSet.
+func (d *deployReconciler) removeManifestWork(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	policy *hubhelper.RemovalPolicy, mw *workv1.ManifestWork, decisions string, now time.Time) (*pendingRemoval, error) {
+	key := fmt.Sprintf("%s/%s", manifestWorkReplicaSetKey(mwrSet), mw.Namespace)
+
+	if !policy.Immediate() {
+		since, err := time.Parse(time.RFC3339, mw.Annotations[hubhelper.RemovalPendingSinceAnnotationKey])
+		if err != nil {
+			since = now
+			if err := d.patchRemovalPendingSince(ctx, mw, now.UTC().Format(time.RFC3339)); err != nil {
+				return nil, err
+			}
+		}
+
+		pending := &pendingRemoval{cluster: mw.Namespace, since: since}
+		if remaining := since.Add(policy.GracePeriod.Duration).Sub(now); remaining > 0 {
+			pending.after = remaining
+		}
+		if pending.after > 0 || (policy.ConsecutiveDecisions > 1 && d.removals.observe(key, decisions) < policy.ConsecutiveDecisions) {
+			return pending, nil
+		}
+	}
+
+	d.removals.forget(key)
+	if !policy.Orphan {
+		return nil, d.workApplier.Delete(ctx, mw.Namespace, mw.Name)
+	}
+
+	// orphan the manifestwork by removing the label of the manifestworkreplicaset, the manifestworkreplicaset is
+	// recorded to adopt the manifestwork again once the cluster is selected again.
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"uid":             mw.UID,
+			"resourceVersion": mw.ResourceVersion,
+			"labels": map[string]interface{}{
+				ManifestWorkReplicaSetControllerNameLabelKey: nil,
+				hubhelper.OrphanedFromLabelKey:               manifestWorkReplicaSetKey(mwrSet),
+			},
+			"annotations": map[string]interface{}{hubhelper.RemovalPendingSinceAnnotationKey: nil},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = d.workClient.WorkV1().ManifestWorks(mw.Namespace).Patch(ctx, mw.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return nil, err
+}
+
+// adoptManifestWork adopts the manifestwork orphaned by the manifestWorkReplicaSet once the cluster is selected
+// again. The orphaned manifestwork is not in the cache of the manifestworks of the manifestworkreplicasets, it is
+// found with the label of the orphaned manifestworks instead. The labels are added back and the manifestwork is
+// updated by the next reconcile triggered by the label.
+func (d *deployReconciler) adoptManifestWork(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	orphaned, required *workv1.ManifestWork) error {
+	key := manifestWorkReplicaSetKey(mwrSet)
+	if orphaned.Labels[hubhelper.OrphanedFromLabelKey] != key {
+		return fmt.Errorf("the manifestwork %s/%s already exists and is not orphaned by the manifestworkreplicaset %s",
+			orphaned.Namespace, orphaned.Name, key)
+	}
+
+	labels := map[string]interface{}{hubhelper.OrphanedFromLabelKey: nil}
+	for k, v := range required.Labels {
+		labels[k] = v
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"uid":             orphaned.UID,
+			"resourceVersion": orphaned.ResourceVersion,
+			"labels":          labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = d.workClient.WorkV1().ManifestWorks(orphaned.Namespace).Patch(ctx, orphaned.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// cancelRemoval cancels the pending removal of the manifestwork once the cluster is selected by the placements again
+func (d *deployReconciler) cancelRemoval(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, mw *workv1.ManifestWork) error {
+	d.removals.forget(fmt.Sprintf("%s/%s", manifestWorkReplicaSetKey(mwrSet), mw.Namespace))
+	if _, ok := mw.Annotations[hubhelper.RemovalPendingSinceAnnotationKey]; !ok {
+		return nil
+	}
+	return d.patchRemovalPendingSince(ctx, mw, nil)
+}
+
+func (d *deployReconciler) patchRemovalPendingSince(ctx context.Context, mw *workv1.ManifestWork, since interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{hubhelper.RemovalPendingSinceAnnotationKey: since},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = d.workClient.WorkV1().ManifestWorks(mw.Namespace).Patch(ctx, mw.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// setRemovalPendingCondition lists the clusters pending removal in the status of the manifestWorkReplicaSet
+func setRemovalPendingCondition(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, policy *hubhelper.RemovalPolicy, pendings []*pendingRemoval) {
+	if len(pendings) == 0 {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRemovalPending)
+		return
+	}
+
+	sort.Slice(pendings, func(i, j int) bool {
+		return pendings[i].cluster < pendings[j].cluster
+	})
+	clusters := []string{}
+	for index, pending := range pendings {
+		if index == maxPendingRemovalsInCondition {
+			clusters = append(clusters, fmt.Sprintf("and %d more", len(pendings)-index))
+			break
+		}
+		clusters = append(clusters, fmt.Sprintf("%s since %s", pending.cluster, pending.since.UTC().Format(time.RFC3339)))
+	}
+
+	action := "deleted"
+	if policy.Orphan {
+		action = "orphaned"
+	}
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionRemovalPending,
+		ReasonRemovalPending, fmt.Sprintf("The manifestworks of %d clusters are pending to be %s: %s",
+			len(pendings), action, strings.Join(clusters, ", ")), metav1.ConditionTrue))
+}
+
+// decisionsVersion returns the version of the placement decisions of the placements, which changes once any of the
+// placement decisions changes.
+func decisionsVersion(placeDecisionLister clusterlister.PlacementDecisionLister, placements []*clusterv1beta1.Placement) (string, error) {
+	versions := []string{}
+	for _, placement := range placements {
+		decisions, err := placeDecisionLister.PlacementDecisions(placement.Namespace).List(
+			labels.SelectorFromSet(labels.Set{clusterv1beta1.PlacementLabel: placement.Name}))
+		if err != nil {
+			return "", err
+		}
+		for _, decision := range decisions {
+			versions = append(versions, fmt.Sprintf("%s/%s/%s", decision.Name, decision.UID, decision.ResourceVersion))
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ","), nil
+}
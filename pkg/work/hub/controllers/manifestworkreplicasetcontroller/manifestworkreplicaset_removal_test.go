@@ -0,0 +1,282 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+func TestDeployReconcileRemovalPolicy(t *testing.T) {
+	pendingSince := func(since time.Time) map[string]string {
+		return map[string]string{hubhelper.RemovalPendingSinceAnnotationKey: since.UTC().Format(time.RFC3339)}
+	}
+
+	cases := []struct {
+		name string
+		// policy is the removal policy of the manifestworkreplicaset, cls2 is no longer selected by the placement
+		policy      string
+		annotations map[string]map[string]string
+		reconciles  int
+		// changeDecisions changes the placement decisions before each reconcile except the first one
+		changeDecisions    bool
+		expectedActions    []string
+		expectedPending    bool
+		expectedNotRequeue bool
+	}{
+		{
+			name:            "removed immediately by default",
+			reconciles:      1,
+			expectedActions: []string{"delete/cls2"},
+		},
+		{
+			name:            "pending removal within the grace period",
+			policy:          `{"gracePeriod":"10m"}`,
+			reconciles:      1,
+			expectedActions: []string{"patch/cls2"},
+			expectedPending: true,
+		},
+		{
+			name:            "removed after the grace period",
+			policy:          `{"gracePeriod":"10m"}`,
+			annotations:     map[string]map[string]string{"cls2": pendingSince(time.Now().Add(-20 * time.Minute))},
+			reconciles:      1,
+			expectedActions: []string{"delete/cls2"},
+		},
+		{
+			name:            "orphaned",
+			policy:          `{"orphan":true}`,
+			reconciles:      1,
+			expectedActions: []string{"patch/cls2"},
+		},
+		{
+			name:            "removed after the consecutive decisions",
+			policy:          `{"consecutiveDecisions":2}`,
+			reconciles:      2,
+			changeDecisions: true,
+			expectedActions: []string{"patch/cls2", "patch/cls2", "delete/cls2"},
+		},
+		{
+			name:               "pending removal until the placement decisions change",
+			policy:             `{"consecutiveDecisions":2}`,
+			reconciles:         3,
+			expectedActions:    []string{"patch/cls2", "patch/cls2", "patch/cls2"},
+			expectedPending:    true,
+			expectedNotRequeue: true,
+		},
+		{
+			name:   "cancel the pending removal of the cluster selected again",
+			policy: `{"gracePeriod":"10m"}`,
+			annotations: map[string]map[string]string{
+				"cls1": pendingSince(time.Now()), "cls2": pendingSince(time.Now()),
+			},
+			reconciles:      1,
+			expectedActions: []string{"patch/cls1"},
+			expectedPending: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+			if len(c.policy) > 0 {
+				mwrSet.Annotations = map[string]string{hubhelper.RemovalPolicyAnnotationKey: c.policy}
+			}
+
+			objects := []runtime.Object{mwrSet}
+			works := []*workv1.ManifestWork{}
+			for _, cls := range []string{"cls1", "cls2"} {
+				mw, _ := CreateManifestWork(mwrSet, cls)
+				mw.Annotations = c.annotations[cls]
+				objects = append(objects, mw)
+				works = append(works, mw)
+			}
+			fWorkClient := fakeworkclient.NewSimpleClientset(objects...)
+			workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+			for _, mw := range works {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+			placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1")
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(
+				fakeclusterclient.NewSimpleClientset(placement, placementDecision), 1*time.Minute)
+			if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+				t.Fatal(err)
+			}
+			if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+				t.Fatal(err)
+			}
+
+			pmwDeployController := &deployReconciler{
+				workClient:          fWorkClient,
+				workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+				manifestWorkLister:  mwLister,
+				orphanedWorkLister:  mwLister,
+				placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+				placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+			}
+
+			var err error
+			for i := 0; i < c.reconciles; i++ {
+				if c.changeDecisions && i > 0 {
+					placementDecision = placementDecision.DeepCopy()
+					placementDecision.ResourceVersion = fmt.Sprintf("%d", i)
+					if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Update(placementDecision); err != nil {
+						t.Fatal(err)
+					}
+				}
+				mwrSet, _, err = pmwDeployController.reconcile(context.TODO(), mwrSet)
+			}
+			expectedRequeue := c.expectedPending && !c.expectedNotRequeue
+			if _, ok := err.(*requeueError); ok != expectedRequeue {
+				t.Errorf("expected requeue %v, but got %v", expectedRequeue, err)
+			}
+
+			actions := []string{}
+			for _, action := range fWorkClient.Actions() {
+				switch action.(type) {
+				case clienttesting.PatchAction, clienttesting.DeleteAction:
+					actions = append(actions, action.GetVerb()+"/"+action.GetNamespace())
+				}
+			}
+			if !reflect.DeepEqual(actions, c.expectedActions) {
+				t.Errorf("expected actions %v, but got %v", c.expectedActions, actions)
+			}
+
+			condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRemovalPending)
+			if (condition != nil && condition.Status == metav1.ConditionTrue) != c.expectedPending {
+				t.Errorf("expected pending removal %v, but got %v", c.expectedPending, condition)
+			}
+			if len(c.policy) > 0 && c.expectedActions[len(c.expectedActions)-1] == "patch/cls2" && !c.expectedPending {
+				mw, err := fWorkClient.WorkV1().ManifestWorks("cls2").Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if mw.Labels[hubhelper.OrphanedFromLabelKey] != manifestWorkReplicaSetKey(mwrSet) {
+					t.Errorf("expected the orphaned manifestwork records the manifestworkreplicaset, but got %v", mw.Labels)
+				}
+			}
+			if mwrSet.Status.Summary.Total != 1 {
+				t.Errorf("expected the cluster pending removal is not counted, but got total %d", mwrSet.Status.Summary.Total)
+			}
+		})
+	}
+}
+
+func TestDeployReconcileAdoptOrphanedWork(t *testing.T) {
+	cases := []struct {
+		name string
+		// orphanedFrom is the manifestworkreplicaset recorded on the unlabelled manifestwork of cls2, which is only
+		// in the cache of the orphaned manifestworks if it is recorded
+		orphanedFrom    string
+		expectedErr     bool
+		expectedOwned   bool
+		expectedActions []string
+	}{
+		{
+			name:            "adopt the manifestwork orphaned by the manifestworkreplicaset",
+			orphanedFrom:    "default.mwrSet-test",
+			expectedOwned:   true,
+			expectedActions: []string{"create/cls1", "patch/cls2"},
+		},
+		{
+			name:            "not adopt the manifestwork orphaned by another manifestworkreplicaset",
+			orphanedFrom:    "default.another",
+			expectedErr:     true,
+			expectedActions: []string{"create/cls1"},
+		},
+		{
+			name:            "not adopt the manifestwork not orphaned",
+			expectedActions: []string{"create/cls1", "create/cls2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+			existing := &workv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{Name: mwrSet.Name, Namespace: "cls2", UID: "orphaned", ResourceVersion: "1"},
+			}
+
+			fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet, existing)
+			workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+			mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+			orphanedWorkInformer := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute).Work().V1().ManifestWorks()
+			if len(c.orphanedFrom) > 0 {
+				existing.Labels = map[string]string{hubhelper.OrphanedFromLabelKey: c.orphanedFrom}
+				if err := orphanedWorkInformer.Informer().GetStore().Add(existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2")
+			clusterInformerFactory := clusterinformers.NewSharedInformerFactory(
+				fakeclusterclient.NewSimpleClientset(placement, placementDecision), 1*time.Minute)
+			if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+				t.Fatal(err)
+			}
+			if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+				t.Fatal(err)
+			}
+
+			pmwDeployController := &deployReconciler{
+				workClient:          fWorkClient,
+				workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+				manifestWorkLister:  mwLister,
+				orphanedWorkLister:  orphanedWorkInformer.Lister(),
+				placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+				placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+			}
+
+			fWorkClient.ClearActions()
+			_, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+
+			actions := []string{}
+			for _, action := range fWorkClient.Actions() {
+				if action.GetVerb() != "get" {
+					actions = append(actions, action.GetVerb()+"/"+action.GetNamespace())
+				}
+			}
+			if !reflect.DeepEqual(actions, c.expectedActions) {
+				t.Errorf("expected actions %v, but got %v", c.expectedActions, actions)
+			}
+
+			mw, err := fWorkClient.WorkV1().ManifestWorks("cls2").Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			owned := mw.Labels[ManifestWorkReplicaSetControllerNameLabelKey] == manifestWorkReplicaSetKey(mwrSet)
+			if owned != c.expectedOwned {
+				t.Errorf("expected the manifestwork is owned %v, but got labels %v", c.expectedOwned, mw.Labels)
+			}
+			if _, ok := mw.Labels[hubhelper.OrphanedFromLabelKey]; c.expectedOwned && ok {
+				t.Errorf("expected the orphaned label is removed, but got labels %v", mw.Labels)
+			}
+			if c.expectedOwned && len(mw.Labels[hubhelper.RevisionHashLabelKey]) == 0 {
+				t.Errorf("expected the revision hash label is added, but got labels %v", mw.Labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+)
+
+const (
+	// ManifestWorkReplicaSetConditionRevisionsRolledOut is the condition type of ManifestWorkReplicaSet reporting
+	// the number of the clusters of each template revision.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionRevisionsRolledOut = "CurrentRevisionRolledOut"
+
+	ReasonMultipleRevisions = "MultipleRevisions"
+)
+
+// revisionReconciler keeps the history of the template revisions of manifestWorkReplicaSet as controllerrevisions,
+// and reports the number of the clusters of each revision.
+type revisionReconciler struct {
+	kubeClient               kubernetes.Interface
+	controllerRevisionLister appslisters.ControllerRevisionLister
+	manifestWorkLister       worklisterv1.ManifestWorkLister
+}
+
+// revisionData is the data of the controllerrevision, which is everything to generate the manifestworks
+type revisionData struct {
+	ManifestWorkTemplate json.RawMessage `json:"manifestWorkTemplate"`
+	Overrides            string          `json:"overrides,omitempty"`
+}
+
+func (r *revisionReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	limit, err := hubhelper.RevisionHistoryLimitOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	revisions, err := listRevisions(r.controllerRevisionLister, mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	current, err := r.syncCurrentRevision(ctx, mwrSet, revisions)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+
+	manifestWorks, err := listManifestWorksByManifestWorkReplicaSet(mwrSet, r.manifestWorkLister)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	clusters := map[string]int{}
+	for _, mw := range manifestWorks {
+		if mw.DeletionTimestamp.IsZero() {
+			clusters[mw.Labels[hubhelper.RevisionHashLabelKey]]++
+		}
+	}
+
+	// the revisions used by any cluster, the rollback or the analysis to roll back to are kept
+	inUse := sets.New[string](current.Name)
+	for _, revision := range revisions {
+		if clusters[revision.Labels[hubhelper.RevisionHashLabelKey]] > 0 {
+			inUse.Insert(revision.Name)
+		}
+	}
+	if rollback, err := hubhelper.RollbackOf(mwrSet); err == nil && rollback != nil {
+		for _, revision := range revisions {
+			if revision.Revision == rollback.Revision {
+				inUse.Insert(revision.Name)
+			}
+		}
+	}
+	if analysis, err := hubhelper.AnalysisOf(mwrSet); err == nil && analysis != nil {
+		if target := rollbackRevision(revisions, current); target != nil {
+			inUse.Insert(target.Name)
+		}
+	}
+
+	// delete the oldest revisions which are not in use beyond the limit
+	old := []*appsv1.ControllerRevision{}
+	for _, revision := range revisions {
+		if !inUse.Has(revision.Name) {
+			old = append(old, revision)
+		}
+	}
+	for i := 0; i < len(old)-limit; i++ {
+		err := r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Delete(ctx, old[i].Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return mwrSet, reconcileContinue, err
+		}
+	}
+
+	setRevisionsCondition(mwrSet, current, revisions, clusters)
+	return mwrSet, reconcileContinue, nil
+}
+
+// syncCurrentRevision creates the controllerrevision of the current template, or increases its revision number if
+// the template is reverted to a previous revision.
+func (r *revisionReconciler) syncCurrentRevision(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
+	revisions []*appsv1.ControllerRevision) (*appsv1.ControllerRevision, error) {
+	hash, err := revisionHash(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int64
+	var current *appsv1.ControllerRevision
+	for _, revision := range revisions {
+		if revision.Revision > latest {
+			latest = revision.Revision
+		}
+		if revision.Labels[hubhelper.RevisionHashLabelKey] == hash {
+			current = revision
+		}
+	}
+
+	switch {
+	case current == nil:
+		required, err := newControllerRevision(mwrSet, hash, latest+1)
+		if err != nil {
+			return nil, err
+		}
+		created, err := r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Create(ctx, required, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			return required, nil
+		}
+		return created, err
+	case current.Revision < latest:
+		current = current.DeepCopy()
+		current.Revision = latest + 1
+		return r.kubeClient.AppsV1().ControllerRevisions(mwrSet.Namespace).Update(ctx, current, metav1.UpdateOptions{})
+	default:
+		return current, nil
+	}
+}
+
+// listRevisions returns the controllerrevisions of the manifestWorkReplicaSet sorted by the revision number
+func listRevisions(controllerRevisionLister appslisters.ControllerRevisionLister,
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]*appsv1.ControllerRevision, error) {
+	revisions, err := controllerRevisionLister.ControllerRevisions(mwrSet.Namespace).List(labels.SelectorFromSet(
+		labels.Set{ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet)}))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// mwrSetOfRevision returns a copy of the manifestWorkReplicaSet with the template and the overrides of the
+// revision, it returns an error if the revision does not exist.
+func mwrSetOfRevision(controllerRevisionLister appslisters.ControllerRevisionLister,
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, revisionNumber int64) (*workapiv1alpha1.ManifestWorkReplicaSet, error) {
+	revisions, err := listRevisions(controllerRevisionLister, mwrSet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, revision := range revisions {
+		if revision.Revision != revisionNumber {
+			continue
+		}
+
+		data := &revisionData{}
+		if err := json.Unmarshal(revision.Data.Raw, data); err != nil {
+			return nil, err
+		}
+		revisionMWRSet := mwrSet.DeepCopy()
+		revisionMWRSet.Spec.ManifestWorkTemplate = workapiv1.ManifestWorkSpec{}
+		if err := json.Unmarshal(data.ManifestWorkTemplate, &revisionMWRSet.Spec.ManifestWorkTemplate); err != nil {
+			return nil, err
+		}
+		setRevisionAnnotation(revisionMWRSet, hubhelper.OverridesAnnotationKey, data.Overrides)
+		return revisionMWRSet, nil
+	}
+	return nil, fmt.Errorf("the revision %d of the manifestWorkReplicaSet %s/%s is not found", revisionNumber, mwrSet.Namespace, mwrSet.Name)
+}
+
+// hashOfRevision returns the hash of the revision of the manifestWorkReplicaSet, or empty if the revision does not
+// exist.
+func hashOfRevision(controllerRevisionLister appslisters.ControllerRevisionLister,
+	mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, revisionNumber int64) (string, error) {
+	revisions, err := listRevisions(controllerRevisionLister, mwrSet)
+	if err != nil {
+		return "", err
+	}
+	for _, revision := range revisions {
+		if revision.Revision == revisionNumber {
+			return revision.Labels[hubhelper.RevisionHashLabelKey], nil
+		}
+	}
+	return "", nil
+}
+
+// setRevisionAnnotation sets the annotation of the manifestWorkReplicaSet to the value in the revision, the
+// annotation is removed if the revision does not have it.
+func setRevisionAnnotation(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, key, value string) {
+	delete(mwrSet.Annotations, key)
+	if len(value) == 0 {
+		return
+	}
+	if mwrSet.Annotations == nil {
+		mwrSet.Annotations = map[string]string{}
+	}
+	mwrSet.Annotations[key] = value
+}
+
+func newRevisionData(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]byte, error) {
+	template, err := json.Marshal(mwrSet.Spec.ManifestWorkTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&revisionData{
+		ManifestWorkTemplate: template,
+		Overrides:            mwrSet.Annotations[hubhelper.OverridesAnnotationKey],
+	})
+}
+
+// revisionHash returns the hash of the template and the overrides of the manifestWorkReplicaSet
+func revisionHash(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (string, error) {
+	data, err := newRevisionData(mwrSet)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())), nil
+}
+
+func newControllerRevision(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, hash string, revision int64) (*appsv1.ControllerRevision, error) {
+	data, err := newRevisionData(mwrSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", mwrSet.Name, hash),
+			Namespace: mwrSet.Namespace,
+			Labels: map[string]string{
+				ManifestWorkReplicaSetControllerNameLabelKey: manifestWorkReplicaSetKey(mwrSet),
+				hubhelper.RevisionHashLabelKey:               hash,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(mwrSet, workapiv1alpha1.GroupVersion.WithKind("ManifestWorkReplicaSet")),
+			},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revision,
+	}, nil
+}
+
+// setRevisionsCondition reports the number of the clusters of each revision, the condition is true if all the
+// clusters are rolled out to the current revision.
+func setRevisionsCondition(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, current *appsv1.ControllerRevision,
+	revisions []*appsv1.ControllerRevision, clusters map[string]int) {
+	currentHash := current.Labels[hubhelper.RevisionHashLabelKey]
+	numbers := map[string]int64{currentHash: current.Revision}
+	for _, revision := range revisions {
+		if _, ok := numbers[revision.Labels[hubhelper.RevisionHashLabelKey]]; !ok {
+			numbers[revision.Labels[hubhelper.RevisionHashLabelKey]] = revision.Revision
+		}
+	}
+
+	hashes := []string{}
+	for hash := range clusters {
+		hashes = append(hashes, hash)
+	}
+	// the latest revisions first
+	sort.Slice(hashes, func(i, j int) bool {
+		if numbers[hashes[i]] != numbers[hashes[j]] {
+			return numbers[hashes[i]] > numbers[hashes[j]]
+		}
+		return hashes[i] < hashes[j]
+	})
+
+	counts := []string{}
+	for _, hash := range hashes {
+		switch number, ok := numbers[hash]; {
+		case len(hash) == 0:
+			counts = append(counts, fmt.Sprintf("no revision: %d clusters", clusters[hash]))
+		case !ok:
+			counts = append(counts, fmt.Sprintf("unknown revision (%s): %d clusters", hash, clusters[hash]))
+		default:
+			counts = append(counts, fmt.Sprintf("revision %d (%s): %d clusters", number, hash, clusters[hash]))
+		}
+	}
+
+	message := fmt.Sprintf("The current revision is %d (%s)", current.Revision, currentHash)
+	if len(counts) > 0 {
+		message = fmt.Sprintf("%s; %s", message, strings.Join(counts, ", "))
+	}
+	if len(clusters) == 0 || (len(clusters) == 1 && clusters[currentHash] > 0) {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionRevisionsRolledOut,
+			workapiv1alpha1.ReasonAsExpected, message, metav1.ConditionTrue))
+		return
+	}
+	apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionRevisionsRolledOut,
+		ReasonMultipleRevisions, message, metav1.ConditionFalse))
+}
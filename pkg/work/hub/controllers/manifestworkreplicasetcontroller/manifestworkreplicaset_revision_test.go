@@ -0,0 +1,327 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+func TestRevisionReconcile(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	currentHash, _ := revisionHash(mwrSet)
+
+	// the revisions of the previous templates, which differ in the overrides
+	previous := func(number int64) *appsv1.ControllerRevision {
+		previousMWRSet := mwrSet.DeepCopy()
+		previousMWRSet.Annotations = map[string]string{
+			hubhelper.OverridesAnnotationKey: fmt.Sprintf(`[{"name":"rule%d","patches":[]}]`, number),
+		}
+		hash, _ := revisionHash(previousMWRSet)
+		revision, _ := newControllerRevision(previousMWRSet, hash, number)
+		return revision
+	}
+	current := func(number int64) *appsv1.ControllerRevision {
+		revision, _ := newControllerRevision(mwrSet, currentHash, number)
+		return revision
+	}
+	work := func(cluster string, revision *appsv1.ControllerRevision) *workapiv1.ManifestWork {
+		mw, _ := CreateManifestWork(mwrSet, cluster)
+		mw.Labels[hubhelper.RevisionHashLabelKey] = revision.Labels[hubhelper.RevisionHashLabelKey]
+		return mw
+	}
+
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		revisions          []*appsv1.ControllerRevision
+		works              []*workapiv1.ManifestWork
+		expectedActions    []string
+		expectedDeleted    []string
+		expectedRevision   int64
+		expectedStatus     metav1.ConditionStatus
+		expectedInMessages []string
+	}{
+		{
+			name:               "create the first revision",
+			expectedActions:    []string{"create"},
+			expectedRevision:   1,
+			expectedStatus:     metav1.ConditionTrue,
+			expectedInMessages: []string{"The current revision is 1"},
+		},
+		{
+			name:               "all the clusters are rolled out to the current revision",
+			revisions:          []*appsv1.ControllerRevision{previous(1), current(2)},
+			works:              []*workapiv1.ManifestWork{work("cls1", current(2)), work("cls2", current(2))},
+			expectedActions:    []string{},
+			expectedRevision:   2,
+			expectedStatus:     metav1.ConditionTrue,
+			expectedInMessages: []string{fmt.Sprintf("revision 2 (%s): 2 clusters", currentHash)},
+		},
+		{
+			name:             "the template is reverted to a previous revision",
+			revisions:        []*appsv1.ControllerRevision{current(1), previous(2)},
+			works:            []*workapiv1.ManifestWork{work("cls1", previous(2))},
+			expectedActions:  []string{"update"},
+			expectedRevision: 3,
+			expectedStatus:   metav1.ConditionFalse,
+			expectedInMessages: []string{
+				fmt.Sprintf("revision 2 (%s): 1 clusters", previous(2).Labels[hubhelper.RevisionHashLabelKey]),
+			},
+		},
+		{
+			name:             "truncate the revisions not in use beyond the limit",
+			annotations:      map[string]string{hubhelper.RevisionHistoryLimitAnnotationKey: "1"},
+			revisions:        []*appsv1.ControllerRevision{previous(1), previous(2), previous(3), previous(4)},
+			works:            []*workapiv1.ManifestWork{work("cls1", previous(2)), work("cls2", previous(4))},
+			expectedActions:  []string{"create", "delete"},
+			expectedDeleted:  []string{previous(1).Name},
+			expectedRevision: 5,
+			expectedStatus:   metav1.ConditionFalse,
+			expectedInMessages: []string{
+				fmt.Sprintf("revision 4 (%s): 1 clusters", previous(4).Labels[hubhelper.RevisionHashLabelKey]),
+				fmt.Sprintf("revision 2 (%s): 1 clusters", previous(2).Labels[hubhelper.RevisionHashLabelKey]),
+			},
+		},
+		{
+			name: "keep the revision to roll back to",
+			annotations: map[string]string{
+				hubhelper.RevisionHistoryLimitAnnotationKey: "0",
+				hubhelper.RollbackAnnotationKey:             `{"revision":1}`,
+			},
+			revisions:        []*appsv1.ControllerRevision{previous(1), previous(2), current(3)},
+			expectedActions:  []string{"delete"},
+			expectedDeleted:  []string{previous(2).Name},
+			expectedRevision: 3,
+			expectedStatus:   metav1.ConditionTrue,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := mwrSet.DeepCopy()
+			mwrSet.Annotations = c.annotations
+
+			objects := []runtime.Object{}
+			for _, revision := range c.revisions {
+				objects = append(objects, revision)
+			}
+			fKubeClient := fakekube.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fKubeClient, 1*time.Minute)
+			for _, revision := range c.revisions {
+				if err := kubeInformerFactory.Apps().V1().ControllerRevisions().Informer().GetStore().Add(revision); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fWorkClient := fakeworkclient.NewSimpleClientset()
+			workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+			for _, mw := range c.works {
+				if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reconciler := revisionReconciler{
+				kubeClient:               fKubeClient,
+				controllerRevisionLister: kubeInformerFactory.Apps().V1().ControllerRevisions().Lister(),
+				manifestWorkLister:       workInformerFactory.Work().V1().ManifestWorks().Lister(),
+			}
+			mwrSet, _, err := reconciler.reconcile(context.TODO(), mwrSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actions := []string{}
+			deleted := []string{}
+			for _, action := range fKubeClient.Actions() {
+				actions = append(actions, action.GetVerb())
+				if deleteAction, ok := action.(clienttesting.DeleteAction); ok {
+					deleted = append(deleted, deleteAction.GetName())
+				}
+			}
+			if !reflect.DeepEqual(actions, c.expectedActions) {
+				t.Errorf("expected actions %v, but got %v", c.expectedActions, actions)
+			}
+			if len(c.expectedDeleted) > 0 && !reflect.DeepEqual(deleted, c.expectedDeleted) {
+				t.Errorf("expected revisions %v deleted, but got %v", c.expectedDeleted, deleted)
+			}
+
+			revision, err := fKubeClient.AppsV1().ControllerRevisions("default").Get(
+				context.TODO(), current(0).Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revision.Revision != c.expectedRevision {
+				t.Errorf("expected current revision %d, but got %d", c.expectedRevision, revision.Revision)
+			}
+
+			condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRevisionsRolledOut)
+			if condition == nil || condition.Status != c.expectedStatus {
+				t.Fatalf("expected condition status %s, but got %v", c.expectedStatus, condition)
+			}
+			for _, message := range c.expectedInMessages {
+				if !strings.Contains(condition.Message, message) {
+					t.Errorf("expected %q in the message %q", message, condition.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployReconcileRollback(t *testing.T) {
+	previousMWRSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	previousHash, _ := revisionHash(previousMWRSet)
+	revision, _ := newControllerRevision(previousMWRSet, previousHash, 1)
+
+	mwrSet := previousMWRSet.DeepCopy()
+	mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+	mwrSet.Annotations = map[string]string{hubhelper.RollbackAnnotationKey: `{"revision":1,"clusters":["cls1"]}`}
+	currentHash, _ := revisionHash(mwrSet)
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fakekube.NewSimpleClientset(), 1*time.Minute)
+	if err := kubeInformerFactory.Apps().V1().ControllerRevisions().Informer().GetStore().Add(revision); err != nil {
+		t.Fatal(err)
+	}
+
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2")
+	fClusterClient := fakeclusterclient.NewSimpleClientset(placement, placementDecision)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fClusterClient, 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:              workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:       mwLister,
+		orphanedWorkLister:       mwLister,
+		placeDecisionLister:      clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:          clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		controllerRevisionLister: kubeInformerFactory.Apps().V1().ControllerRevisions().Lister(),
+	}
+
+	if _, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cluster := range []string{"cls1", "cls2"} {
+		mw, err := fWorkClient.WorkV1().ManifestWorks(cluster).Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedHash, expectedManifests := currentHash, 0
+		if cluster == "cls1" {
+			expectedHash, expectedManifests = previousHash, len(previousMWRSet.Spec.ManifestWorkTemplate.Workload.Manifests)
+		}
+		if hash := mw.Labels[hubhelper.RevisionHashLabelKey]; hash != expectedHash {
+			t.Errorf("expected the manifestwork in %s of the revision %s, but got %s", cluster, expectedHash, hash)
+		}
+		if len(mw.Spec.Workload.Manifests) != expectedManifests {
+			t.Errorf("expected %d manifests in %s, but got %d", expectedManifests, cluster, len(mw.Spec.Workload.Manifests))
+		}
+	}
+
+	// the rollback fails if the revision does not exist
+	mwrSet.Annotations[hubhelper.RollbackAnnotationKey] = `{"revision":2}`
+	if _, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet); err == nil {
+		t.Errorf("expected error for the missing revision")
+	}
+}
+
+func TestDeployReconcileFailedRevision(t *testing.T) {
+	previousMWRSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	previousHash, _ := revisionHash(previousMWRSet)
+	previous, _ := newControllerRevision(previousMWRSet, previousHash, 1)
+
+	mwrSet := previousMWRSet.DeepCopy()
+	mwrSet.Spec.ManifestWorkTemplate.Workload.Manifests = nil
+	mwrSet.Annotations = map[string]string{hubhelper.RollbackAnnotationKey: `{"revision":1,"clusters":["cls1"],"failedRevision":2}`}
+	currentHash, _ := revisionHash(mwrSet)
+	current, _ := newControllerRevision(mwrSet, currentHash, 2)
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fakekube.NewSimpleClientset(), 1*time.Minute)
+	for _, revision := range []*appsv1.ControllerRevision{previous, current} {
+		if err := kubeInformerFactory.Apps().V1().ControllerRevisions().Informer().GetStore().Add(revision); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the manifestworks of cls1 and cls2 are rolled out to the failed revision, and cls3 is not rolled out yet
+	existing := []runtime.Object{mwrSet}
+	for _, cluster := range []string{"cls1", "cls2"} {
+		mw, _ := CreateManifestWork(mwrSet, cluster)
+		existing = append(existing, mw)
+	}
+	fWorkClient := fakeworkclient.NewSimpleClientset(existing...)
+	workInformerFactory := workinformers.NewSharedInformerFactory(fWorkClient, 1*time.Minute)
+	for _, obj := range existing[1:] {
+		if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2", "cls3")
+	fClusterClient := fakeclusterclient.NewSimpleClientset(placement, placementDecision)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(fClusterClient, 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:              workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:       mwLister,
+		orphanedWorkLister:       mwLister,
+		placeDecisionLister:      clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:          clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		controllerRevisionLister: kubeInformerFactory.Apps().V1().ControllerRevisions().Lister(),
+	}
+
+	if _, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed cluster is rolled back, the cluster not rolled out yet is kept at the previous revision, and the
+	// other cluster stays at the failed revision
+	expectedHashes := map[string]string{"cls1": previousHash, "cls2": currentHash, "cls3": previousHash}
+	for cluster, expectedHash := range expectedHashes {
+		mw, err := fWorkClient.WorkV1().ManifestWorks(cluster).Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash := mw.Labels[hubhelper.RevisionHashLabelKey]; hash != expectedHash {
+			t.Errorf("expected the manifestwork in %s of the revision %s, but got %s", cluster, expectedHash, hash)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"fmt"
+	"sort"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	clusterlister "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+)
+
+const (
+	// ManifestWorkReplicaSetConditionRolloutProgressing is the condition type of ManifestWorkReplicaSet reporting
+	// the progress of rolling out the manifestwork template to the selected clusters.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionRolloutProgressing = "RolloutProgressing"
+
+	ReasonRolloutProgressing = "Progressing"
+	ReasonRolloutCompleted   = "Completed"
+	ReasonRolloutPaused      = "Paused"
+)
+
+type clusterRolloutStatus int
+
+const (
+	// clusterToApply is the status of the clusters whose manifestwork is not created or not updated yet
+	clusterToApply clusterRolloutStatus = iota
+	clusterProgressing
+	clusterSucceeded
+	clusterFailed
+)
+
+type rolloutCluster struct {
+	name string
+	// group is the decision group index of the cluster
+	group  int
+	status clusterRolloutStatus
+}
+
+// rolloutPlan is the result of planning the rollout of a manifestworkreplicaset
+type rolloutPlan struct {
+	// toApply is the clusters to apply the manifestwork in this reconcile
+	toApply []string
+
+	total       int
+	succeeded   int
+	progressing int
+	failed      int
+	// maxFailures is the max number of the failed clusters before the rollout is paused
+	maxFailures int
+	paused      bool
+}
+
+// rolloutStatusOf returns the rollout status of a cluster by comparing its existing manifestwork with the
+// required one. The cluster succeeds once the manifestwork is available, and fails once the manifestwork is
+// degraded or failed to apply, the conditions of the previous generations are ignored.
+func rolloutStatusOf(existing, required *workv1.ManifestWork) clusterRolloutStatus {
+	if existing == nil || !workapplier.ManifestWorkEqual(required, existing) {
+		return clusterToApply
+	}
+	return observedRolloutStatus(existing)
+}
+
+// observedRolloutStatus returns the rollout status of a cluster whose existing manifestwork is the required one
+func observedRolloutStatus(existing *workv1.ManifestWork) clusterRolloutStatus {
+	observed := func(conditionType string, status metav1.ConditionStatus) bool {
+		condition := apimeta.FindStatusCondition(existing.Status.Conditions, conditionType)
+		return condition != nil && condition.ObservedGeneration == existing.Generation && condition.Status == status
+	}
+
+	switch {
+	case observed(workv1.WorkDegraded, metav1.ConditionTrue), observed(workv1.WorkApplied, metav1.ConditionFalse):
+		return clusterFailed
+	case observed(workv1.WorkAvailable, metav1.ConditionTrue):
+		return clusterSucceeded
+	default:
+		return clusterProgressing
+	}
+}
+
+// planRollout decides the clusters to apply the manifestwork with the rollout strategy. The manifestworks of
+// the clusters already rolled out are always applied so their drifts are corrected, while the clusters not
+// rolled out yet are applied only when the strategy allows. The clusters are rolled out in the order of the
+// decision group index and the cluster name.
+func planRollout(strategy *hubhelper.RolloutStrategy, clusters []rolloutCluster) (*rolloutPlan, error) {
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].group != clusters[j].group {
+			return clusters[i].group < clusters[j].group
+		}
+		return clusters[i].name < clusters[j].name
+	})
+
+	plan := &rolloutPlan{total: len(clusters)}
+	concurrency, err := strategy.Concurrency(plan.total)
+	if err != nil {
+		return nil, err
+	}
+	plan.maxFailures, err = strategy.FailureThreshold(plan.total)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []rolloutCluster{}
+	// activeGroup is the first decision group which is not rolled out completely
+	activeGroup := -1
+	for _, cluster := range clusters {
+		switch cluster.status {
+		case clusterToApply:
+			pending = append(pending, cluster)
+		case clusterProgressing:
+			plan.progressing++
+		case clusterSucceeded:
+			plan.succeeded++
+		case clusterFailed:
+			plan.failed++
+		}
+		if cluster.status != clusterToApply {
+			plan.toApply = append(plan.toApply, cluster.name)
+		}
+		if cluster.status != clusterSucceeded && activeGroup == -1 {
+			activeGroup = cluster.group
+		}
+	}
+
+	if strategy.Type != hubhelper.RolloutAll && plan.failed > plan.maxFailures {
+		plan.paused = true
+		return plan, nil
+	}
+
+	for _, cluster := range pending {
+		if plan.progressing >= concurrency {
+			break
+		}
+		if strategy.Type == hubhelper.RolloutProgressivePerGroup && cluster.group != activeGroup {
+			break
+		}
+		plan.toApply = append(plan.toApply, cluster.name)
+		plan.progressing++
+	}
+	return plan, nil
+}
+
+// decisionGroups returns the decision group index of the clusters selected by the placements, the lowest index
+// is returned if a cluster is selected by multiple decision groups.
+func decisionGroups(placeDecisionLister clusterlister.PlacementDecisionLister,
+	placements []*clusterv1beta1.Placement) (map[string]int, error) {
+	groups := map[string]int{}
+	for _, placement := range placements {
+		decisions, err := placeDecisionLister.PlacementDecisions(placement.Namespace).List(
+			labels.SelectorFromSet(labels.Set{clusterv1beta1.PlacementLabel: placement.Name}))
+		if err != nil {
+			return nil, err
+		}
+		for _, decision := range decisions {
+			index := hubhelper.DecisionGroupIndex(decision.Labels)
+			for _, d := range decision.Status.Decisions {
+				if existing, ok := groups[d.ClusterName]; !ok || index < existing {
+					groups[d.ClusterName] = index
+				}
+			}
+		}
+	}
+	return groups, nil
+}
+
+// setRolloutCondition reports the progress of the rollout in the status of the manifestworkreplicaset
+func setRolloutCondition(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, plan *rolloutPlan) {
+	switch {
+	case plan.total == 0:
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+	case plan.paused:
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+			ManifestWorkReplicaSetConditionRolloutProgressing, ReasonRolloutPaused,
+			fmt.Sprintf("The rollout is paused since %d of %d clusters failed, exceeding the max failures %d",
+				plan.failed, plan.total, plan.maxFailures), metav1.ConditionFalse))
+	case plan.succeeded == plan.total:
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+			ManifestWorkReplicaSetConditionRolloutProgressing, ReasonRolloutCompleted,
+			fmt.Sprintf("The manifestwork is rolled out to all the %d clusters", plan.total), metav1.ConditionFalse))
+	default:
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(
+			ManifestWorkReplicaSetConditionRolloutProgressing, ReasonRolloutProgressing,
+			fmt.Sprintf("The manifestwork is rolled out to %d of %d clusters, %d clusters are progressing and %d failed",
+				plan.succeeded, plan.total, plan.progressing, plan.failed), metav1.ConditionTrue))
+	}
+}
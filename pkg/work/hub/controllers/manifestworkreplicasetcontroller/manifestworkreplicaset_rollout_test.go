@@ -0,0 +1,217 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+func TestRolloutStatusOf(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	required, _ := CreateManifestWork(mwrSet, "cls1")
+
+	withConditions := func(generation int64, conditions ...metav1.Condition) *workv1.ManifestWork {
+		mw := required.DeepCopy()
+		mw.Generation = generation
+		mw.Status.Conditions = conditions
+		return mw
+	}
+	outdated := required.DeepCopy()
+	outdated.Spec.Workload.Manifests = nil
+
+	cases := []struct {
+		name     string
+		existing *workv1.ManifestWork
+		expected clusterRolloutStatus
+	}{
+		{
+			name:     "not created",
+			expected: clusterToApply,
+		},
+		{
+			name:     "outdated",
+			existing: outdated,
+			expected: clusterToApply,
+		},
+		{
+			name:     "no conditions",
+			existing: withConditions(1),
+			expected: clusterProgressing,
+		},
+		{
+			name: "available of the previous generation",
+			existing: withConditions(2,
+				metav1.Condition{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue, ObservedGeneration: 1}),
+			expected: clusterProgressing,
+		},
+		{
+			name: "available",
+			existing: withConditions(2,
+				metav1.Condition{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue, ObservedGeneration: 2}),
+			expected: clusterSucceeded,
+		},
+		{
+			name: "failed to apply",
+			existing: withConditions(2,
+				metav1.Condition{Type: workv1.WorkApplied, Status: metav1.ConditionFalse, ObservedGeneration: 2}),
+			expected: clusterFailed,
+		},
+		{
+			name: "degraded",
+			existing: withConditions(2,
+				metav1.Condition{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue, ObservedGeneration: 2},
+				metav1.Condition{Type: workv1.WorkDegraded, Status: metav1.ConditionTrue, ObservedGeneration: 2}),
+			expected: clusterFailed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if status := rolloutStatusOf(c.existing, required); status != c.expected {
+				t.Errorf("expected status %v, but got %v", c.expected, status)
+			}
+		})
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	two := intstr.FromInt(2)
+	one := intstr.FromInt(1)
+
+	cases := []struct {
+		name            string
+		strategy        *hubhelper.RolloutStrategy
+		clusters        []rolloutCluster
+		expectedToApply []string
+		expectedPaused  bool
+	}{
+		{
+			name:     "all",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutAll},
+			clusters: []rolloutCluster{
+				{name: "cls3"}, {name: "cls1", status: clusterFailed}, {name: "cls2"},
+			},
+			expectedToApply: []string{"cls1", "cls2", "cls3"},
+		},
+		{
+			name:     "progressive with max concurrency",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutProgressive, MaxConcurrency: &two},
+			clusters: []rolloutCluster{
+				{name: "cls4"}, {name: "cls3"}, {name: "cls1", status: clusterSucceeded}, {name: "cls2", status: clusterProgressing},
+			},
+			expectedToApply: []string{"cls1", "cls2", "cls3"},
+		},
+		{
+			name:     "progressive with failures not exceeding the max failures",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutProgressive, MaxFailures: &one},
+			clusters: []rolloutCluster{
+				{name: "cls1", status: clusterFailed}, {name: "cls2"}, {name: "cls3"},
+			},
+			expectedToApply: []string{"cls1", "cls2"},
+		},
+		{
+			name:     "progressive paused",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutProgressive, MaxConcurrency: &two},
+			clusters: []rolloutCluster{
+				{name: "cls1", status: clusterFailed}, {name: "cls2"}, {name: "cls3"},
+			},
+			expectedToApply: []string{"cls1"},
+			expectedPaused:  true,
+		},
+		{
+			name:     "progressive per group waits for the previous group",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutProgressivePerGroup},
+			clusters: []rolloutCluster{
+				{name: "cls1", group: 0, status: clusterProgressing}, {name: "cls2", group: 0},
+				{name: "cls3", group: 1}, {name: "cls4", group: 1},
+			},
+			expectedToApply: []string{"cls1", "cls2"},
+		},
+		{
+			name:     "progressive per group rolls out the next group",
+			strategy: &hubhelper.RolloutStrategy{Type: hubhelper.RolloutProgressivePerGroup},
+			clusters: []rolloutCluster{
+				{name: "cls1", group: 0, status: clusterSucceeded}, {name: "cls2", group: 0, status: clusterSucceeded},
+				{name: "cls4", group: 1}, {name: "cls3", group: 1}, {name: "cls5", group: 2},
+			},
+			expectedToApply: []string{"cls1", "cls2", "cls3", "cls4"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan, err := planRollout(c.strategy, c.clusters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plan.toApply, c.expectedToApply) {
+				t.Errorf("expected clusters %v to apply, but got %v", c.expectedToApply, plan.toApply)
+			}
+			if plan.paused != c.expectedPaused {
+				t.Errorf("expected paused %v, but got %v", c.expectedPaused, plan.paused)
+			}
+		})
+	}
+}
+
+func TestDeployReconcileProgressive(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	mwrSet.Annotations = map[string]string{hubhelper.RolloutStrategyAnnotationKey: `{"type":"Progressive"}`}
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Minute)
+	mwLister := workInformerFactory.Work().V1().ManifestWorks().Lister()
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", "cls1", "cls2", "cls3")
+	fClusterClient := fakeclusterclient.NewSimpleClientset(placement, placementDecision)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(fClusterClient, 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	pmwDeployController := deployReconciler{
+		workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+		manifestWorkLister:  mwLister,
+		orphanedWorkLister:  mwLister,
+		placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+		placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+	}
+
+	mwrSet, _, err := pmwDeployController.reconcile(context.TODO(), mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the first cluster is rolled out
+	created := []string{}
+	for _, action := range fWorkClient.Actions() {
+		if action.GetVerb() == "create" {
+			created = append(created, action.GetNamespace())
+		}
+	}
+	if !reflect.DeepEqual(created, []string{"cls1"}) {
+		t.Errorf("expected the manifestwork is only created in cls1, but got %v", created)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionRolloutProgressing)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonRolloutProgressing {
+		t.Errorf("expected the rollout is progressing, but got %v", condition)
+	}
+}
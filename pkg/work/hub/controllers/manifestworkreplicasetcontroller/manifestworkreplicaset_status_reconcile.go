@@ -2,12 +2,35 @@ package manifestworkreplicasetcontroller
 
 import (
 	"context"
+	"strings"
 
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
+)
+
+const (
+	// ManifestWorkReplicaSetConditionFeedbackAggregated is the condition type of ManifestWorkReplicaSet with the
+	// summary of each feedback aggregation as the message.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionFeedbackAggregated = "FeedbackAggregated"
+
+	// ManifestWorkReplicaSetConditionClustersFailed is the condition type of ManifestWorkReplicaSet which is true if
+	// the manifestwork fails on any cluster, with the summary of the failing clusters as the message.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionClustersFailed = "ClustersFailed"
+
+	ReasonClustersFailed = "ClustersFailed"
+
+	// ManifestWorkReplicaSetConditionPlacementsSummarized is the condition type of ManifestWorkReplicaSet with
+	// several placements, with the summary of each placement as the message.
+	// TODO move this to the api repo
+	ManifestWorkReplicaSetConditionPlacementsSummarized = "PlacementsSummarized"
 )
 
 // statusReconciler is to update manifestWorkReplicaSet status.
@@ -17,6 +40,14 @@ type statusReconciler struct {
 
 func (d *statusReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet,
 ) (*workapiv1alpha1.ManifestWorkReplicaSet, reconcileState, error) {
+	rules, err := hubhelper.FeedbackAggregationsOf(mwrSet)
+	if err != nil {
+		return mwrSet, reconcileContinue, err
+	}
+	if len(rules) == 0 {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionFeedbackAggregated)
+	}
+
 	// The logic for update manifestWorkReplicaSet status
 	if mwrSet.Status.Summary.Total == 0 {
 		condition := apimeta.FindStatusCondition(mwrSet.Status.Conditions, workapiv1alpha1.ManifestWorkReplicaSetConditionPlacementVerified)
@@ -25,6 +56,8 @@ func (d *statusReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		} else {
 			apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonNotAsExpected, ""))
 		}
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionClustersFailed)
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionPlacementsSummarized)
 
 		return mwrSet, reconcileContinue, nil
 	}
@@ -34,43 +67,99 @@ func (d *statusReconciler) reconcile(ctx context.Context, mwrSet *workapiv1alpha
 		return mwrSet, reconcileContinue, err
 	}
 
-	appliedCount, availableCount, degradCount, processingCount := 0, 0, 0, 0
+	activeWorks := []*workapiv1.ManifestWork{}
 	for _, mw := range manifestWorks {
-		if !mw.DeletionTimestamp.IsZero() {
+		// the manifestworks pending removal are not counted since their clusters are not selected anymore
+		if _, ok := mw.Annotations[hubhelper.RemovalPendingSinceAnnotationKey]; ok || !mw.DeletionTimestamp.IsZero() {
 			continue
 		}
+		activeWorks = append(activeWorks, mw)
+	}
+
+	summary := summarize(activeWorks)
+	mwrSet.Status.Summary.Available = summary.Available
+	mwrSet.Status.Summary.Degraded = summary.Degraded
+	mwrSet.Status.Summary.Progressing = summary.Progressing
+	mwrSet.Status.Summary.Applied = summary.Applied
+
+	if mwrSet.Status.Summary.Available == mwrSet.Status.Summary.Total &&
+		mwrSet.Status.Summary.Progressing == 0 && mwrSet.Status.Summary.Degraded == 0 {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonAsExpected, ""))
+	} else if mwrSet.Status.Summary.Progressing > 0 && mwrSet.Status.Summary.Degraded == 0 {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonProcessing, ""))
+	} else {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonNotAsExpected, ""))
+	}
+
+	if failures := hubhelper.ClusterFailuresOf(activeWorks); failures.Total > 0 {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionClustersFailed,
+			ReasonClustersFailed, hubhelper.TruncateConditionMessage(failures.String()), metav1.ConditionTrue))
+	} else {
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionClustersFailed,
+			workapiv1alpha1.ReasonAsExpected, "", metav1.ConditionFalse))
+	}
+
+	if len(mwrSet.Spec.PlacementRefs) > 1 {
+		summaries := []string{}
+		for _, summary := range summarizePlacements(mwrSet, activeWorks) {
+			summaries = append(summaries, summary.String())
+		}
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionPlacementsSummarized,
+			workapiv1alpha1.ReasonAsExpected, hubhelper.TruncateConditionMessage(strings.Join(summaries, "; ")),
+			metav1.ConditionTrue))
+	} else {
+		apimeta.RemoveStatusCondition(&mwrSet.Status.Conditions, ManifestWorkReplicaSetConditionPlacementsSummarized)
+	}
+
+	if len(rules) > 0 {
+		aggregations := []string{}
+		for _, aggregation := range hubhelper.AggregateFeedback(rules, activeWorks) {
+			aggregations = append(aggregations, aggregation.String())
+		}
+		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, getCondition(ManifestWorkReplicaSetConditionFeedbackAggregated,
+			workapiv1alpha1.ReasonAsExpected, hubhelper.TruncateConditionMessage(strings.Join(aggregations, ". ")),
+			metav1.ConditionTrue))
+	}
+
+	return mwrSet, reconcileContinue, nil
+}
 
+// summarize counts the manifestworks by their conditions
+func summarize(manifestWorks []*workapiv1.ManifestWork) workapiv1alpha1.ManifestWorkReplicaSetSummary {
+	summary := workapiv1alpha1.ManifestWorkReplicaSetSummary{Total: len(manifestWorks)}
+	for _, mw := range manifestWorks {
 		// applied condition
 		if apimeta.IsStatusConditionTrue(mw.Status.Conditions, workapiv1.WorkApplied) {
-			appliedCount++
+			summary.Applied++
 		}
 		// Progressing condition
 		if apimeta.IsStatusConditionTrue(mw.Status.Conditions, workapiv1.WorkProgressing) {
-			processingCount++
+			summary.Progressing++
 		}
 		// Available condition
 		if apimeta.IsStatusConditionTrue(mw.Status.Conditions, workapiv1.WorkAvailable) {
-			availableCount++
+			summary.Available++
 		}
 		// Degraded condition
 		if apimeta.IsStatusConditionTrue(mw.Status.Conditions, workapiv1.WorkDegraded) {
-			degradCount++
+			summary.Degraded++
 		}
 	}
+	return summary
+}
 
-	mwrSet.Status.Summary.Available = availableCount
-	mwrSet.Status.Summary.Degraded = degradCount
-	mwrSet.Status.Summary.Progressing = processingCount
-	mwrSet.Status.Summary.Applied = appliedCount
-
-	if mwrSet.Status.Summary.Available == mwrSet.Status.Summary.Total &&
-		mwrSet.Status.Summary.Progressing == 0 && mwrSet.Status.Summary.Degraded == 0 {
-		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonAsExpected, ""))
-	} else if mwrSet.Status.Summary.Progressing > 0 && mwrSet.Status.Summary.Degraded == 0 {
-		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonProcessing, ""))
-	} else {
-		apimeta.SetStatusCondition(&mwrSet.Status.Conditions, GetManifestworkApplied(workapiv1alpha1.ReasonNotAsExpected, ""))
+// summarizePlacements summarizes the manifestworks of each placement in the order of the placementRefs, the
+// manifestworks are grouped by the placement recorded in their annotation.
+func summarizePlacements(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, manifestWorks []*workapiv1.ManifestWork) []hubhelper.PlacementSummary {
+	placementWorks := map[string][]*workapiv1.ManifestWork{}
+	for _, mw := range manifestWorks {
+		placement := mw.Annotations[hubhelper.PlacementAnnotationKey]
+		placementWorks[placement] = append(placementWorks[placement], mw)
 	}
 
-	return mwrSet, reconcileContinue, nil
+	summaries := []hubhelper.PlacementSummary{}
+	for _, ref := range mwrSet.Spec.PlacementRefs {
+		summaries = append(summaries, hubhelper.PlacementSummary{Placement: ref.Name, Summary: summarize(placementWorks[ref.Name])})
+	}
+	return summaries
 }
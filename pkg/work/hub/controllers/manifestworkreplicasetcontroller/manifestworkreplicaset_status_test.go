@@ -7,12 +7,14 @@ import (
 
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	workv1 "open-cluster-management.io/api/work/v1"
 	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
 )
 
@@ -229,3 +231,133 @@ func TestStatusReconcileNotAsExpected(t *testing.T) {
 		t.Fatal("Applied condition Reason not match NotAsExpected ", appliedCondition)
 	}
 }
+
+func TestStatusReconcileFeedbackAggregated(t *testing.T) {
+	mwrSetTest := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	mwrSetTest.Annotations = map[string]string{hubhelper.FeedbackAggregationsAnnotationKey: `[{"name":"readyReplicas",` +
+		`"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},` +
+		`"feedbackName":"ReadyReplicas"}]`}
+	mwrSetTest.Status.Summary.Total = 2
+
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fakeworkclient.NewSimpleClientset(), 1*time.Second)
+	for index, cls := range []string{"cls1", "cls2"} {
+		mw, _ := CreateManifestWork(mwrSetTest, cls)
+		mw.Status.ResourceStatus.Manifests = []workv1.ManifestCondition{{
+			ResourceMeta: workv1.ManifestResourceMeta{Group: "apps", Resource: "deployments", Namespace: "default", Name: "test"},
+			StatusFeedbacks: workv1.StatusFeedbackResult{Values: []workv1.FeedbackValue{
+				{Name: "ReadyReplicas", Value: workv1.FieldValue{Type: workv1.Integer, Integer: pointer.Int64(int64(index + 1))}},
+			}},
+		}}
+		if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mwrSetStatusController := statusReconciler{
+		manifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}
+	mwrSetTest, _, err := mwrSetStatusController.reconcile(context.TODO(), mwrSetTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSetTest.Status.Conditions, ManifestWorkReplicaSetConditionFeedbackAggregated)
+	if condition == nil {
+		t.Fatal("FeedbackAggregated condition not found ", mwrSetTest.Status.Conditions)
+	}
+	expected := `readyReplicas: 2 clusters reported; sum 3, min 1, max 2; values "1"=1, "2"=1; outliers cls2`
+	if condition.Message != expected {
+		t.Errorf("expected the ready replicas of 2 clusters summed to 3 in the message %q, but got %q", expected, condition.Message)
+	}
+
+	// the condition is removed with the aggregation rules
+	mwrSetTest.Annotations = nil
+	mwrSetTest, _, err = mwrSetStatusController.reconcile(context.TODO(), mwrSetTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apimeta.FindStatusCondition(mwrSetTest.Status.Conditions, ManifestWorkReplicaSetConditionFeedbackAggregated) != nil {
+		t.Errorf("expected the FeedbackAggregated condition is removed")
+	}
+}
+
+func TestStatusReconcileClustersFailed(t *testing.T) {
+	clusters := []string{"cls1", "cls2", "cls3"}
+	mwrSetTest := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	mwrSetTest.Status.Summary.Total = len(clusters)
+
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fakeworkclient.NewSimpleClientset(), 1*time.Second)
+	for _, cls := range clusters {
+		mw, _ := CreateManifestWork(mwrSetTest, cls)
+		apimeta.SetStatusCondition(&mw.Status.Conditions, getCondition(workv1.WorkApplied, "AppliedManifestWorkComplete", "", metav1.ConditionTrue))
+		if cls != "cls1" {
+			apimeta.SetStatusCondition(&mw.Status.Conditions, getCondition(workv1.WorkDegraded, "CrashLoop", "crashed", metav1.ConditionTrue))
+		}
+		if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mwrSetStatusController := statusReconciler{
+		manifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}
+	mwrSetTest, _, err := mwrSetStatusController.reconcile(context.TODO(), mwrSetTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSetTest.Status.Conditions, ManifestWorkReplicaSetConditionClustersFailed)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatal("ClustersFailed condition not True ", mwrSetTest.Status.Conditions)
+	}
+	expected := "2 clusters failed (CrashLoop: 2); cls2 is Degraded with CrashLoop: crashed; cls3 is Degraded with CrashLoop: crashed"
+	if condition.Message != expected {
+		t.Errorf("expected cls2 and cls3 failed in the message %q, but got %q", expected, condition.Message)
+	}
+}
+
+func TestStatusReconcilePlacementsSummarized(t *testing.T) {
+	mwrSetTest := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "canary")
+	mwrSetTest.Spec.PlacementRefs = append(mwrSetTest.Spec.PlacementRefs, workapiv1alpha1.LocalPlacementReference{Name: "prod"})
+	mwrSetTest.Status.Summary.Total = 3
+
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fakeworkclient.NewSimpleClientset(), 1*time.Second)
+	for cls, placement := range map[string]string{"cls1": "canary", "cls2": "prod", "cls3": "prod"} {
+		mw, _ := CreateManifestWork(mwrSetTest, cls)
+		mw.Annotations = map[string]string{hubhelper.PlacementAnnotationKey: placement}
+		if cls != "cls3" {
+			apimeta.SetStatusCondition(&mw.Status.Conditions, getCondition(workv1.WorkAvailable, "", "", metav1.ConditionTrue))
+		}
+		if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(mw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mwrSetStatusController := statusReconciler{
+		manifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}
+	mwrSetTest, _, err := mwrSetStatusController.reconcile(context.TODO(), mwrSetTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	condition := apimeta.FindStatusCondition(mwrSetTest.Status.Conditions, ManifestWorkReplicaSetConditionPlacementsSummarized)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatal("PlacementsSummarized condition not True ", mwrSetTest.Status.Conditions)
+	}
+	expected := "canary: 1 clusters, 0 applied, 1 available, 0 progressing, 0 degraded; " +
+		"prod: 2 clusters, 0 applied, 1 available, 0 progressing, 0 degraded"
+	if condition.Message != expected {
+		t.Errorf("expected summaries %q, but got %q", expected, condition.Message)
+	}
+
+	// the condition is removed with a single placement
+	mwrSetTest.Spec.PlacementRefs = mwrSetTest.Spec.PlacementRefs[:1]
+	mwrSetTest, _, err = mwrSetStatusController.reconcile(context.TODO(), mwrSetTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apimeta.FindStatusCondition(mwrSetTest.Status.Conditions, ManifestWorkReplicaSetConditionPlacementsSummarized) != nil {
+		t.Errorf("expected the PlacementsSummarized condition is removed")
+	}
+}
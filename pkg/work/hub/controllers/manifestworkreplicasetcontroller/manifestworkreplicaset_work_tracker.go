@@ -0,0 +1,96 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// appliedWork is the manifestwork last applied to a cluster
+type appliedWork struct {
+	// key identifies everything to generate the manifestwork, see workKey
+	key         string
+	uid         types.UID
+	generation  int64
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// workTracker tracks the manifestworks applied by the deployReconciler, so the manifestworks which are not
+// changed since they are applied are neither generated nor applied again. The spec of a manifestwork changed by
+// others is detected by its generation and applied again to correct the drift. The tracker is kept in memory, all
+// the manifestworks are generated and compared once the controller restarts.
+type workTracker struct {
+	lock sync.Mutex
+	// works is the applied manifestworks by the cluster name of each manifestWorkReplicaSet key
+	works map[string]map[string]*appliedWork
+}
+
+// upToDate returns true if the existing manifestwork of the cluster is applied with the same key and it is not
+// changed since then.
+func (t *workTracker) upToDate(mwrSetKey, cluster, key string, existing *workv1.ManifestWork) bool {
+	if existing == nil || !existing.DeletionTimestamp.IsZero() {
+		return false
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	applied, ok := t.works[mwrSetKey][cluster]
+	if !ok || applied.key != key || applied.uid != existing.UID || applied.generation != existing.Generation {
+		return false
+	}
+	return containsAll(existing.Labels, applied.labels) && containsAll(existing.Annotations, applied.annotations)
+}
+
+// record records the manifestwork applied to the cluster with the key
+func (t *workTracker) record(mwrSetKey, cluster, key string, required, applied *workv1.ManifestWork) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.works == nil {
+		t.works = map[string]map[string]*appliedWork{}
+	}
+	if t.works[mwrSetKey] == nil {
+		t.works[mwrSetKey] = map[string]*appliedWork{}
+	}
+	t.works[mwrSetKey][cluster] = &appliedWork{
+		key:         key,
+		uid:         applied.UID,
+		generation:  applied.Generation,
+		labels:      required.Labels,
+		annotations: required.Annotations,
+	}
+}
+
+// retain forgets the manifestworks of the clusters which are not selected by the manifestWorkReplicaSet
+func (t *workTracker) retain(mwrSetKey string, clusters sets.Set[string]) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for cluster := range t.works[mwrSetKey] {
+		if !clusters.Has(cluster) {
+			delete(t.works[mwrSetKey], cluster)
+		}
+	}
+	if len(t.works[mwrSetKey]) == 0 {
+		delete(t.works, mwrSetKey)
+	}
+}
+
+// forget forgets all the manifestworks of the manifestWorkReplicaSet
+func (t *workTracker) forget(mwrSetKey string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.works, mwrSetKey)
+}
+
+// containsAll returns true if all the entries of the required map are in the existing map
+func containsAll(existing, required map[string]string) bool {
+	for key, value := range required {
+		if existingValue, ok := existing[key]; !ok || existingValue != value {
+			return false
+		}
+	}
+	return true
+}
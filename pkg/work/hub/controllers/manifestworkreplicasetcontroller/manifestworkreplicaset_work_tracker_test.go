@@ -0,0 +1,253 @@
+package manifestworkreplicasetcontroller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	fakeclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	"open-cluster-management.io/api/utils/work/v1/workapplier"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+
+	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
+)
+
+func TestWorkTracker(t *testing.T) {
+	required := &workv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{"a": "b"}, Annotations: map[string]string{"c": "d"}}}
+	applied := required.DeepCopy()
+	applied.UID, applied.Generation = "uid", 2
+
+	cases := []struct {
+		name     string
+		key      string
+		existing func() *workv1.ManifestWork
+		expected bool
+	}{
+		{
+			name:     "up to date",
+			key:      "key",
+			existing: applied.DeepCopy,
+			expected: true,
+		},
+		{
+			name:     "key changed",
+			key:      "changed",
+			existing: applied.DeepCopy,
+		},
+		{
+			name: "not found",
+			key:  "key",
+			existing: func() *workv1.ManifestWork {
+				return nil
+			},
+		},
+		{
+			name: "spec changed",
+			key:  "key",
+			existing: func() *workv1.ManifestWork {
+				existing := applied.DeepCopy()
+				existing.Generation = 3
+				return existing
+			},
+		},
+		{
+			name: "recreated",
+			key:  "key",
+			existing: func() *workv1.ManifestWork {
+				existing := applied.DeepCopy()
+				existing.UID = "recreated"
+				return existing
+			},
+		},
+		{
+			name: "annotation removed",
+			key:  "key",
+			existing: func() *workv1.ManifestWork {
+				existing := applied.DeepCopy()
+				existing.Annotations = nil
+				return existing
+			},
+		},
+		{
+			name: "other labels added",
+			key:  "key",
+			existing: func() *workv1.ManifestWork {
+				existing := applied.DeepCopy()
+				existing.Labels["e"] = "f"
+				return existing
+			},
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tracker := &workTracker{}
+			tracker.record("ns.mwrset", "cls1", "key", required, applied)
+			if actual := tracker.upToDate("ns.mwrset", "cls1", c.key, c.existing()); actual != c.expected {
+				t.Errorf("expected up to date %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+
+	tracker := &workTracker{}
+	tracker.record("ns.mwrset", "cls1", "key", required, applied)
+	tracker.retain("ns.mwrset", sets.New[string]("cls2"))
+	if tracker.upToDate("ns.mwrset", "cls1", "key", applied) || len(tracker.works) != 0 {
+		t.Errorf("expected the cluster not selected is forgotten, but got %v", tracker.works)
+	}
+}
+
+// deployFixture is a deployReconciler with the manifestworks of the clusters created and synced into the cache
+type deployFixture struct {
+	deployer    *deployReconciler
+	workClient  *fakeworkclient.Clientset
+	workIndexer cache.Indexer
+}
+
+func newDeployFixture(t testing.TB, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet, clusters ...string) *deployFixture {
+	fWorkClient := fakeworkclient.NewSimpleClientset(mwrSet)
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fWorkClient, 1*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	if err := workInformer.AddIndexers(cache.Indexers{
+		manifestWorkByManifestWorkReplicaSet: indexManifestWorkByManifestWorkReplicaSet,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mwLister := &manifestWorkIndexLister{
+		ManifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		indexer:            workInformer.GetIndexer(),
+	}
+
+	placement, placementDecision := helpertest.CreateTestPlacement("place-test", "default", clusters...)
+	clusterInformerFactory := clusterinformers.NewSharedInformerFactoryWithOptions(
+		fakeclusterclient.NewSimpleClientset(placement, placementDecision), 1*time.Minute)
+	if err := clusterInformerFactory.Cluster().V1beta1().Placements().Informer().GetStore().Add(placement); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Informer().GetStore().Add(placementDecision); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &deployFixture{
+		deployer: &deployReconciler{
+			workClient:          fWorkClient,
+			workApplier:         workapplier.NewWorkApplierWithTypedClient(fWorkClient, mwLister),
+			manifestWorkLister:  mwLister,
+			orphanedWorkLister:  mwLister,
+			placeDecisionLister: clusterInformerFactory.Cluster().V1beta1().PlacementDecisions().Lister(),
+			placementLister:     clusterInformerFactory.Cluster().V1beta1().Placements().Lister(),
+		},
+		workClient:  fWorkClient,
+		workIndexer: workInformer.GetIndexer(),
+	}
+	f.reconcile(t, mwrSet)
+	f.syncCache(t)
+	f.workClient.ClearActions()
+	return f
+}
+
+func (f *deployFixture) reconcile(t testing.TB, mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) {
+	if _, _, err := f.deployer.reconcile(context.TODO(), mwrSet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// syncCache syncs the manifestworks from the client into the cache
+func (f *deployFixture) syncCache(t testing.TB) {
+	works, err := f.workClient.WorkV1().ManifestWorks(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := []interface{}{}
+	for i := range works.Items {
+		objs = append(objs, &works.Items[i])
+	}
+	if err := f.workIndexer.Replace(objs, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeployReconcileUnchangedWorks(t *testing.T) {
+	mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+	f := newDeployFixture(t, mwrSet, "cls1", "cls2", "cls3")
+
+	// nothing is applied if nothing changes
+	f.reconcile(t, mwrSet)
+	if actions := f.workClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no actions, but got %v", actions)
+	}
+
+	// the drift of a manifestwork is corrected
+	drifted, err := f.workClient.WorkV1().ManifestWorks("cls1").Get(context.TODO(), mwrSet.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifted.Spec.Workload.Manifests = nil
+	drifted.Generation++
+	if err := f.workIndexer.Update(drifted); err != nil {
+		t.Fatal(err)
+	}
+	f.workClient.ClearActions()
+	f.reconcile(t, mwrSet)
+	if actions := f.workClient.Actions(); len(actions) != 1 || actions[0].GetVerb() != "patch" || actions[0].GetNamespace() != "cls1" {
+		t.Errorf("expected the manifestwork of cls1 patched, but got %v", actions)
+	}
+	f.syncCache(t)
+	f.workClient.ClearActions()
+
+	// all the manifestworks are updated once the template changes
+	mwrSet = mwrSet.DeepCopy()
+	mwrSet.Spec.ManifestWorkTemplate.DeleteOption = &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan}
+	f.reconcile(t, mwrSet)
+	if actions := f.workClient.Actions(); len(actions) != 3 {
+		t.Errorf("expected 3 manifestworks patched, but got %v", actions)
+	}
+}
+
+func BenchmarkDeployReconcile(b *testing.B) {
+	for _, clusters := range []int{100, 1000} {
+		mwrSet := helpertest.CreateTestManifestWorkReplicaSet("mwrSet-test", "default", "place-test")
+		names := []string{}
+		for i := 0; i < clusters; i++ {
+			names = append(names, fmt.Sprintf("cls%d", i))
+		}
+		f := newDeployFixture(b, mwrSet, names...)
+		// other manifestworks on the hub not belonging to the manifestWorkReplicaSet
+		for i := 0; i < clusters*10; i++ {
+			other := &workv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Namespace: names[i%clusters], Name: fmt.Sprintf("other%d", i)}}
+			if err := f.workIndexer.Add(other); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		run := func(b *testing.B, tracked bool) {
+			for i := 0; i < b.N; i++ {
+				if !tracked {
+					// every manifestwork is generated and compared as if it is not tracked
+					f.deployer.appliedWorks = workTracker{}
+				}
+				f.reconcile(b, mwrSet.DeepCopy())
+			}
+			b.ReportMetric(float64(len(f.workClient.Actions()))/float64(b.N), "actions/op")
+		}
+		b.Run(fmt.Sprintf("untracked-%d", clusters), func(b *testing.B) {
+			run(b, false)
+		})
+		// apply once to track the manifestworks
+		f.reconcile(b, mwrSet.DeepCopy())
+		b.Run(fmt.Sprintf("tracked-%d", clusters), func(b *testing.B) {
+			run(b, true)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// FeedbackAggregationsAnnotationKey is the annotation key on manifestworkreplicaset of the json of the
+	// FeedbackAggregationRule list to reduce the status feedback values across the generated manifestworks.
+	// TODO move this to the api repo
+	FeedbackAggregationsAnnotationKey = "work.open-cluster-management.io/feedback-aggregations"
+
+	// MaxFeedbackAggregations is the max number of the feedback aggregation rules of a manifestworkreplicaset
+	MaxFeedbackAggregations = 10
+	// MaxAggregatedValueLength is the max length of a value counted by a feedback aggregation, the longer values
+	// are truncated.
+	MaxAggregatedValueLength = 64
+	// MaxAggregatedValues is the max number of the distinct values counted by a feedback aggregation, the clusters
+	// of the other values are counted as OtherAggregatedValues.
+	MaxAggregatedValues = 10
+	// MaxAggregatedOutliers is the max number of the outlier clusters listed by a feedback aggregation
+	MaxAggregatedOutliers = 10
+	// OtherAggregatedValues is the key counting the values beyond MaxAggregatedValues
+	OtherAggregatedValues = "(other)"
+)
+
+// FeedbackAggregationRule reduces a named status feedback value of a manifest across the generated manifestworks
+type FeedbackAggregationRule struct {
+	Name               string                       `json:"name"`
+	ResourceIdentifier workapiv1.ResourceIdentifier `json:"resourceIdentifier"`
+	// FeedbackName is the name of the status feedback value to reduce
+	FeedbackName string `json:"feedbackName"`
+}
+
+// FeedbackAggregation is the result of a FeedbackAggregationRule. Sum, Min and Max are only set if the values are
+// integers. Outliers are the clusters not reporting the value or reporting a value different from the most common
+// one, sorted by name.
+type FeedbackAggregation struct {
+	Name string `json:"name"`
+	// Reported is the number of the clusters reporting the value
+	Reported     int            `json:"reported"`
+	Sum          *int64         `json:"sum,omitempty"`
+	Min          *int64         `json:"min,omitempty"`
+	Max          *int64         `json:"max,omitempty"`
+	CountByValue map[string]int `json:"countByValue,omitempty"`
+	Outliers     []string       `json:"outliers,omitempty"`
+	// Truncated is true if the values or the outliers exceed the bounds
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// FeedbackAggregationsOf returns the feedback aggregation rules of the manifestworkreplicaset
+func FeedbackAggregationsOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]FeedbackAggregationRule, error) {
+	value, ok := mwrSet.Annotations[FeedbackAggregationsAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rules := []FeedbackAggregationRule{}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid list of feedback aggregation rules: %v",
+			FeedbackAggregationsAnnotationKey, err)
+	}
+
+	if len(rules) > MaxFeedbackAggregations {
+		return nil, fmt.Errorf("the number of the feedback aggregation rules should be at most %d", MaxFeedbackAggregations)
+	}
+
+	names := sets.New[string]()
+	for _, rule := range rules {
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the feedback aggregation rule is empty")
+		}
+		if names.Has(rule.Name) {
+			return nil, fmt.Errorf("the feedback aggregation rule %s is duplicated", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		if len(rule.ResourceIdentifier.Resource) == 0 || len(rule.ResourceIdentifier.Name) == 0 || len(rule.FeedbackName) == 0 {
+			return nil, fmt.Errorf("the resource, the name and the feedback name of the feedback aggregation rule %s are required",
+				rule.Name)
+		}
+	}
+	return rules, nil
+}
+
+// AggregateFeedback reduces the status feedback values of the manifestworks with the rules
+func AggregateFeedback(rules []FeedbackAggregationRule, manifestWorks []*workapiv1.ManifestWork) []FeedbackAggregation {
+	aggregations := []FeedbackAggregation{}
+	for _, rule := range rules {
+		aggregation := FeedbackAggregation{Name: rule.Name, CountByValue: map[string]int{}}
+		clustersByValue := map[string][]string{}
+		missing := []string{}
+		integers := true
+
+		for _, mw := range manifestWorks {
+			value, ok := feedbackValueOf(mw, rule.ResourceIdentifier, rule.FeedbackName)
+			if !ok {
+				missing = append(missing, mw.Namespace)
+				continue
+			}
+
+			aggregation.Reported++
+			key := truncate(fieldValueString(value), MaxAggregatedValueLength)
+			clustersByValue[key] = append(clustersByValue[key], mw.Namespace)
+			if value.Integer == nil {
+				integers = false
+				continue
+			}
+			if aggregation.Sum == nil {
+				aggregation.Sum = pointer.Int64(0)
+			}
+			*aggregation.Sum += *value.Integer
+			if aggregation.Min == nil || *value.Integer < *aggregation.Min {
+				aggregation.Min = pointer.Int64(*value.Integer)
+			}
+			if aggregation.Max == nil || *value.Integer > *aggregation.Max {
+				aggregation.Max = pointer.Int64(*value.Integer)
+			}
+		}
+		if !integers {
+			aggregation.Sum, aggregation.Min, aggregation.Max = nil, nil, nil
+		}
+
+		// the most common values first
+		values := []string{}
+		for value := range clustersByValue {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if len(clustersByValue[values[i]]) != len(clustersByValue[values[j]]) {
+				return len(clustersByValue[values[i]]) > len(clustersByValue[values[j]])
+			}
+			return values[i] < values[j]
+		})
+		for index, value := range values {
+			if index < MaxAggregatedValues {
+				aggregation.CountByValue[value] = len(clustersByValue[value])
+				continue
+			}
+			aggregation.CountByValue[OtherAggregatedValues] += len(clustersByValue[value])
+			aggregation.Truncated = true
+		}
+
+		outliers := missing
+		for index, value := range values {
+			if index > 0 {
+				outliers = append(outliers, clustersByValue[value]...)
+			}
+		}
+		sort.Strings(outliers)
+		if len(outliers) > MaxAggregatedOutliers {
+			outliers = outliers[:MaxAggregatedOutliers]
+			aggregation.Truncated = true
+		}
+		if len(outliers) > 0 {
+			aggregation.Outliers = outliers
+		}
+
+		aggregations = append(aggregations, aggregation)
+	}
+	return aggregations
+}
+
+// String returns the human-readable summary of the aggregation, e.g. readyReplicas: 3 clusters reported; sum 6,
+// min 1, max 3; values "1"=1, "2"=1, "3"=1; outliers cls1, cls3, cls4
+func (a FeedbackAggregation) String() string {
+	parts := []string{fmt.Sprintf("%d clusters reported", a.Reported)}
+	if a.Sum != nil && a.Min != nil && a.Max != nil {
+		parts = append(parts, fmt.Sprintf("sum %d, min %d, max %d", *a.Sum, *a.Min, *a.Max))
+	}
+
+	// the most common values first, and the other values last
+	values := []string{}
+	for value := range a.CountByValue {
+		if value != OtherAggregatedValues {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if a.CountByValue[values[i]] != a.CountByValue[values[j]] {
+			return a.CountByValue[values[i]] > a.CountByValue[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	counts := []string{}
+	for _, value := range values {
+		counts = append(counts, fmt.Sprintf("%q=%d", value, a.CountByValue[value]))
+	}
+	if count, ok := a.CountByValue[OtherAggregatedValues]; ok {
+		counts = append(counts, fmt.Sprintf("%s=%d", OtherAggregatedValues, count))
+	}
+	if len(counts) > 0 {
+		parts = append(parts, "values "+strings.Join(counts, ", "))
+	}
+
+	if len(a.Outliers) > 0 {
+		parts = append(parts, "outliers "+strings.Join(a.Outliers, ", "))
+	}
+	if a.Truncated {
+		parts = append(parts, "truncated")
+	}
+	return fmt.Sprintf("%s: %s", a.Name, strings.Join(parts, "; "))
+}
+
+// feedbackValueOf returns the named status feedback value of the resource in the manifestwork
+func feedbackValueOf(mw *workapiv1.ManifestWork, identifier workapiv1.ResourceIdentifier, name string) (*workapiv1.FieldValue, bool) {
+	for _, manifest := range mw.Status.ResourceStatus.Manifests {
+		meta := manifest.ResourceMeta
+		if meta.Group != identifier.Group || meta.Resource != identifier.Resource ||
+			meta.Namespace != identifier.Namespace || meta.Name != identifier.Name {
+			continue
+		}
+		for i := range manifest.StatusFeedbacks.Values {
+			if manifest.StatusFeedbacks.Values[i].Name == name {
+				return &manifest.StatusFeedbacks.Values[i].Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func fieldValueString(value *workapiv1.FieldValue) string {
+	switch {
+	case value.Integer != nil:
+		return strconv.FormatInt(*value.Integer, 10)
+	case value.String != nil:
+		return *value.String
+	case value.Boolean != nil:
+		return strconv.FormatBool(*value.Boolean)
+	case value.JsonRaw != nil:
+		return *value.JsonRaw
+	default:
+		return ""
+	}
+}
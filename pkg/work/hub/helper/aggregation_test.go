@@ -0,0 +1,170 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+func TestFeedbackAggregationsOf(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotation    string
+		expectErr     bool
+		expectedRules int
+	}{
+		{
+			name: "no aggregations",
+		},
+		{
+			name: "valid aggregations",
+			annotation: `[{"name":"readyReplicas","resourceIdentifier":{"group":"apps","resource":"deployments",` +
+				`"namespace":"default","name":"test"},"feedbackName":"ReadyReplicas"}]`,
+			expectedRules: 1,
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"name":"readyReplicas"}`,
+			expectErr:  true,
+		},
+		{
+			name: "feedback name is missing",
+			annotation: `[{"name":"readyReplicas","resourceIdentifier":{"group":"apps","resource":"deployments",` +
+				`"namespace":"default","name":"test"}}]`,
+			expectErr: true,
+		},
+		{
+			name: "duplicated rules",
+			annotation: `[{"name":"image","resourceIdentifier":{"resource":"pods","name":"test"},"feedbackName":"Image"},` +
+				`{"name":"image","resourceIdentifier":{"resource":"pods","name":"test"},"feedbackName":"Image"}]`,
+			expectErr: true,
+		},
+		{
+			name: "too many rules",
+			annotation: "[" + strings.TrimSuffix(strings.Repeat(
+				`{"name":"image","resourceIdentifier":{"resource":"pods","name":"test"},"feedbackName":"Image"},`,
+				MaxFeedbackAggregations+1), ",") + "]",
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{FeedbackAggregationsAnnotationKey: c.annotation}
+			}
+
+			rules, err := FeedbackAggregationsOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if len(rules) != c.expectedRules {
+				t.Errorf("expected %d rules, but got %d", c.expectedRules, len(rules))
+			}
+		})
+	}
+}
+
+func TestAggregateFeedback(t *testing.T) {
+	identifier := workapiv1.ResourceIdentifier{Group: "apps", Resource: "deployments", Namespace: "default", Name: "test"}
+	rules := []FeedbackAggregationRule{
+		{Name: "readyReplicas", ResourceIdentifier: identifier, FeedbackName: "ReadyReplicas"},
+		{Name: "image", ResourceIdentifier: identifier, FeedbackName: "Image"},
+	}
+	newWork := func(cluster string, values ...workapiv1.FeedbackValue) *workapiv1.ManifestWork {
+		return &workapiv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster, Name: "test"},
+			Status: workapiv1.ManifestWorkStatus{ResourceStatus: workapiv1.ManifestResourceStatus{
+				Manifests: []workapiv1.ManifestCondition{{
+					ResourceMeta: workapiv1.ManifestResourceMeta{
+						Group: identifier.Group, Resource: identifier.Resource, Namespace: identifier.Namespace, Name: identifier.Name,
+					},
+					StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: values},
+				}},
+			}},
+		}
+	}
+	feedback := func(replicas int64, image string) []workapiv1.FeedbackValue {
+		return []workapiv1.FeedbackValue{
+			{Name: "ReadyReplicas", Value: workapiv1.FieldValue{Type: workapiv1.Integer, Integer: pointer.Int64(replicas)}},
+			{Name: "Image", Value: workapiv1.FieldValue{Type: workapiv1.String, String: pointer.String(image)}},
+		}
+	}
+
+	t.Run("reduce the values", func(t *testing.T) {
+		works := []*workapiv1.ManifestWork{
+			newWork("cls1", feedback(3, "quay.io/test:v1")...),
+			newWork("cls2", feedback(1, "quay.io/test:v2")...),
+			newWork("cls3", feedback(2, "quay.io/test:v1")...),
+			newWork("cls4"),
+		}
+
+		actual, _ := json.Marshal(AggregateFeedback(rules, works))
+		expected := `[{"name":"readyReplicas","reported":3,"sum":6,"min":1,"max":3,` +
+			`"countByValue":{"1":1,"2":1,"3":1},"outliers":["cls1","cls3","cls4"]},` +
+			`{"name":"image","reported":3,"countByValue":{"quay.io/test:v1":2,"quay.io/test:v2":1},` +
+			`"outliers":["cls2","cls4"]}]`
+		if string(actual) != expected {
+			t.Errorf("expected aggregations %s, but got %s", expected, actual)
+		}
+	})
+
+	t.Run("summarize the aggregation", func(t *testing.T) {
+		works := []*workapiv1.ManifestWork{
+			newWork("cls1", feedback(3, "quay.io/test:v1")...),
+			newWork("cls2", feedback(1, "quay.io/test:v2")...),
+			newWork("cls3", feedback(2, "quay.io/test:v1")...),
+		}
+
+		aggregations := AggregateFeedback(rules, works)
+		expected := `readyReplicas: 3 clusters reported; sum 6, min 1, max 3; values "1"=1, "2"=1, "3"=1; outliers cls1, cls3`
+		if actual := aggregations[0].String(); actual != expected {
+			t.Errorf("expected %q, but got %q", expected, actual)
+		}
+		expected = `image: 3 clusters reported; values "quay.io/test:v1"=2, "quay.io/test:v2"=1; outliers cls2`
+		if actual := aggregations[1].String(); actual != expected {
+			t.Errorf("expected %q, but got %q", expected, actual)
+		}
+	})
+
+	t.Run("the values and the outliers are bounded", func(t *testing.T) {
+		works := []*workapiv1.ManifestWork{}
+		for i := 0; i < 2*MaxAggregatedValues; i++ {
+			works = append(works, newWork(fmt.Sprintf("cls%02d", i), feedback(int64(i), "test")...))
+		}
+
+		aggregation := AggregateFeedback(rules[:1], works)[0]
+		if len(aggregation.CountByValue) != MaxAggregatedValues+1 ||
+			aggregation.CountByValue[OtherAggregatedValues] != MaxAggregatedValues {
+			t.Errorf("expected %d values counted as the other values, but got %v", MaxAggregatedValues, aggregation.CountByValue)
+		}
+		if len(aggregation.Outliers) != MaxAggregatedOutliers || !aggregation.Truncated {
+			t.Errorf("expected the outliers are truncated, but got %v", aggregation.Outliers)
+		}
+		if *aggregation.Sum != 190 || *aggregation.Min != 0 || *aggregation.Max != 19 {
+			t.Errorf("expected sum 190, min 0 and max 19, but got %d, %d and %d", *aggregation.Sum, *aggregation.Min, *aggregation.Max)
+		}
+		if summary := aggregation.String(); !strings.Contains(summary, OtherAggregatedValues+"=10") ||
+			!strings.HasSuffix(summary, "; truncated") {
+			t.Errorf("expected the other values and the truncation in the summary, but got %q", summary)
+		}
+
+		// the long values are truncated
+		aggregation = AggregateFeedback(rules[1:], []*workapiv1.ManifestWork{
+			newWork("cls1", feedback(1, strings.Repeat("a", 2*MaxAggregatedValueLength))...),
+		})[0]
+		for value := range aggregation.CountByValue {
+			if len(value) != MaxAggregatedValueLength {
+				t.Errorf("expected the value is truncated to %d, but got %d", MaxAggregatedValueLength, len(value))
+			}
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// AnalysisAnnotationKey is the annotation key on manifestworkreplicaset of the json of the Analysis, which
+	// analyzes the status feedback of the clusters rolled out to the current template revision, and rolls back the
+	// failed clusters to the last revision passing the analysis if any cluster fails.
+	// TODO move this to the api repo
+	AnalysisAnnotationKey = "work.open-cluster-management.io/analysis"
+
+	// AnalysisPassedAnnotationKey is the annotation key on the controllerrevision of a manifestworkreplicaset, it
+	// is set once all the clusters are rolled out to the revision and pass the analysis, the value is the time in
+	// RFC3339 format.
+	// TODO move this to the api repo
+	AnalysisPassedAnnotationKey = "work.open-cluster-management.io/analysis-passed"
+
+	// RevisionAppliedTimeAnnotationKey is the annotation key on the manifestwork generated by a
+	// manifestworkreplicaset with the analysis, the value is the time in RFC3339 format when the template revision
+	// is applied to the manifestwork, the bake period of the cluster starts from it.
+	// TODO move this to the api repo
+	RevisionAppliedTimeAnnotationKey = "work.open-cluster-management.io/revision-applied-time"
+
+	// DefaultAnalysisFeedbackTimeout is the default duration to wait for the status feedback after the bake period
+	DefaultAnalysisFeedbackTimeout = 10 * time.Minute
+
+	// MaxAnalysisRules is the max number of the rules of an analysis
+	MaxAnalysisRules = 16
+
+	// MaxAnalysisExpressionLength is the max length of the expression of an analysis rule
+	MaxAnalysisExpressionLength = 1024
+
+	// AnalysisCostLimit is the max cost of evaluating the expression of an analysis rule, it is the same as the
+	// per call limit of the CEL expressions in kubernetes, so a rule could not exhaust the hub controller.
+	AnalysisCostLimit = 1000000
+
+	// feedbackVariable is the name of the variable of the status feedback values in the analysis expressions
+	feedbackVariable = "feedback"
+)
+
+// Analysis is evaluated on each cluster once the bake period passes since the current template revision is
+// applied to the cluster.
+type Analysis struct {
+	// BakePeriod is the duration to wait before evaluating the rules on a cluster, e.g. 5m
+	BakePeriod metav1.Duration `json:"bakePeriod,omitempty"`
+	// FeedbackTimeout is the duration to wait for the status feedback once the bake period passes, a cluster is
+	// still baking until the timeout if its manifest has not reported the status feedback. It is 10m by default.
+	FeedbackTimeout *metav1.Duration `json:"feedbackTimeout,omitempty"`
+	// Rules should all be satisfied for a cluster to pass the analysis
+	Rules []AnalysisRule `json:"rules"`
+}
+
+// AnalysisRule is a CEL expression of bool over the status feedback values of a manifest, the values are accessed by
+// their names on the variable feedback, e.g. feedback.ReadyReplicas == feedback.Replicas. The rule is not satisfied
+// if the manifest has not reported the values, and ErrNoFeedback is returned if the manifest has not reported the
+// status feedback at all.
+type AnalysisRule struct {
+	Name               string                       `json:"name"`
+	ResourceIdentifier workapiv1.ResourceIdentifier `json:"resourceIdentifier"`
+	Expression         string                       `json:"expression"`
+
+	program cel.Program
+}
+
+// AnalysisOf returns the analysis of the manifestworkreplicaset with the expressions of the rules compiled, it
+// returns nil if the annotation AnalysisAnnotationKey is not set.
+func AnalysisOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*Analysis, error) {
+	value, ok := mwrSet.Annotations[AnalysisAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	analysis := &Analysis{}
+	if err := json.Unmarshal([]byte(value), analysis); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid analysis: %v", AnalysisAnnotationKey, err)
+	}
+	if analysis.BakePeriod.Duration < 0 {
+		return nil, fmt.Errorf("the bake period of the analysis should not be negative")
+	}
+	if analysis.FeedbackTimeout == nil {
+		analysis.FeedbackTimeout = &metav1.Duration{Duration: DefaultAnalysisFeedbackTimeout}
+	}
+	if analysis.FeedbackTimeout.Duration < 0 {
+		return nil, fmt.Errorf("the feedback timeout of the analysis should not be negative")
+	}
+	if len(analysis.Rules) == 0 {
+		return nil, fmt.Errorf("the analysis should have at least one rule")
+	}
+	if len(analysis.Rules) > MaxAnalysisRules {
+		return nil, fmt.Errorf("the analysis should have at most %d rules", MaxAnalysisRules)
+	}
+
+	env, err := cel.NewEnv(cel.Variable(feedbackVariable, cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		return nil, err
+	}
+
+	names := sets.New[string]()
+	for i := range analysis.Rules {
+		rule := &analysis.Rules[i]
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the analysis rule is empty")
+		}
+		if names.Has(rule.Name) {
+			return nil, fmt.Errorf("the analysis rule %s is duplicated", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		if len(rule.ResourceIdentifier.Resource) == 0 || len(rule.ResourceIdentifier.Name) == 0 {
+			return nil, fmt.Errorf("the resource and the name of the analysis rule %s are required", rule.Name)
+		}
+
+		if len(rule.Expression) > MaxAnalysisExpressionLength {
+			return nil, fmt.Errorf("the expression of the analysis rule %s should be at most %d characters",
+				rule.Name, MaxAnalysisExpressionLength)
+		}
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("the expression of the analysis rule %s is invalid: %v", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("the expression of the analysis rule %s should be a bool, but got %s",
+				rule.Name, ast.OutputType())
+		}
+		rule.program, err = env.Program(ast, cel.CostLimit(AnalysisCostLimit))
+		if err != nil {
+			return nil, fmt.Errorf("the expression of the analysis rule %s is invalid: %v", rule.Name, err)
+		}
+	}
+	return analysis, nil
+}
+
+// ErrNoFeedback is returned by the evaluation of an analysis rule if the manifest has not reported the status feedback
+var ErrNoFeedback = errors.New("no status feedback")
+
+// Evaluate returns nil if the manifestwork satisfies the rule, otherwise it returns the reason.
+func (r *AnalysisRule) Evaluate(mw *workapiv1.ManifestWork) error {
+	feedback, ok := feedbackOf(mw, r.ResourceIdentifier)
+	if !ok {
+		return fmt.Errorf("%w of %s %s/%s", ErrNoFeedback, r.ResourceIdentifier.Resource,
+			r.ResourceIdentifier.Namespace, r.ResourceIdentifier.Name)
+	}
+
+	out, _, err := r.program.Eval(map[string]interface{}{feedbackVariable: feedback})
+	if err != nil {
+		return fmt.Errorf("rule %s is not satisfied: %v", r.Name, err)
+	}
+	if satisfied, ok := out.Value().(bool); !ok || !satisfied {
+		return fmt.Errorf("rule %s is not satisfied", r.Name)
+	}
+	return nil
+}
+
+// feedbackOf returns the status feedback values of the resource in the manifestwork
+func feedbackOf(mw *workapiv1.ManifestWork, identifier workapiv1.ResourceIdentifier) (map[string]interface{}, bool) {
+	for _, manifest := range mw.Status.ResourceStatus.Manifests {
+		meta := manifest.ResourceMeta
+		if meta.Group != identifier.Group || meta.Resource != identifier.Resource ||
+			meta.Namespace != identifier.Namespace || meta.Name != identifier.Name {
+			continue
+		}
+		if len(manifest.StatusFeedbacks.Values) == 0 {
+			return nil, false
+		}
+
+		feedback := map[string]interface{}{}
+		for _, value := range manifest.StatusFeedbacks.Values {
+			switch {
+			case value.Value.Integer != nil:
+				feedback[value.Name] = *value.Value.Integer
+			case value.Value.String != nil:
+				feedback[value.Name] = *value.Value.String
+			case value.Value.Boolean != nil:
+				feedback[value.Name] = *value.Value.Boolean
+			case value.Value.JsonRaw != nil:
+				var raw interface{}
+				if err := json.Unmarshal([]byte(*value.Value.JsonRaw), &raw); err == nil {
+					feedback[value.Name] = raw
+				}
+			}
+		}
+		return feedback, true
+	}
+	return nil, false
+}
@@ -0,0 +1,186 @@
+package helper
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const testAnalysis = `{"bakePeriod":"5m","rules":[{"name":"ready",` +
+	`"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},` +
+	`"expression":"feedback.ReadyReplicas == feedback.Replicas"}]}`
+
+func TestAnalysisOf(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		expectErr  bool
+		expectNil  bool
+	}{
+		{
+			name:      "no analysis",
+			expectNil: true,
+		},
+		{
+			name:       "valid analysis",
+			annotation: testAnalysis,
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"bakePeriod":5}`,
+			expectErr:  true,
+		},
+		{
+			name:       "no rules",
+			annotation: `{"bakePeriod":"5m","rules":[]}`,
+			expectErr:  true,
+		},
+		{
+			name: "resource is missing",
+			annotation: `{"rules":[{"name":"ready","resourceIdentifier":{"name":"test"},` +
+				`"expression":"feedback.ReadyReplicas == feedback.Replicas"}]}`,
+			expectErr: true,
+		},
+		{
+			name: "invalid expression",
+			annotation: `{"rules":[{"name":"ready","resourceIdentifier":{"resource":"deployments","name":"test"},` +
+				`"expression":"feedback.ReadyReplicas =="}]}`,
+			expectErr: true,
+		},
+		{
+			name: "expression is not a bool",
+			annotation: `{"rules":[{"name":"ready","resourceIdentifier":{"resource":"deployments","name":"test"},` +
+				`"expression":"1 + 1"}]}`,
+			expectErr: true,
+		},
+		{
+			name: "expression is too long",
+			annotation: `{"rules":[{"name":"ready","resourceIdentifier":{"resource":"deployments","name":"test"},` +
+				`"expression":"` + strings.Repeat("true && ", MaxAnalysisExpressionLength/8) + `true"}]}`,
+			expectErr: true,
+		},
+		{
+			name:       "too many rules",
+			annotation: `{"rules":[` + strings.Repeat(`{"name":"ready","resourceIdentifier":{"resource":"deployments","name":"test"},"expression":"true"},`, MaxAnalysisRules) + `{}]}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{AnalysisAnnotationKey: c.annotation}
+			}
+
+			analysis, err := AnalysisOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if !c.expectErr && (analysis == nil) != c.expectNil {
+				t.Errorf("expected nil analysis %v, but got %v", c.expectNil, analysis)
+			}
+			if analysis != nil && analysis.BakePeriod.Duration != 5*time.Minute {
+				t.Errorf("expected bake period 5m, but got %v", analysis.BakePeriod.Duration)
+			}
+		})
+	}
+}
+
+func TestAnalysisRuleEvaluate(t *testing.T) {
+	mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: map[string]string{AnalysisAnnotationKey: testAnalysis}},
+	}
+	analysis, err := AnalysisOf(mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFeedback := func(values ...workapiv1.FeedbackValue) *workapiv1.ManifestWork {
+		return &workapiv1.ManifestWork{Status: workapiv1.ManifestWorkStatus{
+			ResourceStatus: workapiv1.ManifestResourceStatus{Manifests: []workapiv1.ManifestCondition{{
+				ResourceMeta: workapiv1.ManifestResourceMeta{
+					Group: "apps", Resource: "deployments", Namespace: "default", Name: "test",
+				},
+				StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: values},
+			}}},
+		}}
+	}
+	integer := func(name string, value int64) workapiv1.FeedbackValue {
+		return workapiv1.FeedbackValue{Name: name, Value: workapiv1.FieldValue{Type: workapiv1.Integer, Integer: pointer.Int64(value)}}
+	}
+
+	cases := []struct {
+		name      string
+		work      *workapiv1.ManifestWork
+		expectErr bool
+	}{
+		{
+			name: "satisfied",
+			work: withFeedback(integer("ReadyReplicas", 3), integer("Replicas", 3)),
+		},
+		{
+			name:      "not satisfied",
+			work:      withFeedback(integer("ReadyReplicas", 1), integer("Replicas", 3)),
+			expectErr: true,
+		},
+		{
+			name:      "value is missing",
+			work:      withFeedback(integer("Replicas", 3)),
+			expectErr: true,
+		},
+		{
+			name:      "no feedback",
+			work:      &workapiv1.ManifestWork{},
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := analysis.Rules[0].Evaluate(c.work)
+			if (err != nil) != c.expectErr {
+				t.Errorf("expected error %v, but got %v", c.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestAnalysisRuleCostLimit(t *testing.T) {
+	mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: map[string]string{AnalysisAnnotationKey: `{"rules":[` +
+			`{"name":"items","resourceIdentifier":{"resource":"configmaps","namespace":"default","name":"test"},` +
+			`"expression":"feedback.Items.all(x, feedback.Items.all(y, x == y || x != y))"}]}`}},
+	}
+	analysis, err := AnalysisOf(mwrSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]int, 1000)
+	data, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := &workapiv1.ManifestWork{Status: workapiv1.ManifestWorkStatus{
+		ResourceStatus: workapiv1.ManifestResourceStatus{Manifests: []workapiv1.ManifestCondition{{
+			ResourceMeta: workapiv1.ManifestResourceMeta{Resource: "configmaps", Namespace: "default", Name: "test"},
+			StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: []workapiv1.FeedbackValue{{
+				Name:  "Items",
+				Value: workapiv1.FieldValue{Type: workapiv1.JsonRaw, JsonRaw: pointer.String(string(data))},
+			}}},
+		}}},
+	}}
+
+	err = analysis.Rules[0].Evaluate(work)
+	if err == nil || !strings.Contains(err.Error(), "cost limit") {
+		t.Errorf("expected the evaluation exceeds the cost limit, but got %v", err)
+	}
+}
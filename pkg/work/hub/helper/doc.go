@@ -0,0 +1,3 @@
+// package helper contains the helpers of the ManifestWorkReplicaSet on the hub, which are shared by the
+// ManifestWorkReplicaSet controller and the webhook.
+package helper
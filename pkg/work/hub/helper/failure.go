@@ -0,0 +1,101 @@
+package helper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// MaxFailedClusters is the max number of the failed clusters listed in ClusterFailures
+	MaxFailedClusters = 20
+	// MaxFailureMessageLength is the max length of the message of a ClusterFailure
+	MaxFailureMessageLength = 256
+)
+
+// ClusterFailure is the failing condition of the manifestwork on a cluster
+type ClusterFailure struct {
+	Cluster string `json:"cluster"`
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// ClusterFailures summarizes the failing clusters of the manifestworks generated by a manifestworkreplicaset
+type ClusterFailures struct {
+	// Total is the number of the failing clusters
+	Total         int            `json:"total"`
+	CountByReason map[string]int `json:"countByReason,omitempty"`
+	// Clusters is the failing clusters sorted by name, which is truncated to MaxFailedClusters
+	Clusters  []ClusterFailure `json:"clusters,omitempty"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+// ClusterFailuresOf returns the failing clusters of the manifestworks. The manifestwork of a cluster fails if it
+// is degraded or not applied, the degraded condition takes precedence.
+func ClusterFailuresOf(manifestWorks []*workapiv1.ManifestWork) *ClusterFailures {
+	failures := &ClusterFailures{CountByReason: map[string]int{}}
+	for _, mw := range manifestWorks {
+		condition := apimeta.FindStatusCondition(mw.Status.Conditions, workapiv1.WorkDegraded)
+		if condition == nil || condition.Status != metav1.ConditionTrue {
+			condition = apimeta.FindStatusCondition(mw.Status.Conditions, workapiv1.WorkApplied)
+			if condition == nil || condition.Status != metav1.ConditionFalse {
+				continue
+			}
+		}
+
+		failures.Total++
+		failures.CountByReason[condition.Reason]++
+		message := truncate(condition.Message, MaxFailureMessageLength)
+		failures.Clusters = append(failures.Clusters, ClusterFailure{
+			Cluster: mw.Namespace,
+			Type:    condition.Type,
+			Reason:  condition.Reason,
+			Message: message,
+		})
+	}
+
+	sort.Slice(failures.Clusters, func(i, j int) bool {
+		return failures.Clusters[i].Cluster < failures.Clusters[j].Cluster
+	})
+	if len(failures.Clusters) > MaxFailedClusters {
+		failures.Clusters = failures.Clusters[:MaxFailedClusters]
+		failures.Truncated = true
+	}
+	return failures
+}
+
+// String returns the human-readable summary of the failing clusters, e.g. 2 clusters failed (CrashLoop: 2);
+// cls1 is Degraded with CrashLoop: crashed; cls2 is Degraded with CrashLoop: crashed
+func (f *ClusterFailures) String() string {
+	reasons := []string{}
+	for reason := range f.CountByReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	counts := []string{}
+	for _, reason := range reasons {
+		counts = append(counts, fmt.Sprintf("%s: %d", reason, f.CountByReason[reason]))
+	}
+
+	parts := []string{fmt.Sprintf("%d clusters failed (%s)", f.Total, strings.Join(counts, ", "))}
+	for _, failure := range f.Clusters {
+		part := fmt.Sprintf("%s is %s with %s", failure.Cluster, failure.Type, failure.Reason)
+		if failure.Type == workapiv1.WorkApplied {
+			part = fmt.Sprintf("%s is not %s with %s", failure.Cluster, failure.Type, failure.Reason)
+		}
+		if len(failure.Message) > 0 {
+			part = fmt.Sprintf("%s: %s", part, failure.Message)
+		}
+		parts = append(parts, part)
+	}
+	if f.Truncated {
+		parts = append(parts, fmt.Sprintf("%d more clusters are omitted", f.Total-len(f.Clusters)))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestClusterFailuresOf(t *testing.T) {
+	newWork := func(cluster string, conditions ...metav1.Condition) *workapiv1.ManifestWork {
+		return &workapiv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cluster, Name: "test"},
+			Status:     workapiv1.ManifestWorkStatus{Conditions: conditions},
+		}
+	}
+	applied := metav1.Condition{Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, Reason: "AppliedManifestWorkComplete"}
+	notApplied := metav1.Condition{Type: workapiv1.WorkApplied, Status: metav1.ConditionFalse, Reason: "AppliedManifestWorkFailed",
+		Message: strings.Repeat("x", 2*MaxFailureMessageLength)}
+	degraded := metav1.Condition{Type: workapiv1.WorkDegraded, Status: metav1.ConditionTrue, Reason: "CrashLoop", Message: "crashed"}
+
+	t.Run("failing clusters", func(t *testing.T) {
+		failures := ClusterFailuresOf([]*workapiv1.ManifestWork{
+			newWork("cls3", applied, degraded),
+			newWork("cls1", notApplied),
+			newWork("cls2", applied),
+			newWork("cls4"),
+		})
+
+		expected := &ClusterFailures{
+			Total:         2,
+			CountByReason: map[string]int{"AppliedManifestWorkFailed": 1, "CrashLoop": 1},
+			Clusters: []ClusterFailure{
+				{Cluster: "cls1", Type: workapiv1.WorkApplied, Reason: "AppliedManifestWorkFailed",
+					Message: strings.Repeat("x", MaxFailureMessageLength-3) + "..."},
+				{Cluster: "cls3", Type: workapiv1.WorkDegraded, Reason: "CrashLoop", Message: "crashed"},
+			},
+		}
+		if !reflect.DeepEqual(failures, expected) {
+			t.Errorf("expected failures %v, but got %v", expected, failures)
+		}
+
+		summary := "2 clusters failed (AppliedManifestWorkFailed: 1, CrashLoop: 1); cls1 is not Applied with " +
+			"AppliedManifestWorkFailed: " + expected.Clusters[0].Message + "; cls3 is Degraded with CrashLoop: crashed"
+		if actual := failures.String(); actual != summary {
+			t.Errorf("expected summary %q, but got %q", summary, actual)
+		}
+	})
+
+	t.Run("the failing clusters are bounded", func(t *testing.T) {
+		works := []*workapiv1.ManifestWork{}
+		for i := 0; i < 2*MaxFailedClusters; i++ {
+			works = append(works, newWork(fmt.Sprintf("cls%02d", i), degraded))
+		}
+
+		failures := ClusterFailuresOf(works)
+		if failures.Total != 2*MaxFailedClusters || failures.CountByReason["CrashLoop"] != 2*MaxFailedClusters {
+			t.Errorf("expected all the failing clusters counted, but got %d", failures.Total)
+		}
+		if len(failures.Clusters) != MaxFailedClusters || !failures.Truncated || failures.Clusters[0].Cluster != "cls00" {
+			t.Errorf("expected the failing clusters are truncated, but got %v", failures.Clusters)
+		}
+		if summary := failures.String(); !strings.HasSuffix(summary, fmt.Sprintf("; %d more clusters are omitted", MaxFailedClusters)) {
+			t.Errorf("expected the omitted clusters in the summary, but got %q", summary)
+		}
+	})
+}
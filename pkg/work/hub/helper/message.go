@@ -0,0 +1,24 @@
+package helper
+
+import "unicode/utf8"
+
+// MaxConditionMessageLength is the max length of the message of a condition accepted by the apiserver
+const MaxConditionMessageLength = 32768
+
+// TruncateConditionMessage truncates the message to MaxConditionMessageLength, so the message composed from the
+// status of many clusters could still be set in a condition.
+func TruncateConditionMessage(message string) string {
+	return truncate(message, MaxConditionMessageLength)
+}
+
+// truncate truncates the string to the max length with the suffix "...", a multibyte character is not split.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max-3]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		name     string
+		s        string
+		max      int
+		expected string
+	}{
+		{
+			name:     "short",
+			s:        "test",
+			max:      10,
+			expected: "test",
+		},
+		{
+			name:     "long",
+			s:        "test message",
+			max:      10,
+			expected: "test me...",
+		},
+		{
+			name:     "multibyte character is not split",
+			s:        "test 测试",
+			max:      10,
+			expected: "test ...",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := truncate(c.s, c.max); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+
+	if message := TruncateConditionMessage(strings.Repeat("a", 2*MaxConditionMessageLength)); len(message) != MaxConditionMessageLength {
+		t.Errorf("expected the message is truncated to %d, but got %d", MaxConditionMessageLength, len(message))
+	}
+}
@@ -0,0 +1,237 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+// OverridesAnnotationKey is the annotation key on manifestworkreplicaset of the json of the OverrideRule list to
+// patch the manifests of the manifestwork template for the selected clusters.
+// TODO move this to the api repo
+const OverridesAnnotationKey = "work.open-cluster-management.io/overrides"
+
+// AppliedOverridesAnnotationKey is the annotation key on the manifestwork generated by a manifestworkreplicaset,
+// the value is the comma separated names of the override rules applied to the manifestwork.
+// TODO move this to the api repo
+const AppliedOverridesAnnotationKey = "work.open-cluster-management.io/applied-overrides"
+
+type ManifestPatchType string
+
+const (
+	JSONPatchType           ManifestPatchType = "JSONPatch"
+	MergePatchType          ManifestPatchType = "MergePatch"
+	StrategicMergePatchType ManifestPatchType = "StrategicMergePatch"
+)
+
+// OverrideRule patches the manifests of the manifestwork template for the clusters it selects. A cluster is
+// selected if it matches all the placements, the cluster names, the cluster selector and the claim selector which
+// are set, so a rule without any of them selects all the clusters.
+type OverrideRule struct {
+	Name string `json:"name"`
+	// Placements is the names of the placements in the placementRefs whose clusters are selected, a cluster
+	// selected by several placements belongs to the first of them in the placementRefs
+	Placements []string `json:"placements,omitempty"`
+	// ClusterNames is the names of the selected clusters
+	ClusterNames []string `json:"clusterNames,omitempty"`
+	// ClusterSelector selects the clusters by the labels of the managedclusters
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// ClaimSelector selects the clusters by the cluster claims in the status of the managedclusters
+	ClaimSelector *metav1.LabelSelector `json:"claimSelector,omitempty"`
+	Patches       []ManifestPatch       `json:"patches"`
+}
+
+// ManifestPatch is a patch to the manifest with the group, kind, namespace and name. The strategic merge patch
+// falls back to the json merge patch for the kinds which are not built in.
+type ManifestPatch struct {
+	Group     string            `json:"group,omitempty"`
+	Kind      string            `json:"kind"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Type      ManifestPatchType `json:"type"`
+	Patch     json.RawMessage   `json:"patch"`
+}
+
+// OverridesOf returns the override rules of the manifestworkreplicaset
+func OverridesOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) ([]OverrideRule, error) {
+	value, ok := mwrSet.Annotations[OverridesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rules := []OverrideRule{}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid list of override rules: %v", OverridesAnnotationKey, err)
+	}
+
+	placements := sets.New[string]()
+	for _, ref := range mwrSet.Spec.PlacementRefs {
+		placements.Insert(ref.Name)
+	}
+	names := sets.New[string]()
+	for _, rule := range rules {
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the override rule is empty")
+		}
+		if names.Has(rule.Name) {
+			return nil, fmt.Errorf("the override rule %s is duplicated", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		for _, placement := range rule.Placements {
+			if !placements.Has(placement) {
+				return nil, fmt.Errorf("the placement %q of the override rule %s is not in the placementRefs", placement, rule.Name)
+			}
+		}
+
+		for _, selector := range []*metav1.LabelSelector{rule.ClusterSelector, rule.ClaimSelector} {
+			if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+				return nil, fmt.Errorf("the selector of the override rule %s is invalid: %v", rule.Name, err)
+			}
+		}
+
+		for _, patch := range rule.Patches {
+			if err := patch.validate(); err != nil {
+				return nil, fmt.Errorf("the patch of the override rule %s is invalid: %v", rule.Name, err)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (p *ManifestPatch) validate() error {
+	if len(p.Kind) == 0 || len(p.Name) == 0 {
+		return fmt.Errorf("the kind and the name of the manifest are required")
+	}
+
+	switch p.Type {
+	case JSONPatchType:
+		_, err := jsonpatch.DecodePatch(p.Patch)
+		return err
+	case MergePatchType, StrategicMergePatchType:
+		patch := map[string]interface{}{}
+		return json.Unmarshal(p.Patch, &patch)
+	default:
+		return fmt.Errorf("the patch type %q is not one of %s, %s and %s",
+			p.Type, JSONPatchType, MergePatchType, StrategicMergePatchType)
+	}
+}
+
+// Selects returns true if the override rule selects the cluster belonging to the placement
+func (r *OverrideRule) Selects(cluster *clusterv1.ManagedCluster, placement string) (bool, error) {
+	if len(r.Placements) > 0 && !sets.New(r.Placements...).Has(placement) {
+		return false, nil
+	}
+	if len(r.ClusterNames) > 0 && !sets.New(r.ClusterNames...).Has(cluster.Name) {
+		return false, nil
+	}
+
+	if r.ClusterSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.ClusterSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(cluster.Labels)) {
+			return false, nil
+		}
+	}
+
+	if r.ClaimSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.ClaimSelector)
+		if err != nil {
+			return false, err
+		}
+		claims := labels.Set{}
+		for _, claim := range cluster.Status.ClusterClaims {
+			claims[claim.Name] = claim.Value
+		}
+		if !selector.Matches(claims) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ApplyOverrides patches the manifests of the manifestwork spec with the override rules selecting the cluster
+// belonging to the placement in order, and returns the names of the rules which patch any of the manifests. The manifests of the spec are
+// replaced rather than modified, so the spec could share the manifests with the template.
+func ApplyOverrides(spec *workapiv1.ManifestWorkSpec, rules []OverrideRule, cluster *clusterv1.ManagedCluster,
+	placement string) ([]string, error) {
+	applied := []string{}
+	manifests := make([]workapiv1.Manifest, len(spec.Workload.Manifests))
+	copy(manifests, spec.Workload.Manifests)
+
+	for _, rule := range rules {
+		selected, err := rule.Selects(cluster, placement)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		patched := false
+		for index, manifest := range manifests {
+			obj := &unstructured.Unstructured{}
+			if err := obj.UnmarshalJSON(manifest.Raw); err != nil {
+				return nil, err
+			}
+
+			raw := manifest.Raw
+			for _, patch := range rule.Patches {
+				if !patch.selects(obj) {
+					continue
+				}
+				raw, err = patch.apply(raw, obj)
+				if err != nil {
+					return nil, fmt.Errorf("failed to apply the override rule %s to %s %s/%s: %w",
+						rule.Name, obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+				}
+				patched = true
+			}
+			manifests[index] = workapiv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}
+		}
+
+		if patched {
+			applied = append(applied, rule.Name)
+		}
+	}
+
+	spec.Workload.Manifests = manifests
+	return applied, nil
+}
+
+func (p *ManifestPatch) selects(obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	return gvk.Group == p.Group && gvk.Kind == p.Kind && obj.GetNamespace() == p.Namespace && obj.GetName() == p.Name
+}
+
+func (p *ManifestPatch) apply(raw []byte, obj *unstructured.Unstructured) ([]byte, error) {
+	switch p.Type {
+	case JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(p.Patch)
+		if err != nil {
+			return nil, err
+		}
+		return patch.Apply(raw)
+	case StrategicMergePatchType:
+		if dataStruct, err := scheme.Scheme.New(obj.GroupVersionKind()); err == nil {
+			return strategicpatch.StrategicMergePatch(raw, p.Patch, dataStruct)
+		}
+		return jsonpatch.MergePatch(raw, p.Patch)
+	default:
+		return jsonpatch.MergePatch(raw, p.Patch)
+	}
+}
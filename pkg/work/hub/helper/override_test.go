@@ -0,0 +1,237 @@
+package helper
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+func TestOverridesOf(t *testing.T) {
+	cases := []struct {
+		name          string
+		annotation    string
+		expectErr     bool
+		expectedRules int
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "valid overrides",
+			annotation: `[{"name":"replicas","clusterSelector":{"matchLabels":{"env":"prod"}},` +
+				`"patches":[{"group":"apps","kind":"Deployment","namespace":"default","name":"test","type":"MergePatch",` +
+				`"patch":{"spec":{"replicas":3}}}]},` +
+				`{"name":"registry","claimSelector":{"matchLabels":{"region":"us"}},` +
+				`"patches":[{"group":"apps","kind":"Deployment","namespace":"default","name":"test","type":"JSONPatch",` +
+				`"patch":[{"op":"replace","path":"/spec/template/spec/containers/0/image","value":"us.io/test"}]}]}]`,
+			expectedRules: 2,
+		},
+		{
+			name: "overrides of a placement",
+			annotation: `[{"name":"canary","placements":["canary"],` +
+				`"patches":[{"group":"apps","kind":"Deployment","namespace":"default","name":"test","type":"MergePatch",` +
+				`"patch":{"spec":{"replicas":1}}}]}]`,
+			expectedRules: 1,
+		},
+		{
+			name:       "placement not in the placementRefs",
+			annotation: `[{"name":"canary","placements":["prod-us"],"patches":[]}]`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"name":"replicas"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "duplicated rules",
+			annotation: `[{"name":"replicas","patches":[]},{"name":"replicas","patches":[]}]`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid selector",
+			annotation: `[{"name":"replicas","clusterSelector":{"matchLabels":{"env":"prod env"}},"patches":[]}]`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid json patch",
+			annotation: `[{"name":"replicas","patches":[{"kind":"Deployment","name":"test","type":"JSONPatch","patch":{"spec":{}}}]}]`,
+			expectErr:  true,
+		},
+		{
+			name:       "manifest name is missing",
+			annotation: `[{"name":"replicas","patches":[{"kind":"Deployment","type":"MergePatch","patch":{"spec":{}}}]}]`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: workapiv1alpha1.ManifestWorkReplicaSetSpec{
+					PlacementRefs: []workapiv1alpha1.LocalPlacementReference{{Name: "canary"}, {Name: "prod-eu"}},
+				},
+			}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{OverridesAnnotationKey: c.annotation}
+			}
+
+			rules, err := OverridesOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if len(rules) != c.expectedRules {
+				t.Errorf("expected %d rules, but got %d", c.expectedRules, len(rules))
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	deployment := newDeployment()
+	template := workapiv1.ManifestWorkSpec{
+		Workload: workapiv1.ManifestsTemplate{Manifests: []workapiv1.Manifest{toManifest(t, deployment)}},
+	}
+	rules := []OverrideRule{
+		{
+			Name:            "replicas",
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			Patches: []ManifestPatch{{
+				Group: "apps", Kind: "Deployment", Namespace: "default", Name: "test",
+				Type: MergePatchType, Patch: json.RawMessage(`{"spec":{"replicas":3}}`),
+			}},
+		},
+		{
+			Name:          "registry",
+			ClaimSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}},
+			Patches: []ManifestPatch{{
+				Group: "apps", Kind: "Deployment", Namespace: "default", Name: "test",
+				Type:  StrategicMergePatchType,
+				Patch: json.RawMessage(`{"spec":{"template":{"spec":{"containers":[{"name":"test","image":"us.io/test"}]}}}}`),
+			}},
+		},
+		{
+			Name:       "canary",
+			Placements: []string{"canary"},
+			Patches: []ManifestPatch{{
+				Group: "apps", Kind: "Deployment", Namespace: "default", Name: "test",
+				Type:  MergePatchType,
+				Patch: json.RawMessage(`{"spec":{"template":{"spec":{"containers":[{"name":"test","image":"canary.io/test"}]}}}}`),
+			}},
+		},
+		{
+			Name:         "hostname",
+			ClusterNames: []string{"cluster1"},
+			Patches: []ManifestPatch{{
+				Group: "networking.k8s.io", Kind: "Ingress", Namespace: "default", Name: "test",
+				Type: JSONPatchType, Patch: json.RawMessage(`[{"op":"add","path":"/spec","value":{}}]`),
+			}},
+		},
+	}
+
+	cases := []struct {
+		name             string
+		cluster          *clusterv1.ManagedCluster
+		placement        string
+		expectedApplied  []string
+		expectedReplicas int64
+		expectedImage    string
+	}{
+		{
+			name:             "no rule selects the cluster",
+			cluster:          &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+			placement:        "prod-eu",
+			expectedApplied:  []string{},
+			expectedReplicas: 1,
+			expectedImage:    "test",
+		},
+		{
+			name:             "rule selects the cluster by placement",
+			cluster:          &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+			placement:        "canary",
+			expectedApplied:  []string{"canary"},
+			expectedReplicas: 1,
+			expectedImage:    "canary.io/test",
+		},
+		{
+			name: "rules select the cluster by labels, claims and name",
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Labels: map[string]string{"env": "prod"}},
+				Status: clusterv1.ManagedClusterStatus{
+					ClusterClaims: []clusterv1.ManagedClusterClaim{{Name: "region", Value: "us"}},
+				},
+			},
+			placement: "prod-eu",
+			// the rule hostname selects the cluster but patches no manifest
+			expectedApplied:  []string{"replicas", "registry"},
+			expectedReplicas: 3,
+			expectedImage:    "us.io/test",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spec := template.DeepCopy()
+			applied, err := ApplyOverrides(spec, rules, c.cluster, c.placement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(applied, c.expectedApplied) {
+				t.Errorf("expected rules %v applied, but got %v", c.expectedApplied, applied)
+			}
+
+			obj := &unstructured.Unstructured{}
+			if err := obj.UnmarshalJSON(spec.Workload.Manifests[0].Raw); err != nil {
+				t.Fatal(err)
+			}
+			replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+			if replicas != c.expectedReplicas {
+				t.Errorf("expected replicas %d, but got %d", c.expectedReplicas, replicas)
+			}
+			containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			if len(containers) != 1 || containers[0].(map[string]interface{})["image"] != c.expectedImage {
+				t.Errorf("expected image %s, but got %v", c.expectedImage, containers)
+			}
+		})
+	}
+
+	// the template is not changed
+	if !reflect.DeepEqual(template.Workload.Manifests[0], toManifest(t, deployment)) {
+		t.Errorf("expected the template is not changed")
+	}
+}
+
+func newDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "test"},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "test", "image": "test"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func toManifest(t *testing.T, obj *unstructured.Unstructured) workapiv1.Manifest {
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workapiv1.Manifest{RawExtension: runtime.RawExtension{Raw: raw}}
+}
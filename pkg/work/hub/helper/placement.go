@@ -0,0 +1,26 @@
+package helper
+
+import (
+	"fmt"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+// PlacementAnnotationKey is the annotation key on the manifestwork generated by a manifestworkreplicaset, the value
+// is the name of the placement the cluster of the manifestwork belongs to. A cluster selected by several placements
+// belongs to the first of them in the placementRefs of the manifestworkreplicaset.
+// TODO move this to the api repo
+const PlacementAnnotationKey = "work.open-cluster-management.io/placement"
+
+// PlacementSummary is the summary of the manifestworks of the clusters belonging to a placement
+type PlacementSummary struct {
+	Placement string                                        `json:"placement"`
+	Summary   workapiv1alpha1.ManifestWorkReplicaSetSummary `json:"summary"`
+}
+
+// String returns the human-readable summary of the placement, e.g. canary: 2 clusters, 2 applied, 1 available,
+// 1 progressing, 0 degraded
+func (s PlacementSummary) String() string {
+	return fmt.Sprintf("%s: %d clusters, %d applied, %d available, %d progressing, %d degraded", s.Placement,
+		s.Summary.Total, s.Summary.Applied, s.Summary.Available, s.Summary.Progressing, s.Summary.Degraded)
+}
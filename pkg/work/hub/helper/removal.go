@@ -0,0 +1,68 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// RemovalPolicyAnnotationKey is the annotation key on manifestworkreplicaset of the json of the RemovalPolicy
+	// applied when a cluster is no longer selected by the placements.
+	// TODO move this to the api repo
+	RemovalPolicyAnnotationKey = "work.open-cluster-management.io/removal-policy"
+
+	// RemovalPendingSinceAnnotationKey is the annotation key on the manifestwork generated by a
+	// manifestworkreplicaset, the value is the time in RFC3339 format since when the cluster is no longer selected
+	// by the placements and the manifestwork is pending removal.
+	// TODO move this to the api repo
+	RemovalPendingSinceAnnotationKey = "work.open-cluster-management.io/removal-pending-since"
+
+	// OrphanedFromLabelKey is the label key on the manifestwork orphaned by a manifestworkreplicaset, the value is
+	// the namespace.name of the manifestworkreplicaset, which adopts the manifestwork again once the cluster is
+	// selected by its placements again.
+	// TODO move this to the api repo
+	OrphanedFromLabelKey = "work.open-cluster-management.io/orphaned-from"
+)
+
+// RemovalPolicy decides how the manifestwork is removed from a cluster which is no longer selected by the
+// placements of the manifestworkreplicaset. The manifestwork is removed once the grace period passes and the
+// cluster is absent from the consecutive changes of the placement decisions, it is removed immediately by default.
+type RemovalPolicy struct {
+	// GracePeriod is the duration to wait before removing the manifestwork, e.g. 10m
+	GracePeriod metav1.Duration `json:"gracePeriod,omitempty"`
+	// ConsecutiveDecisions is the number of the consecutive changes of the placement decisions, including the one
+	// dropping the cluster, from which the cluster should be absent before removing the manifestwork
+	ConsecutiveDecisions int `json:"consecutiveDecisions,omitempty"`
+	// Orphan removes the manifestwork from the manifestworkreplicaset instead of deleting it, so the manifestwork
+	// and its resources are kept on the cluster.
+	Orphan bool `json:"orphan,omitempty"`
+}
+
+// Immediate returns true if the manifestwork is removed without waiting
+func (p *RemovalPolicy) Immediate() bool {
+	return p.GracePeriod.Duration == 0 && p.ConsecutiveDecisions <= 1
+}
+
+// RemovalPolicyOf returns the removal policy of the manifestworkreplicaset
+func RemovalPolicyOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*RemovalPolicy, error) {
+	policy := &RemovalPolicy{}
+	value, ok := mwrSet.Annotations[RemovalPolicyAnnotationKey]
+	if !ok {
+		return policy, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid removal policy: %v", RemovalPolicyAnnotationKey, err)
+	}
+	if policy.GracePeriod.Duration < 0 {
+		return nil, fmt.Errorf("the grace period of the removal policy should not be negative")
+	}
+	if policy.ConsecutiveDecisions < 0 {
+		return nil, fmt.Errorf("the consecutive decisions of the removal policy should not be negative")
+	}
+	return policy, nil
+}
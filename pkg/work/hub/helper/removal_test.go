@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+func TestRemovalPolicyOf(t *testing.T) {
+	cases := []struct {
+		name              string
+		annotation        string
+		expectErr         bool
+		expected          RemovalPolicy
+		expectedImmediate bool
+	}{
+		{
+			name:              "removed immediately by default",
+			expectedImmediate: true,
+		},
+		{
+			name:       "grace period and orphan",
+			annotation: `{"gracePeriod":"10m","orphan":true}`,
+			expected:   RemovalPolicy{GracePeriod: metav1.Duration{Duration: 10 * time.Minute}, Orphan: true},
+		},
+		{
+			name:       "consecutive decisions",
+			annotation: `{"consecutiveDecisions":3}`,
+			expected:   RemovalPolicy{ConsecutiveDecisions: 3},
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"gracePeriod":10}`,
+			expectErr:  true,
+		},
+		{
+			name:       "negative grace period",
+			annotation: `{"gracePeriod":"-10m"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "negative consecutive decisions",
+			annotation: `{"consecutiveDecisions":-1}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{RemovalPolicyAnnotationKey: c.annotation}
+			}
+
+			policy, err := RemovalPolicyOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if *policy != c.expected {
+				t.Errorf("expected policy %v, but got %v", c.expected, *policy)
+			}
+			if policy.Immediate() != c.expectedImmediate {
+				t.Errorf("expected immediate %v, but got %v", c.expectedImmediate, policy.Immediate())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+const (
+	// RevisionHashLabelKey is the label key on the manifestworks generated by a manifestworkreplicaset and on the
+	// controllerrevisions of the manifestworkreplicaset, the value is the hash of the template revision.
+	// TODO move this to the api repo
+	RevisionHashLabelKey = "work.open-cluster-management.io/revision-hash"
+
+	// RevisionHistoryLimitAnnotationKey is the annotation key on manifestworkreplicaset of the max number of the
+	// old template revisions to keep, the revisions still used by any cluster are always kept.
+	// TODO move this to the api repo
+	RevisionHistoryLimitAnnotationKey = "work.open-cluster-management.io/revision-history-limit"
+
+	// RollbackAnnotationKey is the annotation key on manifestworkreplicaset of the json of the Rollback, which
+	// rolls back all or the selected clusters to a previous template revision until the annotation is removed.
+	// TODO move this to the api repo
+	RollbackAnnotationKey = "work.open-cluster-management.io/rollback"
+
+	// DefaultRevisionHistoryLimit is the number of the old template revisions kept by default
+	DefaultRevisionHistoryLimit = 10
+)
+
+// Rollback re-targets the clusters of a manifestworkreplicaset to a previous template revision
+type Rollback struct {
+	// Revision is the number of the revision to roll back to
+	Revision int64 `json:"revision"`
+	// Clusters is the names of the clusters to roll back, all the clusters are rolled back if it is empty
+	Clusters []string `json:"clusters,omitempty"`
+	// FailedRevision is set if the rollback is made automatically since the revision failed the analysis, the
+	// clusters not rolled out to the failed revision yet are rolled back as well, so the rollout of the failed
+	// revision is stopped. The rollback is removed once the template is changed from the failed revision.
+	FailedRevision int64 `json:"failedRevision,omitempty"`
+}
+
+// Selects returns true if the cluster is rolled back
+func (r *Rollback) Selects(cluster string) bool {
+	return len(r.Clusters) == 0 || sets.New(r.Clusters...).Has(cluster)
+}
+
+// RollbackOf returns the rollback of the manifestworkreplicaset, it returns nil if the annotation
+// RollbackAnnotationKey is not set.
+func RollbackOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*Rollback, error) {
+	value, ok := mwrSet.Annotations[RollbackAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	rollback := &Rollback{}
+	if err := json.Unmarshal([]byte(value), rollback); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid rollback: %v", RollbackAnnotationKey, err)
+	}
+	if rollback.Revision <= 0 {
+		return nil, fmt.Errorf("the revision to roll back to should be positive, but got %d", rollback.Revision)
+	}
+	return rollback, nil
+}
+
+// RevisionHistoryLimitOf returns the max number of the old template revisions of the manifestworkreplicaset
+func RevisionHistoryLimitOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (int, error) {
+	value, ok := mwrSet.Annotations[RevisionHistoryLimitAnnotationKey]
+	if !ok {
+		return DefaultRevisionHistoryLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("the annotation %s should be a non-negative integer, but got %q", RevisionHistoryLimitAnnotationKey, value)
+	}
+	return limit, nil
+}
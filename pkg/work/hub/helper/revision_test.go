@@ -0,0 +1,114 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+func TestRollbackOf(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		expectErr  bool
+		expected   *Rollback
+	}{
+		{
+			name: "no rollback",
+		},
+		{
+			name:       "rollback all the clusters",
+			annotation: `{"revision":2}`,
+			expected:   &Rollback{Revision: 2},
+		},
+		{
+			name:       "rollback the selected clusters",
+			annotation: `{"revision":1,"clusters":["cluster1"]}`,
+			expected:   &Rollback{Revision: 1, Clusters: []string{"cluster1"}},
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"revision":"1"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid revision",
+			annotation: `{"clusters":["cluster1"]}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{RollbackAnnotationKey: c.annotation}
+			}
+
+			rollback, err := RollbackOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if !reflect.DeepEqual(rollback, c.expected) {
+				t.Errorf("expected rollback %v, but got %v", c.expected, rollback)
+			}
+		})
+	}
+
+	rollback := &Rollback{Revision: 1, Clusters: []string{"cluster1"}}
+	if !rollback.Selects("cluster1") || rollback.Selects("cluster2") {
+		t.Errorf("expected only cluster1 is rolled back")
+	}
+	if rollback := (&Rollback{Revision: 1}); !rollback.Selects("cluster2") {
+		t.Errorf("expected all the clusters are rolled back")
+	}
+}
+
+func TestRevisionHistoryLimitOf(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		expectErr  bool
+		expected   int
+	}{
+		{
+			name:     "default",
+			expected: DefaultRevisionHistoryLimit,
+		},
+		{
+			name:       "valid",
+			annotation: "3",
+			expected:   3,
+		},
+		{
+			name:       "negative",
+			annotation: "-1",
+			expectErr:  true,
+		},
+		{
+			name:       "not a number",
+			annotation: "three",
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{RevisionHistoryLimitAnnotationKey: c.annotation}
+			}
+
+			limit, err := RevisionHistoryLimitOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if limit != c.expected {
+				t.Errorf("expected limit %d, but got %d", c.expected, limit)
+			}
+		})
+	}
+}
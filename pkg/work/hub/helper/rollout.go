@@ -0,0 +1,127 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+// RolloutStrategyAnnotationKey is the annotation key on manifestworkreplicaset of the json of the RolloutStrategy
+// to roll out the changes of the manifestwork template to the selected clusters. The changes are rolled out to
+// all the clusters at once if it is not set.
+// TODO move this to the api repo
+const RolloutStrategyAnnotationKey = "work.open-cluster-management.io/rollout-strategy"
+
+// DecisionGroupIndexLabelKey is the label key on placementdecision of the index of the decision group the
+// decisions belong to, the decisions without the label belong to the group 0.
+// TODO use the decision groups of the api repo once the placement supports them
+const DecisionGroupIndexLabelKey = "cluster.open-cluster-management.io/decision-group-index"
+
+type RolloutType string
+
+const (
+	// RolloutAll rolls out the changes to all the clusters at once
+	RolloutAll RolloutType = "All"
+	// RolloutProgressive rolls out the changes to at most maxConcurrency clusters at a time, the next clusters
+	// are rolled out once the previous ones are available
+	RolloutProgressive RolloutType = "Progressive"
+	// RolloutProgressivePerGroup rolls out the changes to the decision groups of the placements one by one in the
+	// order of the group index, the next group is rolled out once all the clusters of the previous groups are
+	// available
+	RolloutProgressivePerGroup RolloutType = "ProgressivePerGroup"
+)
+
+// RolloutStrategy is the strategy to roll out the changes of the manifestwork template of a manifestworkreplicaset
+type RolloutStrategy struct {
+	Type RolloutType `json:"type"`
+	// MaxConcurrency is the max number or percentage of the selected clusters rolled out at a time, it is 1 for
+	// Progressive and all the clusters of a group for ProgressivePerGroup by default.
+	MaxConcurrency *intstr.IntOrString `json:"maxConcurrency,omitempty"`
+	// MaxFailures is the max number or percentage of the selected clusters which are degraded or failed to apply
+	// the changes, the rollout is paused once the failures exceed it. It is 0 by default.
+	MaxFailures *intstr.IntOrString `json:"maxFailures,omitempty"`
+}
+
+// RolloutStrategyOf returns the rollout strategy of the manifestworkreplicaset, it is All if the annotation
+// RolloutStrategyAnnotationKey is not set.
+func RolloutStrategyOf(mwrSet *workapiv1alpha1.ManifestWorkReplicaSet) (*RolloutStrategy, error) {
+	value, ok := mwrSet.Annotations[RolloutStrategyAnnotationKey]
+	if !ok {
+		return &RolloutStrategy{Type: RolloutAll}, nil
+	}
+
+	strategy := &RolloutStrategy{}
+	if err := json.Unmarshal([]byte(value), strategy); err != nil {
+		return nil, fmt.Errorf("the annotation %s is not a valid rollout strategy: %v", RolloutStrategyAnnotationKey, err)
+	}
+
+	switch strategy.Type {
+	case RolloutAll, RolloutProgressive, RolloutProgressivePerGroup:
+	default:
+		return nil, fmt.Errorf("the rollout strategy type %q is not one of %s, %s and %s",
+			strategy.Type, RolloutAll, RolloutProgressive, RolloutProgressivePerGroup)
+	}
+
+	// validate the values with an arbitrary total
+	if _, err := strategy.Concurrency(100); err != nil {
+		return nil, err
+	}
+	if _, err := strategy.FailureThreshold(100); err != nil {
+		return nil, err
+	}
+	return strategy, nil
+}
+
+// Concurrency returns the max number of the clusters rolled out at a time out of the total clusters, the
+// percentage is rounded up so at least one cluster is rolled out.
+func (s *RolloutStrategy) Concurrency(total int) (int, error) {
+	switch {
+	case s.Type == RolloutAll:
+		return total, nil
+	case s.MaxConcurrency != nil:
+		concurrency, err := intstr.GetScaledValueFromIntOrPercent(s.MaxConcurrency, total, true)
+		if err != nil {
+			return 0, fmt.Errorf("invalid maxConcurrency: %v", err)
+		}
+		if concurrency < 0 {
+			return 0, fmt.Errorf("invalid maxConcurrency: %s is negative", s.MaxConcurrency.String())
+		}
+		if concurrency == 0 {
+			concurrency = 1
+		}
+		return concurrency, nil
+	case s.Type == RolloutProgressive:
+		return 1, nil
+	default:
+		return total, nil
+	}
+}
+
+// FailureThreshold returns the max number of the failed clusters out of the total clusters before the rollout
+// is paused, the percentage is rounded down.
+func (s *RolloutStrategy) FailureThreshold(total int) (int, error) {
+	if s.MaxFailures == nil {
+		return 0, nil
+	}
+	threshold, err := intstr.GetScaledValueFromIntOrPercent(s.MaxFailures, total, false)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maxFailures: %v", err)
+	}
+	if threshold < 0 {
+		return 0, fmt.Errorf("invalid maxFailures: %s is negative", s.MaxFailures.String())
+	}
+	return threshold, nil
+}
+
+// DecisionGroupIndex returns the decision group index in the labels of a placementdecision
+func DecisionGroupIndex(labels map[string]string) int {
+	index, err := strconv.Atoi(labels[DecisionGroupIndexLabelKey])
+	if err != nil || index < 0 {
+		return 0
+	}
+	return index
+}
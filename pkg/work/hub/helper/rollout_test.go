@@ -0,0 +1,104 @@
+package helper
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	workapiv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+)
+
+func TestRolloutStrategyOf(t *testing.T) {
+	cases := []struct {
+		name                string
+		annotation          string
+		expectErr           bool
+		expectedType        RolloutType
+		expectedConcurrency int
+		expectedMaxFailures int
+	}{
+		{
+			name:                "all by default",
+			expectedType:        RolloutAll,
+			expectedConcurrency: 10,
+		},
+		{
+			name:                "progressive with one cluster at a time by default",
+			annotation:          `{"type":"Progressive"}`,
+			expectedType:        RolloutProgressive,
+			expectedConcurrency: 1,
+		},
+		{
+			name:                "progressive with percentages",
+			annotation:          `{"type":"Progressive","maxConcurrency":"25%","maxFailures":"25%"}`,
+			expectedType:        RolloutProgressive,
+			expectedConcurrency: 3,
+			expectedMaxFailures: 2,
+		},
+		{
+			name:                "progressive per group with all the clusters of a group by default",
+			annotation:          `{"type":"ProgressivePerGroup","maxFailures":1}`,
+			expectedType:        RolloutProgressivePerGroup,
+			expectedConcurrency: 10,
+			expectedMaxFailures: 1,
+		},
+		{
+			name:       "invalid json",
+			annotation: `Progressive`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid type",
+			annotation: `{"type":"Canary"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "invalid percentage",
+			annotation: `{"type":"Progressive","maxConcurrency":"half"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "negative max failures",
+			annotation: `{"type":"Progressive","maxFailures":-1}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mwrSet := &workapiv1alpha1.ManifestWorkReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if len(c.annotation) > 0 {
+				mwrSet.Annotations = map[string]string{RolloutStrategyAnnotationKey: c.annotation}
+			}
+
+			strategy, err := RolloutStrategyOf(mwrSet)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("expected error %v, but got %v", c.expectErr, err)
+			}
+			if c.expectErr {
+				return
+			}
+			if strategy.Type != c.expectedType {
+				t.Errorf("expected type %s, but got %s", c.expectedType, strategy.Type)
+			}
+			if concurrency, _ := strategy.Concurrency(10); concurrency != c.expectedConcurrency {
+				t.Errorf("expected concurrency %d, but got %d", c.expectedConcurrency, concurrency)
+			}
+			if maxFailures, _ := strategy.FailureThreshold(10); maxFailures != c.expectedMaxFailures {
+				t.Errorf("expected max failures %d, but got %d", c.expectedMaxFailures, maxFailures)
+			}
+		})
+	}
+}
+
+func TestDecisionGroupIndex(t *testing.T) {
+	if index := DecisionGroupIndex(nil); index != 0 {
+		t.Errorf("expected index 0, but got %d", index)
+	}
+	if index := DecisionGroupIndex(map[string]string{DecisionGroupIndexLabelKey: "2"}); index != 2 {
+		t.Errorf("expected index 2, but got %d", index)
+	}
+	if index := DecisionGroupIndex(map[string]string{DecisionGroupIndexLabelKey: "first"}); index != 0 {
+		t.Errorf("expected index 0, but got %d", index)
+	}
+}
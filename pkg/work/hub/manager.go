@@ -5,18 +5,60 @@ import (
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 
 	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
 	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 
+	"open-cluster-management.io/ocm/pkg/work/hub/controllers/manifestworkgccontroller"
 	"open-cluster-management.io/ocm/pkg/work/hub/controllers/manifestworkreplicasetcontroller"
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 )
 
+// WorkHubManagerOptions defines the flags for work hub manager
+type WorkHubManagerOptions struct {
+	ManifestWorkGCInterval                 time.Duration
+	ManifestWorkGCOrphanedReplicaSet       bool
+	ManifestWorkGCCompletedTTL             time.Duration
+	ManifestWorkGCUnreachableClusterPeriod time.Duration
+	ManifestWorkGCDryRun                   bool
+}
+
+// NewWorkHubManagerOptions returns the flags with default value set
+func NewWorkHubManagerOptions() *WorkHubManagerOptions {
+	return &WorkHubManagerOptions{
+		ManifestWorkGCInterval: 10 * time.Minute,
+	}
+}
+
+// AddFlags register and binds the default flags
+func (o *WorkHubManagerOptions) AddFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.DurationVar(&o.ManifestWorkGCInterval, "manifestwork-gc-interval", o.ManifestWorkGCInterval,
+		"Interval to garbage collect the manifestworks on the hub with the enabled policies.")
+	flags.BoolVar(&o.ManifestWorkGCOrphanedReplicaSet, "manifestwork-gc-orphaned-replicaset", o.ManifestWorkGCOrphanedReplicaSet,
+		"Delete the manifestworks whose manifestworkreplicaset does not exist anymore.")
+	flags.DurationVar(&o.ManifestWorkGCCompletedTTL, "manifestwork-gc-completed-ttl", o.ManifestWorkGCCompletedTTL,
+		"Duration after which the completed manifestworks are deleted, a manifestwork is completed once all its jobs "+
+			"report the well-known status feedback "+manifestworkgccontroller.JobCompleteFeedbackName+" as True, the "+
+			"manifestworks of the manifestworkreplicasets are skipped. The completed manifestworks are not deleted if it is "+
+			"not positive.")
+	flags.DurationVar(&o.ManifestWorkGCUnreachableClusterPeriod, "manifestwork-gc-unreachable-cluster-period",
+		o.ManifestWorkGCUnreachableClusterPeriod, "Duration after which the manifestworks of the clusters not available are "+
+			"deleted with the finalizer of the work agent removed, the manifestworks of the manifestworkreplicasets are "+
+			"skipped. The manifestworks of the unavailable clusters are not deleted if it is not positive.")
+	flags.BoolVar(&o.ManifestWorkGCDryRun, "manifestwork-gc-dry-run", o.ManifestWorkGCDryRun,
+		"Report the manifestworks to garbage collect with the events and the condition "+
+			manifestworkgccontroller.ManifestWorkConditionGarbageCollectable+" on the manifestworks rather than deleting them.")
+}
+
 // RunWorkHubManager starts the controllers on hub.
-func RunWorkHubManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
+func (o *WorkHubManagerOptions) RunWorkHubManager(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
 	hubWorkClient, err := workclientset.NewForConfig(controllerContext.KubeConfig)
 	if err != nil {
 		return err
@@ -27,39 +69,90 @@ func RunWorkHubManager(ctx context.Context, controllerContext *controllercmd.Con
 		return err
 	}
 
+	hubKubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+
 	clusterInformerFactory := clusterinformers.NewSharedInformerFactory(hubClusterClient, 30*time.Minute)
 	workInformerFactory := workinformers.NewSharedInformerFactory(hubWorkClient, 30*time.Minute)
 
 	// we need a separated filtered manifestwork informers so we only watch the manifestworks that manifestworkreplicaset cares.
 	// This could reduce a lot of memory consumptions
-	manifestWorkInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 30*time.Minute, workinformers.WithTweakListOptions(
-		func(listOptions *metav1.ListOptions) {
-			selector := &metav1.LabelSelector{
-				MatchExpressions: []metav1.LabelSelectorRequirement{
-					{
-						Key:      manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey,
-						Operator: metav1.LabelSelectorOpExists,
-					},
-				},
-			}
-			listOptions.LabelSelector = metav1.FormatLabelSelector(selector)
-		},
-	))
+	manifestWorkInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 30*time.Minute,
+		workinformers.WithTweakListOptions(manifestWorkReplicaSetLabelSelector))
+	// the manifestworks orphaned by the manifestworkreplicasets are watched to adopt them again
+	orphanedWorkInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 30*time.Minute,
+		workinformers.WithTweakListOptions(orphanedManifestWorkLabelSelector))
+
+	// the controllerrevisions of manifestworkreplicasets are filtered in the same way
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(hubKubeClient, 30*time.Minute,
+		kubeinformers.WithTweakListOptions(manifestWorkReplicaSetLabelSelector))
 
 	manifestWorkReplicaSetController := manifestworkreplicasetcontroller.NewManifestWorkReplicaSetController(
 		controllerContext.EventRecorder,
 		hubWorkClient,
+		hubKubeClient,
 		workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets(),
 		manifestWorkInformerFactory.Work().V1().ManifestWorks(),
+		orphanedWorkInformerFactory.Work().V1().ManifestWorks(),
 		clusterInformerFactory.Cluster().V1beta1().Placements(),
 		clusterInformerFactory.Cluster().V1beta1().PlacementDecisions(),
+		clusterInformerFactory.Cluster().V1().ManagedClusters(),
+		kubeInformerFactory.Apps().V1().ControllerRevisions(),
 	)
 
+	gcPolicies := manifestworkgccontroller.Policies{
+		OrphanedReplicaSet:       o.ManifestWorkGCOrphanedReplicaSet,
+		CompletedTTL:             o.ManifestWorkGCCompletedTTL,
+		UnreachableClusterPeriod: o.ManifestWorkGCUnreachableClusterPeriod,
+		DryRun:                   o.ManifestWorkGCDryRun,
+	}
+	if gcPolicies.Enabled() {
+		// the garbage collection checks all the manifestworks, including the ones not created by manifestworkreplicasets
+		manifestWorkGCController := manifestworkgccontroller.NewManifestWorkGarbageCollectionController(
+			controllerContext.EventRecorder,
+			hubWorkClient,
+			workInformerFactory.Work().V1().ManifestWorks(),
+			workInformerFactory.Work().V1alpha1().ManifestWorkReplicaSets(),
+			clusterInformerFactory.Cluster().V1().ManagedClusters(),
+			gcPolicies,
+			o.ManifestWorkGCInterval,
+		)
+		go manifestWorkGCController.Run(ctx, 1)
+	}
+
 	go clusterInformerFactory.Start(ctx.Done())
 	go workInformerFactory.Start(ctx.Done())
 	go manifestWorkInformerFactory.Start(ctx.Done())
+	go orphanedWorkInformerFactory.Start(ctx.Done())
+	go kubeInformerFactory.Start(ctx.Done())
 	go manifestWorkReplicaSetController.Run(ctx, 5)
 
 	<-ctx.Done()
 	return nil
 }
+
+func manifestWorkReplicaSetLabelSelector(listOptions *metav1.ListOptions) {
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      manifestworkreplicasetcontroller.ManifestWorkReplicaSetControllerNameLabelKey,
+				Operator: metav1.LabelSelectorOpExists,
+			},
+		},
+	}
+	listOptions.LabelSelector = metav1.FormatLabelSelector(selector)
+}
+
+func orphanedManifestWorkLabelSelector(listOptions *metav1.ListOptions) {
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      hubhelper.OrphanedFromLabelKey,
+				Operator: metav1.LabelSelectorOpExists,
+			},
+		},
+	}
+	listOptions.LabelSelector = metav1.FormatLabelSelector(selector)
+}
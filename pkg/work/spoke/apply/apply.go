@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	workapiv1 "open-cluster-management.io/api/work/v1"
 )
@@ -38,6 +39,23 @@ func NewAppliers(dynamicClient dynamic.Interface, kubeclient kubernetes.Interfac
 	}
 }
 
+// NewAppliersForConfig builds the clients with the config and returns the appliers using these clients
+func NewAppliersForConfig(config *rest.Config) (*Appliers, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	apiExtensionClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewAppliers(dynamicClient, kubeClient, apiExtensionClient), nil
+}
+
 func (a *Appliers) GetApplier(strategy workapiv1.UpdateStrategyType) Applier {
 	return a.appliers[strategy]
 }
@@ -15,7 +15,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 
-	workapiv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/ocm/pkg/work/helper"
 )
 
 type NotAllowedError struct {
@@ -46,20 +46,35 @@ type SarValidator struct {
 	newImpersonateClientFunc newImpersonateClient
 }
 
-type newImpersonateClient func(config *rest.Config, username string) (dynamic.Interface, error)
+type newImpersonateClient func(config *rest.Config, subject *helper.ExecutorSubject) (dynamic.Interface, error)
 
-func defaultNewImpersonateClient(config *rest.Config, username string) (dynamic.Interface, error) {
+func defaultNewImpersonateClient(config *rest.Config, subject *helper.ExecutorSubject) (dynamic.Interface, error) {
+	impersonatedConfig, err := NewImpersonatedConfig(config, subject)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(impersonatedConfig)
+}
+
+// NewImpersonatedConfig returns a copy of the config which impersonates the executor subject
+func NewImpersonatedConfig(config *rest.Config, subject *helper.ExecutorSubject) (*rest.Config, error) {
 	if config == nil {
 		return nil, fmt.Errorf("kube config should not be nil")
 	}
-	impersonatedConfig := *config
-	impersonatedConfig.Impersonate.UserName = username
-	return dynamic.NewForConfig(&impersonatedConfig)
+	impersonatedConfig := rest.CopyConfig(config)
+	impersonatedConfig.Impersonate.UserName = subject.UserName()
+	// the api server adds the groups of service accounts and authenticated users by itself, only the group of a
+	// group executor needs to be impersonated. A user executor is impersonated without the other groups of the
+	// user, the same as the subject access reviews with the groups of the subject.
+	if subject.Type == helper.ExecutorSubjectTypeGroup {
+		impersonatedConfig.Impersonate.Groups = []string{subject.Group.Name}
+	}
+	return impersonatedConfig, nil
 }
 
 // Validate checks whether the executor has permission to operate the specific gvr resource by
 // sending sar requests to the api server.
-func (v *SarValidator) Validate(ctx context.Context, executor *workapiv1.ManifestWorkExecutor,
+func (v *SarValidator) Validate(ctx context.Context, executor *helper.Executor,
 	gvr schema.GroupVersionResource, namespace, name string,
 	ownedByTheWork bool, obj *unstructured.Unstructured) error {
 	if executor == nil {
@@ -70,31 +85,38 @@ func (v *SarValidator) Validate(ctx context.Context, executor *workapiv1.Manifes
 		return err
 	}
 
-	if err := v.CheckSubjectAccessReviews(ctx, executor.Subject.ServiceAccount,
+	if err := v.CheckSubjectAccessReviews(ctx, &executor.Subject,
 		gvr, namespace, name, ownedByTheWork); err != nil {
 		return err
 	}
 
 	// subjectaccessreview can not check permission escalation, use an impersonation request to check again
-	return v.CheckEscalation(ctx, executor.Subject.ServiceAccount, gvr, namespace, name, obj)
+	return v.CheckEscalation(ctx, &executor.Subject, gvr, namespace, name, obj)
 }
 
-// ExecutorBasicCheck do some basic checks for the executor
-func (v *SarValidator) ExecutorBasicCheck(executor *workapiv1.ManifestWorkExecutor) error {
-	if executor.Subject.Type != workapiv1.ExecutorSubjectTypeServiceAccount {
-		return fmt.Errorf("only support %s type for the executor", workapiv1.ExecutorSubjectTypeServiceAccount)
+// ValidateRead checks whether the executor has permission to read the specific gvr resource by sending a sar
+// request to the api server.
+func (v *SarValidator) ValidateRead(ctx context.Context, executor *helper.Executor,
+	gvr schema.GroupVersionResource, namespace, name string) error {
+	if executor == nil {
+		return nil
 	}
 
-	sa := executor.Subject.ServiceAccount
-	if sa == nil {
-		return fmt.Errorf("the executor service account is nil")
+	if err := v.ExecutorBasicCheck(executor); err != nil {
+		return err
 	}
 
-	return nil
+	return v.CheckReadSubjectAccessReview(ctx, &executor.Subject, gvr, namespace, name)
 }
 
-// CheckSubjectAccessReviews checks if the sa has permission to operate the gvr resource by subjectAccessReview requests
-func (v *SarValidator) CheckSubjectAccessReviews(ctx context.Context, sa *workapiv1.ManifestWorkSubjectServiceAccount,
+// ExecutorBasicCheck do some basic checks for the executor
+func (v *SarValidator) ExecutorBasicCheck(executor *helper.Executor) error {
+	return executor.Subject.Validate()
+}
+
+// CheckSubjectAccessReviews checks if the subject has permission to operate the gvr resource by subjectAccessReview
+// requests
+func (v *SarValidator) CheckSubjectAccessReviews(ctx context.Context, subject *helper.ExecutorSubject,
 	gvr schema.GroupVersionResource, namespace, name string, ownedByTheWork bool) error {
 
 	verbs := []string{"create", "update", "patch", "get"}
@@ -114,7 +136,7 @@ func (v *SarValidator) CheckSubjectAccessReviews(ctx context.Context, sa *workap
 		Resource:  gvr.Resource,
 	}
 
-	reviews := buildSubjectAccessReviews(sa.Namespace, sa.Name, resource, verbs...)
+	reviews := buildSubjectAccessReviews(subject.UserName(), subject.Groups(), resource, verbs...)
 	allowed, err := validateBySubjectAccessReviews(ctx, v.kubeClient, reviews)
 	if err != nil {
 		return err
@@ -131,8 +153,37 @@ func (v *SarValidator) CheckSubjectAccessReviews(ctx context.Context, sa *workap
 	return nil
 }
 
-// CheckEscalation checks whether the sa is escalated to operate the gvr(RBAC) resources.
-func (v *SarValidator) CheckEscalation(ctx context.Context, sa *workapiv1.ManifestWorkSubjectServiceAccount,
+// CheckReadSubjectAccessReview checks if the subject has permission to get the gvr resource by a
+// subjectAccessReview request
+func (v *SarValidator) CheckReadSubjectAccessReview(ctx context.Context, subject *helper.ExecutorSubject,
+	gvr schema.GroupVersionResource, namespace, name string) error {
+	resource := authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Name:      name,
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Resource:  gvr.Resource,
+	}
+
+	reviews := buildSubjectAccessReviews(subject.UserName(), subject.Groups(), resource, "get")
+	allowed, err := validateBySubjectAccessReviews(ctx, v.kubeClient, reviews)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return &NotAllowedError{
+			Err: fmt.Errorf("not allowed to read the resource %s %s, %s %s",
+				resource.Group, resource.Resource, resource.Namespace, resource.Name),
+			RequeueTime: 60 * time.Second,
+		}
+	}
+
+	return nil
+}
+
+// CheckEscalation checks whether the subject is escalated to operate the gvr(RBAC) resources.
+func (v *SarValidator) CheckEscalation(ctx context.Context, subject *helper.ExecutorSubject,
 	gvr schema.GroupVersionResource, namespace, name string, obj *unstructured.Unstructured) error {
 
 	if gvr.Group != "rbac.authorization.k8s.io" {
@@ -143,7 +194,7 @@ func (v *SarValidator) CheckEscalation(ctx context.Context, sa *workapiv1.Manife
 		return nil
 	}
 
-	dynamicClient, err := v.newImpersonateClientFunc(v.config, username(sa.Namespace, sa.Name))
+	dynamicClient, err := v.newImpersonateClientFunc(v.config, subject)
 	if err != nil {
 		return err
 	}
@@ -169,15 +220,7 @@ func (v *SarValidator) CheckEscalation(ctx context.Context, sa *workapiv1.Manife
 	return err
 }
 
-func username(saNamespace, saName string) string {
-	return fmt.Sprintf("system:serviceaccount:%s:%s", saNamespace, saName)
-}
-func groups(saNamespace string) []string {
-	return []string{"system:serviceaccounts", "system:authenticated",
-		fmt.Sprintf("system:serviceaccounts:%s", saNamespace)}
-}
-
-func buildSubjectAccessReviews(saNamespace string, saName string,
+func buildSubjectAccessReviews(username string, groups []string,
 	resource authorizationv1.ResourceAttributes,
 	verbs ...string) []authorizationv1.SubjectAccessReview {
 
@@ -194,8 +237,8 @@ func buildSubjectAccessReviews(saNamespace string, saName string,
 					Namespace:   resource.Namespace,
 					Verb:        verb,
 				},
-				User:   username(saNamespace, saName),
-				Groups: groups(saNamespace),
+				User:   username,
+				Groups: groups,
 			},
 		})
 	}
@@ -3,6 +3,7 @@ package basic
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 
 	v1 "k8s.io/api/authorization/v1"
@@ -10,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	fakekube "k8s.io/client-go/kubernetes/fake"
@@ -18,13 +20,14 @@ import (
 
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
 func TestValidate(t *testing.T) {
 
 	tests := map[string]struct {
-		executor  *workapiv1.ManifestWorkExecutor
+		executor  *helper.Executor
 		namespace string
 		name      string
 		expect    error
@@ -34,24 +37,25 @@ func TestValidate(t *testing.T) {
 			expect:   nil,
 		},
 		"unsupported type": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: "test",
 				},
 			},
-			expect: fmt.Errorf("only support %s type for the executor", workapiv1.ExecutorSubjectTypeServiceAccount),
+			expect: fmt.Errorf("only support %s, %s and %s type for the executor",
+				workapiv1.ExecutorSubjectTypeServiceAccount, helper.ExecutorSubjectTypeUser, helper.ExecutorSubjectTypeGroup),
 		},
 		"sa nil": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 				},
 			},
 			expect: fmt.Errorf("the executor service account is nil"),
 		},
 		"forbidden": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -63,9 +67,59 @@ func TestValidate(t *testing.T) {
 			name:      "test",
 			expect:    fmt.Errorf("not allowed to apply the resource  secrets, test-deny test, will try again in 1m0s"),
 		},
+		"user empty": {
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
+					Type: helper.ExecutorSubjectTypeUser,
+					User: &helper.ExecutorSubjectUser{},
+				},
+			},
+			expect: fmt.Errorf("the executor user is empty"),
+		},
+		"group nil": {
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
+					Type: helper.ExecutorSubjectTypeGroup,
+				},
+			},
+			expect: fmt.Errorf("the executor group is empty"),
+		},
+		"user forbidden": {
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
+					Type: helper.ExecutorSubjectTypeUser,
+					User: &helper.ExecutorSubjectUser{Name: "test-user"},
+				},
+			},
+			namespace: "test-deny",
+			name:      "test",
+			expect:    fmt.Errorf("not allowed to apply the resource  secrets, test-deny test, will try again in 1m0s"),
+		},
+		"user allow": {
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
+					Type: helper.ExecutorSubjectTypeUser,
+					User: &helper.ExecutorSubjectUser{Name: "test-user"},
+				},
+			},
+			namespace: "test-allow",
+			name:      "test",
+			expect:    nil,
+		},
+		"group allow": {
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
+					Type:  helper.ExecutorSubjectTypeGroup,
+					Group: &helper.ExecutorSubjectGroup{Name: "test-group"},
+				},
+			},
+			namespace: "test-allow",
+			name:      "test",
+			expect:    nil,
+		},
 		"allow": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -118,18 +172,69 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateUserExecutorBindings(t *testing.T) {
+	executor := &helper.Executor{
+		Subject: helper.ExecutorSubject{
+			Type: helper.ExecutorSubjectTypeUser,
+			User: &helper.ExecutorSubjectUser{Name: "user1"},
+		},
+	}
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	// user1 is a member of group1 in the identity provider, the namespace group-bound grants the permission to
+	// group1, and the namespace user-bound grants the permission to user1 directly
+	kubeClient := fakekube.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			obj := action.(clienttesting.CreateActionImpl).Object.(*v1.SubjectAccessReview)
+			allowed := false
+			switch obj.Spec.ResourceAttributes.Namespace {
+			case "group-bound":
+				allowed = sets.New[string](obj.Spec.Groups...).Has("group1")
+			case "user-bound":
+				allowed = obj.Spec.User == "user1"
+			}
+			return true, &v1.SubjectAccessReview{Status: v1.SubjectAccessReviewStatus{Allowed: allowed, Denied: !allowed}}, nil
+		},
+	)
+	validator := NewSARValidator(nil, kubeClient)
+
+	if err := validator.Validate(context.TODO(), executor, gvr, "group-bound", "test", true, nil); err == nil {
+		t.Errorf("expected the permission bound to the group of the user is not effective for the user executor")
+	}
+	if err := validator.Validate(context.TODO(), executor, gvr, "user-bound", "test", true, nil); err != nil {
+		t.Errorf("expected the permission bound to the user is effective for the user executor, but got %v", err)
+	}
+
+	// the user executor is impersonated with the same identity as the subject access reviews, the api server adds
+	// the group system:authenticated only
+	config, err := NewImpersonatedConfig(&rest.Config{}, &executor.Subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Impersonate.UserName != "user1" || len(config.Impersonate.Groups) != 0 {
+		t.Errorf("expected user1 is impersonated without groups, but got %v", config.Impersonate)
+	}
+	for _, action := range kubeClient.Actions() {
+		review := action.(clienttesting.CreateActionImpl).Object.(*v1.SubjectAccessReview)
+		if !reflect.DeepEqual(review.Spec.Groups, []string{"system:authenticated"}) {
+			t.Errorf("expected the subject access review with the group system:authenticated, but got %v", review.Spec.Groups)
+		}
+	}
+}
+
 func TestValidateEscalation(t *testing.T) {
 
 	tests := map[string]struct {
-		executor  *workapiv1.ManifestWorkExecutor
+		executor  *helper.Executor
 		namespace string
 		name      string
 		obj       *unstructured.Unstructured
 		expect    error
 	}{
 		"forbidden": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -143,8 +248,8 @@ func TestValidateEscalation(t *testing.T) {
 			expect:    fmt.Errorf("not allowed to apply the resource rbac.authorization.k8s.io roles, test-deny test, error: permission escalation, will try again in 1m0s"),
 		},
 		"allow": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -190,7 +295,7 @@ func TestValidateEscalation(t *testing.T) {
 		})
 	validator := &SarValidator{
 		kubeClient: kubeClient,
-		newImpersonateClientFunc: func(config *rest.Config, username string) (dynamic.Interface, error) {
+		newImpersonateClientFunc: func(config *rest.Config, subject *helper.ExecutorSubject) (dynamic.Interface, error) {
 			return dynamicClient, nil
 		},
 	}
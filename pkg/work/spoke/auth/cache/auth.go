@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -13,20 +14,35 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 
 	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
 )
 
-// SubjectAccessReviewCheckFn is a function to checks if the executor has permission to operate
+// SubjectAccessReviewCheckFn is a function to checks if the executor has permission to execute the action on
 // the gvr resource by subjectaccessreview
-type SubjectAccessReviewCheckFn func(ctx context.Context, executor *workapiv1.ManifestWorkSubjectServiceAccount,
-	gvr schema.GroupVersionResource, namespace, name string, ownedByTheWork bool) error
+type SubjectAccessReviewCheckFn func(ctx context.Context, executor *helper.ExecutorSubject,
+	gvr schema.GroupVersionResource, namespace, name string, action store.ExecuteAction) error
+
+// newSubjectAccessReviewCheckFn returns a SubjectAccessReviewCheckFn which checks the read action with the read
+// subjectaccessreview, and the other actions with the apply subjectaccessreviews of the validator
+func newSubjectAccessReviewCheckFn(validator *basic.SarValidator) SubjectAccessReviewCheckFn {
+	return func(ctx context.Context, executor *helper.ExecutorSubject,
+		gvr schema.GroupVersionResource, namespace, name string, action store.ExecuteAction) error {
+		if action == store.ReadAction {
+			return validator.CheckReadSubjectAccessReview(ctx, executor, gvr, namespace, name)
+		}
+		return validator.CheckSubjectAccessReviews(ctx, executor, gvr, namespace, name, store.GetOwnedByWork(action))
+	}
+}
 
 type sarCacheValidator struct {
 	kubeClient kubernetes.Interface
@@ -38,9 +54,17 @@ type sarCacheValidator struct {
 	validator                        *basic.SarValidator
 	spokeInformer                    informers.SharedInformerFactory
 	cacheController                  factory.Controller
+	// restoredExecutors contains the executors whose caches are restored from the persisted caches
+	restoredExecutors *safeSet
+	// cachesPersister persists the executor caches every persistInterval, it is nil if the persistence is disabled
+	cachesPersister *cachesPersister
+	persistInterval time.Duration
 }
 
-// NewExecutorCacheValidator creates a sarCacheValidator
+// NewExecutorCacheValidator creates a sarCacheValidator for the manifestworks of the hub with the hubName, which is
+// empty for the default hub. If the cachesClient is not nil and the persistInterval is positive, the executor caches
+// are persisted into a configmap of the hub with the cachesClient every persistInterval, and restored from the
+// configmap when the validator starts.
 func NewExecutorCacheValidator(
 	ctx context.Context,
 	recorder events.Recorder,
@@ -48,6 +72,9 @@ func NewExecutorCacheValidator(
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
 	restMapper meta.RESTMapper,
 	validator *basic.SarValidator,
+	hubName string,
+	cachesClient corev1client.ConfigMapInterface,
+	persistInterval time.Duration,
 ) *sarCacheValidator {
 
 	manifestWorkExecutorCachesLoader := &defaultManifestWorkExecutorCachesLoader{
@@ -67,6 +94,13 @@ func NewExecutorCacheValidator(
 		executorCaches:                   executorCaches,
 		manifestWorkExecutorCachesLoader: manifestWorkExecutorCachesLoader,
 		spokeInformer:                    spokeKubeInformerFactory,
+		restoredExecutors:                newSafeSet(),
+		persistInterval:                  persistInterval,
+	}
+
+	if cachesClient != nil && persistInterval > 0 {
+		v.cachesPersister = newCachesPersister(cachesClient,
+			helper.HubControllerName(ExecutorCachesConfigMapName, hubName), executorCaches)
 	}
 
 	v.cacheController = NewExecutorCacheController(ctx, recorder,
@@ -76,7 +110,9 @@ func NewExecutorCacheValidator(
 		v.spokeInformer.Rbac().V1().Roles(),
 		manifestWorkExecutorCachesLoader,
 		executorCaches,
-		v.validator.CheckSubjectAccessReviews,
+		v.restoredExecutors,
+		newSubjectAccessReviewCheckFn(v.validator),
+		hubName,
 	)
 
 	return v
@@ -91,6 +127,19 @@ func (v *sarCacheValidator) Start(ctx context.Context) {
 	// have no chance to initialize after the work pod restarts
 	v.manifestWorkExecutorCachesLoader.loadAllValuableCaches(v.executorCaches)
 
+	if v.cachesPersister != nil {
+		// restore the persisted caches after the caches skeleton is initialized, so only the caches which are
+		// still necessary will be restored
+		executors, err := v.cachesPersister.restore(ctx, v.executorCaches)
+		if err != nil {
+			klog.Errorf("Failed to restore the executor caches: %v", err)
+		} else {
+			v.restoredExecutors.insert(executors...)
+			klog.Infof("Restored the caches of %d executors", len(executors))
+		}
+		go v.cachesPersister.run(ctx, v.persistInterval)
+	}
+
 	v.spokeInformer.Start(ctx.Done())
 	v.cacheController.Run(ctx, 1)
 }
@@ -98,7 +147,7 @@ func (v *sarCacheValidator) Start(ctx context.Context) {
 // Validate checks whether the executor has permission to operate the specific gvr resource.
 // it will first try to get the subject access review checking result from caches, if there is no result in caches,
 // then it will send sar requests to the api server and store the result into caches.
-func (v *sarCacheValidator) Validate(ctx context.Context, executor *workapiv1.ManifestWorkExecutor,
+func (v *sarCacheValidator) Validate(ctx context.Context, executor *helper.Executor,
 	gvr schema.GroupVersionResource, namespace, name string,
 	ownedByTheWork bool, obj *unstructured.Unstructured) error {
 	if executor == nil {
@@ -109,20 +158,15 @@ func (v *sarCacheValidator) Validate(ctx context.Context, executor *workapiv1.Ma
 		return err
 	}
 
-	sa := executor.Subject.ServiceAccount
-	executorKey := store.ExecutorKey(sa.Namespace, sa.Name)
-	dimension := store.Dimension{
-		Namespace:     namespace,
-		Name:          name,
-		Resource:      gvr.Resource,
-		Group:         gvr.Group,
-		Version:       gvr.Version,
-		ExecuteAction: store.GetExecuteAction(ownedByTheWork),
-	}
+	subject := &executor.Subject
+	executorKey := executorKey(subject)
+	dimension := newDimension(gvr, namespace, name, ownedByTheWork)
 
+	// the restored caches are trusted until they are revalidated by the cache controller, so the work agent does
+	// not send a subject access review for every manifest once it restarts
 	allowed, _ := v.executorCaches.Get(executorKey, dimension)
 	if allowed == nil {
-		err := v.validator.CheckSubjectAccessReviews(ctx, sa, gvr, namespace, name, ownedByTheWork)
+		err := v.validator.CheckSubjectAccessReviews(ctx, subject, gvr, namespace, name, ownedByTheWork)
 		updateSARCheckResultToCache(v.executorCaches, executorKey, dimension, err)
 		if err != nil {
 			return err
@@ -138,7 +182,78 @@ func (v *sarCacheValidator) Validate(ctx context.Context, executor *workapiv1.Ma
 		}
 	}
 
-	return v.validator.CheckEscalation(ctx, sa, gvr, namespace, name, obj)
+	return v.validator.CheckEscalation(ctx, subject, gvr, namespace, name, obj)
+}
+
+// ValidateRead checks whether the executor has permission to read the specific gvr resource, the subject access
+// review checking result is cached in the same way as Validate.
+func (v *sarCacheValidator) ValidateRead(ctx context.Context, executor *helper.Executor,
+	gvr schema.GroupVersionResource, namespace, name string) error {
+	if executor == nil {
+		return nil
+	}
+
+	if err := v.validator.ExecutorBasicCheck(executor); err != nil {
+		return err
+	}
+
+	subject := &executor.Subject
+	executorKey := executorKey(subject)
+	dimension := newReadDimension(gvr, namespace, name)
+
+	allowed, _ := v.executorCaches.Get(executorKey, dimension)
+	if allowed == nil {
+		err := v.validator.CheckReadSubjectAccessReview(ctx, subject, gvr, namespace, name)
+		updateSARCheckResultToCache(v.executorCaches, executorKey, dimension, err)
+		return err
+	}
+
+	klog.V(4).Infof("Get auth from cache executor %s, dimension: %+v allow: %v", executorKey, dimension, *allowed)
+	if !*allowed {
+		return &basic.NotAllowedError{
+			Err: fmt.Errorf("not allowed to read the resource %s %s, %s %s",
+				gvr.Group, gvr.Resource, namespace, name),
+			RequeueTime: 60 * time.Second,
+		}
+	}
+	return nil
+}
+
+// CachedPermission returns the cached subject access review result of the executor to operate the resource, cached
+// is false if the result is not in the caches.
+func (v *sarCacheValidator) CachedPermission(executor *helper.Executor, gvr schema.GroupVersionResource,
+	namespace, name string, ownedByTheWork bool) (allowed bool, cached bool) {
+	if executor == nil {
+		return false, false
+	}
+
+	result, _ := v.executorCaches.Get(executorKey(&executor.Subject), newDimension(gvr, namespace, name, ownedByTheWork))
+	if result == nil {
+		return false, false
+	}
+	return *result, true
+}
+
+func newDimension(gvr schema.GroupVersionResource, namespace, name string, ownedByTheWork bool) store.Dimension {
+	return store.Dimension{
+		Namespace:     namespace,
+		Name:          name,
+		Resource:      gvr.Resource,
+		Group:         gvr.Group,
+		Version:       gvr.Version,
+		ExecuteAction: store.GetExecuteAction(ownedByTheWork),
+	}
+}
+
+func newReadDimension(gvr schema.GroupVersionResource, namespace, name string) store.Dimension {
+	return store.Dimension{
+		Namespace:     namespace,
+		Name:          name,
+		Resource:      gvr.Resource,
+		Group:         gvr.Group,
+		Version:       gvr.Version,
+		ExecuteAction: store.ReadAction,
+	}
 }
 
 // updateSARCheckResultToCache updates the subjectAccessReview checking result to the executor cache
@@ -153,3 +268,45 @@ func updateSARCheckResultToCache(executorCaches *store.ExecutorCaches, executorK
 		executorCaches.Upsert(executorKey, dimension, pointer.Bool(false))
 	}
 }
+
+// executorKey returns the key of the executor caches map for the subject. The key of a service account executor is
+// in the format of {namespace}/{name}, and the key of a user or group executor is in the format of {type}:{name}.
+// The two formats do not conflict since the namespace of a service account cannot contain a colon.
+func executorKey(subject *helper.ExecutorSubject) string {
+	switch subject.Type {
+	case helper.ExecutorSubjectTypeUser:
+		return store.UserExecutorKey(subject.User.Name)
+	case helper.ExecutorSubjectTypeGroup:
+		return store.GroupExecutorKey(subject.Group.Name)
+	}
+	return store.ExecutorKey(subject.ServiceAccount.Namespace, subject.ServiceAccount.Name)
+}
+
+// executorSubjectFromKey is the reverse of executorKey, it returns the executor subject of the executor key.
+func executorSubjectFromKey(key string) (*helper.ExecutorSubject, error) {
+	if name, ok := strings.CutPrefix(key, store.UserExecutorKey("")); ok {
+		return &helper.ExecutorSubject{
+			Type: helper.ExecutorSubjectTypeUser,
+			User: &helper.ExecutorSubjectUser{Name: name},
+		}, nil
+	}
+
+	if name, ok := strings.CutPrefix(key, store.GroupExecutorKey("")); ok {
+		return &helper.ExecutorSubject{
+			Type:  helper.ExecutorSubjectTypeGroup,
+			Group: &helper.ExecutorSubjectGroup{Name: name},
+		}, nil
+	}
+
+	saNamespace, saName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &helper.ExecutorSubject{
+		Type: workapiv1.ExecutorSubjectTypeServiceAccount,
+		ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
+			Namespace: saNamespace,
+			Name:      saName,
+		},
+	}, nil
+}
@@ -2,7 +2,9 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -14,12 +16,15 @@ import (
 	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -35,6 +40,7 @@ func newExecutorCacheValidator(t *testing.T, ctx context.Context, clusterName st
 		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(clusterName),
 		spoketesting.NewFakeRestMapper(),
 		basicValidater,
+		"", nil, 0,
 	)
 
 	go func() {
@@ -49,7 +55,7 @@ func newExecutorCacheValidator(t *testing.T, ctx context.Context, clusterName st
 func TestValidate(t *testing.T) {
 
 	tests := map[string]struct {
-		executor  *workapiv1.ManifestWorkExecutor
+		executor  *helper.Executor
 		namespace string
 		name      string
 		expect    error
@@ -59,24 +65,25 @@ func TestValidate(t *testing.T) {
 			expect:   nil,
 		},
 		"unsupported type": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: "test",
 				},
 			},
-			expect: fmt.Errorf("only support %s type for the executor", workapiv1.ExecutorSubjectTypeServiceAccount),
+			expect: fmt.Errorf("only support %s, %s and %s type for the executor",
+				workapiv1.ExecutorSubjectTypeServiceAccount, helper.ExecutorSubjectTypeUser, helper.ExecutorSubjectTypeGroup),
 		},
 		"sa nil": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 				},
 			},
 			expect: fmt.Errorf("the executor service account is nil"),
 		},
 		"forbidden": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -89,8 +96,8 @@ func TestValidate(t *testing.T) {
 			expect:    fmt.Errorf("not allowed to apply the resource  secrets, test-deny test, will try again in 1m0s"),
 		},
 		"allow": {
-			executor: &workapiv1.ManifestWorkExecutor{
-				Subject: workapiv1.ManifestWorkExecutorSubject{
+			executor: &helper.Executor{
+				Subject: helper.ExecutorSubject{
 					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
 						Namespace: "test-ns",
@@ -147,7 +154,7 @@ func TestValidate(t *testing.T) {
 }
 
 func TestCacheWorks(t *testing.T) {
-	executor := &workapiv1.ManifestWorkExecutor{
+	executor := helper.NewExecutor(&workapiv1.ManifestWorkExecutor{
 		Subject: workapiv1.ManifestWorkExecutorSubject{
 			Type: workapiv1.ExecutorSubjectTypeServiceAccount,
 			ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
@@ -155,10 +162,10 @@ func TestCacheWorks(t *testing.T) {
 				Name:      "test-name",
 			},
 		},
-	}
+	})
 
 	tests := map[string]struct {
-		executor  *workapiv1.ManifestWorkExecutor
+		executor  *helper.Executor
 		namespace string
 		name      string
 		expect    error
@@ -209,7 +216,12 @@ func TestCacheWorks(t *testing.T) {
 		spoketesting.NewUnstructured("v1", "Secret", "test-allow", "test"),
 		spoketesting.NewUnstructured("v1", "Secret", "test-deny", "test"),
 	)
-	work.Spec.Executor = executor
+	work.Spec.Executor = &workapiv1.ManifestWorkExecutor{
+		Subject: workapiv1.ManifestWorkExecutorSubject{
+			Type:           executor.Subject.Type,
+			ServiceAccount: executor.Subject.ServiceAccount,
+		},
+	}
 
 	cacheValidator := newExecutorCacheValidator(t, ctx, clusterName, kubeClient, work)
 	for testName, test := range tests {
@@ -241,4 +253,141 @@ func TestCacheWorks(t *testing.T) {
 	if len(actualSARActions) != 6 {
 		t.Errorf("Expected kube client has 6 subject access review action but got %#v", len(actualSARActions))
 	}
+
+	for namespace, expectedAllowed := range map[string]bool{"test-allow": true, "test-deny": false} {
+		allowed, cached := cacheValidator.CachedPermission(executor, gvr, namespace, "test", true)
+		if !cached || allowed != expectedAllowed {
+			t.Errorf("expected the cached permission of %s is %v, but got %v %v", namespace, expectedAllowed, allowed, cached)
+		}
+	}
+	if _, cached := cacheValidator.CachedPermission(executor, gvr, "test-allow", "test", false); cached {
+		t.Errorf("expected the permission without deletion is not cached")
+	}
+}
+
+func TestExecutorKey(t *testing.T) {
+	subjects := []*helper.ExecutorSubject{
+		{
+			Type: workapiv1.ExecutorSubjectTypeServiceAccount,
+			ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
+				Namespace: "test-ns",
+				Name:      "test-name",
+			},
+		},
+		{
+			Type: helper.ExecutorSubjectTypeUser,
+			User: &helper.ExecutorSubjectUser{Name: "test-user"},
+		},
+		{
+			Type:  helper.ExecutorSubjectTypeGroup,
+			Group: &helper.ExecutorSubjectGroup{Name: "test-group"},
+		},
+	}
+
+	for _, subject := range subjects {
+		key := executorKey(subject)
+		actual, err := executorSubjectFromKey(key)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		if !reflect.DeepEqual(actual, subject) {
+			t.Errorf("expect subject %v from key %s, but got %v", subject, key, actual)
+		}
+	}
+}
+
+func TestValidateRestoredCaches(t *testing.T) {
+	executor := &helper.Executor{
+		Subject: helper.ExecutorSubject{
+			Type: helper.ExecutorSubjectTypeUser,
+			User: &helper.ExecutorSubjectUser{Name: "user1"},
+		},
+	}
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	key := executorKey(&executor.Subject)
+	dimension := newDimension(gvr, "ns1", "test", true)
+
+	// the permission was allowed before the work agent restarts, and is revoked since, which is only known once the
+	// cache is revalidated
+	snapshotCaches := store.NewExecutorCache()
+	snapshotCaches.Upsert(key, dimension, pointer.Bool(true))
+	data, err := snapshotCaches.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	executorCaches := store.NewExecutorCache()
+	executorCaches.Upsert(key, dimension, nil)
+	if _, err := executorCaches.Restore(data, executorCaches); err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient := fakekube.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &v1.SubjectAccessReview{Status: v1.SubjectAccessReviewStatus{Denied: true}}, nil
+		},
+	)
+	validator := &sarCacheValidator{
+		executorCaches: executorCaches,
+		validator:      basic.NewSARValidator(nil, kubeClient),
+	}
+
+	// the restored cache is trusted until it is revalidated by the cache controller
+	for i := 0; i < 2; i++ {
+		if err := validator.Validate(context.TODO(), executor, gvr, "ns1", "test", true, nil); err != nil {
+			t.Errorf("expected the restored cache is used, but got %v", err)
+		}
+	}
+	if len(kubeClient.Actions()) != 0 {
+		t.Errorf("expected no subject access review for the restored cache, but got %d actions", len(kubeClient.Actions()))
+	}
+}
+
+func TestValidateRead(t *testing.T) {
+	executor := &helper.Executor{
+		Subject: helper.ExecutorSubject{
+			Type: helper.ExecutorSubjectTypeUser,
+			User: &helper.ExecutorSubjectUser{Name: "user1"},
+		},
+	}
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+
+	// user1 is only allowed to get the node node1
+	verbs := []string{}
+	kubeClient := fakekube.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			obj := action.(clienttesting.CreateActionImpl).Object.(*v1.SubjectAccessReview)
+			verbs = append(verbs, obj.Spec.ResourceAttributes.Verb)
+			allowed := obj.Spec.ResourceAttributes.Name == "node1"
+			return true, &v1.SubjectAccessReview{Status: v1.SubjectAccessReviewStatus{Allowed: allowed, Denied: !allowed}}, nil
+		},
+	)
+	validator := &sarCacheValidator{
+		executorCaches: store.NewExecutorCache(),
+		validator:      basic.NewSARValidator(nil, kubeClient),
+	}
+
+	// the read permission is checked once and then got from the caches
+	for i := 0; i < 2; i++ {
+		if err := validator.ValidateRead(context.TODO(), executor, gvr, "", "node1"); err != nil {
+			t.Errorf("expected allowed to read node1, but got %v", err)
+		}
+		var notAllowedErr *basic.NotAllowedError
+		if err := validator.ValidateRead(context.TODO(), executor, gvr, "", "node2"); !errors.As(err, &notAllowedErr) {
+			t.Errorf("expected not allowed to read node2, but got %v", err)
+		}
+	}
+	if !reflect.DeepEqual(verbs, []string{"get", "get"}) {
+		t.Errorf("expected only a get subject access review for each node, but got %v", verbs)
+	}
+
+	// the read permission is cached apart from the apply permission
+	allowed, _ := validator.executorCaches.Get(executorKey(&executor.Subject), newReadDimension(gvr, "", "node1"))
+	if allowed == nil || !*allowed {
+		t.Errorf("expected the read permission of node1 is cached")
+	}
+	if _, cached := validator.CachedPermission(executor, gvr, "", "node1", true); cached {
+		t.Errorf("expected the apply permission of node1 is not cached")
+	}
 }
@@ -7,7 +7,7 @@ import (
 )
 
 type roleBindingEventHandler struct {
-	enqueueUpsertFunc func(key string, subjects []rbacapiv1.Subject)
+	enqueueUpsertFunc func(key string, subjects []rbacapiv1.Subject, isInInitialList bool)
 	enqueueDeleteFunc func(key string, subjects []rbacapiv1.Subject)
 }
 
@@ -15,7 +15,7 @@ func (h *roleBindingEventHandler) OnAdd(obj interface{}, isInInitialList bool) {
 	rb, ok := obj.(*rbacapiv1.RoleBinding)
 	if ok {
 		key, _ := cache.MetaNamespaceKeyFunc(rb)
-		h.enqueueUpsertFunc(key, rb.Subjects)
+		h.enqueueUpsertFunc(key, rb.Subjects, isInInitialList)
 	}
 }
 
@@ -42,7 +42,7 @@ func (h *roleBindingEventHandler) OnDelete(obj interface{}) {
 }
 
 type clusterRoleBindingEventHandler struct {
-	enqueueUpsertFunc func(key string, subjects []rbacapiv1.Subject)
+	enqueueUpsertFunc func(key string, subjects []rbacapiv1.Subject, isInInitialList bool)
 	enqueueDeleteFunc func(key string, subjects []rbacapiv1.Subject)
 }
 
@@ -50,7 +50,7 @@ func (h *clusterRoleBindingEventHandler) OnAdd(obj interface{}, isInInitialList
 	crb, ok := obj.(*rbacapiv1.ClusterRoleBinding)
 	if ok {
 		key, _ := cache.MetaNamespaceKeyFunc(crb)
-		h.enqueueUpsertFunc(key, crb.Subjects)
+		h.enqueueUpsertFunc(key, crb.Subjects, isInInitialList)
 	}
 }
 
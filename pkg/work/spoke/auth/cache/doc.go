@@ -5,5 +5,8 @@
 // resource. At the same time, it also contains a controller, which watches the RBAC
 // resources(role, roleBinding, clusterRole, clusterRoleBinding) related to the executors
 // used by the ManifestWorks in the cluster, and refresh the cache results of the
-// corresponding executor when these RBAC resources have any changes.
+// corresponding executor when these RBAC resources have any changes. The caches could also be
+// persisted into a ConfigMap in the namespace of the work agent, and restored when the work agent
+// restarts, the restored caches are trusted until they are revalidated lazily, unless the RBAC
+// resources change.
 package cache
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -13,17 +14,20 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	rbacv1 "k8s.io/client-go/informers/rbac/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
-	workapiv1 "open-cluster-management.io/api/work/v1"
-
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
 )
 
 var (
 	ResyncInterval = 10 * time.Minute
+	// RestoredCachesRevalidationPeriod is the period within which the restored caches of the executors are
+	// revalidated after the work agent starts
+	RestoredCachesRevalidationPeriod = 30 * time.Minute
 )
 
 // CacheController is to refresh the executor auth result for manfiestwork workloads on the spoke cluster.
@@ -43,8 +47,17 @@ type CacheController struct {
 	// The key of the map could be:
 	// - a ClusterRoleBinding, in the format of "cluster-role-binding-name"
 	// - OR a RoleBinding, in the format of "role-binding-namespace/role-binding-name"
-	// The value of the map is the executor in the format of "executor-namespace/executor-name"
+	// The value of the map is the executor key, e.g. "executor-namespace/executor-name" for a service account
 	bindingExecutorsMapper *safeMap
+	// restoredExecutors contains the executors whose caches are restored from the persisted caches and have not
+	// been revalidated yet. These executors are enqueued once the controller starts, and revalidated at a random
+	// time within RestoredCachesRevalidationPeriod rather than immediately, to avoid a flood of subject access
+	// reviews after the work agent restarts. The restored caches are trusted by the validator in the meantime. The
+	// executors are removed once their binding resources are changed, so the stale caches are still refreshed
+	// immediately.
+	restoredExecutors *safeSet
+	// hubName is the name of the hub whose manifestworks use the executors, it is empty for the default hub
+	hubName string
 }
 
 // NewExecutorCacheController returns an ExecutorCacheController, the controller will watch all the RBAC resources(role,
@@ -59,7 +72,9 @@ func NewExecutorCacheController(
 	rInformer rbacv1.RoleInformer,
 	manifestWorkExecutorCachesLoader manifestWorkExecutorCachesLoader,
 	executorCaches *store.ExecutorCaches,
+	restoredExecutors *safeSet,
 	sarCheckerFn SubjectAccessReviewCheckFn,
+	hubName string,
 ) factory.Controller {
 
 	controller := &CacheController{
@@ -67,11 +82,20 @@ func NewExecutorCacheController(
 		executorCaches:                   executorCaches,
 		sarCheckerFn:                     sarCheckerFn,
 		bindingExecutorsMapper:           newSafeMap(),
+		restoredExecutors:                restoredExecutors,
+		hubName:                          hubName,
 	}
 
 	return newControllerInner(controller, recorder, crbInformer, rbInformer, crInformer, rInformer)
 }
 
+func newSafeSet() *safeSet {
+	return &safeSet{
+		lock:  sync.RWMutex{},
+		items: sets.New[string](),
+	}
+}
+
 func newSafeMap() *safeMap {
 	return &safeMap{
 		lock:  sync.RWMutex{},
@@ -107,7 +131,7 @@ func newControllerInner(controller *CacheController,
 		utilruntime.HandleError(err)
 	}
 
-	cacheControllerName := "ManifestWorkExecutorCache"
+	cacheControllerName := helper.HubControllerName("ManifestWorkExecutorCache", controller.hubName)
 	syncCtx := factory.NewSyncContext(cacheControllerName, recorder)
 
 	_, err = rbInformer.Informer().AddEventHandler(&roleBindingEventHandler{
@@ -136,10 +160,20 @@ func newControllerInner(controller *CacheController,
 			crInformer.Informer()).
 		WithBareInformers(rbInformer.Informer(), crbInformer.Informer()).
 		WithSync(controller.sync).
+		WithPostStartHooks(controller.enqueueRestoredExecutors).
 		ResyncEvery(ResyncInterval). // cleanup unnecessary cache every ResyncInterval
 		ToController(cacheControllerName, recorder)
 }
 
+// enqueueRestoredExecutors enqueues the executors whose caches are restored, so their caches are revalidated even if
+// they are not enqueued by any RBAC resource
+func (c *CacheController) enqueueRestoredExecutors(ctx context.Context, syncCtx factory.SyncContext) error {
+	for _, executor := range c.restoredExecutors.list() {
+		syncCtx.Queue().Add(executor)
+	}
+	return nil
+}
+
 func (c *CacheController) roleEnqueueFu(rbIndexer cache.Indexer) func(runtime.Object) []string {
 	return func(obj runtime.Object) []string {
 		accessor, _ := meta.Accessor(obj)
@@ -200,11 +234,15 @@ func (c *CacheController) clusterRoleEnqueueFu(
 }
 
 func (c *CacheController) bindingResourceUpsertEnqueueFn(
-	syncCtx factory.SyncContext) func(key string, subjects []rbacapiv1.Subject) {
+	syncCtx factory.SyncContext) func(key string, subjects []rbacapiv1.Subject, isInInitialList bool) {
 
-	return func(key string, subjects []rbacapiv1.Subject) {
+	return func(key string, subjects []rbacapiv1.Subject, isInInitialList bool) {
 		executors := getInterestedExecutors(subjects, c.executorCaches)
 		for _, executor := range executors {
+			if !isInInitialList {
+				// the binding resource is changed, the restored caches of the executor may be stale
+				c.restoredExecutors.delete(executor)
+			}
 			syncCtx.Queue().Add(executor)
 		}
 		if len(executors) > 0 {
@@ -221,11 +259,13 @@ func (c *CacheController) bindingResourceDeleteEnqueueFn(
 		enqueued := false
 		if subjects != nil {
 			for _, executor := range getInterestedExecutors(subjects, c.executorCaches) {
+				c.restoredExecutors.delete(executor)
 				syncCtx.Queue().Add(executor)
 				enqueued = true
 			}
 		} else {
 			for _, executor := range c.bindingExecutorsMapper.get(key) {
+				c.restoredExecutors.delete(executor)
 				syncCtx.Queue().Add(executor)
 				enqueued = true
 				klog.V(4).Infof("Deletion event, enqueue executor %s from binding executor mapper key %s", executor, key)
@@ -242,11 +282,19 @@ func (c *CacheController) bindingResourceDeleteEnqueueFn(
 func getInterestedExecutors(subjects []rbacapiv1.Subject, executorCaches *store.ExecutorCaches) []string {
 	executors := make([]string, 0)
 	for _, subject := range subjects {
-		if subject.Kind == "ServiceAccount" {
-			executor := store.ExecutorKey(subject.Namespace, subject.Name)
-			if ok := executorCaches.DimensionCachesExists(executor); ok {
-				executors = append(executors, executor)
-			}
+		var executor string
+		switch subject.Kind {
+		case rbacapiv1.ServiceAccountKind:
+			executor = store.ExecutorKey(subject.Namespace, subject.Name)
+		case rbacapiv1.UserKind:
+			executor = store.UserExecutorKey(subject.Name)
+		case rbacapiv1.GroupKind:
+			executor = store.GroupExecutorKey(subject.Name)
+		default:
+			continue
+		}
+		if ok := executorCaches.DimensionCachesExists(executor); ok {
+			executors = append(executors, executor)
 		}
 	}
 	return executors
@@ -265,28 +313,33 @@ func (c *CacheController) sync(ctx context.Context, controllerContext factory.Sy
 		return nil
 	}
 
-	saNamespace, saName, err := cache.SplitMetaNamespaceKey(executorKey)
+	if c.restoredExecutors.delete(executorKey) {
+		// the caches of the executor are restored, revalidate them lazily
+		delay := time.Duration(rand.Int63n(int64(RestoredCachesRevalidationPeriod)))
+		klog.V(4).Infof("Revalidate the restored caches of executor %s after %v", executorKey, delay)
+		controllerContext.Queue().AddAfter(executorKey, delay)
+		return nil
+	}
+
+	subject, err := executorSubjectFromKey(executorKey)
 	if err != nil {
-		// ignore executor whose key is not in format: namespace/name
+		// ignore executor whose key is not in a valid format
 		return nil
 	}
 
-	c.executorCaches.IterateCacheItems(executorKey, c.iterateCacheItemsFn(ctx, executorKey, saNamespace, saName))
+	c.executorCaches.IterateCacheItems(executorKey, c.iterateCacheItemsFn(ctx, executorKey, subject))
 	return nil
 }
 
 func (c *CacheController) iterateCacheItemsFn(ctx context.Context,
-	executorKey, saNamespace, saName string) func(v store.CacheValue) error {
+	executorKey string, subject *helper.ExecutorSubject) func(v store.CacheValue) error {
 	return func(v store.CacheValue) error {
-		err := c.sarCheckerFn(ctx, &workapiv1.ManifestWorkSubjectServiceAccount{
-			Namespace: saNamespace,
-			Name:      saName,
-		}, schema.GroupVersionResource{
+		err := c.sarCheckerFn(ctx, subject, schema.GroupVersionResource{
 			Group:    v.Dimension.Group,
 			Version:  v.Dimension.Version,
 			Resource: v.Dimension.Resource,
 		},
-			v.Dimension.Namespace, v.Dimension.Name, store.GetOwnedByWork(v.Dimension.ExecuteAction))
+			v.Dimension.Namespace, v.Dimension.Name, v.Dimension.ExecuteAction)
 
 		klog.V(4).Infof("Update executor cache for executorKey: %s, dimension: %+v result: %v",
 			executorKey, v.Dimension, err)
@@ -332,3 +385,32 @@ func (m *safeMap) count() int {
 	defer m.lock.RUnlock()
 	return len(m.items)
 }
+
+type safeSet struct {
+	lock  sync.RWMutex
+	items sets.Set[string]
+}
+
+func (s *safeSet) insert(items ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items.Insert(items...)
+}
+
+// list returns the items of the set
+func (s *safeSet) list() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return sets.List(s.items)
+}
+
+// delete removes the item from the set and returns whether the item existed
+func (s *safeSet) delete(item string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.items.Has(item) {
+		return false
+	}
+	s.items.Delete(item)
+	return true
+}
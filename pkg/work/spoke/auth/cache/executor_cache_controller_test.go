@@ -11,17 +11,21 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	k8scache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
@@ -44,8 +48,9 @@ func newExecutorCacheController(t *testing.T, ctx context.Context, clusterName s
 	cacheController := &CacheController{
 		executorCaches:                   store.NewExecutorCache(),
 		manifestWorkExecutorCachesLoader: manifestWorkExecutorCachesLoader,
-		sarCheckerFn:                     basic.NewSARValidator(nil, kubeClient).CheckSubjectAccessReviews,
+		sarCheckerFn:                     newSubjectAccessReviewCheckFn(basic.NewSARValidator(nil, kubeClient)),
 		bindingExecutorsMapper:           newSafeMap(),
+		restoredExecutors:                newSafeSet(),
 	}
 	controllerFactory := newControllerInner(cacheController, eventstesting.NewTestingEventRecorder(t),
 		spokeInformer.Rbac().V1().ClusterRoleBindings(),
@@ -298,3 +303,60 @@ func checkBindingExecutorMapperInitialized(t *testing.T, m *safeMap, roleKey, ex
 		t.Errorf("Expected role key %s has the executor %s but got %s", roleKey, executorKey, actualExecutors[0])
 	}
 }
+
+func TestSyncRestoredExecutor(t *testing.T) {
+	executorKey := store.UserExecutorKey("user1")
+	dimension := store.Dimension{Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "test"}
+
+	checked := 0
+	executorCaches := store.NewExecutorCache()
+	executorCaches.Upsert(executorKey, dimension, pointer.Bool(true))
+	controller := &CacheController{
+		executorCaches: executorCaches,
+		sarCheckerFn: func(ctx context.Context, subject *helper.ExecutorSubject, gvr schema.GroupVersionResource,
+			namespace, name string, action store.ExecuteAction) error {
+			checked++
+			return nil
+		},
+		bindingExecutorsMapper: newSafeMap(),
+		restoredExecutors:      newSafeSet(),
+	}
+	controller.restoredExecutors.insert(executorKey)
+
+	// the restored executor is enqueued once the controller starts
+	syncContext := testingcommon.NewFakeSyncContext(t, executorKey)
+	if err := controller.enqueueRestoredExecutors(context.TODO(), syncContext); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if syncContext.Queue().Len() != 1 {
+		t.Errorf("expected the restored executor is enqueued, but got %d keys", syncContext.Queue().Len())
+	}
+
+	// the restored executor is revalidated lazily
+	if err := controller.sync(context.TODO(), syncContext); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if checked != 0 {
+		t.Errorf("expected no subject access review for the restored executor, but got %d", checked)
+	}
+
+	// the executor is revalidated when it is enqueued again
+	if err := controller.sync(context.TODO(), syncContext); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if checked != 1 {
+		t.Errorf("expected 1 subject access review, but got %d", checked)
+	}
+
+	// the binding change invalidates the restored caches immediately
+	controller.restoredExecutors.insert(executorKey)
+	controller.bindingResourceUpsertEnqueueFn(syncContext)("ns1/rb1", []rbacv1.Subject{
+		{Kind: rbacv1.UserKind, Name: "user1"},
+	}, false)
+	if err := controller.sync(context.TODO(), syncContext); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if checked != 2 {
+		t.Errorf("expected 2 subject access reviews, but got %d", checked)
+	}
+}
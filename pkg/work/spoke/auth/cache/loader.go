@@ -7,14 +7,13 @@ import (
 	"k8s.io/klog/v2"
 
 	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
-	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
 )
 
 type manifestWorkExecutorCachesLoader interface {
-	// loadAllValuableCaches gets all manifestworks with service account executor at the current time, and then
+	// loadAllValuableCaches gets all manifestworks with executor at the current time, and then
 	// stores these executors and corresponding resources in the form of store.ExecutorCaches data structure.
 	// Note that this method only guarantees the correctness of all keys in store.ExecutorCaches, and the
 	// value is usually fake. so callers are recommended to only use this to know what executors and resources
@@ -38,19 +37,15 @@ func (g *defaultManifestWorkExecutorCachesLoader) loadAllValuableCaches(retainab
 	}
 
 	for _, mw := range mws {
-		var executor string
-		if mw.Spec.Executor == nil {
+		workExecutor, err := helper.ManifestWorkExecutor(mw)
+		if err != nil || workExecutor == nil {
 			continue
 		}
-		if mw.Spec.Executor.Subject.Type != workapiv1.ExecutorSubjectTypeServiceAccount {
-			continue
-		}
-		if mw.Spec.Executor.Subject.ServiceAccount == nil {
+		if err := workExecutor.Subject.Validate(); err != nil {
 			continue
 		}
 
-		executor = store.ExecutorKey(
-			mw.Spec.Executor.Subject.ServiceAccount.Namespace, mw.Spec.Executor.Subject.ServiceAccount.Name)
+		executor := executorKey(&workExecutor.Subject)
 
 		for index, manifest := range mw.Spec.Workload.Manifests {
 			// parse the required and set resource meta
@@ -82,5 +77,22 @@ func (g *defaultManifestWorkExecutorCachesLoader) loadAllValuableCaches(retainab
 			)
 
 		}
+
+		// the status references are only read by the executor
+		references, err := helper.StatusReferences(mw)
+		if err != nil {
+			klog.Infof("Get status references for the manifest work %s failed %v", mw.Name, err)
+			continue
+		}
+		for _, reference := range references {
+			retainableCache.Upsert(executor, store.Dimension{
+				Group:         reference.Group,
+				Version:       reference.Version,
+				Resource:      reference.Resource,
+				Namespace:     reference.Namespace,
+				Name:          reference.Name,
+				ExecuteAction: store.ReadAction,
+			}, nil)
+		}
 	}
 }
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
+)
+
+const (
+	// ExecutorCachesConfigMapName is the name of the configmap which persists the executor caches of the default
+	// hub, the name of the configmap of an additional hub is suffixed with the hub name
+	ExecutorCachesConfigMapName = "work-agent-executor-caches"
+
+	executorCachesDataKey = "caches"
+)
+
+// cachesPersister snapshots the executor caches into a configmap, and restores the caches from the configmap after
+// the work agent restarts, so the work agent does not need to send subject access reviews for all the executors and
+// resources again once it starts. The restored caches are trusted until the cache controller revalidates them.
+type cachesPersister struct {
+	configMapClient corev1client.ConfigMapInterface
+	configMapName   string
+	executorCaches  *store.ExecutorCaches
+	// lastSnapshot is the last snapshot persisted or restored, it is used to avoid updating the configmap when
+	// the caches are not changed
+	lastSnapshot []byte
+}
+
+func newCachesPersister(configMapClient corev1client.ConfigMapInterface, configMapName string,
+	executorCaches *store.ExecutorCaches) *cachesPersister {
+	return &cachesPersister{
+		configMapClient: configMapClient,
+		configMapName:   configMapName,
+		executorCaches:  executorCaches,
+	}
+}
+
+// restore loads the persisted caches into the executor caches, only the caches existing in the retainableCaches
+// are restored. It returns the executors whose caches are restored.
+func (p *cachesPersister) restore(ctx context.Context, retainableCaches *store.ExecutorCaches) ([]string, error) {
+	cm, err := p.configMapClient.Get(ctx, p.configMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte(cm.Data[executorCachesDataKey])
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	executors, err := p.executorCaches.Restore(data, retainableCaches)
+	if err != nil {
+		return nil, err
+	}
+	p.lastSnapshot = data
+	return executors, nil
+}
+
+// persist saves the snapshot of the executor caches into the configmap if the caches are changed.
+func (p *cachesPersister) persist(ctx context.Context) error {
+	data, err := p.executorCaches.Snapshot()
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, p.lastSnapshot) {
+		return nil
+	}
+
+	cm, err := p.configMapClient.Get(ctx, p.configMapName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = p.configMapClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: p.configMapName,
+			},
+			Data: map[string]string{executorCachesDataKey: string(data)},
+		}, metav1.CreateOptions{})
+	case err == nil:
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[executorCachesDataKey] = string(data)
+		_, err = p.configMapClient.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("Persisted %d executor cache items", p.executorCaches.Count())
+	p.lastSnapshot = data
+	return nil
+}
+
+// run persists the executor caches every interval until the context is done.
+func (p *cachesPersister) run(ctx context.Context, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.persist(ctx); err != nil {
+			klog.Errorf("Failed to persist the executor caches: %v", err)
+		}
+	}, interval)
+}
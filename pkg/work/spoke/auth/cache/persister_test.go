@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth/store"
+)
+
+func TestPersistAndRestore(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fakekube.NewSimpleClientset()
+	configMapClient := kubeClient.CoreV1().ConfigMaps("open-cluster-management-agent")
+
+	allowed := store.Dimension{Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "allowed"}
+	denied := store.Dimension{Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "denied"}
+
+	executorCaches := store.NewExecutorCache()
+	executorCaches.Upsert("ns1/sa1", allowed, pointer.Bool(true))
+	executorCaches.Upsert("ns1/sa1", denied, pointer.Bool(false))
+	executorCaches.Upsert("User:user1", allowed, pointer.Bool(true))
+
+	persister := newCachesPersister(configMapClient, ExecutorCachesConfigMapName, executorCaches)
+	if err := persister.persist(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the configmap is not updated if the caches are not changed
+	if err := persister.persist(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	executorCaches.Upsert("ns1/sa1", denied, pointer.Bool(true))
+	if err := persister.persist(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	actions := []string{}
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() != "get" {
+			actions = append(actions, action.GetVerb())
+		}
+	}
+	if len(actions) != 2 || actions[0] != "create" || actions[1] != "update" {
+		t.Errorf("expected create and update actions, but got %v", actions)
+	}
+
+	// only the caches of the executor ns1/sa1 are still necessary
+	restoredCaches := store.NewExecutorCache()
+	restoredCaches.Upsert("ns1/sa1", allowed, nil)
+	restoredCaches.Upsert("ns1/sa1", denied, nil)
+
+	restorer := newCachesPersister(configMapClient, ExecutorCachesConfigMapName, restoredCaches)
+	executors, err := restorer.restore(ctx, restoredCaches)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(executors) != 1 || executors[0] != "ns1/sa1" {
+		t.Errorf("expected executor ns1/sa1 restored, but got %v", executors)
+	}
+	if result, _ := restoredCaches.Get("ns1/sa1", denied); result == nil || !*result {
+		t.Errorf("expected the denied dimension is allowed, but got %v", result)
+	}
+	if restoredCaches.DimensionCachesExists("User:user1") {
+		t.Errorf("expected the caches of User:user1 are not restored")
+	}
+
+	// nothing is restored if the configmap does not exist
+	executors, err = newCachesPersister(kubeClient.CoreV1().ConfigMaps("other"), ExecutorCachesConfigMapName, store.NewExecutorCache()).
+		restore(ctx, restoredCaches)
+	if err != nil || len(executors) != 0 {
+		t.Errorf("expected nothing restored, but got %v, %v", executors, err)
+	}
+}
@@ -2,19 +2,21 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	k8scache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
-	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/cache"
 )
@@ -24,8 +26,21 @@ import (
 type ExecutorValidator interface {
 	// Validate whether the work executor subject has permission to operate the specific manifest,
 	// if there is no permission will return a basic.NotAllowedError.
-	Validate(ctx context.Context, executor *workapiv1.ManifestWorkExecutor, gvr schema.GroupVersionResource,
+	Validate(ctx context.Context, executor *helper.Executor, gvr schema.GroupVersionResource,
 		namespace, name string, ownedByTheWork bool, obj *unstructured.Unstructured) error
+	// ValidateRead validates whether the work executor subject has permission to read the specific resource,
+	// if there is no permission will return a basic.NotAllowedError.
+	ValidateRead(ctx context.Context, executor *helper.Executor, gvr schema.GroupVersionResource,
+		namespace, name string) error
+}
+
+// ExecutorCachesInspector inspects the caches of the subject access review results of the executors, it is
+// implemented by the ExecutorValidator when the executor caches are enabled.
+type ExecutorCachesInspector interface {
+	// CachedPermission returns the cached result of whether the executor has permission to operate the specific
+	// manifest, cached is false if the result is not in the caches.
+	CachedPermission(executor *helper.Executor, gvr schema.GroupVersionResource,
+		namespace, name string, ownedByTheWork bool) (allowed bool, cached bool)
 }
 
 type validatorFactory struct {
@@ -35,6 +50,9 @@ type validatorFactory struct {
 	clusterName          string
 	recorder             events.Recorder
 	restMapper           meta.RESTMapper
+	hubName              string
+	cachesClient         corev1client.ConfigMapInterface
+	persistInterval      time.Duration
 }
 
 func NewFactory(
@@ -54,6 +72,21 @@ func NewFactory(
 	}
 }
 
+// WithHubName sets the name of the hub whose manifestworks are validated, it is empty for the default hub. The
+// executor caches of each hub are persisted separately.
+func (f *validatorFactory) WithHubName(hubName string) *validatorFactory {
+	f.hubName = hubName
+	return f
+}
+
+// WithCachesPersistence persists the executor caches into a configmap with the client every interval when the
+// executor caches are enabled.
+func (f *validatorFactory) WithCachesPersistence(client corev1client.ConfigMapInterface, interval time.Duration) *validatorFactory {
+	f.cachesClient = client
+	f.persistInterval = interval
+	return f
+}
+
 func (f *validatorFactory) NewExecutorValidator(ctx context.Context, isCacheValidator bool) ExecutorValidator {
 	klog.Infof("Executor caches enabled: %v", isCacheValidator)
 	sarValidator := basic.NewSARValidator(f.config, f.kubeClient)
@@ -68,11 +101,14 @@ func (f *validatorFactory) NewExecutorValidator(ctx context.Context, isCacheVali
 		f.manifestWorkInformer.Lister().ManifestWorks(f.clusterName),
 		f.restMapper,
 		sarValidator,
+		f.hubName,
+		f.cachesClient,
+		f.persistInterval,
 	)
 
 	go func() {
 		// Wait for cache synced before starting to make sure all manifestworks could be processed
-		k8scache.WaitForNamedCacheSync("ExecutorCacheValidator", ctx.Done(),
+		k8scache.WaitForNamedCacheSync(helper.HubControllerName("ExecutorCacheValidator", f.hubName), ctx.Done(),
 			f.manifestWorkInformer.Informer().HasSynced)
 		cacheValidator.Start(ctx)
 	}()
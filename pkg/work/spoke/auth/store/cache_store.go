@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 
 	"k8s.io/klog/v2"
@@ -19,10 +20,12 @@ const (
 	// ApplyNoDeleteAction represents only applying(create/update) resource to the managed cluster,
 	// but is not responsiable for deleting the resource
 	ApplyNoDeleteAction
+	// ReadAction represents only reading the resource on the managed cluster, e.g. the status references
+	ReadAction
 )
 
 func (a ExecuteAction) String() string {
-	return [...]string{"ApplyAndDelete", "ApplyNoDelete"}[a]
+	return [...]string{"ApplyAndDelete", "ApplyNoDelete", "Read"}[a]
 }
 
 // GetExecuteAction get the execute action by judging whether a resource is owned by work
@@ -46,13 +49,13 @@ func NewExecutorCache() *ExecutorCaches {
 	}
 }
 
-// ExecutorCaches is a two-level map cache structure, the 1-level map's key is the executor(service account)
-// in the format of {namespace}/{name}, and the 2-level map's key is the hash value of the dimension(cached
+// ExecutorCaches is a two-level map cache structure, the 1-level map's key is the executor in the format of
+// {namespace}/{name} for a service account, User:{name} for a user or Group:{name} for a group, and the 2-level map's key is the hash value of the dimension(cached
 // subject access review result of a specific resource, group-version-resource-namespace-name-action)
 type ExecutorCaches struct {
 	lock sync.RWMutex
 
-	// map key: executor in format of {namespace}/{name}
+	// map key: executor in format of {namespace}/{name}, User:{name} or Group:{name}
 	items map[string]*DimensionCaches
 }
 
@@ -168,6 +171,55 @@ func (c *ExecutorCaches) DimensionCachesExists(executor string) bool {
 	return ok
 }
 
+// Snapshot serializes all cache items whose results are known, the items of each executor are sorted by the
+// dimension hash so the snapshot of the same caches is always the same.
+func (c *ExecutorCaches) Snapshot() ([]byte, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	snapshot := map[string][]CacheValue{}
+	for executor, caches := range c.items {
+		values := caches.knownValues()
+		if len(values) == 0 {
+			continue
+		}
+		snapshot[executor] = values
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// Restore loads the cache items from a snapshot generated by Snapshot. Only the items which also exist in the
+// retainableCaches are restored, and the executors whose caches are restored are returned.
+func (c *ExecutorCaches) Restore(data []byte, retainableCaches *ExecutorCaches) ([]string, error) {
+	snapshot := map[string][]CacheValue{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	executors := []string{}
+	for executor, values := range snapshot {
+		restored := false
+		for _, value := range values {
+			if value.Allowed == nil {
+				continue
+			}
+			if _, ok := retainableCaches.getByHash(executor, value.Dimension.Hash()); !ok {
+				continue
+			}
+			c.upsertDimensionCaches(executor)
+			dimensionCaches, _ := c.getDimensionCaches(executor)
+			dimensionCaches.upsert(value.Dimension, value.Allowed)
+			restored = true
+		}
+		if restored {
+			executors = append(executors, executor)
+		}
+	}
+	sort.Strings(executors)
+	return executors, nil
+}
+
 // upsertDimensionCaches will insert new dimension caches or update existing dimension caches
 func (c *ExecutorCaches) upsertDimensionCaches(executor string) {
 	c.lock.Lock()
@@ -245,6 +297,26 @@ func (c *DimensionCaches) upsert(dimension Dimension, allowed *bool) {
 	}
 }
 
+// knownValues returns the cache values whose results are known, sorted by the dimension hash
+func (c *DimensionCaches) knownValues() []CacheValue {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	hashes := make([]string, 0, len(c.items))
+	for hash, value := range c.items {
+		if value.Allowed != nil {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+
+	values := make([]CacheValue, 0, len(hashes))
+	for _, hash := range hashes {
+		values = append(values, c.items[hash])
+	}
+	return values
+}
+
 func (c *DimensionCaches) getCacheItems() map[string]CacheValue {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -268,3 +340,13 @@ func (d *Dimension) Hash() string {
 func ExecutorKey(namespace, name string) string {
 	return fmt.Sprintf("%s/%s", namespace, name)
 }
+
+// UserExecutorKey return a key of executor caches map for a user executor
+func UserExecutorKey(name string) string {
+	return fmt.Sprintf("User:%s", name)
+}
+
+// GroupExecutorKey return a key of executor caches map for a group executor
+func GroupExecutorKey(name string) string {
+	return fmt.Sprintf("Group:%s", name)
+}
@@ -95,3 +95,54 @@ func TestBasic(t *testing.T) {
 		t.Errorf("Expected dimension name joining result 45 but got %v", dimensionNameAccumulate)
 	}
 }
+
+func TestSnapshotAndRestore(t *testing.T) {
+	allowed, denied := true, false
+	caches := NewExecutorCache()
+	caches.Upsert("ns1/sa1", Dimension{Name: "allowed"}, &allowed)
+	caches.Upsert("ns1/sa1", Dimension{Name: "denied"}, &denied)
+	caches.Upsert("ns1/sa1", Dimension{Name: "unknown"}, nil)
+	caches.Upsert("User:user1", Dimension{Name: "allowed"}, &allowed)
+	caches.Upsert("Group:group1", Dimension{Name: "unknown"}, nil)
+
+	data, err := caches.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	again, err := caches.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(data) != string(again) {
+		t.Errorf("expected the same snapshot, but got %s and %s", data, again)
+	}
+
+	// the executor User:user1 is not necessary anymore
+	retainableCaches := NewExecutorCache()
+	retainableCaches.Upsert("ns1/sa1", Dimension{Name: "allowed"}, nil)
+	retainableCaches.Upsert("ns1/sa1", Dimension{Name: "denied"}, nil)
+	retainableCaches.Upsert("ns1/sa1", Dimension{Name: "unknown"}, nil)
+	retainableCaches.Upsert("Group:group1", Dimension{Name: "unknown"}, nil)
+
+	restoredCaches := NewExecutorCache()
+	executors, err := restoredCaches.Restore(data, retainableCaches)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(executors) != 1 || executors[0] != "ns1/sa1" {
+		t.Errorf("expected executor ns1/sa1 restored, but got %v", executors)
+	}
+	if count := restoredCaches.Count(); count != 2 {
+		t.Errorf("expected 2 cache items restored, but got %d", count)
+	}
+	if result, _ := restoredCaches.Get("ns1/sa1", Dimension{Name: "allowed"}); result == nil || !*result {
+		t.Errorf("expected allowed, but got %v", result)
+	}
+	if result, _ := restoredCaches.Get("ns1/sa1", Dimension{Name: "denied"}); result == nil || *result {
+		t.Errorf("expected denied, but got %v", result)
+	}
+
+	if _, err := restoredCaches.Restore([]byte("invalid"), retainableCaches); err == nil {
+		t.Errorf("expected error for an invalid snapshot")
+	}
+}
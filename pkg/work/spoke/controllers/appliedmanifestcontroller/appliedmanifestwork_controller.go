@@ -25,16 +25,23 @@ import (
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 )
 
+const controllerName = "AppliedManifestWorkController"
+
 // AppliedManifestWorkController is to sync the applied resources of appliedmanifestwork with related
-// manifestwork and delete any resource which is no longer maintained by the manifestwork
+// manifestwork and delete any resource which is no longer maintained by the manifestwork. The protected
+// resources are orphaned, or kept until their deletion is confirmed on the manifestwork.
 type AppliedManifestWorkController struct {
 	manifestWorkClient        workv1client.ManifestWorkInterface
 	manifestWorkLister        worklister.ManifestWorkNamespaceLister
+	statusUpdater             *hubcache.StatusUpdater
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface
 	appliedManifestWorkLister worklister.AppliedManifestWorkLister
 	spokeDynamicClient        dynamic.Interface
+	deletionProtection        *helper.DeletionProtection
+	deletionTimeout           *helper.DeletionTimeout
 	hubHash                   string
 	rateLimiter               workqueue.RateLimiter
 }
@@ -46,16 +53,22 @@ func NewAppliedManifestWorkController(
 	manifestWorkClient workv1client.ManifestWorkInterface,
 	manifestWorkInformer workinformer.ManifestWorkInformer,
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	statusUpdater *hubcache.StatusUpdater,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	appliedManifestWorkInformer workinformer.AppliedManifestWorkInformer,
-	hubHash string) factory.Controller {
+	deletionProtection *helper.DeletionProtection,
+	deletionTimeout *helper.DeletionTimeout,
+	hubHash, hubName string) factory.Controller {
 
 	controller := &AppliedManifestWorkController{
 		manifestWorkClient:        manifestWorkClient,
 		manifestWorkLister:        manifestWorkLister,
+		statusUpdater:             statusUpdater,
 		appliedManifestWorkClient: appliedManifestWorkClient,
 		appliedManifestWorkLister: appliedManifestWorkInformer.Lister(),
 		spokeDynamicClient:        spokeDynamicClient,
+		deletionProtection:        deletionProtection,
+		deletionTimeout:           deletionTimeout,
 		hubHash:                   hubHash,
 		rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
 	}
@@ -69,7 +82,7 @@ func NewAppliedManifestWorkController(
 			helper.AppliedManifestworkQueueKeyFunc(hubHash),
 			helper.AppliedManifestworkHubHashFilter(hubHash),
 			appliedManifestWorkInformer.Informer()).
-		WithSync(controller.sync).ToController("AppliedManifestWorkController", recorder)
+		WithSync(controller.sync).ToController(helper.HubControllerName(controllerName, hubName), recorder)
 }
 
 func (m *AppliedManifestWorkController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -84,8 +97,8 @@ func (m *AppliedManifestWorkController) sync(ctx context.Context, controllerCont
 	if err != nil {
 		return err
 	}
-	// no work to do if we're deleted
-	if !manifestWork.DeletionTimestamp.IsZero() {
+	// no work to do if we're deleted or paused
+	if !manifestWork.DeletionTimestamp.IsZero() || helper.IsPaused(manifestWork) {
 		return nil
 	}
 
@@ -121,6 +134,11 @@ func (m *AppliedManifestWorkController) syncManifestWork(
 	var appliedResources []workapiv1.AppliedManifestResourceMeta
 	var errs []error
 	for _, resourceStatus := range manifestWork.Status.ResourceStatus.Manifests {
+		// the resources of the status references are not maintained by the manifest work
+		if helper.IsWatchOnly(resourceStatus) {
+			continue
+		}
+
 		gvr := schema.GroupVersionResource{
 			Group:    resourceStatus.ResourceMeta.Group,
 			Version:  resourceStatus.ResourceMeta.Version,
@@ -170,13 +188,26 @@ func (m *AppliedManifestWorkController) syncManifestWork(
 
 	reason := fmt.Sprintf("it is no longer maintained by manifestwork %s", manifestWork.Name)
 
-	resourcesPendingFinalization, errs := helper.DeleteAppliedResources(
-		ctx, noLongerMaintainedResources, reason, m.spokeDynamicClient, controllerContext.Recorder(), *owner)
+	deletion, errs := helper.DeleteAppliedResources(
+		ctx, noLongerMaintainedResources, reason, m.spokeDynamicClient, controllerContext.Recorder(), *owner,
+		m.deletionProtection, helper.ConfirmedDeletions(manifestWork), m.deletionTimeout)
 	if len(errs) != 0 {
 		return utilerrors.NewAggregate(errs)
 	}
+	resourcesPendingFinalization, resourcesPendingConfirmation := deletion.PendingFinalization, deletion.PendingConfirmation
+
+	// report the protected resources waiting for the deletion confirmation and the resources stuck in deletion,
+	// the work is resynced once the confirmation annotation is added to the manifestwork.
+	_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName, manifestWork.DeepCopy(),
+		helper.SetProtectedDeletionPendingCondition(manifestWork.Generation, resourcesPendingConfirmation),
+		helper.SetDeletionStuckCondition(manifestWork.Generation, deletion.Stuck))
+	if err != nil {
+		return err
+	}
 
+	// the resources pending for the confirmation are still tracked until they are deleted
 	appliedResources = append(appliedResources, resourcesPendingFinalization...)
+	appliedResources = append(appliedResources, resourcesPendingConfirmation...)
 
 	// sort applied resources
 	sort.SliceStable(appliedResources, func(i, j int) bool {
@@ -211,7 +242,7 @@ func (m *AppliedManifestWorkController) syncManifestWork(
 	// update appliedmanifestwork status with latest applied resources. if this conflicts, we'll try again later
 	// for retrying update without reassessing the status can cause overwriting of valid information.
 	appliedManifestWork.Status.AppliedResources = appliedResources
-	_, err := m.appliedManifestWorkClient.UpdateStatus(ctx, appliedManifestWork, metav1.UpdateOptions{})
+	_, err = m.appliedManifestWorkClient.UpdateStatus(ctx, appliedManifestWork, metav1.UpdateOptions{})
 	return err
 }
 
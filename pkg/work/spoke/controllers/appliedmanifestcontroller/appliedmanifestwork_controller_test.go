@@ -3,10 +3,13 @@ package appliedmanifestcontroller
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -21,6 +24,7 @@ import (
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -46,6 +50,8 @@ func TestSyncManifestWork(t *testing.T) {
 		existingResources                  []runtime.Object
 		appliedResources                   []workapiv1.AppliedManifestResourceMeta
 		manifests                          []workapiv1.ManifestCondition
+		annotations                        map[string]string
+		deletionProtection                 *helper.DeletionProtection
 		validateAppliedManifestWorkActions func(t *testing.T, actions []clienttesting.Action)
 		expectedDeleteActions              []clienttesting.DeleteActionImpl
 		expectedQueueLen                   int
@@ -66,6 +72,30 @@ func TestSyncManifestWork(t *testing.T) {
 			},
 			expectedDeleteActions: []clienttesting.DeleteActionImpl{},
 		},
+		{
+			name: "ignore status references",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+				spoketesting.NewUnstructuredSecret("ns2", "n2", false, "ns2-n2"),
+			},
+			appliedResources: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			manifests: []workapiv1.ManifestCondition{
+				newManifest("", "v1", "secrets", "ns1", "n1"),
+				func() workapiv1.ManifestCondition {
+					manifest := newManifest("", "v1", "secrets", "ns2", "n2")
+					manifest.ResourceMeta.Ordinal = helper.WatchOnlyOrdinal
+					return manifest
+				}(),
+			},
+			validateAppliedManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) > 0 {
+					t.Fatal(spew.Sdump(actions))
+				}
+			},
+			expectedDeleteActions: []clienttesting.DeleteActionImpl{},
+		},
 		{
 			name: "delete untracked resources",
 			existingResources: []runtime.Object{
@@ -190,6 +220,53 @@ func TestSyncManifestWork(t *testing.T) {
 			},
 			expectedDeleteActions: []clienttesting.DeleteActionImpl{},
 		},
+		{
+			name: "do not delete untracked resources when work is paused",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+				spoketesting.NewUnstructuredSecret("ns2", "n2", false, "ns2-n2", *owner),
+			},
+			appliedResources: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns2", Name: "n2"}, UID: "ns2-n2"},
+			},
+			manifests:   []workapiv1.ManifestCondition{newManifest("", "v1", "secrets", "ns1", "n1")},
+			annotations: map[string]string{helper.PausedAnnotationKey: "true"},
+			validateAppliedManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) > 0 {
+					t.Fatal(spew.Sdump(actions))
+				}
+			},
+			expectedDeleteActions: []clienttesting.DeleteActionImpl{},
+		},
+		{
+			name: "keep protected resources until the deletion is confirmed",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+				spoketesting.NewUnstructuredSecret("ns2", "n2", false, "ns2-n2", *owner),
+			},
+			appliedResources: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns2", Name: "n2"}, UID: "ns2-n2"},
+			},
+			manifests: []workapiv1.ManifestCondition{newManifest("", "v1", "secrets", "ns1", "n1")},
+			deletionProtection: func() *helper.DeletionProtection {
+				protection, _ := helper.NewDeletionProtection([]string{"secrets"}, helper.DeletionProtectionConfirm)
+				return protection
+			}(),
+			validateAppliedManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				// the status of the manifestwork is updated to report the pending deletion
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				condition := meta.FindStatusCondition(work.Status.Conditions, helper.WorkProtectedDeletionPending)
+				if condition == nil || !strings.Contains(condition.Message, "secrets/ns2/n2") {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+			},
+			expectedDeleteActions: []clienttesting.DeleteActionImpl{},
+		},
 	}
 
 	for _, c := range cases {
@@ -198,6 +275,7 @@ func TestSyncManifestWork(t *testing.T) {
 			testingAppliedWork := appliedWork.DeepCopy()
 			testingAppliedWork.Status.AppliedResources = c.appliedResources
 			testingWork.Status.ResourceStatus.Manifests = c.manifests
+			testingWork.Annotations = c.annotations
 
 			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.existingResources...)
 			fakeClient := fakeworkclient.NewSimpleClientset(testingWork, testingAppliedWork)
@@ -211,11 +289,14 @@ func TestSyncManifestWork(t *testing.T) {
 			}
 
 			controller := AppliedManifestWorkController{
-				manifestWorkClient:        fakeClient.WorkV1().ManifestWorks(testingWork.Namespace),
-				manifestWorkLister:        informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+				manifestWorkClient: fakeClient.WorkV1().ManifestWorks(testingWork.Namespace),
+				manifestWorkLister: informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+				statusUpdater: hubcache.NewStatusUpdater(fakeClient.WorkV1().ManifestWorks(testingWork.Namespace), nil,
+					hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))),
 				appliedManifestWorkClient: fakeClient.WorkV1().AppliedManifestWorks(),
 				appliedManifestWorkLister: informerFactory.Work().V1().AppliedManifestWorks().Lister(),
 				spokeDynamicClient:        fakeDynamicClient,
+				deletionProtection:        c.deletionProtection,
 				hubHash:                   "test",
 				rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(0, 1*time.Second),
 			}
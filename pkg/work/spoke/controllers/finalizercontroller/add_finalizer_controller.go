@@ -32,6 +32,7 @@ func NewAddFinalizerController(
 	manifestWorkClient workv1client.ManifestWorkInterface,
 	manifestWorkInformer workinformer.ManifestWorkInformer,
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	hubName string,
 ) factory.Controller {
 
 	controller := &AddFinalizerController{
@@ -44,7 +45,7 @@ func NewAddFinalizerController(
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, manifestWorkInformer.Informer()).
-		WithSync(controller.sync).ToController("ManifestWorkAddFinalizerController", recorder)
+		WithSync(controller.sync).ToController(helper.HubControllerName("ManifestWorkAddFinalizerController", hubName), recorder)
 }
 
 func (m *AddFinalizerController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
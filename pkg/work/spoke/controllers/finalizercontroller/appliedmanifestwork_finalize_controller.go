@@ -15,6 +15,7 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	workinformer "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
@@ -23,38 +24,67 @@ import (
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 )
 
+const appliedManifestWorkFinalizerName = "AppliedManifestWorkFinalizer"
+
 // AppliedManifestWorkFinalizeController handles cleanup of appliedmanifestwork resources before deletion is allowed.
 // It should handle all appliedmanifestworks belonging to this agent identified by the agentID.
 type AppliedManifestWorkFinalizeController struct {
+	manifestWorkLister        worklister.ManifestWorkNamespaceLister
+	statusUpdater             *hubcache.StatusUpdater
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface
 	appliedManifestWorkLister worklister.AppliedManifestWorkLister
 	spokeDynamicClient        dynamic.Interface
+	deletionProtection        *helper.DeletionProtection
+	deletionTimeout           *helper.DeletionTimeout
+	maintenanceWindow         *helper.MaintenanceWindow
+	clock                     clock.Clock
+	hubHash                   string
 	rateLimiter               workqueue.RateLimiter
 }
 
 func NewAppliedManifestWorkFinalizeController(
 	recorder events.Recorder,
 	spokeDynamicClient dynamic.Interface,
+	manifestWorkInformer workinformer.ManifestWorkInformer,
+	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	statusUpdater *hubcache.StatusUpdater,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	appliedManifestWorkInformer workinformer.AppliedManifestWorkInformer,
-	agentID string,
+	deletionProtection *helper.DeletionProtection,
+	deletionTimeout *helper.DeletionTimeout,
+	maintenanceWindow *helper.MaintenanceWindow,
+	hubHash, agentID, hubName string,
 ) factory.Controller {
 
 	controller := &AppliedManifestWorkFinalizeController{
+		manifestWorkLister:        manifestWorkLister,
+		statusUpdater:             statusUpdater,
 		appliedManifestWorkClient: appliedManifestWorkClient,
 		appliedManifestWorkLister: appliedManifestWorkInformer.Lister(),
 		spokeDynamicClient:        spokeDynamicClient,
+		deletionProtection:        deletionProtection,
+		deletionTimeout:           deletionTimeout,
+		maintenanceWindow:         maintenanceWindow,
+		clock:                     clock.RealClock{},
+		hubHash:                   hubHash,
 		rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
 	}
 
 	return factory.New().
+		// resync the appliedmanifestwork once the deletion of its protected resources is confirmed on the
+		// manifestwork, or the manifestwork is gone.
+		WithInformersQueueKeyFunc(func(obj runtime.Object) string {
+			accessor, _ := meta.Accessor(obj)
+			return fmt.Sprintf("%s-%s", hubHash, accessor.GetName())
+		}, manifestWorkInformer.Informer()).
 		WithFilteredEventsInformersQueueKeyFunc(func(obj runtime.Object) string {
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, helper.AppliedManifestworkAgentIDFilter(agentID), appliedManifestWorkInformer.Informer()).
-		WithSync(controller.sync).ToController("AppliedManifestWorkFinalizer", recorder)
+		WithSync(controller.sync).ToController(helper.HubControllerName(appliedManifestWorkFinalizerName, hubName), recorder)
 }
 
 func (m *AppliedManifestWorkFinalizeController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -91,19 +121,73 @@ func (m *AppliedManifestWorkFinalizeController) syncAppliedManifestWork(ctx cont
 
 	var err error
 
+	// the deletion of the protected resources is confirmed on the manifestwork, it can only be confirmed if the
+	// manifestwork is from the current hub and not gone yet.
+	var manifestWork *workapiv1.ManifestWork
+	if appliedManifestWork.Spec.HubHash == m.hubHash {
+		manifestWork, err = m.manifestWorkLister.Get(appliedManifestWork.Spec.ManifestWorkName)
+		switch {
+		case errors.IsNotFound(err):
+			manifestWork = nil
+		case err != nil:
+			return err
+		}
+	}
+
+	// the resources are not deleted until the manifestwork is resumed
+	if manifestWork != nil && helper.IsPaused(manifestWork) {
+		klog.V(4).Infof("ManifestWork %s is paused, skip deleting its resources", manifestWork.Name)
+		return nil
+	}
+
+	// the resources are deleted in the maintenance window unless the deletion is forced
+	if manifestWork != nil && !helper.IsForceDeletion(manifestWork) {
+		window, err := helper.MaintenanceWindowOf(manifestWork, m.maintenanceWindow)
+		if err != nil {
+			return err
+		}
+		if window != nil {
+			now := m.clock.Now()
+			if open, next := window.Check(now); !open {
+				_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, appliedManifestWorkFinalizerName, manifestWork.DeepCopy(),
+					helper.SetPendingWindowCondition(manifestWork.Generation, "deletion", next))
+				if err != nil {
+					return err
+				}
+				controllerContext.Queue().AddAfter(appliedManifestWork.Name, next.Sub(now))
+				return nil
+			}
+		}
+	}
+
 	owner := helper.NewAppliedManifestWorkOwner(appliedManifestWork)
 
 	// Work is deleting, we remove its related resources on spoke cluster
 	// We still need to run delete for every resource even with ownerref on it, since ownerref does not handle cluster
 	// scoped resource correctly.
 	reason := fmt.Sprintf("manifestwork %s is terminating", appliedManifestWork.Spec.ManifestWorkName)
-	resourcesPendingFinalization, errs := helper.DeleteAppliedResources(
-		ctx, appliedManifestWork.Status.AppliedResources, reason, m.spokeDynamicClient, controllerContext.Recorder(), *owner)
+	deletion, errs := helper.DeleteAppliedResources(
+		ctx, appliedManifestWork.Status.AppliedResources, reason, m.spokeDynamicClient, controllerContext.Recorder(), *owner,
+		m.deletionProtection, helper.ConfirmedDeletions(manifestWork), m.deletionTimeout)
+	resourcesPendingFinalization, resourcesPendingConfirmation := deletion.PendingFinalization, deletion.PendingConfirmation
+
+	// report the deletion progress on the terminating manifestwork
+	if manifestWork != nil {
+		_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, appliedManifestWorkFinalizerName, manifestWork.DeepCopy(),
+			helper.SetProtectedDeletionPendingCondition(manifestWork.Generation, resourcesPendingConfirmation),
+			helper.SetDeletionStuckCondition(manifestWork.Generation, deletion.Stuck),
+			helper.SetPendingWindowCondition(manifestWork.Generation, "", time.Time{}))
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"failed to update status of ManifestWork %s: %w", manifestWork.Name, err))
+		}
+	}
 
+	remainingResources := append(resourcesPendingFinalization, resourcesPendingConfirmation...)
 	updatedAppliedManifestWork := false
-	if len(appliedManifestWork.Status.AppliedResources) != len(resourcesPendingFinalization) {
+	if len(appliedManifestWork.Status.AppliedResources) != len(remainingResources) {
 		// update the status of the manifest work accordingly
-		appliedManifestWork.Status.AppliedResources = resourcesPendingFinalization
+		appliedManifestWork.Status.AppliedResources = remainingResources
 		appliedManifestWork, err = m.appliedManifestWorkClient.UpdateStatus(ctx, appliedManifestWork, metav1.UpdateOptions{})
 		if err != nil {
 			errs = append(errs, fmt.Errorf(
@@ -125,6 +209,12 @@ func (m *AppliedManifestWorkFinalizeController) syncAppliedManifestWork(ctx cont
 		return nil
 	}
 
+	// wait until the deletion of the protected resources is confirmed
+	if len(resourcesPendingConfirmation) != 0 {
+		klog.V(4).Infof("%d protected resources pending deletion confirmation", len(resourcesPendingConfirmation))
+		return nil
+	}
+
 	// reset the rate limiter for the appliedmanifestwork
 	m.rateLimiter.Forget(appliedManifestWork.Name)
 
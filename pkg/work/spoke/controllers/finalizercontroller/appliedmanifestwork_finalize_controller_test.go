@@ -3,10 +3,13 @@ package finalizercontroller
 import (
 	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -14,13 +17,16 @@ import (
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/util/workqueue"
+	testingclock "k8s.io/utils/clock/testing"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
 	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -35,7 +41,12 @@ func TestFinalize(t *testing.T) {
 		existingResources                  []runtime.Object
 		resourcesToRemove                  []workapiv1.AppliedManifestResourceMeta
 		terminated                         bool
+		work                               *workapiv1.ManifestWork
+		deletionProtection                 *helper.DeletionProtection
+		deletionTimeout                    *helper.DeletionTimeout
+		maintenanceWindow                  *helper.MaintenanceWindow
 		validateAppliedManifestWorkActions func(t *testing.T, actions []clienttesting.Action)
+		validateManifestWorkActions        func(t *testing.T, actions []clienttesting.Action)
 		validateDynamicActions             func(t *testing.T, actions []clienttesting.Action)
 		expectedQueueLen                   int
 	}{
@@ -178,6 +189,171 @@ func TestFinalize(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:               "wait for the deletion confirmation of protected resources",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work:                               newManifestWork(""),
+			deletionProtection:                 newDeletionProtection(t, helper.DeletionProtectionConfirm, "secrets"),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				if !meta.IsStatusConditionTrue(work.Status.Conditions, helper.WorkProtectedDeletionPending) {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "get")
+			},
+		},
+		{
+			name:               "delete confirmed protected resources",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work:                               newManifestWork("secrets/ns1/n1"),
+			deletionProtection:                 newDeletionProtection(t, helper.DeletionProtectionConfirm, "secrets"),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions:        noAction,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "get", "delete")
+			},
+			expectedQueueLen: 1,
+		},
+		{
+			name:               "do not delete resources when work is paused",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work: func() *workapiv1.ManifestWork {
+				work := newManifestWork("")
+				work.Annotations = map[string]string{helper.PausedAnnotationKey: "true"}
+				return work
+			}(),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions:        noAction,
+			validateDynamicActions:             noAction,
+		},
+		{
+			name:               "do not delete resources outside the maintenance window",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work:                               newManifestWork(""),
+			maintenanceWindow:                  newMaintenanceWindow(t, "0 2 * * *"),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				condition := meta.FindStatusCondition(work.Status.Conditions, helper.WorkPendingWindow)
+				if condition == nil || condition.Message !=
+					"The deletion is pending until the next maintenance window at 2023-07-02T02:00:00Z" {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+			},
+			validateDynamicActions: noAction,
+		},
+		{
+			name:               "force deletion outside the maintenance window",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work: func() *workapiv1.ManifestWork {
+				work := newManifestWork("")
+				work.Annotations = map[string]string{helper.ForceDeletionAnnotationKey: "true"}
+				return work
+			}(),
+			maintenanceWindow:                  newMaintenanceWindow(t, "0 2 * * *"),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions:        noAction,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "get", "delete")
+			},
+			expectedQueueLen: 1,
+		},
+		{
+			name:               "report resources stuck in deletion",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				func() runtime.Object {
+					secret := spoketesting.NewUnstructuredSecret("ns1", "n1", true, "ns1-n1", *owner)
+					secret.SetFinalizers([]string{"example.com/cleanup"})
+					return secret
+				}(),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			work: newManifestWork(""),
+			deletionTimeout: func() *helper.DeletionTimeout {
+				timeout, _ := helper.NewDeletionTimeout(5*time.Minute, 0, helper.DeletionTimeoutWait)
+				return timeout.WithClock(testingclock.NewFakeClock(time.Now().Add(10 * time.Minute)))
+			}(),
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update")
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				condition := meta.FindStatusCondition(work.Status.Conditions, helper.WorkDeletionStuck)
+				if condition == nil || !strings.Contains(condition.Message, "example.com/cleanup") {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+			},
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "get")
+			},
+			expectedQueueLen: 1,
+		},
+		{
+			name:               "orphan protected resources if the work is gone",
+			terminated:         true,
+			existingFinalizers: []string{controllers.AppliedManifestWorkFinalizer},
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1", *owner),
+			},
+			resourcesToRemove: []workapiv1.AppliedManifestResourceMeta{
+				{Version: "v1", ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "ns1", Name: "n1"}, UID: "ns1-n1"},
+			},
+			deletionProtection: newDeletionProtection(t, helper.DeletionProtectionConfirm, "secrets"),
+			validateAppliedManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "update", "update")
+				work := actions[1].(clienttesting.UpdateAction).GetObject().(*workapiv1.AppliedManifestWork)
+				if len(work.Finalizers) != 0 {
+					t.Fatal(spew.Sdump(actions[1]))
+				}
+			},
+			validateManifestWorkActions: noAction,
+			validateDynamicActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertActions(t, actions, "get", "patch")
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -192,9 +368,30 @@ func TestFinalize(t *testing.T) {
 
 			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.existingResources...)
 			fakeClient := fakeworkclient.NewSimpleClientset(testingWork)
+
+			hubObjects := []runtime.Object{}
+			if c.work != nil {
+				hubObjects = append(hubObjects, c.work)
+			}
+			fakeHubClient := fakeworkclient.NewSimpleClientset(hubObjects...)
+			informerFactory := workinformers.NewSharedInformerFactory(fakeHubClient, 5*time.Minute)
+			for _, obj := range hubObjects {
+				if err := informerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			controller := AppliedManifestWorkFinalizeController{
+				manifestWorkLister: informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+				statusUpdater: hubcache.NewStatusUpdater(fakeHubClient.WorkV1().ManifestWorks("cluster1"), nil,
+					hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))),
 				appliedManifestWorkClient: fakeClient.WorkV1().AppliedManifestWorks(),
 				spokeDynamicClient:        fakeDynamicClient,
+				deletionProtection:        c.deletionProtection,
+				deletionTimeout:           c.deletionTimeout,
+				maintenanceWindow:         c.maintenanceWindow,
+				clock:                     testingclock.NewFakeClock(time.Date(2023, time.July, 1, 12, 0, 0, 0, time.UTC)),
+				hubHash:                   "test",
 				rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(0, 1*time.Second),
 			}
 
@@ -205,6 +402,9 @@ func TestFinalize(t *testing.T) {
 			}
 			c.validateAppliedManifestWorkActions(t, fakeClient.Actions())
 			c.validateDynamicActions(t, fakeDynamicClient.Actions())
+			if c.validateManifestWorkActions != nil {
+				c.validateManifestWorkActions(t, fakeHubClient.Actions())
+			}
 
 			queueLen := controllerContext.Queue().Len()
 			if queueLen != c.expectedQueueLen {
@@ -214,6 +414,33 @@ func TestFinalize(t *testing.T) {
 	}
 }
 
+func newManifestWork(confirmedDeletions string) *workapiv1.ManifestWork {
+	work, _ := spoketesting.NewManifestWork(0)
+	if len(confirmedDeletions) > 0 {
+		work.Annotations = map[string]string{helper.ConfirmDeletionAnnotationKey: confirmedDeletions}
+	}
+	return work
+}
+
+func newMaintenanceWindow(t *testing.T, schedule string) *helper.MaintenanceWindow {
+	window, err := helper.NewMaintenanceWindow(helper.MaintenanceWindowSpec{
+		Schedule: schedule,
+		Duration: metav1.Duration{Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return window
+}
+
+func newDeletionProtection(t *testing.T, policy helper.DeletionProtectionPolicy, resources ...string) *helper.DeletionProtection {
+	protection, err := helper.NewDeletionProtection(resources, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return protection
+}
+
 func noAction(t *testing.T, actions []clienttesting.Action) {
 	if len(actions) > 0 {
 		t.Fatal(spew.Sdump(actions))
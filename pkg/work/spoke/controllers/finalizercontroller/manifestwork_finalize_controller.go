@@ -20,6 +20,7 @@ import (
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 )
 
 // ManifestWorkFinalizeController handles cleanup of manifestwork resources before deletion is allowed.
@@ -30,6 +31,9 @@ type ManifestWorkFinalizeController struct {
 	appliedManifestWorkLister worklister.AppliedManifestWorkLister
 	hubHash                   string
 	rateLimiter               workqueue.RateLimiter
+	// hubConnection suppresses the deletion of the appliedmanifestworks when the hub is not reachable, since the
+	// manifestworks may be listed from a stale cache
+	hubConnection *hubcache.HubConnection
 }
 
 func NewManifestWorkFinalizeController(
@@ -39,7 +43,8 @@ func NewManifestWorkFinalizeController(
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	appliedManifestWorkInformer workinformer.AppliedManifestWorkInformer,
-	hubHash string,
+	hubConnection *hubcache.HubConnection,
+	hubHash, hubName string,
 ) factory.Controller {
 
 	controller := &ManifestWorkFinalizeController{
@@ -49,6 +54,7 @@ func NewManifestWorkFinalizeController(
 		appliedManifestWorkLister: appliedManifestWorkInformer.Lister(),
 		hubHash:                   hubHash,
 		rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 1000*time.Second),
+		hubConnection:             hubConnection,
 	}
 
 	return factory.New().
@@ -60,7 +66,7 @@ func NewManifestWorkFinalizeController(
 			helper.AppliedManifestworkQueueKeyFunc(hubHash),
 			helper.AppliedManifestworkHubHashFilter(hubHash),
 			appliedManifestWorkInformer.Informer()).
-		WithSync(controller.sync).ToController("ManifestWorkFinalizer", recorder)
+		WithSync(controller.sync).ToController(helper.HubControllerName("ManifestWorkFinalizer", hubName), recorder)
 }
 
 func (m *ManifestWorkFinalizeController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -77,6 +83,13 @@ func (m *ManifestWorkFinalizeController) sync(ctx context.Context, controllerCon
 		return nil
 	case err != nil:
 		return err
+	case !manifestWork.DeletionTimestamp.IsZero() && helper.IsPaused(manifestWork):
+		// the appliedmanifestwork is deleted once the manifestwork is resumed
+		return nil
+	case !manifestWork.DeletionTimestamp.IsZero() && !m.hubConnection.Connected():
+		// the appliedmanifestwork is deleted once the hub is reachable again
+		controllerContext.Queue().AddAfter(manifestWorkName, hubUnreachableRequeueDelay)
+		return nil
 	case !manifestWork.DeletionTimestamp.IsZero():
 		err := m.deleteAppliedManifestWork(ctx, appliedManifestWorkName)
 		if err != nil {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/util/workqueue"
@@ -15,7 +16,9 @@ import (
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 )
 
 func TestSyncManifestWorkController(t *testing.T) {
@@ -26,6 +29,7 @@ func TestSyncManifestWorkController(t *testing.T) {
 		workName                           string
 		work                               *workapiv1.ManifestWork
 		appliedWork                        *workapiv1.AppliedManifestWork
+		hubUnreachable                     bool
 		validateAppliedManifestWorkActions func(t *testing.T, actions []clienttesting.Action)
 		validateManifestWorkActions        func(t *testing.T, actions []clienttesting.Action)
 		expectedQueueLen                   int
@@ -102,6 +106,31 @@ func TestSyncManifestWorkController(t *testing.T) {
 			},
 			expectedQueueLen: 1,
 		},
+		{
+			name:     "do not delete applied work when work is paused",
+			workName: "work",
+			work: &workapiv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "work",
+					Namespace:         "cluster1",
+					DeletionTimestamp: &now,
+					Finalizers:        []string{controllers.ManifestWorkFinalizer},
+					Annotations:       map[string]string{helper.PausedAnnotationKey: "true"},
+				},
+			},
+			appliedWork: &workapiv1.AppliedManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-work", hubHash),
+				},
+			},
+			validateAppliedManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+			validateManifestWorkActions: func(t *testing.T, actions []clienttesting.Action) {
+				testingcommon.AssertNoActions(t, actions)
+			},
+			expectedQueueLen: 0,
+		},
 		{
 			name:     "requeue work when applied work is deleting",
 			workName: "work",
@@ -184,6 +213,27 @@ func TestSyncManifestWorkController(t *testing.T) {
 			},
 			expectedQueueLen: 0,
 		},
+		{
+			name:           "do not delete appliedmanifestwork when the hub is not reachable",
+			workName:       "work",
+			hubUnreachable: true,
+			work: &workapiv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "work",
+					Namespace:         "cluster1",
+					DeletionTimestamp: &now,
+					Finalizers:        []string{controllers.ManifestWorkFinalizer},
+				},
+			},
+			appliedWork: &workapiv1.AppliedManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("%s-work", hubHash),
+				},
+			},
+			validateAppliedManifestWorkActions: noAction,
+			validateManifestWorkActions:        noAction,
+			expectedQueueLen:                   0,
+		},
 	}
 
 	for _, c := range cases {
@@ -196,6 +246,10 @@ func TestSyncManifestWorkController(t *testing.T) {
 			if err := informerFactory.Work().V1().AppliedManifestWorks().Informer().GetStore().Add(c.appliedWork); err != nil {
 				t.Fatal(err)
 			}
+			hubConnection := hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+			if c.hubUnreachable {
+				hubConnection.MarkDisconnected(fmt.Errorf("connection refused"))
+			}
 			controller := &ManifestWorkFinalizeController{
 				manifestWorkClient:        fakeClient.WorkV1().ManifestWorks("cluster1"),
 				manifestWorkLister:        informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
@@ -203,6 +257,7 @@ func TestSyncManifestWorkController(t *testing.T) {
 				appliedManifestWorkLister: informerFactory.Work().V1().AppliedManifestWorks().Lister(),
 				hubHash:                   hubHash,
 				rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(0, 1*time.Second),
+				hubConnection:             hubConnection,
 			}
 
 			controllerContext := testingcommon.NewFakeSyncContext(t, c.workName)
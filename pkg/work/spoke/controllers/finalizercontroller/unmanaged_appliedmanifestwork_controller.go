@@ -24,16 +24,26 @@ import (
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
+	"open-cluster-management.io/ocm/pkg/work/spoke/metrics"
 )
 
+// hubUnreachableRequeueDelay is the delay to requeue the appliedmanifestwork whose eviction is suppressed because
+// the hub is not reachable
+const hubUnreachableRequeueDelay = 1 * time.Minute
+
 type unmanagedAppliedWorkController struct {
 	manifestWorkLister        worklister.ManifestWorkNamespaceLister
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface
 	appliedManifestWorkLister worklister.AppliedManifestWorkLister
 	hubHash                   string
 	agentID                   string
+	hubName                   string
 	evictionGracePeriod       time.Duration
 	rateLimiter               workqueue.RateLimiter
+	// hubConnection suppresses the eviction when the hub is not reachable, since the manifestworks may be listed
+	// from a stale cache in which the recently created manifestworks are missing
+	hubConnection *hubcache.HubConnection
 }
 
 // NewUnManagedAppliedWorkController returns a controller to evict the unmanaged appliedmanifestworks.
@@ -44,7 +54,8 @@ type unmanagedAppliedWorkController struct {
 //
 // One unmanaged appliedmanifestwork will be evicted from the managed cluster after a grace period (by
 // default, 10 minutes), after one appliedmanifestwork is evicted from the managed cluster, its owned
-// resources will also be evicted from the managed cluster with Kubernetes garbage collection.
+// resources will also be evicted from the managed cluster with Kubernetes garbage collection. The eviction is
+// suppressed when the hub is not reachable.
 func NewUnManagedAppliedWorkController(
 	recorder events.Recorder,
 	manifestWorkInformer workinformer.ManifestWorkInformer,
@@ -52,7 +63,8 @@ func NewUnManagedAppliedWorkController(
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	appliedManifestWorkInformer workinformer.AppliedManifestWorkInformer,
 	evictionGracePeriod time.Duration,
-	hubHash, agentID string,
+	hubConnection *hubcache.HubConnection,
+	hubHash, agentID, hubName string,
 ) factory.Controller {
 	controller := &unmanagedAppliedWorkController{
 		manifestWorkLister:        manifestWorkLister,
@@ -60,8 +72,10 @@ func NewUnManagedAppliedWorkController(
 		appliedManifestWorkLister: appliedManifestWorkInformer.Lister(),
 		hubHash:                   hubHash,
 		agentID:                   agentID,
+		hubName:                   hubName,
 		evictionGracePeriod:       evictionGracePeriod,
 		rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(1*time.Minute, evictionGracePeriod),
+		hubConnection:             hubConnection,
 	}
 
 	return factory.New().
@@ -74,7 +88,7 @@ func NewUnManagedAppliedWorkController(
 				accessor, _ := meta.Accessor(obj)
 				return accessor.GetName()
 			}, helper.AppliedManifestworkAgentIDFilter(agentID), appliedManifestWorkInformer.Informer()).
-		WithSync(controller.sync).ToController("UnManagedAppliedManifestWork", recorder)
+		WithSync(controller.sync).ToController(helper.HubControllerName("UnManagedAppliedManifestWork", hubName), recorder)
 }
 
 func (m *unmanagedAppliedWorkController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -93,7 +107,8 @@ func (m *unmanagedAppliedWorkController) sync(ctx context.Context, controllerCon
 	_, err = m.manifestWorkLister.Get(appliedManifestWork.Spec.ManifestWorkName)
 	if errors.IsNotFound(err) {
 		// evict the current appliedmanifestwork when its relating manifestwork is missing on the hub
-		return m.evictAppliedManifestWork(ctx, controllerContext, appliedManifestWork)
+		return m.evictAppliedManifestWork(ctx, controllerContext, appliedManifestWork,
+			metrics.EvictionReasonManifestWorkMissing)
 	}
 	if err != nil {
 		return err
@@ -101,7 +116,7 @@ func (m *unmanagedAppliedWorkController) sync(ctx context.Context, controllerCon
 
 	// manifestwork exists but hub changed
 	if !strings.HasPrefix(appliedManifestWork.Name, m.hubHash) {
-		return m.evictAppliedManifestWork(ctx, controllerContext, appliedManifestWork)
+		return m.evictAppliedManifestWork(ctx, controllerContext, appliedManifestWork, metrics.EvictionReasonHubChanged)
 	}
 
 	// stop to evict the current appliedmanifestwork when its relating manifestwork is recreated on the hub
@@ -109,12 +124,22 @@ func (m *unmanagedAppliedWorkController) sync(ctx context.Context, controllerCon
 }
 
 func (m *unmanagedAppliedWorkController) evictAppliedManifestWork(ctx context.Context,
-	controllerContext factory.SyncContext, appliedManifestWork *workapiv1.AppliedManifestWork) error {
+	controllerContext factory.SyncContext, appliedManifestWork *workapiv1.AppliedManifestWork, reason string) error {
+	if !m.hubConnection.Connected() {
+		klog.V(4).Infof("Suppress the eviction of appliedWork %s since the hub is not reachable", appliedManifestWork.Name)
+		controllerContext.Queue().AddAfter(appliedManifestWork.Name, hubUnreachableRequeueDelay)
+		return nil
+	}
+
 	now := time.Now()
 
 	evictionStartTime := appliedManifestWork.Status.EvictionStartTime
 	if evictionStartTime == nil {
-		return m.patchEvictionStartTime(ctx, appliedManifestWork, &metav1.Time{Time: now})
+		if err := m.patchEvictionStartTime(ctx, appliedManifestWork, &metav1.Time{Time: now}); err != nil {
+			return err
+		}
+		metrics.RecordEviction(m.hubName, reason, metrics.EvictionPhaseStarted)
+		return nil
 	}
 
 	if now.Before(evictionStartTime.Add(m.evictionGracePeriod)) {
@@ -123,7 +148,11 @@ func (m *unmanagedAppliedWorkController) evictAppliedManifestWork(ctx context.Co
 	}
 
 	klog.V(2).Infof("Delete appliedWork %s by agent %s after eviction grace periodby", appliedManifestWork.Name, m.agentID)
-	return m.appliedManifestWorkClient.Delete(ctx, appliedManifestWork.Name, metav1.DeleteOptions{})
+	if err := m.appliedManifestWorkClient.Delete(ctx, appliedManifestWork.Name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	metrics.RecordEviction(m.hubName, reason, metrics.EvictionPhaseCompleted)
+	return nil
 }
 
 func (m *unmanagedAppliedWorkController) stopToEvictAppliedManifestWork(
@@ -133,7 +162,11 @@ func (m *unmanagedAppliedWorkController) stopToEvictAppliedManifestWork(
 	}
 
 	m.rateLimiter.Forget(appliedManifestWork.Name)
-	return m.patchEvictionStartTime(ctx, appliedManifestWork, nil)
+	if err := m.patchEvictionStartTime(ctx, appliedManifestWork, nil); err != nil {
+		return err
+	}
+	metrics.RecordEviction(m.hubName, metrics.EvictionReasonManifestWorkMissing, metrics.EvictionPhaseStopped)
+	return nil
 }
 
 func (m *unmanagedAppliedWorkController) patchEvictionStartTime(ctx context.Context,
@@ -2,9 +2,11 @@ package finalizercontroller
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clienttesting "k8s.io/client-go/testing"
@@ -15,6 +17,7 @@ import (
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 )
 
 func TestSyncUnamanagedAppliedWork(t *testing.T) {
@@ -24,6 +27,7 @@ func TestSyncUnamanagedAppliedWork(t *testing.T) {
 		hubHash                            string
 		agentID                            string
 		evictionGracePeriod                time.Duration
+		hubUnreachable                     bool
 		works                              []runtime.Object
 		appliedWorks                       []runtime.Object
 		expectedQueueLen                   int
@@ -185,6 +189,54 @@ func TestSyncUnamanagedAppliedWork(t *testing.T) {
 			expectedQueueLen:                   1,
 			validateAppliedManifestWorkActions: noAction,
 		},
+		{
+			name:                    "suppress the eviction when the hub is not reachable",
+			appliedManifestWorkName: "hubhash-test",
+			hubHash:                 "hubhash",
+			agentID:                 "test-agent",
+			hubUnreachable:          true,
+			works:                   []runtime.Object{},
+			appliedWorks: []runtime.Object{
+				&workapiv1.AppliedManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "hubhash-test",
+					},
+					Spec: workapiv1.AppliedManifestWorkSpec{
+						ManifestWorkName: "test",
+						HubHash:          "hubhash",
+						AgentID:          "test-agent",
+					},
+				},
+			},
+			validateAppliedManifestWorkActions: noAction,
+		},
+		{
+			name:                    "suppress the deletion after eviction grace period when the hub is not reachable",
+			appliedManifestWorkName: "hubhash-test",
+			hubHash:                 "hubhash",
+			agentID:                 "test-agent",
+			evictionGracePeriod:     10 * time.Minute,
+			hubUnreachable:          true,
+			works:                   []runtime.Object{},
+			appliedWorks: []runtime.Object{
+				&workapiv1.AppliedManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "hubhash-test",
+					},
+					Spec: workapiv1.AppliedManifestWorkSpec{
+						ManifestWorkName: "test",
+						HubHash:          "hubhash",
+						AgentID:          "test-agent",
+					},
+					Status: workapiv1.AppliedManifestWorkStatus{
+						EvictionStartTime: &metav1.Time{
+							Time: time.Now().Add(-10 * time.Minute),
+						},
+					},
+				},
+			},
+			validateAppliedManifestWorkActions: noAction,
+		},
 	}
 
 	for _, c := range cases {
@@ -202,6 +254,11 @@ func TestSyncUnamanagedAppliedWork(t *testing.T) {
 				}
 			}
 
+			hubConnection := hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+			if c.hubUnreachable {
+				hubConnection.MarkDisconnected(fmt.Errorf("connection refused"))
+			}
+
 			controller := &unmanagedAppliedWorkController{
 				manifestWorkLister:        informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("test"),
 				appliedManifestWorkClient: fakeClient.WorkV1().AppliedManifestWorks(),
@@ -210,6 +267,7 @@ func TestSyncUnamanagedAppliedWork(t *testing.T) {
 				agentID:                   c.agentID,
 				evictionGracePeriod:       c.evictionGracePeriod,
 				rateLimiter:               workqueue.NewItemExponentialFailureRateLimiter(0, c.evictionGracePeriod),
+				hubConnection:             hubConnection,
 			}
 
 			controllerContext := testingcommon.NewFakeSyncContext(t, c.appliedManifestWorkName)
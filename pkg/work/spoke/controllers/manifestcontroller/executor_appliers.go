@@ -0,0 +1,43 @@
+package manifestcontroller
+
+import (
+	"fmt"
+	"sync"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/apply"
+)
+
+// executorAppliers caches the appliers impersonating the user and group executors, so the clients of an executor
+// are built once rather than on every sync of its manifestworks.
+type executorAppliers struct {
+	lock     sync.Mutex
+	appliers map[string]*apply.Appliers
+	// newAppliers returns the appliers impersonating the executor subject
+	newAppliers func(subject *helper.ExecutorSubject) (*apply.Appliers, error)
+}
+
+func newExecutorAppliers(newAppliers func(subject *helper.ExecutorSubject) (*apply.Appliers, error)) *executorAppliers {
+	return &executorAppliers{
+		appliers:    map[string]*apply.Appliers{},
+		newAppliers: newAppliers,
+	}
+}
+
+// get returns the cached appliers of the executor subject, or builds them if they are not cached yet
+func (e *executorAppliers) get(subject *helper.ExecutorSubject) (*apply.Appliers, error) {
+	key := fmt.Sprintf("%s/%s", subject.Type, subject.String())
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if appliers, ok := e.appliers[key]; ok {
+		return appliers, nil
+	}
+
+	appliers, err := e.newAppliers(subject)
+	if err != nil {
+		return nil, err
+	}
+	e.appliers[key] = appliers
+	return appliers, nil
+}
@@ -21,8 +21,10 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	workinformer "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
@@ -34,8 +36,12 @@ import (
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
+	"open-cluster-management.io/ocm/pkg/work/spoke/metrics"
 )
 
+const controllerName = "ManifestWorkAgent"
+
 var (
 	ResyncInterval     = 5 * time.Minute
 	MaxRequeueDuration = 24 * time.Hour
@@ -44,16 +50,23 @@ var (
 // ManifestWorkController is to reconcile the workload resources
 // fetched from hub cluster on spoke cluster.
 type ManifestWorkController struct {
-	manifestWorkClient        workv1client.ManifestWorkInterface
+	statusUpdater             *hubcache.StatusUpdater
 	manifestWorkLister        worklister.ManifestWorkNamespaceLister
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface
 	appliedManifestWorkLister worklister.AppliedManifestWorkLister
 	spokeDynamicClient        dynamic.Interface
 	hubHash                   string
 	agentID                   string
+	hubName                   string
 	restMapper                meta.RESTMapper
 	appliers                  *apply.Appliers
-	validator                 auth.ExecutorValidator
+	// executorAppliers are the appliers impersonating the user or group executors
+	executorAppliers *executorAppliers
+	validator        auth.ExecutorValidator
+	// maintenanceWindow is the window to apply the new generations of the manifestworks, they are applied
+	// at any time if it is nil
+	maintenanceWindow *helper.MaintenanceWindow
+	clock             clock.Clock
 }
 
 type applyResult struct {
@@ -66,29 +79,41 @@ type applyResult struct {
 // NewManifestWorkController returns a ManifestWorkController
 func NewManifestWorkController(
 	recorder events.Recorder,
+	spokeRestConfig *rest.Config,
 	spokeDynamicClient dynamic.Interface,
 	spokeKubeClient kubernetes.Interface,
 	spokeAPIExtensionClient apiextensionsclient.Interface,
-	manifestWorkClient workv1client.ManifestWorkInterface,
+	statusUpdater *hubcache.StatusUpdater,
 	manifestWorkInformer workinformer.ManifestWorkInformer,
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
 	appliedManifestWorkClient workv1client.AppliedManifestWorkInterface,
 	appliedManifestWorkInformer workinformer.AppliedManifestWorkInformer,
-	hubHash, agentID string,
+	hubHash, agentID, hubName string,
 	restMapper meta.RESTMapper,
-	validator auth.ExecutorValidator) factory.Controller {
+	validator auth.ExecutorValidator,
+	maintenanceWindow *helper.MaintenanceWindow) factory.Controller {
 
 	controller := &ManifestWorkController{
-		manifestWorkClient:        manifestWorkClient,
+		statusUpdater:             statusUpdater,
 		manifestWorkLister:        manifestWorkLister,
 		appliedManifestWorkClient: appliedManifestWorkClient,
 		appliedManifestWorkLister: appliedManifestWorkInformer.Lister(),
 		spokeDynamicClient:        spokeDynamicClient,
 		hubHash:                   hubHash,
 		agentID:                   agentID,
+		hubName:                   hubName,
 		restMapper:                restMapper,
 		appliers:                  apply.NewAppliers(spokeDynamicClient, spokeKubeClient, spokeAPIExtensionClient),
-		validator:                 validator,
+		executorAppliers: newExecutorAppliers(func(subject *helper.ExecutorSubject) (*apply.Appliers, error) {
+			config, err := basic.NewImpersonatedConfig(spokeRestConfig, subject)
+			if err != nil {
+				return nil, err
+			}
+			return apply.NewAppliersForConfig(config)
+		}),
+		validator:         validator,
+		maintenanceWindow: maintenanceWindow,
+		clock:             clock.RealClock{},
 	}
 
 	return factory.New().
@@ -100,7 +125,7 @@ func NewManifestWorkController(
 			helper.AppliedManifestworkQueueKeyFunc(hubHash),
 			helper.AppliedManifestworkHubHashFilter(hubHash),
 			appliedManifestWorkInformer.Informer()).
-		WithSync(controller.sync).ResyncEvery(ResyncInterval).ToController("ManifestWorkAgent", recorder)
+		WithSync(controller.sync).ResyncEvery(ResyncInterval).ToController(helper.HubControllerName(controllerName, hubName), recorder)
 }
 
 // sync is the main reconcile loop for manifest work. It is triggered in two scenarios
@@ -120,9 +145,18 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 	}
 	manifestWork = manifestWork.DeepCopy()
 
-	// no work to do if we're deleted
+	// the manifests of a paused manifestwork are not applied, only the Paused condition is reported
+	if helper.IsPaused(manifestWork) {
+		_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName,
+			manifestWork, helper.SetPausedCondition(manifestWork.Generation, true))
+		return err
+	}
+
+	// no work to do if we're deleted, except removing the Paused condition if it is resumed during the deletion
 	if !manifestWork.DeletionTimestamp.IsZero() {
-		return nil
+		_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName,
+			manifestWork, helper.SetPausedCondition(manifestWork.Generation, false))
+		return err
 	}
 
 	// don't do work if the finalizer is not present
@@ -131,6 +165,27 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 		return nil
 	}
 
+	// the new generation is queued until the maintenance window opens
+	window, err := helper.MaintenanceWindowOf(manifestWork, m.maintenanceWindow)
+	if err != nil {
+		controllerContext.Recorder().Warningf("InvalidMaintenanceWindow", "Invalid maintenance window of manifestwork %s: %v",
+			manifestWorkName, err)
+		return err
+	}
+	if window != nil && !helper.GenerationApplied(manifestWork) {
+		now := m.clock.Now()
+		if open, next := window.Check(now); !open {
+			_, _, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName, manifestWork,
+				helper.SetPendingWindowCondition(manifestWork.Generation,
+					fmt.Sprintf("generation %d", manifestWork.Generation), next))
+			if err != nil {
+				return err
+			}
+			controllerContext.Queue().AddAfter(manifestWorkName, next.Sub(now))
+			return nil
+		}
+	}
+
 	// Apply appliedManifestWork
 	appliedManifestWork, err := m.applyAppliedManifestWork(ctx, manifestWork.Name, m.hubHash, m.agentID)
 	if err != nil {
@@ -140,12 +195,30 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 	// We creat a ownerref instead of controller ref since multiple controller can declare the ownership of a manifests
 	owner := helper.NewAppliedManifestWorkOwner(appliedManifestWork)
 
+	executor, err := helper.ManifestWorkExecutor(manifestWork)
+	if err != nil {
+		controllerContext.Recorder().Warningf("InvalidExecutor", "Invalid executor of manifestwork %s: %v",
+			manifestWorkName, err)
+		return err
+	}
+
+	// the resources are applied by the work agent itself, unless the executor is a user or group, in which case
+	// the work agent impersonates the executor to apply the resources, see helper.Executor for the details
+	appliers := m.appliers
+	if executor != nil && executor.Subject.Type != workapiv1.ExecutorSubjectTypeServiceAccount {
+		appliers, err = m.executorAppliers.get(&executor.Subject)
+		if err != nil {
+			return err
+		}
+	}
+
 	errs := []error{}
 	// Apply resources on spoke cluster.
 	resourceResults := make([]applyResult, len(manifestWork.Spec.Workload.Manifests))
 	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		resourceResults = m.applyManifests(
-			ctx, manifestWork.Spec.Workload.Manifests, manifestWork.Spec, controllerContext.Recorder(), *owner, resourceResults)
+			ctx, manifestWork.Spec.Workload.Manifests, manifestWork.Spec, executor, appliers,
+			controllerContext.Recorder(), *owner, resourceResults)
 
 		for _, result := range resourceResults {
 			if apierrors.IsConflict(result.Error) {
@@ -159,9 +232,22 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 		klog.Errorf("failed to apply resource with error %v", err)
 	}
 
+	metrics.ObserveManifestWorkResources(m.hubName, len(resourceResults))
+
 	newManifestConditions := []workapiv1.ManifestCondition{}
+	hubConflicts := []helper.HubConflict{}
 	var requeueTime = MaxRequeueDuration
 	for _, result := range resourceResults {
+		// detect the resources also applied by the manifestworks of other hubs
+		if result.Error == nil {
+			if accessor, err := meta.Accessor(result.Result); err == nil {
+				if owners := helper.ConflictingAppliedManifestWorks(accessor, m.hubHash); len(owners) > 0 {
+					hubConflicts = append(hubConflicts,
+						helper.HubConflict{ResourceMeta: result.resourceMeta, AppliedManifestWorks: owners})
+				}
+			}
+		}
+
 		manifestCondition := workapiv1.ManifestCondition{
 			ResourceMeta: result.resourceMeta,
 			Conditions:   []metav1.Condition{},
@@ -192,12 +278,20 @@ func (m *ManifestWorkController) sync(ctx context.Context, controllerContext fac
 	}
 
 	// Update work status
-	_, updated, err := helper.UpdateManifestWorkStatus(
-		ctx, m.manifestWorkClient, manifestWork, m.generateUpdateStatusFunc(manifestWork.Generation, newManifestConditions))
+	_, updated, err := m.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName,
+		manifestWork, m.generateUpdateStatusFunc(manifestWork.Generation, newManifestConditions),
+		helper.SetPausedCondition(manifestWork.Generation, false),
+		helper.SetPendingWindowCondition(manifestWork.Generation, "", time.Time{}),
+		helper.SetHubConflictCondition(manifestWork.Generation, hubConflicts))
 	if err != nil {
 		errs = append(errs, fmt.Errorf("failed to update work status with err %w", err))
 	}
 
+	if updated && len(hubConflicts) > 0 {
+		controllerContext.Recorder().Warningf("HubConflict", "%d resources of manifestwork %s are also applied by other hubs",
+			len(hubConflicts), manifestWorkName)
+	}
+
 	if !updated && requeueTime < MaxRequeueDuration {
 		controllerContext.Queue().AddAfter(manifestWorkName, requeueTime)
 	}
@@ -272,6 +366,8 @@ func (m *ManifestWorkController) applyManifests(
 	ctx context.Context,
 	manifests []workapiv1.Manifest,
 	workSpec workapiv1.ManifestWorkSpec,
+	executor *helper.Executor,
+	appliers *apply.Appliers,
 	recorder events.Recorder,
 	owner metav1.OwnerReference,
 	existingResults []applyResult) []applyResult {
@@ -280,10 +376,10 @@ func (m *ManifestWorkController) applyManifests(
 		switch {
 		case existingResults[index].Result == nil:
 			// Apply if there is no result.
-			existingResults[index] = m.applyOneManifest(ctx, index, manifest, workSpec, recorder, owner)
+			existingResults[index] = m.applyOneManifest(ctx, index, manifest, workSpec, executor, appliers, recorder, owner)
 		case apierrors.IsConflict(existingResults[index].Error):
 			// Apply if there is a resource conflict error.
-			existingResults[index] = m.applyOneManifest(ctx, index, manifest, workSpec, recorder, owner)
+			existingResults[index] = m.applyOneManifest(ctx, index, manifest, workSpec, executor, appliers, recorder, owner)
 		}
 	}
 
@@ -295,10 +391,16 @@ func (m *ManifestWorkController) applyOneManifest(
 	index int,
 	manifest workapiv1.Manifest,
 	workSpec workapiv1.ManifestWorkSpec,
+	executor *helper.Executor,
+	appliers *apply.Appliers,
 	recorder events.Recorder,
-	owner metav1.OwnerReference) applyResult {
+	owner metav1.OwnerReference) (result applyResult) {
 
-	result := applyResult{}
+	// the strategy is unknown until the manifest is parsed
+	start, strategy := time.Now(), workapiv1.UpdateStrategy{Type: metrics.UnknownStrategy}
+	defer func() {
+		metrics.ObserveManifestApply(m.hubName, string(strategy.Type), applyFailureReason(result.Error), time.Since(start))
+	}()
 
 	// parse the required and set resource meta
 	required := &unstructured.Unstructured{}
@@ -314,11 +416,19 @@ func (m *ManifestWorkController) applyOneManifest(
 		return result
 	}
 
+	// find update strategy option.
+	option := helper.FindManifestConiguration(resMeta, workSpec.ManifestConfigs)
+	// strategy is update by default
+	strategy = workapiv1.UpdateStrategy{Type: workapiv1.UpdateStrategyTypeUpdate}
+	if option != nil && option.UpdateStrategy != nil {
+		strategy = *option.UpdateStrategy
+	}
+
 	// check if the resource to be applied should be owned by the manifest work
 	ownedByTheWork := helper.OwnedByTheWork(gvr, resMeta.Namespace, resMeta.Name, workSpec.DeleteOption)
 
 	// check the Executor subject permission before applying
-	err = m.validator.Validate(ctx, workSpec.Executor, gvr, resMeta.Namespace, resMeta.Name, ownedByTheWork, required)
+	err = m.validator.Validate(ctx, executor, gvr, resMeta.Namespace, resMeta.Name, ownedByTheWork, required)
 	if err != nil {
 		result.Error = err
 		return result
@@ -327,15 +437,7 @@ func (m *ManifestWorkController) applyOneManifest(
 	// compute required ownerrefs based on delete option
 	requiredOwner := manageOwnerRef(ownedByTheWork, owner)
 
-	// find update strategy option.
-	option := helper.FindManifestConiguration(resMeta, workSpec.ManifestConfigs)
-	// strategy is update by default
-	strategy := workapiv1.UpdateStrategy{Type: workapiv1.UpdateStrategyTypeUpdate}
-	if option != nil && option.UpdateStrategy != nil {
-		strategy = *option.UpdateStrategy
-	}
-
-	applier := m.appliers.GetApplier(strategy.Type)
+	applier := appliers.GetApplier(strategy.Type)
 	result.Result, result.Error = applier.Apply(ctx, gvr, required, requiredOwner, option, recorder)
 
 	// patch the ownerref
@@ -346,6 +448,24 @@ func (m *ManifestWorkController) applyOneManifest(
 	return result
 }
 
+// applyFailureReason returns the reason of the apply failure for the metrics, it is empty if the apply succeeds.
+func applyFailureReason(err error) string {
+	var authError *basic.NotAllowedError
+	var ssaConflict *apply.ServerSideApplyConflictError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &authError):
+		return "NotAllowed"
+	case errors.As(err, &ssaConflict):
+		return "ServerSideApplyConflict"
+	case meta.IsNoMatchError(err):
+		return "NoKindMatch"
+	default:
+		return metrics.ReasonForError(err)
+	}
+}
+
 // manageOwnerRef return a ownerref based on the resource and the ownedByTheWork indicating whether the owneref
 // should be removed or added. If the resource is not owned by the work, the owner's UID is updated for removal.
 func manageOwnerRef(
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -18,6 +19,7 @@ import (
 	fakedynamic "k8s.io/client-go/dynamic/fake"
 	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
@@ -28,6 +30,7 @@ import (
 	"open-cluster-management.io/ocm/pkg/work/spoke/apply"
 	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -43,7 +46,9 @@ func newController(t *testing.T, work *workapiv1.ManifestWork, appliedWork *work
 	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(fakeWorkClient, 5*time.Minute, workinformers.WithNamespace("cluster1"))
 	spokeKubeClient := fakekube.NewSimpleClientset()
 	controller := &ManifestWorkController{
-		manifestWorkClient:        fakeWorkClient.WorkV1().ManifestWorks("cluster1"),
+		statusUpdater: hubcache.NewStatusUpdater(fakeWorkClient.WorkV1().ManifestWorks("cluster1"),
+			workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+			hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))),
 		manifestWorkLister:        workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
 		appliedManifestWorkClient: fakeWorkClient.WorkV1().AppliedManifestWorks(),
 		appliedManifestWorkLister: workInformerFactory.Work().V1().AppliedManifestWorks().Lister(),
@@ -366,6 +371,236 @@ func TestFailedToApplyResource(t *testing.T) {
 	tc.validate(t, controller.dynamicClient, controller.workClient, controller.kubeClient)
 }
 
+// Test applying resources with the user executor
+func TestSyncWithUserExecutor(t *testing.T) {
+	tc := newTestCase("create single resource").
+		withWorkManifest(spoketesting.NewUnstructured("v1", "Secret", "ns1", "test")).
+		withExpectedWorkAction("update").
+		withAppliedWorkAction("create").
+		withExpectedKubeAction("get", "create").
+		withExpectedManifestCondition(expectedCondition{string(workapiv1.ManifestApplied), metav1.ConditionTrue}).
+		withExpectedWorkCondition(expectedCondition{string(workapiv1.WorkApplied), metav1.ConditionTrue})
+
+	work, workKey := spoketesting.NewManifestWork(0, tc.workManifest...)
+	work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+	work.Annotations = map[string]string{helper.ExecutorUserAnnotationKey: "user1"}
+	controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+	c := controller.toController()
+	c.validator = &allowAllValidator{}
+
+	var impersonated []*helper.ExecutorSubject
+	appliers := c.appliers
+	c.appliers = nil
+	c.executorAppliers = newExecutorAppliers(func(subject *helper.ExecutorSubject) (*apply.Appliers, error) {
+		impersonated = append(impersonated, subject)
+		return appliers, nil
+	})
+
+	syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+	if err := c.sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	if len(impersonated) != 1 || impersonated[0].Type != helper.ExecutorSubjectTypeUser || impersonated[0].User.Name != "user1" {
+		t.Errorf("expected to impersonate user user1, but got %v", impersonated)
+	}
+
+	tc.validate(t, controller.dynamicClient, controller.workClient, controller.kubeClient)
+
+	// the appliers of the executor are cached
+	if _, err := c.executorAppliers.get(&helper.ExecutorSubject{
+		Type: helper.ExecutorSubjectTypeUser, User: &helper.ExecutorSubjectUser{Name: "user1"}}); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	if len(impersonated) != 1 {
+		t.Errorf("expected the appliers of user1 built once, but got %d", len(impersonated))
+	}
+}
+
+// Test the manifestwork with an invalid executor
+func TestSyncWithInvalidExecutor(t *testing.T) {
+	work, workKey := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+	work.Annotations = map[string]string{
+		helper.ExecutorUserAnnotationKey:  "user1",
+		helper.ExecutorGroupAnnotationKey: "group1",
+	}
+	controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+
+	syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+	if err := controller.toController().sync(context.TODO(), syncContext); err == nil {
+		t.Errorf("Should return an err")
+	}
+	if actions := controller.kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no kube actions, but got %v", actions)
+	}
+}
+
+// Test the manifests of a paused manifestwork are not applied until it is resumed
+func TestSyncPausedWork(t *testing.T) {
+	work, workKey := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+	work.Annotations = map[string]string{helper.PausedAnnotationKey: "true"}
+	controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+
+	syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+	if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	if actions := controller.kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no kube actions, but got %v", actions)
+	}
+	workActions := controller.workClient.Actions()
+	testingcommon.AssertActions(t, workActions, "update")
+	pausedWork := workActions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+	assertCondition(t, pausedWork.Status.Conditions, helper.WorkPaused, metav1.ConditionTrue)
+
+	// resume the manifestwork
+	pausedWork.Annotations = map[string]string{}
+	controller = newController(t, pausedWork, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+	if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	testingcommon.AssertActions(t, controller.kubeClient.Actions(), "get", "create")
+	workActions = controller.workClient.Actions()
+	testingcommon.AssertActions(t, workActions, "create", "update")
+	resumedWork := workActions[1].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+	if meta.FindStatusCondition(resumedWork.Status.Conditions, helper.WorkPaused) != nil {
+		t.Errorf("expected the Paused condition is removed, but got %v", resumedWork.Status.Conditions)
+	}
+}
+
+func TestSyncWorkInMaintenanceWindow(t *testing.T) {
+	work, workKey := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+	work.Generation = 2
+	window, err := helper.NewMaintenanceWindow(helper.MaintenanceWindowSpec{
+		Schedule: "0 2 * * *",
+		Duration: metav1.Duration{Duration: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new generation is pending outside the window
+	controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+	controller.controller.maintenanceWindow = window
+	controller.controller.clock = testingclock.NewFakeClock(time.Date(2023, time.July, 1, 12, 0, 0, 0, time.UTC))
+	syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+	if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	if actions := controller.kubeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no kube actions, but got %v", actions)
+	}
+	workActions := controller.workClient.Actions()
+	testingcommon.AssertActions(t, workActions, "update")
+	pendingWork := workActions[0].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+	assertCondition(t, pendingWork.Status.Conditions, helper.WorkPendingWindow, metav1.ConditionTrue)
+	expectedMessage := "The generation 2 is pending until the next maintenance window at 2023-07-02T02:00:00Z"
+	if condition := meta.FindStatusCondition(pendingWork.Status.Conditions, helper.WorkPendingWindow); condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+
+	// the new generation is applied in the window
+	controller = newController(t, pendingWork, nil, spoketesting.NewFakeRestMapper()).withKubeObject().withUnstructuredObject()
+	controller.controller.maintenanceWindow = window
+	controller.controller.clock = testingclock.NewFakeClock(time.Date(2023, time.July, 2, 2, 30, 0, 0, time.UTC))
+	if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+	testingcommon.AssertActions(t, controller.kubeClient.Actions(), "get", "create")
+	workActions = controller.workClient.Actions()
+	testingcommon.AssertActions(t, workActions, "create", "update")
+	appliedWork := workActions[1].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+	if meta.FindStatusCondition(appliedWork.Status.Conditions, helper.WorkPendingWindow) != nil {
+		t.Errorf("expected the PendingWindow condition is removed, but got %v", appliedWork.Status.Conditions)
+	}
+	assertCondition(t, appliedWork.Status.Conditions, string(workapiv1.WorkApplied), metav1.ConditionTrue)
+}
+
+func TestSyncWorkWithHubConflict(t *testing.T) {
+	work, workKey := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work.Finalizers = []string{controllers.ManifestWorkFinalizer}
+	otherHubOwner := metav1.OwnerReference{
+		APIVersion: "work.open-cluster-management.io/v1",
+		Kind:       "AppliedManifestWork",
+		Name:       "otherhub-work-0",
+		UID:        "otherhub",
+	}
+	existingSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns1",
+			Name:            "test",
+			OwnerReferences: []metav1.OwnerReference{otherHubOwner},
+		},
+	}
+
+	controller := newController(t, work, nil, spoketesting.NewFakeRestMapper()).
+		withKubeObject(existingSecret).withUnstructuredObject()
+	controller.controller.hubHash = "testhub"
+	syncContext := testingcommon.NewFakeSyncContext(t, workKey)
+	if err := controller.toController().sync(context.TODO(), syncContext); err != nil {
+		t.Errorf("Should be success with no err: %v", err)
+	}
+
+	workActions := controller.workClient.Actions()
+	testingcommon.AssertActions(t, workActions, "create", "update")
+	updatedWork := workActions[1].(clienttesting.UpdateActionImpl).Object.(*workapiv1.ManifestWork)
+	assertCondition(t, updatedWork.Status.Conditions, helper.WorkHubConflict, metav1.ConditionTrue)
+	condition := meta.FindStatusCondition(updatedWork.Status.Conditions, helper.WorkHubConflict)
+	expectedMessage := "1 resources are also applied by the manifestworks of other hubs: secrets/ns1/test is owned by [otherhub-work-0]"
+	if condition.Message != expectedMessage {
+		t.Errorf("expected message %q, but got %q", expectedMessage, condition.Message)
+	}
+}
+
+func TestApplyFailureReason(t *testing.T) {
+	cases := []struct {
+		name           string
+		err            error
+		expectedReason string
+	}{
+		{
+			name: "succeeded",
+		},
+		{
+			name:           "not allowed",
+			err:            &basic.NotAllowedError{Err: fmt.Errorf("not allowed")},
+			expectedReason: "NotAllowed",
+		},
+		{
+			name:           "no kind match",
+			err:            &meta.NoKindMatchError{GroupKind: schema.GroupKind{Kind: "Foo"}},
+			expectedReason: "NoKindMatch",
+		},
+		{
+			name:           "api status error",
+			err:            errors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "test", nil),
+			expectedReason: "Invalid",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if reason := applyFailureReason(c.err); reason != c.expectedReason {
+				t.Errorf("expected reason %q, but got %q", c.expectedReason, reason)
+			}
+		})
+	}
+}
+
+type allowAllValidator struct{}
+
+func (v *allowAllValidator) Validate(_ context.Context, _ *helper.Executor, _ schema.GroupVersionResource,
+	_, _ string, _ bool, _ *unstructured.Unstructured) error {
+	return nil
+}
+
+func (v *allowAllValidator) ValidateRead(_ context.Context, _ *helper.Executor, _ schema.GroupVersionResource,
+	_, _ string) error {
+	return nil
+}
+
 func TestUpdateStrategy(t *testing.T) {
 	cases := []*testCase{
 		newTestCase("update single resource with nil updateStrategy").
@@ -3,6 +3,7 @@ package statuscontroller
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -19,43 +20,68 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 
-	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	workinformer "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
 	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
+	"open-cluster-management.io/ocm/pkg/work/spoke/metrics"
 	"open-cluster-management.io/ocm/pkg/work/spoke/statusfeedback"
 )
 
-const statusFeedbackConditionType = "StatusFeedbackSynced"
+const (
+	controllerName = "AvailableStatusController"
+
+	statusFeedbackConditionType = "StatusFeedbackSynced"
+
+	// TODO move this to the api repo
+	statusFeedbackTruncatedConditionType = "StatusFeedbackTruncated"
+
+	// maxDroppedValuesInMessage is the max number of the dropped feedback values listed in the condition message
+	maxDroppedValuesInMessage = 10
+)
 
 // AvailableStatusController is to update the available status conditions of both manifests and manifestworks.
 // It is also used to get the status value based on status feedback configuration in manifestwork. The two functions
 // are logically disinct, however, they are put in the same control loop to reduce live get call to spoke apiserver
 // and status update call to hub apiserver.
 type AvailableStatusController struct {
-	manifestWorkClient workv1client.ManifestWorkInterface
+	statusUpdater      *hubcache.StatusUpdater
 	manifestWorkLister worklister.ManifestWorkNamespaceLister
 	spokeDynamicClient dynamic.Interface
 	statusReader       *statusfeedback.StatusReader
+	relatedReader      *statusfeedback.RelatedResourcesReader
+	// validator checks whether the executor of the manifestwork is allowed to read the status references
+	validator auth.ExecutorValidator
+	// feedbackBudget is the max total size in bytes of the feedback values of a manifestwork
+	feedbackBudget int
+	hubName        string
 }
 
 // NewAvailableStatusController returns a AvailableStatusController
 func NewAvailableStatusController(
 	recorder events.Recorder,
 	spokeDynamicClient dynamic.Interface,
-	manifestWorkClient workv1client.ManifestWorkInterface,
+	statusUpdater *hubcache.StatusUpdater,
 	manifestWorkInformer workinformer.ManifestWorkInformer,
 	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	validator auth.ExecutorValidator,
 	syncInterval time.Duration,
+	feedbackBudget int,
+	hubName string,
 ) factory.Controller {
 	controller := &AvailableStatusController{
-		manifestWorkClient: manifestWorkClient,
+		statusUpdater:      statusUpdater,
 		manifestWorkLister: manifestWorkLister,
 		spokeDynamicClient: spokeDynamicClient,
 		statusReader:       statusfeedback.NewStatusReader(),
+		relatedReader:      statusfeedback.NewRelatedResourcesReader(spokeDynamicClient),
+		validator:          validator,
+		feedbackBudget:     feedbackBudget,
+		hubName:            hubName,
 	}
 
 	return factory.New().
@@ -63,7 +89,7 @@ func NewAvailableStatusController(
 			accessor, _ := meta.Accessor(obj)
 			return accessor.GetName()
 		}, manifestWorkInformer.Informer()).
-		WithSync(controller.sync).ResyncEvery(syncInterval).ToController("AvailableStatusController", recorder)
+		WithSync(controller.sync).ResyncEvery(syncInterval).ToController(helper.HubControllerName(controllerName, hubName), recorder)
 }
 
 func (c *AvailableStatusController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
@@ -103,6 +129,9 @@ func (c *AvailableStatusController) sync(ctx context.Context, controllerContext
 func (c *AvailableStatusController) syncManifestWork(ctx context.Context, originalManifestWork *workapiv1.ManifestWork) error {
 	klog.V(4).Infof("Reconciling ManifestWork %q", originalManifestWork.Name)
 	manifestWork := originalManifestWork.DeepCopy()
+	defer func(start time.Time) {
+		metrics.ObserveStatusFeedbackSync(c.hubName, time.Since(start))
+	}(time.Now())
 
 	// do nothing when finalizer is not added.
 	if !helper.HasFinalizer(manifestWork.Finalizers, controllers.ManifestWorkFinalizer) {
@@ -114,9 +143,17 @@ func (c *AvailableStatusController) syncManifestWork(ctx context.Context, origin
 		return nil
 	}
 
+	// an invalid related resources feedback annotation fails the status feedback of all the manifests
+	relatedRules, relatedRulesErr := helper.RelatedResourcesFeedbackRules(manifestWork)
+
 	// handle status condition of manifests
 	// TODO revist this controller since this might bring races when user change the manifests in spec.
 	for index, manifest := range manifestWork.Status.ResourceStatus.Manifests {
+		// the status references are synced separately
+		if helper.IsWatchOnly(manifest) {
+			continue
+		}
+
 		obj, availableStatusCondition, err := buildAvailableStatusCondition(manifest.ResourceMeta, c.spokeDynamicClient)
 		meta.SetStatusCondition(&manifestWork.Status.ResourceStatus.Manifests[index].Conditions, availableStatusCondition)
 		if err != nil {
@@ -125,11 +162,27 @@ func (c *AvailableStatusController) syncManifestWork(ctx context.Context, origin
 		}
 
 		// Read status of the resource according to feedback rules.
-		values, statusFeedbackCondition := c.getFeedbackValues(manifest.ResourceMeta, obj, manifestWork.Spec.ManifestConfigs)
+		values, statusFeedbackCondition := c.getFeedbackValues(ctx, manifest.ResourceMeta, obj, manifestWork.Spec.ManifestConfigs,
+			relatedRules, relatedRulesErr)
 		meta.SetStatusCondition(&manifestWork.Status.ResourceStatus.Manifests[index].Conditions, statusFeedbackCondition)
 		manifestWork.Status.ResourceStatus.Manifests[index].StatusFeedbacks.Values = values
 	}
 
+	// sync the status of the status references, the status of the references is kept unchanged if the annotation
+	// is invalid.
+	references, err := helper.StatusReferences(manifestWork)
+	if err != nil {
+		klog.Errorf("Failed to get the status references of manifestwork %s: %v", manifestWork.Name, err)
+	} else {
+		manifestWork.Status.ResourceStatus.Manifests = c.syncStatusReferences(ctx, manifestWork, references)
+	}
+
+	// drop the feedback values with the lowest priority if the feedback values exceed the budget, so the status
+	// update of the manifestwork does not fail because of the size of the manifestwork.
+	dropped := statusfeedback.TruncateFeedbackValues(manifestWork.Status.ResourceStatus.Manifests, c.feedbackBudget)
+	truncatedCondition := buildFeedbackTruncatedCondition(manifestWork.Generation, dropped, c.feedbackBudget)
+	setFeedbackTruncatedCondition(&manifestWork.Status.Conditions, truncatedCondition)
+
 	// aggregate ManifestConditions and update work status condition
 	workAvailableStatusCondition := aggregateManifestConditions(manifestWork.Generation, manifestWork.Status.ResourceStatus.Manifests)
 	meta.SetStatusCondition(&manifestWork.Status.Conditions, workAvailableStatusCondition)
@@ -140,14 +193,191 @@ func (c *AvailableStatusController) syncManifestWork(ctx context.Context, origin
 		return nil
 	}
 
-	// update status of manifestwork. if this conflicts, try again later
-	_, err := c.manifestWorkClient.UpdateStatus(ctx, manifestWork, metav1.UpdateOptions{})
+	// update status of manifestwork
+	_, _, err = c.statusUpdater.UpdateManifestWorkStatus(ctx, controllerName, originalManifestWork.DeepCopy(),
+		generateUpdateStatusFunc(manifestWork.Generation, manifestWork.Status.ResourceStatus.Manifests, truncatedCondition))
 	return err
 }
 
+// syncStatusReferences returns the manifest conditions of the manifestwork with the conditions of the status
+// references synced. The referenced resources are only read, a missing resource is reported with the Available
+// condition of the reference and does not fail the manifestwork.
+func (c *AvailableStatusController) syncStatusReferences(ctx context.Context, manifestWork *workapiv1.ManifestWork,
+	references []helper.StatusReference) []workapiv1.ManifestCondition {
+	executor, executorErr := helper.ManifestWorkExecutor(manifestWork)
+
+	synced := []workapiv1.ManifestCondition{}
+	existing := map[workapiv1.ResourceIdentifier]workapiv1.ManifestCondition{}
+	for _, manifest := range manifestWork.Status.ResourceStatus.Manifests {
+		if !helper.IsWatchOnly(manifest) {
+			synced = append(synced, manifest)
+			continue
+		}
+		existing[workapiv1.ResourceIdentifier{
+			Group:     manifest.ResourceMeta.Group,
+			Resource:  manifest.ResourceMeta.Resource,
+			Namespace: manifest.ResourceMeta.Namespace,
+			Name:      manifest.ResourceMeta.Name,
+		}] = manifest
+	}
+
+	for _, reference := range references {
+		condition := existing[reference.ResourceIdentifier()]
+		condition.ResourceMeta = workapiv1.ManifestResourceMeta{
+			Ordinal:   helper.WatchOnlyOrdinal,
+			Group:     reference.Group,
+			Version:   reference.Version,
+			Kind:      condition.ResourceMeta.Kind,
+			Resource:  reference.Resource,
+			Namespace: reference.Namespace,
+			Name:      reference.Name,
+		}
+
+		var obj *unstructured.Unstructured
+		availableStatusCondition, err := c.authorizeStatusReference(ctx, executor, executorErr, reference)
+		if err == nil {
+			obj, availableStatusCondition, err = buildAvailableStatusCondition(condition.ResourceMeta, c.spokeDynamicClient)
+		}
+		meta.SetStatusCondition(&condition.Conditions, availableStatusCondition)
+		if err != nil {
+			// the feedback values are stale if the resource is not available
+			meta.RemoveStatusCondition(&condition.Conditions, statusFeedbackConditionType)
+			condition.StatusFeedbacks.Values = nil
+			synced = append(synced, condition)
+			continue
+		}
+
+		condition.ResourceMeta.Kind = obj.GetKind()
+		values, statusFeedbackCondition := c.getFeedbackValues(ctx, condition.ResourceMeta, obj,
+			[]workapiv1.ManifestConfigOption{{ResourceIdentifier: reference.ResourceIdentifier(), FeedbackRules: reference.FeedbackRules}},
+			nil, nil)
+		meta.SetStatusCondition(&condition.Conditions, statusFeedbackCondition)
+		condition.StatusFeedbacks.Values = values
+		synced = append(synced, condition)
+	}
+
+	return synced
+}
+
+// authorizeStatusReference checks whether the executor of the manifestwork is allowed to read the referenced
+// resource, since the resource is read by the work agent on behalf of the manifestwork. The secrets can only be
+// referenced by a manifestwork with an executor. It returns the Available condition of the reference and an error
+// if the reference is not allowed.
+func (c *AvailableStatusController) authorizeStatusReference(ctx context.Context, executor *helper.Executor,
+	executorErr error, reference helper.StatusReference) (metav1.Condition, error) {
+	err := executorErr
+	switch {
+	case err != nil:
+	case executor == nil && len(reference.Group) == 0 && reference.Resource == "secrets":
+		err = fmt.Errorf("the secrets can only be referenced by the manifestwork with an executor")
+	default:
+		err = c.validator.ValidateRead(ctx, executor, schema.GroupVersionResource{
+			Group:    reference.Group,
+			Version:  reference.Version,
+			Resource: reference.Resource,
+		}, reference.Namespace, reference.Name)
+	}
+	if err != nil {
+		return metav1.Condition{
+			Type:    string(workapiv1.ManifestAvailable),
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ReadingResourceNotAllowed",
+			Message: fmt.Sprintf("Failed to read resource: %v", err),
+		}, err
+	}
+
+	return metav1.Condition{}, nil
+}
+
+// generateUpdateStatusFunc returns a func to set the available and status feedback conditions and the status
+// feedback values of the manifests, and the available and feedback truncated conditions of the manifestwork. The
+// manifests are matched by the resource meta, so the func could also be applied on a newer status of the manifestwork.
+func generateUpdateStatusFunc(generation int64, manifests []workapiv1.ManifestCondition,
+	truncatedCondition *metav1.Condition) helper.UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		// the status references are only maintained by this controller, replace them as a whole
+		synced := []workapiv1.ManifestCondition{}
+		for _, manifest := range status.ResourceStatus.Manifests {
+			if !helper.IsWatchOnly(manifest) {
+				synced = append(synced, manifest)
+			}
+		}
+		for _, updated := range manifests {
+			if helper.IsWatchOnly(updated) {
+				synced = append(synced, updated)
+			}
+		}
+		status.ResourceStatus.Manifests = synced
+
+		for index, manifest := range status.ResourceStatus.Manifests {
+			if helper.IsWatchOnly(manifest) {
+				continue
+			}
+			for _, updated := range manifests {
+				if updated.ResourceMeta != manifest.ResourceMeta {
+					continue
+				}
+				for _, conditionType := range []string{string(workapiv1.ManifestAvailable), statusFeedbackConditionType} {
+					if cond := meta.FindStatusCondition(updated.Conditions, conditionType); cond != nil {
+						meta.SetStatusCondition(&status.ResourceStatus.Manifests[index].Conditions, *cond)
+					}
+				}
+				status.ResourceStatus.Manifests[index].StatusFeedbacks = updated.StatusFeedbacks
+				break
+			}
+		}
+
+		meta.SetStatusCondition(&status.Conditions, aggregateManifestConditions(generation, status.ResourceStatus.Manifests))
+		setFeedbackTruncatedCondition(&status.Conditions, truncatedCondition)
+		return nil
+	}
+}
+
+// buildFeedbackTruncatedCondition returns the condition listing the dropped feedback values, it returns nil if no
+// feedback value is dropped.
+func buildFeedbackTruncatedCondition(generation int64, dropped []string, budget int) *metav1.Condition {
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	listed := dropped
+	if len(listed) > maxDroppedValuesInMessage {
+		listed = listed[:maxDroppedValuesInMessage]
+	}
+	message := fmt.Sprintf("%d status feedback values are dropped since the total size exceeds the budget of %d bytes: %s",
+		len(dropped), budget, strings.Join(listed, ", "))
+	if len(dropped) > len(listed) {
+		message = fmt.Sprintf("%s and %d more", message, len(dropped)-len(listed))
+	}
+
+	return &metav1.Condition{
+		Type:               statusFeedbackTruncatedConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             "FeedbackBudgetExceeded",
+		ObservedGeneration: generation,
+		Message:            message,
+	}
+}
+
+// setFeedbackTruncatedCondition sets the feedback truncated condition, or removes it if it is nil
+func setFeedbackTruncatedCondition(conditions *[]metav1.Condition, truncatedCondition *metav1.Condition) {
+	if truncatedCondition == nil {
+		meta.RemoveStatusCondition(conditions, statusFeedbackTruncatedConditionType)
+		return
+	}
+	meta.SetStatusCondition(conditions, *truncatedCondition)
+}
+
 // aggregateManifestConditions aggregates status conditions of manifests and returns a status
-// condition for manifestwork
-func aggregateManifestConditions(generation int64, manifests []workapiv1.ManifestCondition) metav1.Condition {
+// condition for manifestwork. The status references are not aggregated.
+func aggregateManifestConditions(generation int64, conditions []workapiv1.ManifestCondition) metav1.Condition {
+	manifests := []workapiv1.ManifestCondition{}
+	for _, manifest := range conditions {
+		if !helper.IsWatchOnly(manifest) {
+			manifests = append(manifests, manifest)
+		}
+	}
+
 	available, unavailable, unknown := 0, 0, 0
 	for _, manifest := range manifests {
 		for _, condition := range manifest.Conditions {
@@ -202,15 +432,17 @@ func aggregateManifestConditions(generation int64, manifests []workapiv1.Manifes
 	}
 }
 
-func (c *AvailableStatusController) getFeedbackValues(
+func (c *AvailableStatusController) getFeedbackValues(ctx context.Context,
 	resourceMeta workapiv1.ManifestResourceMeta, obj *unstructured.Unstructured,
-	manifestOptions []workapiv1.ManifestConfigOption) ([]workapiv1.FeedbackValue, metav1.Condition) {
+	manifestOptions []workapiv1.ManifestConfigOption,
+	relatedRules []helper.RelatedResourcesFeedbackRule, relatedRulesErr error) ([]workapiv1.FeedbackValue, metav1.Condition) {
 	errs := []error{}
 	values := []workapiv1.FeedbackValue{}
 
 	option := helper.FindManifestConiguration(resourceMeta, manifestOptions)
+	relatedRule := helper.FindRelatedResourcesFeedbackRule(resourceMeta, relatedRules)
 
-	if option == nil || len(option.FeedbackRules) == 0 {
+	if (option == nil || len(option.FeedbackRules) == 0) && relatedRule == nil && relatedRulesErr == nil {
 		return values, metav1.Condition{
 			Type:   statusFeedbackConditionType,
 			Reason: "NoStatusFeedbackSynced",
@@ -218,14 +450,28 @@ func (c *AvailableStatusController) getFeedbackValues(
 		}
 	}
 
-	for _, rule := range option.FeedbackRules {
-		valuesByRule, err := c.statusReader.GetValuesByRule(obj, rule)
+	if option != nil {
+		for _, rule := range option.FeedbackRules {
+			valuesByRule, err := c.statusReader.GetValuesByRule(obj, rule)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if len(valuesByRule) > 0 {
+				values = append(values, valuesByRule...)
+			}
+		}
+	}
+
+	if relatedRulesErr != nil {
+		errs = append(errs, relatedRulesErr)
+	}
+
+	if relatedRule != nil {
+		valuesByRule, err := c.relatedReader.GetValuesByRule(ctx, obj, *relatedRule)
 		if err != nil {
 			errs = append(errs, err)
 		}
-		if len(valuesByRule) > 0 {
-			values = append(values, valuesByRule...)
-		}
+		values = append(values, valuesByRule...)
 	}
 
 	err := utilerrors.NewAggregate(errs)
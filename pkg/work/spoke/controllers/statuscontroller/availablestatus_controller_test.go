@@ -2,20 +2,28 @@ package statuscontroller
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekube "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/utils/pointer"
 
 	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workapiv1 "open-cluster-management.io/api/work/v1"
 
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth/basic"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 	"open-cluster-management.io/ocm/pkg/work/spoke/statusfeedback"
 )
@@ -206,7 +214,8 @@ func TestSyncManifestWork(t *testing.T) {
 			fakeClient := fakeworkclient.NewSimpleClientset(testingWork)
 			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.existingResources...)
 			controller := AvailableStatusController{
-				manifestWorkClient: fakeClient.WorkV1().ManifestWorks(testingWork.Namespace),
+				statusUpdater: hubcache.NewStatusUpdater(fakeClient.WorkV1().ManifestWorks(testingWork.Namespace), nil,
+					hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))),
 				spokeDynamicClient: fakeDynamicClient,
 			}
 
@@ -225,6 +234,9 @@ func TestStatusFeedback(t *testing.T) {
 		existingResources []runtime.Object
 		configOption      []workapiv1.ManifestConfigOption
 		manifests         []workapiv1.ManifestCondition
+		feedbackBudget    int
+		annotations       map[string]string
+		executor          *workapiv1.ManifestWorkExecutor
 		validateActions   func(t *testing.T, actions []clienttesting.Action)
 	}{
 		{
@@ -317,6 +329,243 @@ func TestStatusFeedback(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "truncate feedback values exceeding the budget",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredWithContent("apps/v1", "Deployment", "ns1", "deploy1",
+					map[string]interface{}{
+						"status": map[string]interface{}{"readyReplicas": int64(2), "replicas": int64(3), "availableReplicas": int64(2)},
+					}),
+			},
+			configOption: []workapiv1.ManifestConfigOption{
+				{
+					ResourceIdentifier: workapiv1.ResourceIdentifier{Group: "apps", Resource: "deployments", Name: "deploy1", Namespace: "ns1"},
+					FeedbackRules:      []workapiv1.FeedbackRule{{Type: workapiv1.WellKnownStatusType}},
+				},
+			},
+			manifests: []workapiv1.ManifestCondition{
+				newManifest("apps", "v1", "deployments", "ns1", "deploy1"),
+			},
+			feedbackBudget: 100,
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				expectedValues := []workapiv1.FeedbackValue{
+					{
+						Name: "ReadyReplicas",
+						Value: workapiv1.FieldValue{
+							Type:    workapiv1.Integer,
+							Integer: pointer.Int64(2),
+						},
+					},
+				}
+				if !equality.Semantic.DeepEqual(work.Status.ResourceStatus.Manifests[0].StatusFeedbacks.Values, expectedValues) {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests[0].StatusFeedbacks.Values))
+				}
+				if !hasStatusCondition(work.Status.Conditions, statusFeedbackTruncatedConditionType, metav1.ConditionTrue) {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+				cond := meta.FindStatusCondition(work.Status.Conditions, statusFeedbackTruncatedConditionType)
+				if !strings.Contains(cond.Message, "deployments ns1/deploy1: AvailableReplicas, deployments ns1/deploy1: Replicas") {
+					t.Errorf("unexpected message %q", cond.Message)
+				}
+			},
+		},
+		{
+			name: "get related resources feedback",
+			existingResources: []runtime.Object{
+				func() runtime.Object {
+					deployment := spoketesting.NewUnstructuredWithContent("apps/v1", "Deployment", "ns1", "deploy1",
+						map[string]interface{}{"status": map[string]interface{}{"replicas": int64(1)}})
+					deployment.SetUID("deploy1-uid")
+					return deployment
+				}(),
+				func() runtime.Object {
+					pod := spoketesting.NewUnstructuredWithContent("v1", "Pod", "ns1", "pod1",
+						map[string]interface{}{"status": map[string]interface{}{"phase": "Running"}})
+					pod.SetOwnerReferences([]metav1.OwnerReference{{UID: "deploy1-uid"}})
+					return pod
+				}(),
+			},
+			annotations: map[string]string{helper.RelatedResourcesFeedbackAnnotationKey: `[{
+				"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"ns1","name":"deploy1"},
+				"relatedResources":[{"version":"v1","resource":"pods"}]}]`},
+			manifests: []workapiv1.ManifestCondition{
+				newManifest("apps", "v1", "deployments", "ns1", "deploy1"),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				expectedValues := []workapiv1.FeedbackValue{
+					{
+						Name: "pods.count",
+						Value: workapiv1.FieldValue{
+							Type:    workapiv1.Integer,
+							Integer: pointer.Int64(1),
+						},
+					},
+					{
+						Name: "pods.phase.Running",
+						Value: workapiv1.FieldValue{
+							Type:    workapiv1.Integer,
+							Integer: pointer.Int64(1),
+						},
+					},
+				}
+				if !equality.Semantic.DeepEqual(work.Status.ResourceStatus.Manifests[0].StatusFeedbacks.Values, expectedValues) {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests[0].StatusFeedbacks.Values))
+				}
+				if !hasStatusCondition(work.Status.ResourceStatus.Manifests[0].Conditions, statusFeedbackConditionType, metav1.ConditionTrue) {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests[0].Conditions))
+				}
+			},
+		},
+		{
+			name: "get status of status references",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1"),
+				spoketesting.NewUnstructuredWithContent("v1", "Node", "", "node1",
+					map[string]interface{}{
+						"status": map[string]interface{}{"phase": "Running"},
+					}),
+			},
+			annotations: map[string]string{helper.StatusReferencesAnnotationKey: `[
+				{"version":"v1","resource":"nodes","name":"node1","feedbackRules":[{"type":"JSONPaths","jsonPaths":[{"name":"phase","path":".status.phase"}]}]},
+				{"group":"cert-manager.io","version":"v1","resource":"certificates","namespace":"ns1","name":"cert1"}
+			]`},
+			manifests: []workapiv1.ManifestCondition{
+				newManifest("", "v1", "secrets", "ns1", "n1"),
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				if len(work.Status.ResourceStatus.Manifests) != 3 {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests))
+				}
+
+				node := work.Status.ResourceStatus.Manifests[1]
+				if node.ResourceMeta.Ordinal != helper.WatchOnlyOrdinal || node.ResourceMeta.Kind != "Node" {
+					t.Fatal(spew.Sdump(node.ResourceMeta))
+				}
+				expectedValues := []workapiv1.FeedbackValue{
+					{
+						Name: "phase",
+						Value: workapiv1.FieldValue{
+							Type:   workapiv1.String,
+							String: pointer.String("Running"),
+						},
+					},
+				}
+				if !equality.Semantic.DeepEqual(node.StatusFeedbacks.Values, expectedValues) {
+					t.Fatal(spew.Sdump(node.StatusFeedbacks.Values))
+				}
+				if !hasStatusCondition(node.Conditions, string(workapiv1.ManifestAvailable), metav1.ConditionTrue) {
+					t.Fatal(spew.Sdump(node.Conditions))
+				}
+
+				cert := work.Status.ResourceStatus.Manifests[2]
+				if !hasStatusCondition(cert.Conditions, string(workapiv1.ManifestAvailable), metav1.ConditionFalse) {
+					t.Fatal(spew.Sdump(cert.Conditions))
+				}
+
+				// the missing referenced resource does not fail the manifestwork
+				if !hasStatusCondition(work.Status.Conditions, workapiv1.WorkAvailable, metav1.ConditionTrue) {
+					t.Fatal(spew.Sdump(work.Status.Conditions))
+				}
+			},
+		},
+		{
+			name: "reject the secrets referenced by the work without executor",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1"),
+			},
+			annotations: map[string]string{helper.StatusReferencesAnnotationKey: `[
+				{"version":"v1","resource":"secrets","namespace":"ns1","name":"n1","feedbackRules":[{"type":"JSONPaths","jsonPaths":[{"name":"uid","path":".metadata.uid"}]}]}
+			]`},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				if len(work.Status.ResourceStatus.Manifests) != 1 {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests))
+				}
+
+				secret := work.Status.ResourceStatus.Manifests[0]
+				if !hasStatusCondition(secret.Conditions, string(workapiv1.ManifestAvailable), metav1.ConditionUnknown) {
+					t.Fatal(spew.Sdump(secret.Conditions))
+				}
+				if len(secret.StatusFeedbacks.Values) != 0 {
+					t.Fatal(spew.Sdump(secret.StatusFeedbacks.Values))
+				}
+			},
+		},
+		{
+			name: "get status of status references allowed for the executor",
+			existingResources: []runtime.Object{
+				spoketesting.NewUnstructuredSecret("ns1", "n1", false, "ns1-n1"),
+				spoketesting.NewUnstructuredWithContent("v1", "Node", "", "node1",
+					map[string]interface{}{
+						"status": map[string]interface{}{"phase": "Running"},
+					}),
+			},
+			annotations: map[string]string{helper.StatusReferencesAnnotationKey: `[
+				{"version":"v1","resource":"secrets","namespace":"ns1","name":"n1","feedbackRules":[{"type":"JSONPaths","jsonPaths":[{"name":"uid","path":".metadata.uid"}]}]},
+				{"version":"v1","resource":"nodes","name":"node1","feedbackRules":[{"type":"JSONPaths","jsonPaths":[{"name":"phase","path":".status.phase"}]}]}
+			]`},
+			executor: &workapiv1.ManifestWorkExecutor{
+				Subject: workapiv1.ManifestWorkExecutorSubject{
+					Type: workapiv1.ExecutorSubjectTypeServiceAccount,
+					ServiceAccount: &workapiv1.ManifestWorkSubjectServiceAccount{
+						Namespace: "ns1",
+						Name:      "sa1",
+					},
+				},
+			},
+			validateActions: func(t *testing.T, actions []clienttesting.Action) {
+				if len(actions) != 1 {
+					t.Fatal(spew.Sdump(actions))
+				}
+
+				work := actions[0].(clienttesting.UpdateAction).GetObject().(*workapiv1.ManifestWork)
+				if len(work.Status.ResourceStatus.Manifests) != 2 {
+					t.Fatal(spew.Sdump(work.Status.ResourceStatus.Manifests))
+				}
+
+				secret := work.Status.ResourceStatus.Manifests[0]
+				expectedValues := []workapiv1.FeedbackValue{
+					{
+						Name: "uid",
+						Value: workapiv1.FieldValue{
+							Type:   workapiv1.String,
+							String: pointer.String("ns1-n1"),
+						},
+					},
+				}
+				if !equality.Semantic.DeepEqual(secret.StatusFeedbacks.Values, expectedValues) {
+					t.Fatal(spew.Sdump(secret.StatusFeedbacks.Values))
+				}
+
+				// the executor is only allowed to read the resources in the namespace ns1
+				node := work.Status.ResourceStatus.Manifests[1]
+				if !hasStatusCondition(node.Conditions, string(workapiv1.ManifestAvailable), metav1.ConditionUnknown) {
+					t.Fatal(spew.Sdump(node.Conditions))
+				}
+				if len(node.StatusFeedbacks.Values) != 0 {
+					t.Fatal(spew.Sdump(node.StatusFeedbacks.Values))
+				}
+			},
+		},
 		{
 			name: "get wrong json path",
 			existingResources: []runtime.Object{
@@ -384,6 +633,8 @@ func TestStatusFeedback(t *testing.T) {
 			testingWork, _ := spoketesting.NewManifestWork(0)
 			testingWork.Finalizers = []string{controllers.ManifestWorkFinalizer}
 			testingWork.Spec.ManifestConfigs = c.configOption
+			testingWork.Annotations = c.annotations
+			testingWork.Spec.Executor = c.executor
 			testingWork.Status = workapiv1.ManifestWorkStatus{
 				ResourceStatus: workapiv1.ManifestResourceStatus{
 					Manifests: c.manifests,
@@ -395,10 +646,25 @@ func TestStatusFeedback(t *testing.T) {
 
 			fakeClient := fakeworkclient.NewSimpleClientset(testingWork)
 			fakeDynamicClient := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), c.existingResources...)
+			// the executors are only allowed to read the resources in the namespace ns1
+			fakeKubeClient := fakekube.NewSimpleClientset()
+			fakeKubeClient.PrependReactor("create", "subjectaccessreviews",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					obj := action.(clienttesting.CreateActionImpl).Object.(*authorizationv1.SubjectAccessReview)
+					allowed := obj.Spec.ResourceAttributes.Namespace == "ns1" && obj.Spec.ResourceAttributes.Verb == "get"
+					return true, &authorizationv1.SubjectAccessReview{
+						Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed, Denied: !allowed},
+					}, nil
+				},
+			)
 			controller := AvailableStatusController{
-				manifestWorkClient: fakeClient.WorkV1().ManifestWorks(testingWork.Namespace),
+				statusUpdater: hubcache.NewStatusUpdater(fakeClient.WorkV1().ManifestWorks(testingWork.Namespace), nil,
+					hubcache.NewHubConnection(eventstesting.NewTestingEventRecorder(t))),
 				spokeDynamicClient: fakeDynamicClient,
 				statusReader:       statusfeedback.NewStatusReader(),
+				relatedReader:      statusfeedback.NewRelatedResourcesReader(fakeDynamicClient),
+				validator:          basic.NewSARValidator(nil, fakeKubeClient),
+				feedbackBudget:     c.feedbackBudget,
 			}
 
 			err := controller.syncManifestWork(context.TODO(), testingWork)
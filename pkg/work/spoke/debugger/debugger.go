@@ -0,0 +1,222 @@
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/auth"
+)
+
+// DebugPath is the path of the debug endpoint of the default hub, the name of the manifestwork is appended to the
+// path. The endpoint is served by the secure server of the agent, so the requests are authenticated and authorized
+// with the nonResourceURLs permission of the path.
+const DebugPath = "/debug/manifestworks/"
+
+// HubDebugPath returns the path of the debug endpoint of the hub with the hubName, it is DebugPath for the default
+// hub whose name is empty, and /debug/hubs/<hub name>/manifestworks/ for an additional hub.
+func HubDebugPath(hubName string) string {
+	if len(hubName) == 0 {
+		return DebugPath
+	}
+	return fmt.Sprintf("/debug/hubs/%s/manifestworks/", hubName)
+}
+
+// ValidateQueryParameter is the query parameter to validate the executor permissions of the manifests live, e.g.
+// ?validate=true. Otherwise only the cached executor permissions are reported, since the validation sends subject
+// access reviews to the managed cluster and fills the caches.
+const ValidateQueryParameter = "validate"
+
+const (
+	// owner reference decisions of the applied resources
+	OwnerReferenceAdded   = "Added"
+	OwnerReferenceRemoved = "Removed"
+
+	// cache states of the executor permissions
+	ExecutorCacheAllowed   = "Allowed"
+	ExecutorCacheDenied    = "Denied"
+	ExecutorCacheNotCached = "NotCached"
+	ExecutorCacheDisabled  = "Disabled"
+
+	executorValidationAllowed = "Allowed"
+)
+
+// Debugger provides a debug http endpoint to explain how the agent applies a manifestwork
+type Debugger struct {
+	path               string
+	manifestWorkLister worklister.ManifestWorkNamespaceLister
+	restMapper         meta.RESTMapper
+	validator          auth.ExecutorValidator
+}
+
+// DebugResult is the result returned by debugger
+type DebugResult struct {
+	// Executor is the executor resolved from the manifestwork
+	Executor  *helper.Executor      `json:"executor,omitempty"`
+	Manifests []ManifestDebugResult `json:"manifests,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// ManifestDebugResult is the debug result of a manifest
+type ManifestDebugResult struct {
+	ResourceMeta workapiv1.ManifestResourceMeta `json:"resourceMeta"`
+	// ManifestConfig is the manifest config option matching the manifest
+	ManifestConfig *workapiv1.ManifestConfigOption `json:"manifestConfig,omitempty"`
+	// Applier is the update strategy of the applier chosen to apply the manifest
+	Applier workapiv1.UpdateStrategyType `json:"applier,omitempty"`
+	// OwnedByTheWork is whether the resource is deleted with the manifestwork
+	OwnedByTheWork bool `json:"ownedByTheWork"`
+	// OwnerReference is whether the owner reference of the appliedmanifestwork is added to or removed from the
+	// resource
+	OwnerReference string `json:"ownerReference,omitempty"`
+	// ExecutorValidation is Allowed if the executor is allowed to apply the manifest, otherwise the validation error.
+	// It is only set if the validation is requested with the ValidateQueryParameter.
+	ExecutorValidation string `json:"executorValidation,omitempty"`
+	// ExecutorCache is the cached permission of the executor before the validation
+	ExecutorCache string `json:"executorCache,omitempty"`
+	// LastApplyError is the error of the last apply reported in the status of the manifestwork
+	LastApplyError string `json:"lastApplyError,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// NewDebugger returns a Debugger of the manifestworks of the hub with the hubName, which is empty for the default hub
+func NewDebugger(
+	hubName string,
+	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	restMapper meta.RESTMapper,
+	validator auth.ExecutorValidator) *Debugger {
+	return &Debugger{
+		path:               HubDebugPath(hubName),
+		manifestWorkLister: manifestWorkLister,
+		restMapper:         restMapper,
+		validator:          validator,
+	}
+}
+
+// Path returns the path the Handler should be registered with
+func (d *Debugger) Path() string {
+	return d.path
+}
+
+func (d *Debugger) Handler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, d.path)
+	if len(name) == 0 || strings.Contains(name, "/") {
+		d.reportErr(w, fmt.Errorf("invalid manifestwork name %q", name))
+		return
+	}
+
+	manifestWork, err := d.manifestWorkLister.Get(name)
+	if err != nil {
+		d.reportErr(w, err)
+		return
+	}
+
+	executor, err := helper.ManifestWorkExecutor(manifestWork)
+	if err != nil {
+		d.reportErr(w, err)
+		return
+	}
+
+	validate, _ := strconv.ParseBool(r.URL.Query().Get(ValidateQueryParameter))
+
+	result := DebugResult{Executor: executor}
+	for index, manifest := range manifestWork.Spec.Workload.Manifests {
+		result.Manifests = append(result.Manifests, d.debugManifest(r, index, manifest, manifestWork, executor, validate))
+	}
+
+	resultByte, _ := json.Marshal(result)
+
+	_, _ = w.Write(resultByte)
+}
+
+// debugManifest explains how the manifest is applied in the same way as the manifest controller, except that the
+// manifest is not applied. The executor permission is only validated if validate is true.
+func (d *Debugger) debugManifest(r *http.Request, index int, manifest workapiv1.Manifest,
+	manifestWork *workapiv1.ManifestWork, executor *helper.Executor, validate bool) ManifestDebugResult {
+	result := ManifestDebugResult{}
+
+	required := &unstructured.Unstructured{}
+	if err := required.UnmarshalJSON(manifest.Raw); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	resMeta, gvr, err := helper.BuildResourceMeta(index, required, d.restMapper)
+	result.ResourceMeta = resMeta
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.LastApplyError = lastApplyError(manifestWork, resMeta)
+
+	result.ManifestConfig = helper.FindManifestConiguration(resMeta, manifestWork.Spec.ManifestConfigs)
+	result.Applier = workapiv1.UpdateStrategyTypeUpdate
+	if result.ManifestConfig != nil && result.ManifestConfig.UpdateStrategy != nil {
+		result.Applier = result.ManifestConfig.UpdateStrategy.Type
+	}
+
+	result.OwnedByTheWork = helper.OwnedByTheWork(gvr, resMeta.Namespace, resMeta.Name, manifestWork.Spec.DeleteOption)
+	result.OwnerReference = OwnerReferenceRemoved
+	if result.OwnedByTheWork {
+		result.OwnerReference = OwnerReferenceAdded
+	}
+
+	// read the caches before the validation, since the validation updates the caches
+	result.ExecutorCache = ExecutorCacheDisabled
+	if inspector, ok := d.validator.(auth.ExecutorCachesInspector); ok {
+		allowed, cached := inspector.CachedPermission(executor, gvr, resMeta.Namespace, resMeta.Name, result.OwnedByTheWork)
+		switch {
+		case !cached:
+			result.ExecutorCache = ExecutorCacheNotCached
+		case allowed:
+			result.ExecutorCache = ExecutorCacheAllowed
+		default:
+			result.ExecutorCache = ExecutorCacheDenied
+		}
+	}
+
+	if !validate {
+		return result
+	}
+
+	result.ExecutorValidation = executorValidationAllowed
+	if err := d.validator.Validate(r.Context(), executor, gvr, resMeta.Namespace, resMeta.Name,
+		result.OwnedByTheWork, required); err != nil {
+		result.ExecutorValidation = err.Error()
+	}
+
+	return result
+}
+
+// lastApplyError returns the message of the Applied condition of the manifest if the last apply fails
+func lastApplyError(manifestWork *workapiv1.ManifestWork, resMeta workapiv1.ManifestResourceMeta) string {
+	for _, manifest := range manifestWork.Status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta != resMeta {
+			continue
+		}
+
+		condition := meta.FindStatusCondition(manifest.Conditions, string(workapiv1.ManifestApplied))
+		if condition != nil && condition.Status == metav1.ConditionFalse {
+			return condition.Message
+		}
+	}
+	return ""
+}
+
+func (d *Debugger) reportErr(w http.ResponseWriter, err error) {
+	result := &DebugResult{Error: err.Error()}
+
+	resultByte, _ := json.Marshal(result)
+
+	_, _ = w.Write(resultByte)
+}
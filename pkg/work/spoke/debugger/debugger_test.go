@@ -0,0 +1,189 @@
+package debugger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+// testValidator denies the resources in the namespace denied, and caches nothing but the denied resources
+type testValidator struct{}
+
+func (v *testValidator) Validate(_ context.Context, _ *helper.Executor, _ schema.GroupVersionResource,
+	namespace, _ string, _ bool, _ *unstructured.Unstructured) error {
+	if namespace == "denied" {
+		return fmt.Errorf("not allowed")
+	}
+	return nil
+}
+
+func (v *testValidator) ValidateRead(_ context.Context, _ *helper.Executor, _ schema.GroupVersionResource,
+	namespace, _ string) error {
+	if namespace == "denied" {
+		return fmt.Errorf("not allowed")
+	}
+	return nil
+}
+
+func (v *testValidator) CachedPermission(_ *helper.Executor, _ schema.GroupVersionResource,
+	namespace, _ string, _ bool) (bool, bool) {
+	return false, namespace == "denied"
+}
+
+func TestDebugger(t *testing.T) {
+	work, _ := spoketesting.NewManifestWork(0,
+		spoketesting.NewUnstructured("v1", "Secret", "allowed", "test"),
+		spoketesting.NewUnstructured("v1", "Secret", "denied", "test"),
+	)
+	work.Spec.ManifestConfigs = []workapiv1.ManifestConfigOption{
+		{
+			ResourceIdentifier: workapiv1.ResourceIdentifier{Resource: "secrets", Namespace: "allowed", Name: "test"},
+			UpdateStrategy:     &workapiv1.UpdateStrategy{Type: workapiv1.UpdateStrategyTypeServerSideApply},
+		},
+	}
+	work.Spec.DeleteOption = &workapiv1.DeleteOption{
+		PropagationPolicy: workapiv1.DeletePropagationPolicyTypeSelectivelyOrphan,
+		SelectivelyOrphan: &workapiv1.SelectivelyOrphan{
+			OrphaningRules: []workapiv1.OrphaningRule{{Resource: "secrets", Namespace: "denied", Name: "test"}},
+		},
+	}
+	work.Status.ResourceStatus.Manifests = []workapiv1.ManifestCondition{
+		{
+			ResourceMeta: workapiv1.ManifestResourceMeta{
+				Ordinal: 1, Version: "v1", Kind: "Secret", Resource: "secrets", Namespace: "denied", Name: "test",
+			},
+			Conditions: []metav1.Condition{
+				{Type: string(workapiv1.ManifestApplied), Status: metav1.ConditionFalse, Message: "not allowed"},
+			},
+		},
+	}
+
+	workClient := fakeworkclient.NewSimpleClientset(work)
+	informerFactory := workinformers.NewSharedInformerFactory(workClient, 5*time.Minute)
+	if err := informerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+		t.Fatal(err)
+	}
+
+	debugger := NewDebugger(
+		"",
+		informerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks("cluster1"),
+		spoketesting.NewFakeRestMapper(),
+		&testValidator{},
+	)
+	server := httptest.NewServer(http.HandlerFunc(debugger.Handler))
+	defer server.Close()
+
+	cases := []struct {
+		name              string
+		workName          string
+		query             string
+		expectedErr       bool
+		expectedManifests []ManifestDebugResult
+	}{
+		{
+			name:     "explain the manifests with the cached permissions",
+			workName: work.Name,
+			expectedManifests: []ManifestDebugResult{
+				{
+					Applier:        workapiv1.UpdateStrategyTypeServerSideApply,
+					OwnedByTheWork: true,
+					OwnerReference: OwnerReferenceAdded,
+					ExecutorCache:  ExecutorCacheNotCached,
+				},
+				{
+					Applier:        workapiv1.UpdateStrategyTypeUpdate,
+					OwnerReference: OwnerReferenceRemoved,
+					ExecutorCache:  ExecutorCacheDenied,
+					LastApplyError: "not allowed",
+				},
+			},
+		},
+		{
+			name:     "explain the manifests with the validation",
+			workName: work.Name,
+			query:    "?" + ValidateQueryParameter + "=true",
+			expectedManifests: []ManifestDebugResult{
+				{
+					Applier:            workapiv1.UpdateStrategyTypeServerSideApply,
+					OwnedByTheWork:     true,
+					OwnerReference:     OwnerReferenceAdded,
+					ExecutorValidation: executorValidationAllowed,
+					ExecutorCache:      ExecutorCacheNotCached,
+				},
+				{
+					Applier:            workapiv1.UpdateStrategyTypeUpdate,
+					OwnerReference:     OwnerReferenceRemoved,
+					ExecutorValidation: "not allowed",
+					ExecutorCache:      ExecutorCacheDenied,
+					LastApplyError:     "not allowed",
+				},
+			},
+		},
+		{
+			name:        "manifestwork not found",
+			workName:    "missing",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s%s", server.URL, DebugPath, c.workName, c.query))
+			if err != nil {
+				t.Fatalf("Expect no error but get %v", err)
+			}
+			defer res.Body.Close()
+
+			responseBody, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("Unexpected error reading response body: %v", err)
+			}
+
+			result := &DebugResult{}
+			if err := json.Unmarshal(responseBody, result); err != nil {
+				t.Fatalf("Unexpected error unmarshaling result: %v", err)
+			}
+
+			if (len(result.Error) > 0) != c.expectedErr {
+				t.Fatalf("Expect error %v, but got %q", c.expectedErr, result.Error)
+			}
+			if len(result.Manifests) != len(c.expectedManifests) {
+				t.Fatalf("Expect %d manifests, but got %v", len(c.expectedManifests), result.Manifests)
+			}
+			for i, expected := range c.expectedManifests {
+				actual := result.Manifests[i]
+				if actual.Applier != expected.Applier || actual.OwnedByTheWork != expected.OwnedByTheWork ||
+					actual.OwnerReference != expected.OwnerReference ||
+					actual.ExecutorValidation != expected.ExecutorValidation ||
+					actual.ExecutorCache != expected.ExecutorCache || actual.LastApplyError != expected.LastApplyError {
+					t.Errorf("Expect manifest %d to be %+v, but got %+v", i, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestHubDebugPath(t *testing.T) {
+	if path := HubDebugPath(""); path != DebugPath {
+		t.Errorf("expected the path of the default hub is %q, but got %q", DebugPath, path)
+	}
+	if path := HubDebugPath("0123abcd"); path != "/debug/hubs/0123abcd/manifestworks/" {
+		t.Errorf("unexpected path of the additional hub %q", path)
+	}
+}
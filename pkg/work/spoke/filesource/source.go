@@ -0,0 +1,342 @@
+package filesource
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/webhook/common"
+)
+
+// FileSource serves the manifestworks read from the files of a local directory, e.g. a mounted configmap, as
+// the hub of the work agent, so the manifestworks are reconciled by the same controllers without a hub.
+//
+// The manifestworks are kept in an in-memory store which behaves as the hub apiserver for the controllers, and
+// the status of each manifestwork is written back to a file in the status directory.
+type FileSource struct {
+	dir       string
+	statusDir string
+	namespace string
+	store     *manifestWorkStore
+
+	lock sync.Mutex
+	// statuses is the content of the status files written, the key is the name of the manifestwork
+	statuses map[string][]byte
+}
+
+// NewFileSource returns a FileSource which reads the manifestworks from the files in the dir and writes their
+// status into the files in the statusDir, the status is not written if statusDir is empty. The manifestworks
+// are put into the namespace, which is the name of the managed cluster.
+func NewFileSource(dir, statusDir, namespace string) *FileSource {
+	return &FileSource{
+		dir:       dir,
+		statusDir: statusDir,
+		namespace: namespace,
+		store:     newManifestWorkStore(),
+		statuses:  map[string][]byte{},
+	}
+}
+
+// Hash returns the hash identifying the file source as a hub
+func (s *FileSource) Hash() string {
+	return helper.HubHash("file://" + s.dir)
+}
+
+// WorkClient returns the work client serving the manifestworks read from the files
+func (s *FileSource) WorkClient() workv1client.ManifestWorksGetter {
+	return s.store
+}
+
+// Run syncs the manifestworks with the files every interval until the context is done.
+func (s *FileSource) Run(ctx context.Context, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.Sync(ctx); err != nil {
+			klog.Errorf("Failed to sync the manifestworks with the files in %s: %v", s.dir, err)
+		}
+	}, interval)
+}
+
+// Sync creates, updates and deletes the manifestworks according to the files, and writes the status of the
+// manifestworks into the status files. The manifestworks are not changed if any of the files is invalid, so
+// a manifestwork is never deleted because its file is being edited.
+func (s *FileSource) Sync(ctx context.Context) error {
+	required, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	works, err := s.store.ManifestWorks(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	existing := map[string]bool{}
+	for i := range works.Items {
+		work := &works.Items[i]
+		existing[work.Name] = true
+		if err := s.syncManifestWork(ctx, work, required[work.Name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for name, work := range required {
+		if existing[name] {
+			continue
+		}
+		if _, err := s.store.ManifestWorks(s.namespace).Create(ctx, work, metav1.CreateOptions{}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := s.writeStatus(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncManifestWork updates the manifestwork with the required one read from the files, or deletes it if it is
+// not required anymore.
+func (s *FileSource) syncManifestWork(ctx context.Context, work, required *workapiv1.ManifestWork) error {
+	// the deleting manifestwork is recreated once it is removed
+	if !work.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	if required == nil {
+		return s.store.ManifestWorks(s.namespace).Delete(ctx, work.Name, metav1.DeleteOptions{})
+	}
+
+	if equality.Semantic.DeepEqual(work.Spec, required.Spec) &&
+		equality.Semantic.DeepEqual(work.Labels, required.Labels) &&
+		equality.Semantic.DeepEqual(work.Annotations, required.Annotations) {
+		return nil
+	}
+
+	work = work.DeepCopy()
+	work.Labels = required.Labels
+	work.Annotations = required.Annotations
+	work.Spec = required.Spec
+	_, err := s.store.ManifestWorks(s.namespace).Update(ctx, work, metav1.UpdateOptions{})
+	return err
+}
+
+// load reads the manifestworks from the yaml or json files in the dir, a file could contain multiple
+// manifestworks separated by "---". The hidden files and the sub directories are ignored, so the files of a
+// mounted configmap are read only once.
+func (s *FileSource) load() (map[string]*workapiv1.ManifestWork, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	works := map[string]*workapiv1.ManifestWork{}
+	files := map[string]string{}
+	errs := []error{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || !isManifestWorkFile(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(s.dir, entry.Name())
+		// follow the symlinks of the mounted configmap
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+
+		fileWorks, err := readManifestWorks(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read the manifestworks from %s: %w", path, err))
+			continue
+		}
+
+		for _, work := range fileWorks {
+			if file, ok := files[work.Name]; ok {
+				errs = append(errs, fmt.Errorf("the manifestwork %s in %s is duplicated with the one in %s", work.Name, path, file))
+				continue
+			}
+			if err := validate(work); err != nil {
+				errs = append(errs, fmt.Errorf("the manifestwork %s in %s is invalid: %w", work.Name, path, err))
+				continue
+			}
+			files[work.Name] = path
+			works[work.Name] = &workapiv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        work.Name,
+					Namespace:   s.namespace,
+					Labels:      work.Labels,
+					Annotations: work.Annotations,
+				},
+				Spec: work.Spec,
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return works, nil
+}
+
+func isManifestWorkFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func readManifestWorks(path string) ([]*workapiv1.ManifestWork, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	works := []*workapiv1.ManifestWork{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		work := &workapiv1.ManifestWork{}
+		err := decoder.Decode(work)
+		if err == io.EOF {
+			return works, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// skip the empty documents
+		if len(work.Kind) == 0 && len(work.Name) == 0 {
+			continue
+		}
+		if work.GroupVersionKind() != workapiv1.SchemeGroupVersion.WithKind("ManifestWork") {
+			return nil, fmt.Errorf("the kind %s is not a ManifestWork", work.GroupVersionKind())
+		}
+		works = append(works, work)
+	}
+}
+
+// validate validates the manifestwork in the same way as the webhook on the hub
+func validate(work *workapiv1.ManifestWork) error {
+	if len(work.Name) == 0 {
+		return fmt.Errorf("the name is empty")
+	}
+	if err := common.ManifestValidator.ValidateManifests(work.Spec.Workload.Manifests); err != nil {
+		return err
+	}
+	if _, err := helper.RelatedResourcesFeedbackRules(work); err != nil {
+		return err
+	}
+	if _, err := helper.StatusReferences(work); err != nil {
+		return err
+	}
+	if err := helper.ValidateDeletionConfirmation(work); err != nil {
+		return err
+	}
+	if err := helper.ValidatePaused(work); err != nil {
+		return err
+	}
+	_, err := helper.MaintenanceWindowOf(work, nil)
+	return err
+}
+
+// writeStatus writes the status of each manifestwork into the file <name>.yaml in the status dir, and removes
+// the status files of the manifestworks which are removed.
+func (s *FileSource) writeStatus(ctx context.Context) error {
+	if len(s.statusDir) == 0 {
+		return nil
+	}
+
+	works, err := s.store.ManifestWorks(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	errs := []error{}
+	names := map[string]bool{}
+	for i := range works.Items {
+		work := works.Items[i]
+		names[work.Name] = true
+
+		data, err := yaml.Marshal(&workapiv1.ManifestWork{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: workapiv1.SchemeGroupVersion.String(),
+				Kind:       "ManifestWork",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       work.Name,
+				Namespace:  work.Namespace,
+				Generation: work.Generation,
+			},
+			Status: work.Status,
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if bytes.Equal(s.statuses[work.Name], data) {
+			continue
+		}
+		if err := writeFile(filepath.Join(s.statusDir, work.Name+".yaml"), data); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.statuses[work.Name] = data
+	}
+
+	removed := []string{}
+	for name := range s.statuses {
+		if !names[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		if err := os.Remove(filepath.Join(s.statusDir, name+".yaml")); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.statuses, name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// writeFile writes the data into a temporary file and renames it to the path, so the readers never see a
+// partially written file.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,189 @@
+package filesource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	work1 = `apiVersion: work.open-cluster-management.io/v1
+kind: ManifestWork
+metadata:
+  name: work1
+spec:
+  workload:
+    manifests:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cm1
+        namespace: default
+`
+	work2 = `apiVersion: work.open-cluster-management.io/v1
+kind: ManifestWork
+metadata:
+  name: work2
+spec:
+  workload:
+    manifests:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cm2
+        namespace: default
+`
+	work1Updated = `apiVersion: work.open-cluster-management.io/v1
+kind: ManifestWork
+metadata:
+  name: work1
+spec:
+  workload:
+    manifests:
+    - apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: cm1
+        namespace: default
+      data:
+        key: value
+`
+)
+
+func TestFileSource(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+	statusDir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "works.yaml"), work1+"---\n"+work2)
+	writeTestFile(t, filepath.Join(dir, "README.md"), "not a manifestwork")
+	// the hidden files of a mounted configmap are ignored
+	writeTestFile(t, filepath.Join(dir, ".hidden.yaml"), work1)
+
+	source := NewFileSource(dir, statusDir, "cluster1")
+	client := source.WorkClient().ManifestWorks("cluster1")
+	if err := source.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	works, err := client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works.Items) != 2 {
+		t.Fatalf("expected 2 manifestworks, but got %d", len(works.Items))
+	}
+	work, err := client.Get(ctx, "work1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Generation != 1 || len(work.UID) == 0 || len(work.ResourceVersion) == 0 {
+		t.Errorf("expected the manifestwork is created as the apiserver does, but got %v", work.ObjectMeta)
+	}
+
+	// the status is only updated with the status subresource, and written into the status file
+	stale := work.DeepCopy()
+	work.Finalizers = []string{"test"}
+	work.Status.Conditions = []metav1.Condition{{Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, Reason: "Applied"}}
+	work, err = client.Update(ctx, work, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(work.Status.Conditions) != 0 || len(work.Finalizers) != 1 {
+		t.Errorf("expected only the finalizers are updated, but got %v", work)
+	}
+	work.Status.Conditions = []metav1.Condition{{Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, Reason: "Applied"}}
+	work, err = client.UpdateStatus(ctx, work, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Update(ctx, stale, metav1.UpdateOptions{}); !errors.IsConflict(err) {
+		t.Errorf("expected a conflict error, but got %v", err)
+	}
+
+	if err := source.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	status := readStatus(t, filepath.Join(statusDir, "work1.yaml"))
+	if !meta.IsStatusConditionTrue(status.Status.Conditions, workapiv1.WorkApplied) || status.Generation != 1 {
+		t.Errorf("expected the status is written into the file, but got %v", status)
+	}
+
+	// the generation is increased once the spec changes
+	writeTestFile(t, filepath.Join(dir, "works.yaml"), work1Updated+"---\n"+work2)
+	if err := source.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	work, err = client.Get(ctx, "work1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Generation != 2 || len(work.Status.Conditions) != 1 {
+		t.Errorf("expected the generation is increased and the status is kept, but got %v", work)
+	}
+
+	// the manifestworks are not changed if any of the files is invalid
+	writeTestFile(t, filepath.Join(dir, "invalid.yaml"), "kind: ConfigMap")
+	if err := source.Sync(ctx); err == nil {
+		t.Errorf("expected an error for the invalid file")
+	}
+	if err := os.Remove(filepath.Join(dir, "invalid.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the manifestwork is deleting until its finalizers are removed
+	writeTestFile(t, filepath.Join(dir, "works.yaml"), work2)
+	if err := source.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	work, err = client.Get(ctx, "work1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.DeletionTimestamp.IsZero() {
+		t.Errorf("expected the manifestwork is deleting")
+	}
+
+	work.Finalizers = nil
+	if _, err := client.Update(ctx, work, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "work1", metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the manifestwork is removed, but got %v", err)
+	}
+
+	if err := source.Sync(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(statusDir, "work1.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected the status file is removed, but got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(statusDir, "work2.yaml")); err != nil {
+		t.Errorf("expected the status file of work2, but got %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, data string) {
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readStatus(t *testing.T, path string) *workapiv1.ManifestWork {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := &workapiv1.ManifestWork{}
+	if err := yaml.Unmarshal(data, work); err != nil {
+		t.Fatal(err)
+	}
+	return work
+}
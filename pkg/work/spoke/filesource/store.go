@@ -0,0 +1,243 @@
+package filesource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/watch"
+
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// watchQueueLength is the length of the queue of the watch events which are not distributed to the watchers yet
+const watchQueueLength = 100
+
+var manifestWorkGR = workapiv1.SchemeGroupVersion.WithResource("manifestworks").GroupResource()
+
+// manifestWorkStore keeps the manifestworks in memory and serves them as the hub apiserver for the controllers of
+// the work agent: the generation is increased once the spec changes, the status is only updated with the status
+// subresource, the updates with a stale resource version are rejected with a conflict, and a deleting
+// manifestwork is removed once its finalizers are removed. The changes are sent to the watchers in order.
+type manifestWorkStore struct {
+	lock            sync.RWMutex
+	works           map[types.NamespacedName]*workapiv1.ManifestWork
+	resourceVersion int64
+	broadcaster     *watch.Broadcaster
+}
+
+func newManifestWorkStore() *manifestWorkStore {
+	return &manifestWorkStore{
+		works:       map[types.NamespacedName]*workapiv1.ManifestWork{},
+		broadcaster: watch.NewBroadcaster(watchQueueLength, watch.WaitIfChannelFull),
+	}
+}
+
+// ManifestWorks returns the client of the manifestworks in the namespace
+func (s *manifestWorkStore) ManifestWorks(namespace string) workv1client.ManifestWorkInterface {
+	return &manifestWorkClient{store: s, namespace: namespace}
+}
+
+// set stores the work with a new resource version and sends the event to the watchers, the lock must be held.
+func (s *manifestWorkStore) set(eventType watch.EventType, work *workapiv1.ManifestWork) *workapiv1.ManifestWork {
+	s.resourceVersion++
+	work.ResourceVersion = strconv.FormatInt(s.resourceVersion, 10)
+
+	key := types.NamespacedName{Namespace: work.Namespace, Name: work.Name}
+	if eventType == watch.Deleted {
+		delete(s.works, key)
+	} else {
+		s.works[key] = work
+	}
+	_ = s.broadcaster.Action(eventType, work.DeepCopy())
+	return work.DeepCopy()
+}
+
+// manifestWorkClient implements the ManifestWorkInterface with the manifestworks of a namespace in the store
+type manifestWorkClient struct {
+	store     *manifestWorkStore
+	namespace string
+}
+
+var _ workv1client.ManifestWorkInterface = &manifestWorkClient{}
+
+func (c *manifestWorkClient) Create(_ context.Context, work *workapiv1.ManifestWork, _ metav1.CreateOptions) (*workapiv1.ManifestWork, error) {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	if _, ok := c.store.works[c.key(work.Name)]; ok {
+		return nil, errors.NewAlreadyExists(manifestWorkGR, work.Name)
+	}
+
+	created := work.DeepCopy()
+	created.Namespace = c.namespace
+	created.UID = uuid.NewUUID()
+	created.CreationTimestamp = metav1.Now()
+	created.DeletionTimestamp = nil
+	created.Generation = 1
+	created.Status = workapiv1.ManifestWorkStatus{}
+	return c.store.set(watch.Added, created), nil
+}
+
+func (c *manifestWorkClient) Update(_ context.Context, work *workapiv1.ManifestWork, _ metav1.UpdateOptions) (*workapiv1.ManifestWork, error) {
+	return c.update(work, false)
+}
+
+func (c *manifestWorkClient) UpdateStatus(_ context.Context, work *workapiv1.ManifestWork, _ metav1.UpdateOptions) (*workapiv1.ManifestWork, error) {
+	return c.update(work, true)
+}
+
+// update updates the manifestwork in the same way as the apiserver, the spec and the metadata are only updated
+// without the status subresource, and the status is only updated with the status subresource.
+func (c *manifestWorkClient) update(work *workapiv1.ManifestWork, status bool) (*workapiv1.ManifestWork, error) {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	existing, ok := c.store.works[c.key(work.Name)]
+	if !ok {
+		return nil, errors.NewNotFound(manifestWorkGR, work.Name)
+	}
+	if work.ResourceVersion != existing.ResourceVersion {
+		return nil, errors.NewConflict(manifestWorkGR, work.Name,
+			fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+
+	var updated *workapiv1.ManifestWork
+	if status {
+		updated = existing.DeepCopy()
+		updated.Status = *work.Status.DeepCopy()
+	} else {
+		updated = work.DeepCopy()
+		updated.Namespace = existing.Namespace
+		updated.UID = existing.UID
+		updated.CreationTimestamp = existing.CreationTimestamp
+		updated.DeletionTimestamp = existing.DeletionTimestamp
+		updated.Generation = existing.Generation
+		updated.Status = existing.Status
+		if !equality.Semantic.DeepEqual(existing.Spec, updated.Spec) {
+			updated.Generation++
+		}
+	}
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return existing.DeepCopy(), nil
+	}
+
+	// the deleting manifestwork is removed once its finalizers are removed
+	if !updated.DeletionTimestamp.IsZero() && len(updated.Finalizers) == 0 {
+		return c.store.set(watch.Deleted, updated), nil
+	}
+	return c.store.set(watch.Modified, updated), nil
+}
+
+// Delete removes the manifestwork if it has no finalizers, otherwise it sets the deletion timestamp of the
+// manifestwork.
+func (c *manifestWorkClient) Delete(_ context.Context, name string, _ metav1.DeleteOptions) error {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+
+	existing, ok := c.store.works[c.key(name)]
+	if !ok {
+		return errors.NewNotFound(manifestWorkGR, name)
+	}
+	if len(existing.Finalizers) == 0 {
+		c.store.set(watch.Deleted, existing.DeepCopy())
+		return nil
+	}
+	if !existing.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	deleting := existing.DeepCopy()
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	c.store.set(watch.Modified, deleting)
+	return nil
+}
+
+func (c *manifestWorkClient) DeleteCollection(_ context.Context, _ metav1.DeleteOptions, _ metav1.ListOptions) error {
+	return errors.NewMethodNotSupported(manifestWorkGR, "deletecollection")
+}
+
+func (c *manifestWorkClient) Get(_ context.Context, name string, _ metav1.GetOptions) (*workapiv1.ManifestWork, error) {
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+
+	existing, ok := c.store.works[c.key(name)]
+	if !ok {
+		return nil, errors.NewNotFound(manifestWorkGR, name)
+	}
+	return existing.DeepCopy(), nil
+}
+
+func (c *manifestWorkClient) List(_ context.Context, opts metav1.ListOptions) (*workapiv1.ManifestWorkList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+
+	list := &workapiv1.ManifestWorkList{
+		ListMeta: metav1.ListMeta{ResourceVersion: strconv.FormatInt(c.store.resourceVersion, 10)},
+	}
+	for key, work := range c.store.works {
+		if key.Namespace != c.namespace || !selector.Matches(labels.Set(work.Labels)) {
+			continue
+		}
+		list.Items = append(list.Items, *work.DeepCopy())
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list, nil
+}
+
+// Watch watches the changes of the manifestworks after the resource version of the options. The history of the
+// changes is not kept, so the watcher has to relist the manifestworks if the resource version is not the latest.
+func (c *manifestWorkClient) Watch(_ context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	// the watcher is added with the read lock held, so no change is missed between the resource version and the
+	// watch
+	c.store.lock.RLock()
+	defer c.store.lock.RUnlock()
+
+	if len(opts.ResourceVersion) > 0 && opts.ResourceVersion != "0" &&
+		opts.ResourceVersion != strconv.FormatInt(c.store.resourceVersion, 10) {
+		return nil, errors.NewResourceExpired(fmt.Sprintf("too old resource version: %s", opts.ResourceVersion))
+	}
+
+	w, err := c.store.broadcaster.Watch()
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		work, ok := event.Object.(*workapiv1.ManifestWork)
+		if !ok {
+			return event, false
+		}
+		return event, work.Namespace == c.namespace && selector.Matches(labels.Set(work.Labels))
+	}), nil
+}
+
+func (c *manifestWorkClient) Patch(_ context.Context, name string, _ types.PatchType, _ []byte,
+	_ metav1.PatchOptions, _ ...string) (*workapiv1.ManifestWork, error) {
+	return nil, errors.NewMethodNotSupported(manifestWorkGR, "patch")
+}
+
+func (c *manifestWorkClient) key(name string) types.NamespacedName {
+	return types.NamespacedName{Namespace: c.namespace, Name: name}
+}
@@ -0,0 +1,84 @@
+package filesource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func TestManifestWorkStoreWatch(t *testing.T) {
+	ctx := context.TODO()
+	store := newManifestWorkStore()
+	client := store.ManifestWorks("cluster1")
+
+	if _, err := client.Create(ctx, &workapiv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: "work1"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ManifestWorks("cluster2").Create(ctx, &workapiv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: "work1"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := client.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Namespace != "cluster1" {
+		t.Fatalf("expected only the manifestwork of cluster1, but got %v", list.Items)
+	}
+
+	// the watcher has to relist if the resource version is not the latest
+	if _, err := client.Watch(ctx, metav1.ListOptions{ResourceVersion: "1"}); !errors.IsResourceExpired(err) {
+		t.Errorf("expected the resource version is expired, but got %v", err)
+	}
+
+	w, err := client.Watch(ctx, metav1.ListOptions{ResourceVersion: list.ResourceVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	work := list.Items[0].DeepCopy()
+	work.Finalizers = []string{"test"}
+	if _, err := client.Update(ctx, work, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ManifestWorks("cluster2").Create(ctx, &workapiv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: "work2"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(ctx, "work1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	work, err = client.Get(ctx, "work1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	work.Finalizers = nil
+	if _, err := client.Update(ctx, work, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []watch.EventType{watch.Modified, watch.Modified, watch.Deleted}
+	for _, eventType := range expected {
+		select {
+		case event := <-w.ResultChan():
+			work := event.Object.(*workapiv1.ManifestWork)
+			if event.Type != eventType || work.Namespace != "cluster1" {
+				t.Errorf("expected %s event of cluster1, but got %s event of %s", eventType, event.Type, work.Namespace)
+			}
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("expected %s event, but got none", eventType)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package hubcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	workinformer "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+const (
+	// saveInterval is the min interval between two saves of the manifestworks, the changes within the interval
+	// are persisted together once the interval elapses
+	saveInterval = 30 * time.Second
+
+	// sizeWarningThreshold is the percentage of the MaxDataSize above which a warning event is recorded
+	sizeWarningThreshold = 80
+)
+
+// ManifestWorkCacheController persists the manifestworks of the hub into the store once the manifestworks change,
+// so the work agent could still reconcile the last seen manifestworks after it restarts when the hub is not
+// reachable.
+type ManifestWorkCacheController struct {
+	manifestWorkLister worklister.ManifestWorkNamespaceLister
+	store              *ManifestWorkStore
+	connection         *HubConnection
+	recorder           events.Recorder
+	clock              clock.Clock
+	// lastSaved is the time when the manifestworks were saved last time, regardless of the result
+	lastSaved time.Time
+	// sizeWarned is true if a warning event has been recorded since the size crossed the threshold
+	sizeWarned bool
+}
+
+// NewManifestWorkCacheController returns a ManifestWorkCacheController
+func NewManifestWorkCacheController(
+	recorder events.Recorder,
+	manifestWorkInformer workinformer.ManifestWorkInformer,
+	manifestWorkLister worklister.ManifestWorkNamespaceLister,
+	store *ManifestWorkStore,
+	connection *HubConnection,
+	hubName string) factory.Controller {
+
+	controller := &ManifestWorkCacheController{
+		manifestWorkLister: manifestWorkLister,
+		store:              store,
+		connection:         connection,
+		recorder:           recorder,
+		clock:              clock.RealClock{},
+	}
+
+	return factory.New().
+		WithInformers(manifestWorkInformer.Informer()).
+		WithSync(controller.sync).
+		ToController(helper.HubControllerName("ManifestWorkCacheController", hubName), recorder)
+}
+
+func (c *ManifestWorkCacheController) sync(ctx context.Context, controllerContext factory.SyncContext) error {
+	// the manifestworks in the informer are the cached ones when the hub is not reachable, do not persist them
+	if !c.connection.Connected() {
+		return nil
+	}
+
+	// rate limit the saves, since any change of the manifestworks including their status triggers the sync
+	if wait := saveInterval - c.clock.Since(c.lastSaved); wait > 0 {
+		controllerContext.Queue().AddAfter(controllerContext.QueueKey(), wait)
+		return nil
+	}
+
+	works, err := c.manifestWorkLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	klog.V(4).Infof("Persist %d manifestworks into the cache", len(works))
+	c.lastSaved = c.clock.Now()
+	size, err := c.store.Save(ctx, works)
+	if size > MaxDataSize {
+		// the manifestworks will be persisted again once they are changed
+		klog.Errorf("Failed to persist the manifestworks: %v", err)
+		c.recorder.Warningf("ManifestWorkCacheTooLarge",
+			"The %d manifestworks are not persisted, the size %d exceeds the limit %d", len(works), size, MaxDataSize)
+		return nil
+	}
+	if size*100 > MaxDataSize*sizeWarningThreshold {
+		if !c.sizeWarned {
+			c.recorder.Warningf("ManifestWorkCacheNearlyFull",
+				"The size %d of the %d persisted manifestworks is approaching the limit %d", size, len(works), MaxDataSize)
+		}
+		c.sizeWarned = true
+	} else {
+		c.sizeWarned = false
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package hubcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+func TestManifestWorkCacheControllerSync(t *testing.T) {
+	cases := []struct {
+		name          string
+		connected     bool
+		lastSaved     time.Duration
+		expectedSaved bool
+	}{
+		{
+			name:          "persist manifestworks",
+			connected:     true,
+			expectedSaved: true,
+		},
+		{
+			name: "do not persist manifestworks when the hub is not reachable",
+		},
+		{
+			name:      "do not persist manifestworks within the save interval",
+			connected: true,
+			lastSaved: 10 * time.Second,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+			workClient := fakeworkclient.NewSimpleClientset(work)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 0)
+			if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+				t.Fatal(err)
+			}
+
+			kubeClient := fakekube.NewSimpleClientset()
+			store := NewManifestWorkStore(kubeClient.CoreV1().Secrets("default"), "test")
+			connection := NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+			if !c.connected {
+				connection.MarkDisconnected(fmt.Errorf("connection refused"))
+			}
+
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			controller := &ManifestWorkCacheController{
+				manifestWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(work.Namespace),
+				store:              store,
+				connection:         connection,
+				recorder:           eventstesting.NewTestingEventRecorder(t),
+				clock:              fakeClock,
+			}
+			if c.lastSaved > 0 {
+				controller.lastSaved = fakeClock.Now().Add(-c.lastSaved)
+			}
+
+			syncContext := testingcommon.NewFakeSyncContext(t, work.Name)
+			if err := controller.sync(context.TODO(), syncContext); err != nil {
+				t.Fatal(err)
+			}
+
+			works, err := store.Load(context.TODO())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.expectedSaved != (works != nil && len(works.Items) == 1) {
+				t.Errorf("expected saved %v, but got %v", c.expectedSaved, works)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package hubcache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// HubConnection tracks whether the hub is reachable from the work agent. The work agent is in the degraded mode
+// when the hub is not reachable, in which the agent keeps reconciling the cached manifestworks, queues the
+// status updates of the manifestworks, and suppresses the eviction and deletion of the appliedmanifestworks since
+// the cached manifestworks may be stale.
+//
+// The connections are reported in the health of the agent by the HubConnectionsChecker.
+type HubConnection struct {
+	lock     sync.RWMutex
+	recorder events.Recorder
+	clock    clock.Clock
+
+	connected bool
+	// disconnectedSince is the time when the hub becomes unreachable
+	disconnectedSince time.Time
+	// lastError is the last error returned by the hub when it is unreachable
+	lastError error
+}
+
+// NewHubConnection returns a HubConnection, the hub is considered reachable initially.
+func NewHubConnection(recorder events.Recorder) *HubConnection {
+	return &HubConnection{
+		recorder:  recorder,
+		clock:     clock.RealClock{},
+		connected: true,
+	}
+}
+
+// Connected returns whether the hub is reachable.
+func (c *HubConnection) Connected() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.connected
+}
+
+// MarkConnected records that the hub is reachable.
+func (c *HubConnection) MarkConnected() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.connected {
+		return
+	}
+
+	klog.Infof("The hub is reachable again after %v", c.clock.Since(c.disconnectedSince))
+	c.recorder.Eventf("HubConnected", "The hub is reachable again after %v", c.clock.Since(c.disconnectedSince))
+	c.connected = true
+	c.lastError = nil
+}
+
+// MarkDisconnected records that the hub is not reachable because of the err.
+func (c *HubConnection) MarkDisconnected(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lastError = err
+	if !c.connected {
+		return
+	}
+
+	klog.Warningf("The hub is not reachable, the work agent is in the degraded mode: %v", err)
+	c.recorder.Warningf("HubDisconnected", "The hub is not reachable, the work agent is in the degraded mode: %v", err)
+	c.connected = false
+	c.disconnectedSince = c.clock.Now()
+}
+
+// Check returns an error if the agent is in the degraded mode.
+func (c *HubConnection) Check(_ *http.Request) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.connected {
+		return nil
+	}
+	return fmt.Errorf("the hub is not reachable since %s: %v", c.disconnectedSince.Format(time.RFC3339), c.lastError)
+}
+
+// HubConnectionsChecker implements the healthz.HealthChecker of the connections to the hubs, the check fails when
+// the agent is in the degraded mode for any hub. The checker is installed on the server of the agent once the agent
+// starts, and the connections are added once the controllers of the hubs are started.
+type HubConnectionsChecker struct {
+	lock        sync.RWMutex
+	connections map[string]*HubConnection
+}
+
+// NewHubConnectionsChecker returns a HubConnectionsChecker without any connection.
+func NewHubConnectionsChecker() *HubConnectionsChecker {
+	return &HubConnectionsChecker{
+		connections: map[string]*HubConnection{},
+	}
+}
+
+// Add adds the connection to the hub with the hubName, which is empty for the default hub.
+func (c *HubConnectionsChecker) Add(hubName string, connection *HubConnection) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.connections[hubName] = connection
+}
+
+// Name returns the name of the health check.
+func (c *HubConnectionsChecker) Name() string {
+	return "hub-connection"
+}
+
+// Check returns an error if the agent is in the degraded mode for any hub.
+func (c *HubConnectionsChecker) Check(r *http.Request) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var messages []string
+	for hubName, connection := range c.connections {
+		if err := connection.Check(r); err != nil {
+			if len(hubName) > 0 {
+				err = fmt.Errorf("hub %s: %v", hubName, err)
+			}
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	sort.Strings(messages)
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// IsHubUnreachable returns whether the err is caused by the hub being not reachable, rather than the request
+// being rejected by the hub.
+func IsHubUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apierrors.IsServiceUnavailable(err) || apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) {
+		return true
+	}
+
+	if utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+
+	// the errors from the transport, e.g. dial or tls handshake errors
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
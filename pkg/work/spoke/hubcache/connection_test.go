@@ -0,0 +1,103 @@
+package hubcache
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestHubConnection(t *testing.T) {
+	connection := NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+	if !connection.Connected() {
+		t.Errorf("expected the hub is connected initially")
+	}
+	if err := connection.Check(nil); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+
+	connection.MarkDisconnected(fmt.Errorf("connection refused"))
+	if connection.Connected() {
+		t.Errorf("expected the hub is disconnected")
+	}
+	if err := connection.Check(nil); err == nil {
+		t.Errorf("expected error in the degraded mode")
+	}
+
+	connection.MarkConnected()
+	if !connection.Connected() {
+		t.Errorf("expected the hub is connected again")
+	}
+	if err := connection.Check(nil); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+}
+
+func TestHubConnectionsChecker(t *testing.T) {
+	checker := NewHubConnectionsChecker()
+	if err := checker.Check(nil); err != nil {
+		t.Errorf("expected no error without connections, but got %v", err)
+	}
+
+	defaultConnection := NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+	connection := NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+	checker.Add("", defaultConnection)
+	checker.Add("0123abcd", connection)
+	if err := checker.Check(nil); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+
+	connection.MarkDisconnected(fmt.Errorf("connection refused"))
+	err := checker.Check(nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "hub 0123abcd: ") {
+		t.Errorf("expected error of the hub 0123abcd, but got %v", err)
+	}
+
+	connection.MarkConnected()
+	if err := checker.Check(nil); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+}
+
+func TestIsHubUnreachable(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:     "dial error",
+			err:      &url.Error{Op: "Get", URL: "https://hub", Err: &net.OpError{Op: "dial", Err: fmt.Errorf("no route")}},
+			expected: true,
+		},
+		{
+			name:     "service unavailable",
+			err:      apierrors.NewServiceUnavailable("unavailable"),
+			expected: true,
+		},
+		{
+			name: "not found",
+			err:  apierrors.NewNotFound(schema.GroupResource{Resource: "manifestworks"}, "test"),
+		},
+		{
+			name: "conflict",
+			err:  apierrors.NewConflict(schema.GroupResource{Resource: "manifestworks"}, "test", fmt.Errorf("conflict")),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := IsHubUnreachable(c.err); actual != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package hubcache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// NewManifestWorkInformerFunc returns a function to build the manifestwork informer of the hub with the client of
+// the manifestworks in the cluster namespace, it could be registered to the hub work informer factory with
+// InformerFor. The informer lists the manifestworks from the
+// store when the hub is not reachable, so the work agent could still start and reconcile the last seen
+// manifestworks in the degraded mode. If the store is nil, the informer behaves the same as the default one
+// except that it records the hub connection.
+func NewManifestWorkInformerFunc(client workv1client.ManifestWorkInterface, store *ManifestWorkStore,
+	connection *HubConnection) func(workclientset.Interface, time.Duration) cache.SharedIndexInformer {
+	return func(_ workclientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		lw := &manifestWorkListWatcher{
+			client:     client,
+			store:      store,
+			connection: connection,
+		}
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc:  lw.list,
+				WatchFunc: lw.watch,
+			},
+			&workapiv1.ManifestWork{},
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	}
+}
+
+type manifestWorkListWatcher struct {
+	client     workv1client.ManifestWorkInterface
+	store      *ManifestWorkStore
+	connection *HubConnection
+
+	// listedFromCache is true if the last list is served from the store
+	listedFromCache atomic.Bool
+}
+
+func (lw *manifestWorkListWatcher) list(options metav1.ListOptions) (runtime.Object, error) {
+	works, err := lw.client.List(context.TODO(), options)
+	if err == nil {
+		lw.connection.MarkConnected()
+		lw.listedFromCache.Store(false)
+		return works, nil
+	}
+
+	if !IsHubUnreachable(err) {
+		return nil, err
+	}
+	lw.connection.MarkDisconnected(err)
+
+	if lw.store == nil {
+		return nil, err
+	}
+
+	cachedWorks, loadErr := lw.store.Load(context.TODO())
+	if loadErr != nil {
+		klog.Errorf("Failed to load the cached manifestworks: %v", loadErr)
+		return nil, err
+	}
+	if cachedWorks == nil {
+		return nil, err
+	}
+
+	klog.V(2).Infof("The hub is not reachable, list %d manifestworks from the cache", len(cachedWorks.Items))
+	lw.listedFromCache.Store(true)
+	return cachedWorks, nil
+}
+
+func (lw *manifestWorkListWatcher) watch(options metav1.ListOptions) (watch.Interface, error) {
+	// the cached manifestworks may be stale, force the informer to relist from the hub rather than watching
+	// from the cached state, otherwise the manifestworks deleted during the disconnection are never removed
+	if lw.listedFromCache.Load() {
+		return nil, fmt.Errorf("the manifestworks are listed from the cache, relist them from the hub")
+	}
+	return lw.client.Watch(context.TODO(), options)
+}
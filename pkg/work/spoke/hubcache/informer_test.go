@@ -0,0 +1,110 @@
+package hubcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+func TestManifestWorkListWatcher(t *testing.T) {
+	work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+
+	cases := []struct {
+		name              string
+		hubReachable      bool
+		cachedWorks       []*workapiv1.ManifestWork
+		withStore         bool
+		expectedErr       bool
+		expectedWorks     int
+		expectedFromCache bool
+		expectedConnected bool
+	}{
+		{
+			name:              "hub is reachable",
+			hubReachable:      true,
+			withStore:         true,
+			expectedWorks:     1,
+			expectedConnected: true,
+		},
+		{
+			name:              "hub is not reachable without store",
+			expectedErr:       true,
+			expectedConnected: false,
+		},
+		{
+			name:              "hub is not reachable without cached manifestworks",
+			withStore:         true,
+			expectedErr:       true,
+			expectedConnected: false,
+		},
+		{
+			name:              "hub is not reachable with cached manifestworks",
+			withStore:         true,
+			cachedWorks:       []*workapiv1.ManifestWork{work},
+			expectedWorks:     1,
+			expectedFromCache: true,
+			expectedConnected: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			workClient := fakeworkclient.NewSimpleClientset(work)
+			if !c.hubReachable {
+				workClient.PrependReactor("list", "manifestworks", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}
+				})
+			}
+
+			var store *ManifestWorkStore
+			if c.withStore {
+				store = NewManifestWorkStore(fakekube.NewSimpleClientset().CoreV1().Secrets("default"), "test")
+				if len(c.cachedWorks) > 0 {
+					if _, err := store.Save(context.TODO(), c.cachedWorks); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			lw := &manifestWorkListWatcher{
+				client:     workClient.WorkV1().ManifestWorks(work.Namespace),
+				store:      store,
+				connection: NewHubConnection(eventstesting.NewTestingEventRecorder(t)),
+			}
+
+			obj, err := lw.list(metav1.ListOptions{})
+			if c.expectedErr != (err != nil) {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if err == nil {
+				works := obj.(*workapiv1.ManifestWorkList)
+				if len(works.Items) != c.expectedWorks {
+					t.Errorf("expected %d manifestworks, but got %d", c.expectedWorks, len(works.Items))
+				}
+			}
+			if lw.listedFromCache.Load() != c.expectedFromCache {
+				t.Errorf("expected listed from cache %v, but got %v", c.expectedFromCache, lw.listedFromCache.Load())
+			}
+			if lw.connection.Connected() != c.expectedConnected {
+				t.Errorf("expected connected %v, but got %v", c.expectedConnected, lw.connection.Connected())
+			}
+
+			_, err = lw.watch(metav1.ListOptions{})
+			if c.expectedFromCache != (err != nil) {
+				t.Errorf("expected watch error %v, but got %v", c.expectedFromCache, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package hubcache
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	metricsSubsystem = "work_agent"
+
+	// reasons of the suppressed status updates
+	suppressedReasonUnchanged = "unchanged"
+	suppressedReasonCoalesced = "coalesced"
+
+	// reasons of the delayed status updates
+	delayedReasonBatching     = "batching"
+	delayedReasonRateLimiting = "rate_limiting"
+	delayedReasonDisconnected = "disconnected"
+)
+
+var (
+	statusUpdatesSuppressed = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "status_updates_suppressed_total",
+			Help:           "Number of the manifestwork status updates which are not written to the hub, by hub and reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub", "reason"},
+	)
+
+	statusUpdatesDelayed = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "status_updates_delayed_total",
+			Help:           "Number of the manifestwork status updates which are delayed before written to the hub, by hub and reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub", "reason"},
+	)
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics registers the metrics of the status updates into the legacy registry
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(statusUpdatesSuppressed)
+		legacyregistry.MustRegister(statusUpdatesDelayed)
+	})
+}
@@ -0,0 +1,325 @@
+package hubcache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	worklister "open-cluster-management.io/api/client/work/listers/work/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+// statusUpdaterWorkers is the number of the workers to write the batched status updates to the hub
+const statusUpdaterWorkers = 5
+
+// StatusUpdater updates the status of the manifestworks on the hub. When the hub is not reachable, the status
+// updates are queued and replayed with helper.UpdateManifestWorkStatus once the hub is reachable again.
+//
+// The queued status updates are grouped by the manifestwork and the source of the updates, the updates from a
+// source replace the previously queued updates from the same source of the manifestwork, since the update funcs
+// of a source always compute the whole status the source is responsible for.
+//
+// With a batching window, the status updates of a manifestwork are always queued and written to the hub once
+// the window since the first queued update elapses, so the updates within the window are coalesced into one
+// write. The writes to the hub are limited by a token bucket shared by all the manifestworks, and the status
+// updates which do not change the status are never written.
+type StatusUpdater struct {
+	client     workv1client.ManifestWorkInterface
+	lister     worklister.ManifestWorkNamespaceLister
+	connection *HubConnection
+	clock      clock.Clock
+	// hub is the value of the hub label of the metrics
+	hub string
+
+	// window is the batching window of the status updates, the updates are written immediately if it is zero
+	window      time.Duration
+	rateLimiter flowcontrol.RateLimiter
+	// queue holds the manifestworks whose batched status updates are waiting to be written
+	queue workqueue.DelayingInterface
+
+	lock sync.Mutex
+	// pending is the queued status updates, the key is the name of the manifestwork
+	pending map[string]*pendingStatusUpdates
+}
+
+// replaySource is the source of the update funcs which are failed to replay
+const replaySource = "replay"
+
+type pendingStatusUpdates struct {
+	// since is the time when the first update is queued, it is zero for the updates which are failed to replay
+	since time.Time
+	// sources records the order of the sources of the updates, so the updates are replayed in the same order
+	sources     []string
+	updateFuncs map[string][]helper.UpdateManifestWorkStatusFunc
+}
+
+// NewStatusUpdater returns a StatusUpdater which writes the status updates immediately without rate limiting
+func NewStatusUpdater(client workv1client.ManifestWorkInterface, lister worklister.ManifestWorkNamespaceLister,
+	connection *HubConnection) *StatusUpdater {
+	return &StatusUpdater{
+		client:      client,
+		lister:      lister,
+		connection:  connection,
+		clock:       clock.RealClock{},
+		rateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+		queue:       workqueue.NewNamedDelayingQueue("StatusUpdater"),
+		pending:     map[string]*pendingStatusUpdates{},
+		hub:         helper.DefaultHubMetricsLabel,
+	}
+}
+
+// WithHubName sets the name of the hub whose manifestworks are updated, it is empty for the default hub.
+func (u *StatusUpdater) WithHubName(hubName string) *StatusUpdater {
+	u.hub = helper.HubMetricsLabel(hubName)
+	return u
+}
+
+// WithBatching sets the batching window of the status updates of a manifestwork, and limits the writes to the
+// hub with a token bucket of the qps and burst. The writes are not limited if the qps is not positive.
+func (u *StatusUpdater) WithBatching(window time.Duration, qps float32, burst int) *StatusUpdater {
+	u.window = window
+	if qps > 0 {
+		u.rateLimiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+	return u
+}
+
+// UpdateManifestWorkStatus updates the status of the manifestwork with the updateFuncs. If the hub is not
+// reachable or the updates are batched, the updateFuncs are queued and no error is returned, the returned
+// status is the status computed locally and updated is false.
+func (u *StatusUpdater) UpdateManifestWorkStatus(ctx context.Context, source string, manifestWork *workapiv1.ManifestWork,
+	updateFuncs ...helper.UpdateManifestWorkStatusFunc) (*workapiv1.ManifestWorkStatus, bool, error) {
+	if u.window <= 0 && u.connection.Connected() {
+		// the manifestwork is mutated by the update, keep the original one in case the updates are queued
+		status, updated, err := u.update(ctx, manifestWork.DeepCopy(), updateFuncs...)
+		if !IsHubUnreachable(err) {
+			return status, updated, err
+		}
+		u.connection.MarkDisconnected(err)
+	}
+
+	status := manifestWork.Status.DeepCopy()
+	for _, update := range updateFuncs {
+		if err := update(status); err != nil {
+			return nil, false, err
+		}
+	}
+
+	unchanged := equality.Semantic.DeepEqual(&manifestWork.Status, status)
+	if !u.enqueue(manifestWork.Name, source, updateFuncs, unchanged) {
+		statusUpdatesSuppressed.WithLabelValues(u.hub, suppressedReasonUnchanged).Inc()
+		return status, false, nil
+	}
+
+	if u.connection.Connected() {
+		statusUpdatesDelayed.WithLabelValues(u.hub, delayedReasonBatching).Inc()
+	} else {
+		statusUpdatesDelayed.WithLabelValues(u.hub, delayedReasonDisconnected).Inc()
+	}
+	return status, false, nil
+}
+
+// Pending returns the number of the manifestworks which have queued status updates
+func (u *StatusUpdater) Pending() int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return len(u.pending)
+}
+
+// Run writes the batched status updates once their window elapses, and replays the queued status updates
+// every interval until the context is done.
+func (u *StatusUpdater) Run(ctx context.Context, interval time.Duration) {
+	defer utilruntime.HandleCrash()
+	defer u.queue.ShutDown()
+
+	for i := 0; i < statusUpdaterWorkers; i++ {
+		go wait.UntilWithContext(ctx, u.runWorker, time.Second)
+	}
+
+	wait.UntilWithContext(ctx, u.replay, interval)
+}
+
+func (u *StatusUpdater) runWorker(ctx context.Context) {
+	for u.processNextWorkItem(ctx) {
+	}
+}
+
+func (u *StatusUpdater) processNextWorkItem(ctx context.Context) bool {
+	key, quit := u.queue.Get()
+	if quit {
+		return false
+	}
+	defer u.queue.Done(key)
+
+	// the queued updates are replayed every interval when the hub is not reachable
+	if !u.connection.Connected() {
+		return true
+	}
+
+	// the updates are requeued if they are failed to write, and replayed in the next round
+	_ = u.flush(ctx, key.(string))
+	return true
+}
+
+// update writes the status of the manifestwork updated by the updateFuncs to the hub if the status is changed,
+// the write waits for the rate limiter.
+func (u *StatusUpdater) update(ctx context.Context, manifestWork *workapiv1.ManifestWork,
+	updateFuncs ...helper.UpdateManifestWorkStatusFunc) (*workapiv1.ManifestWorkStatus, bool, error) {
+	status := manifestWork.Status.DeepCopy()
+	for _, update := range updateFuncs {
+		if err := update(status); err != nil {
+			return nil, false, err
+		}
+	}
+	if equality.Semantic.DeepEqual(&manifestWork.Status, status) {
+		statusUpdatesSuppressed.WithLabelValues(u.hub, suppressedReasonUnchanged).Inc()
+		return status, false, nil
+	}
+
+	if !u.rateLimiter.TryAccept() {
+		statusUpdatesDelayed.WithLabelValues(u.hub, delayedReasonRateLimiting).Inc()
+		if err := u.rateLimiter.Wait(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return helper.UpdateManifestWorkStatus(ctx, u.client, manifestWork, updateFuncs...)
+}
+
+// enqueue queues the update funcs of the manifestwork from the source. If the manifestwork has no queued updates
+// and the update funcs do not change the status, the update funcs are not queued and false is returned.
+func (u *StatusUpdater) enqueue(name, source string, updateFuncs []helper.UpdateManifestWorkStatusFunc, unchanged bool) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	updates, ok := u.pending[name]
+	if !ok {
+		if unchanged {
+			return false
+		}
+		updates = &pendingStatusUpdates{
+			since:       u.clock.Now(),
+			updateFuncs: map[string][]helper.UpdateManifestWorkStatusFunc{},
+		}
+		u.pending[name] = updates
+		u.queue.AddAfter(name, u.window)
+	}
+	if _, ok := updates.updateFuncs[source]; ok {
+		statusUpdatesSuppressed.WithLabelValues(u.hub, suppressedReasonCoalesced).Inc()
+	} else {
+		updates.sources = append(updates.sources, source)
+	}
+	updates.updateFuncs[source] = updateFuncs
+	klog.V(4).Infof("Queue the status update of manifestwork %s from %s", name, source)
+	return true
+}
+
+// dequeue returns the queued update funcs of the manifestwork and removes them from the queue
+func (u *StatusUpdater) dequeue(name string) []helper.UpdateManifestWorkStatusFunc {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	updates, ok := u.pending[name]
+	if !ok {
+		return nil
+	}
+	delete(u.pending, name)
+
+	updateFuncs := []helper.UpdateManifestWorkStatusFunc{}
+	for _, source := range updates.sources {
+		updateFuncs = append(updateFuncs, updates.updateFuncs[source]...)
+	}
+	return updateFuncs
+}
+
+// requeue puts the update funcs back to the queue, they are replayed before the updates queued after them
+func (u *StatusUpdater) requeue(name string, updateFuncs []helper.UpdateManifestWorkStatusFunc) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	updates, ok := u.pending[name]
+	if !ok {
+		updates = &pendingStatusUpdates{updateFuncs: map[string][]helper.UpdateManifestWorkStatusFunc{}}
+		u.pending[name] = updates
+	}
+	updates.since = time.Time{}
+	updates.sources = append([]string{replaySource}, updates.sources...)
+	updates.updateFuncs[replaySource] = updateFuncs
+}
+
+// due returns the names of the manifestworks whose queued updates should be replayed. When the hub is reachable,
+// the updates still in the batching window are written by the workers once the window elapses.
+func (u *StatusUpdater) due() []string {
+	connected := u.connection.Connected()
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	names := make([]string, 0, len(u.pending))
+	for name, updates := range u.pending {
+		if connected && u.clock.Since(updates.since) < u.window {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (u *StatusUpdater) replay(ctx context.Context) {
+	for _, name := range u.due() {
+		if err := u.flush(ctx, name); IsHubUnreachable(err) {
+			// the hub is still not reachable, try again in the next round
+			return
+		}
+	}
+}
+
+// flush writes the queued updates of the manifestwork to the hub, the updates are requeued if they are failed
+// to write.
+func (u *StatusUpdater) flush(ctx context.Context, name string) error {
+	updateFuncs := u.dequeue(name)
+	if len(updateFuncs) == 0 {
+		return nil
+	}
+
+	manifestWork, err := u.lister.Get(name)
+	if errors.IsNotFound(err) {
+		// the manifestwork is deleted, drop its status updates
+		return nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to get manifestwork %s: %v", name, err)
+		u.requeue(name, updateFuncs)
+		return err
+	}
+
+	_, _, err = u.update(ctx, manifestWork.DeepCopy(), updateFuncs...)
+	switch {
+	case IsHubUnreachable(err):
+		u.connection.MarkDisconnected(err)
+		u.requeue(name, updateFuncs)
+		return err
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		klog.Errorf("Failed to write the status update of manifestwork %s: %v", name, err)
+		u.requeue(name, updateFuncs)
+		return err
+	}
+
+	u.connection.MarkConnected()
+	klog.V(4).Infof("Wrote the queued status update of manifestwork %s", name)
+	return nil
+}
@@ -0,0 +1,235 @@
+package hubcache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+
+	fakeworkclient "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+func setCondition(conditionType string) helper.UpdateManifestWorkStatusFunc {
+	return func(status *workapiv1.ManifestWorkStatus) error {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:   conditionType,
+			Status: metav1.ConditionTrue,
+			Reason: "Test",
+		})
+		return nil
+	}
+}
+
+func TestStatusUpdater(t *testing.T) {
+	work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	workClient := fakeworkclient.NewSimpleClientset(work)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 0)
+	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+		t.Fatal(err)
+	}
+
+	hubReachable := false
+	workClient.PrependReactor("*", "manifestworks", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if hubReachable {
+			return false, nil, nil
+		}
+		return true, nil, &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}
+	})
+
+	connection := NewHubConnection(eventstesting.NewTestingEventRecorder(t))
+	updater := NewStatusUpdater(
+		workClient.WorkV1().ManifestWorks(work.Namespace),
+		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(work.Namespace),
+		connection,
+	)
+
+	// the status updates are queued when the hub is not reachable
+	status, updated, err := updater.UpdateManifestWorkStatus(context.TODO(), "a", work.DeepCopy(), setCondition("A1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated {
+		t.Errorf("expected not updated")
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, "A1") {
+		t.Errorf("expected the status is computed locally, but got %v", status.Conditions)
+	}
+	if connection.Connected() {
+		t.Errorf("expected the hub is disconnected")
+	}
+
+	// the queued updates from the same source are replaced
+	if _, _, err := updater.UpdateManifestWorkStatus(context.TODO(), "a", work.DeepCopy(), setCondition("A2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := updater.UpdateManifestWorkStatus(context.TODO(), "b", work.DeepCopy(), setCondition("B")); err != nil {
+		t.Fatal(err)
+	}
+	if updater.Pending() != 1 {
+		t.Errorf("expected 1 pending manifestwork, but got %d", updater.Pending())
+	}
+
+	// the updates are kept in the queue if the hub is still not reachable
+	updater.replay(context.TODO())
+	if updater.Pending() != 1 {
+		t.Errorf("expected 1 pending manifestwork, but got %d", updater.Pending())
+	}
+
+	// the updates are replayed once the hub is reachable
+	hubReachable = true
+	workClient.ClearActions()
+	updater.replay(context.TODO())
+	if updater.Pending() != 0 {
+		t.Errorf("expected no pending manifestwork, but got %d", updater.Pending())
+	}
+	if !connection.Connected() {
+		t.Errorf("expected the hub is connected")
+	}
+
+	actual, err := workClient.WorkV1().ManifestWorks(work.Namespace).Get(context.TODO(), work.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(actual.Status.Conditions, "A1") != nil {
+		t.Errorf("expected the replaced update is not replayed, but got %v", actual.Status.Conditions)
+	}
+	if !meta.IsStatusConditionTrue(actual.Status.Conditions, "A2") || !meta.IsStatusConditionTrue(actual.Status.Conditions, "B") {
+		t.Errorf("expected the queued updates are replayed, but got %v", actual.Status.Conditions)
+	}
+
+	// the status is updated directly once the hub is reachable
+	_, updated, err = updater.UpdateManifestWorkStatus(context.TODO(), "c", actual, setCondition("C"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated || updater.Pending() != 0 {
+		t.Errorf("expected the status is updated directly")
+	}
+}
+
+func TestStatusUpdaterBatching(t *testing.T) {
+	RegisterMetrics()
+
+	work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	workClient := fakeworkclient.NewSimpleClientset(work)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 0)
+	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+		t.Fatal(err)
+	}
+
+	updater := NewStatusUpdater(
+		workClient.WorkV1().ManifestWorks(work.Namespace),
+		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(work.Namespace),
+		NewHubConnection(eventstesting.NewTestingEventRecorder(t)),
+	).WithBatching(time.Hour, 0, 0)
+
+	unchanged, _ := testutil.GetCounterMetricValue(statusUpdatesSuppressed.WithLabelValues(helper.DefaultHubMetricsLabel, suppressedReasonUnchanged))
+	coalesced, _ := testutil.GetCounterMetricValue(statusUpdatesSuppressed.WithLabelValues(helper.DefaultHubMetricsLabel, suppressedReasonCoalesced))
+	batched, _ := testutil.GetCounterMetricValue(statusUpdatesDelayed.WithLabelValues(helper.DefaultHubMetricsLabel, delayedReasonBatching))
+
+	// the update which does not change the status is suppressed
+	noop := func(status *workapiv1.ManifestWorkStatus) error { return nil }
+	if _, _, err := updater.UpdateManifestWorkStatus(context.TODO(), "a", work.DeepCopy(), noop); err != nil {
+		t.Fatal(err)
+	}
+	if updater.Pending() != 0 {
+		t.Errorf("expected no pending manifestwork, but got %d", updater.Pending())
+	}
+
+	// the updates within the window are coalesced
+	for _, update := range []struct {
+		source        string
+		conditionType string
+	}{{"a", "A1"}, {"b", "B"}, {"a", "A2"}} {
+		status, updated, err := updater.UpdateManifestWorkStatus(context.TODO(), update.source, work.DeepCopy(), setCondition(update.conditionType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated || !meta.IsStatusConditionTrue(status.Conditions, update.conditionType) {
+			t.Errorf("expected the update is batched and the status is computed locally, but got %v, %v", updated, status)
+		}
+	}
+	if updater.Pending() != 1 {
+		t.Errorf("expected 1 pending manifestwork, but got %d", updater.Pending())
+	}
+	if len(updater.due()) != 0 {
+		t.Errorf("expected no manifestwork is due in the window, but got %v", updater.due())
+	}
+
+	assertCounterDelta(t, statusUpdatesSuppressed.WithLabelValues(helper.DefaultHubMetricsLabel, suppressedReasonUnchanged), unchanged, 1)
+	assertCounterDelta(t, statusUpdatesSuppressed.WithLabelValues(helper.DefaultHubMetricsLabel, suppressedReasonCoalesced), coalesced, 1)
+	assertCounterDelta(t, statusUpdatesDelayed.WithLabelValues(helper.DefaultHubMetricsLabel, delayedReasonBatching), batched, 3)
+
+	if err := updater.flush(context.TODO(), work.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	updateActions := 0
+	for _, action := range workClient.Actions() {
+		if action.GetVerb() == "update" {
+			updateActions++
+		}
+	}
+	if updateActions != 1 {
+		t.Errorf("expected 1 update action, but got %d", updateActions)
+	}
+
+	actual, err := workClient.WorkV1().ManifestWorks(work.Namespace).Get(context.TODO(), work.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(actual.Status.Conditions, "A1") != nil ||
+		!meta.IsStatusConditionTrue(actual.Status.Conditions, "A2") || !meta.IsStatusConditionTrue(actual.Status.Conditions, "B") {
+		t.Errorf("expected the coalesced updates are written, but got %v", actual.Status.Conditions)
+	}
+}
+
+func TestStatusUpdaterRateLimiting(t *testing.T) {
+	RegisterMetrics()
+
+	work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	workClient := fakeworkclient.NewSimpleClientset(work)
+	updater := NewStatusUpdater(
+		workClient.WorkV1().ManifestWorks(work.Namespace),
+		nil,
+		NewHubConnection(eventstesting.NewTestingEventRecorder(t)),
+	).WithBatching(0, 100, 1)
+
+	delayed, _ := testutil.GetCounterMetricValue(statusUpdatesDelayed.WithLabelValues(helper.DefaultHubMetricsLabel, delayedReasonRateLimiting))
+
+	for _, conditionType := range []string{"A", "B"} {
+		_, updated, err := updater.UpdateManifestWorkStatus(context.TODO(), "a", work.DeepCopy(), setCondition(conditionType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated {
+			t.Errorf("expected the status is updated")
+		}
+	}
+
+	assertCounterDelta(t, statusUpdatesDelayed.WithLabelValues(helper.DefaultHubMetricsLabel, delayedReasonRateLimiting), delayed, 1)
+}
+
+func assertCounterDelta(t *testing.T, counter metrics.CounterMetric, before, expectedDelta float64) {
+	actual, err := testutil.GetCounterMetricValue(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual-before != expectedDelta {
+		t.Errorf("expected the counter is increased by %v, but got %v", expectedDelta, actual-before)
+	}
+}
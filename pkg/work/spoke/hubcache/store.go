@@ -0,0 +1,174 @@
+package hubcache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// manifestWorksDataKey is the key of the secret data which contains the gzipped manifestwork list
+	manifestWorksDataKey = "manifestworks.json.gz"
+
+	// MaxDataSize is the max size of the persisted manifestworks, a secret is limited to 1MiB and the rest is
+	// left for the metadata of the secret
+	MaxDataSize = 1000 * 1024
+)
+
+// ManifestWorkStore persists the manifestworks of the hub into a secret on the managed cluster
+type ManifestWorkStore struct {
+	secretClient corev1client.SecretInterface
+	secretName   string
+
+	lock sync.Mutex
+	// lastData is the data last persisted or loaded, it is used to avoid requesting the secret when the
+	// manifestworks are not changed
+	lastData []byte
+}
+
+// NewManifestWorkStore returns a ManifestWorkStore which persists the manifestworks into the secret with the
+// secretName in the namespace of the secretClient.
+func NewManifestWorkStore(secretClient corev1client.SecretInterface, secretName string) *ManifestWorkStore {
+	return &ManifestWorkStore{
+		secretClient: secretClient,
+		secretName:   secretName,
+	}
+}
+
+// Load returns the persisted manifestworks, it returns nil if there is no persisted manifestworks.
+func (s *ManifestWorkStore) Load(ctx context.Context) (*workapiv1.ManifestWorkList, error) {
+	secret, err := s.secretClient.Get(ctx, s.secretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data[manifestWorksDataKey]
+	if !ok {
+		return nil, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	works := &workapiv1.ManifestWorkList{}
+	if err := json.Unmarshal(raw, works); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.lastData = data
+	s.lock.Unlock()
+	return works, nil
+}
+
+// Save persists the manifestworks and returns the size of the persisted data. The secret is not requested if the
+// manifestworks are not changed, and an error is returned without persisting the manifestworks if the size of the
+// data exceeds the MaxDataSize.
+func (s *ManifestWorkStore) Save(ctx context.Context, works []*workapiv1.ManifestWork) (int, error) {
+	data, err := encode(works)
+	if err != nil {
+		return 0, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if bytes.Equal(s.lastData, data) {
+		return len(data), nil
+	}
+	if len(data) > MaxDataSize {
+		return len(data), fmt.Errorf("the size %d of the %d manifestworks exceeds the limit %d of the secret %s",
+			len(data), len(works), MaxDataSize, s.secretName)
+	}
+
+	secret, err := s.secretClient.Get(ctx, s.secretName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		_, err = s.secretClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: s.secretName,
+			},
+			Data: map[string][]byte{manifestWorksDataKey: data},
+		}, metav1.CreateOptions{})
+	case err != nil:
+	case !bytes.Equal(secret.Data[manifestWorksDataKey], data):
+		secret = secret.DeepCopy()
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[manifestWorksDataKey] = data
+		_, err = s.secretClient.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return len(data), err
+	}
+
+	s.lastData = data
+	return len(data), nil
+}
+
+// encode returns the gzipped json of the manifestwork list. Only the parts of the manifestworks needed to
+// reconcile them are kept: the metadata set by the hub apiserver is dropped, the status is dropped except the
+// applied generation, and the manifestworks are sorted by name, so the data only changes when the manifestworks
+// are changed by their owners or a new generation is applied.
+func encode(works []*workapiv1.ManifestWork) ([]byte, error) {
+	list := &workapiv1.ManifestWorkList{}
+	for _, work := range works {
+		applied := meta.FindStatusCondition(work.Status.Conditions, workapiv1.WorkApplied)
+		work = work.DeepCopy()
+		work.ManagedFields = nil
+		work.ResourceVersion = ""
+		work.SelfLink = ""
+		work.Status = workapiv1.ManifestWorkStatus{}
+		// the applied generation is kept, otherwise the generations applied before the agent restarts are
+		// considered new and wait for the maintenance window
+		if applied != nil {
+			work.Status.Conditions = []metav1.Condition{{
+				Type:               workapiv1.WorkApplied,
+				Status:             applied.Status,
+				ObservedGeneration: applied.ObservedGeneration,
+			}}
+		}
+		list.Items = append(list.Items, *work)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	if _, err := writer.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,109 @@
+package hubcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
+)
+
+func TestManifestWorkStore(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fakekube.NewSimpleClientset()
+	store := NewManifestWorkStore(kubeClient.CoreV1().Secrets("open-cluster-management-agent"), "manifestworks-test")
+
+	works, err := store.Load(ctx)
+	if err != nil || works != nil {
+		t.Fatalf("expected nothing loaded, but got %v, %v", works, err)
+	}
+
+	work1, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work2, _ := spoketesting.NewManifestWork(1, spoketesting.NewUnstructured("v1", "Secret", "ns2", "test"))
+	work1.ManagedFields = nil
+
+	if _, err := store.Save(ctx, []*workapiv1.ManifestWork{work2, work1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the secret is not requested if the manifestworks are only changed by the hub apiserver or in the status
+	kubeClient.ClearActions()
+	updatedWork1 := work1.DeepCopy()
+	updatedWork1.ResourceVersion = "2"
+	updatedWork1.Status.Conditions = []metav1.Condition{{Type: workapiv1.WorkAvailable, Status: metav1.ConditionTrue}}
+	if _, err := store.Save(ctx, []*workapiv1.ManifestWork{work2, updatedWork1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(kubeClient.Actions()) != 0 {
+		t.Errorf("expected no action, but got %v", kubeClient.Actions())
+	}
+
+	works, err = store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(works.Items) != 2 || works.Items[0].Name != work1.Name || works.Items[1].Name != work2.Name {
+		t.Errorf("expected manifestworks %s and %s, but got %v", work1.Name, work2.Name, works.Items)
+	}
+	if len(works.Items[0].ResourceVersion) != 0 || len(works.Items[0].Status.Conditions) != 0 {
+		t.Errorf("expected the resourceVersion and status are not persisted, but got %v", works.Items[0])
+	}
+
+	// the applied generation is persisted, so the applied manifestwork is not gated by the maintenance window
+	updatedWork1.Generation = 2
+	updatedWork1.Status.Conditions = append(updatedWork1.Status.Conditions, metav1.Condition{
+		Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, ObservedGeneration: 2, Reason: "AppliedManifestWorkComplete",
+	})
+	if _, err := store.Save(ctx, []*workapiv1.ManifestWork{work2, updatedWork1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	works, err = store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expectedConditions := []metav1.Condition{{Type: workapiv1.WorkApplied, Status: metav1.ConditionTrue, ObservedGeneration: 2}}
+	if !reflect.DeepEqual(works.Items[0].Status.Conditions, expectedConditions) || !helper.GenerationApplied(&works.Items[0]) {
+		t.Errorf("expected only the applied generation is persisted, but got %v", works.Items[0].Status)
+	}
+
+	if _, err := store.Save(ctx, []*workapiv1.ManifestWork{work1}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	works, err = store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(works.Items) != 1 || works.Items[0].Name != work1.Name {
+		t.Errorf("expected manifestwork %s, but got %v", work1.Name, works.Items)
+	}
+}
+
+func TestManifestWorkStoreSizeLimit(t *testing.T) {
+	ctx := context.TODO()
+	kubeClient := fakekube.NewSimpleClientset()
+	store := NewManifestWorkStore(kubeClient.CoreV1().Secrets("open-cluster-management-agent"), "manifestworks-test")
+
+	// random data is not compressible
+	data := make([]byte, MaxDataSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	work, _ := spoketesting.NewManifestWork(0, spoketesting.NewUnstructured("v1", "Secret", "ns1", "test"))
+	work.Annotations = map[string]string{"data": base64.StdEncoding.EncodeToString(data)}
+
+	size, err := store.Save(ctx, []*workapiv1.ManifestWork{work})
+	if err == nil || size <= MaxDataSize {
+		t.Errorf("expected error of the size %d over the limit, but got %v", size, err)
+	}
+	if len(kubeClient.Actions()) != 0 {
+		t.Errorf("expected no action, but got %v", kubeClient.Actions())
+	}
+}
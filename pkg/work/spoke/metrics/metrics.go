@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+const (
+	metricsSubsystem = "work_agent"
+
+	// results of applying a manifest
+	ApplyResultSuccess = "success"
+	ApplyResultFailure = "failure"
+
+	// reasons of the evictions of the appliedmanifestworks
+	EvictionReasonManifestWorkMissing = "manifestwork_missing"
+	EvictionReasonHubChanged          = "hub_changed"
+
+	// phases of the evictions of the appliedmanifestworks
+	EvictionPhaseStarted   = "started"
+	EvictionPhaseStopped   = "stopped"
+	EvictionPhaseCompleted = "completed"
+
+	// UnknownStrategy is the update strategy of the manifests which could not be parsed
+	UnknownStrategy = "Unknown"
+
+	// UnknownReason is the failure reason of the errors which are not an api status error
+	UnknownReason = "Unknown"
+)
+
+var (
+	manifestApplyDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "manifest_apply_duration_seconds",
+			Help:           "Duration in seconds to apply a manifest of the manifestwork, by hub, update strategy and result.",
+			Buckets:        []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub", "strategy", "result"},
+	)
+
+	manifestApplyFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "manifest_apply_failures_total",
+			Help:           "Number of the failures to apply a manifest of the manifestwork, by hub, update strategy and reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub", "strategy", "reason"},
+	)
+
+	manifestWorkResources = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "manifestwork_resources",
+			Help:           "Number of the resources managed by a manifestwork, observed on each reconcile of the manifestwork, by hub.",
+			Buckets:        []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub"},
+	)
+
+	statusFeedbackSyncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "status_feedback_sync_duration_seconds",
+			Help:           "Duration in seconds to sync the status and the status feedback of the resources of a manifestwork, by hub.",
+			Buckets:        []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub"},
+	)
+
+	appliedManifestWorkEvictions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "appliedmanifestwork_evictions_total",
+			Help:           "Number of the evictions of the unmanaged appliedmanifestworks, by hub, reason and phase.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"hub", "reason", "phase"},
+	)
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics registers the metrics of the work agent into the legacy registry
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(manifestApplyDuration)
+		legacyregistry.MustRegister(manifestApplyFailures)
+		legacyregistry.MustRegister(manifestWorkResources)
+		legacyregistry.MustRegister(statusFeedbackSyncDuration)
+		legacyregistry.MustRegister(appliedManifestWorkEvictions)
+	})
+}
+
+// ObserveManifestApply records the duration and the result of applying a manifest of the hub with the update
+// strategy, the apply fails if the failure reason is not empty.
+func ObserveManifestApply(hubName, strategy, failureReason string, duration time.Duration) {
+	hub := helper.HubMetricsLabel(hubName)
+	if len(failureReason) == 0 {
+		manifestApplyDuration.WithLabelValues(hub, strategy, ApplyResultSuccess).Observe(duration.Seconds())
+		return
+	}
+
+	manifestApplyDuration.WithLabelValues(hub, strategy, ApplyResultFailure).Observe(duration.Seconds())
+	manifestApplyFailures.WithLabelValues(hub, strategy, failureReason).Inc()
+}
+
+// ObserveManifestWorkResources records the number of the resources managed by a manifestwork of the hub
+func ObserveManifestWorkResources(hubName string, count int) {
+	manifestWorkResources.WithLabelValues(helper.HubMetricsLabel(hubName)).Observe(float64(count))
+}
+
+// ObserveStatusFeedbackSync records the duration to sync the status feedback of a manifestwork of the hub
+func ObserveStatusFeedbackSync(hubName string, duration time.Duration) {
+	statusFeedbackSyncDuration.WithLabelValues(helper.HubMetricsLabel(hubName)).Observe(duration.Seconds())
+}
+
+// RecordEviction records a phase of the eviction of an unmanaged appliedmanifestwork of the hub
+func RecordEviction(hubName, reason, phase string) {
+	appliedManifestWorkEvictions.WithLabelValues(helper.HubMetricsLabel(hubName), reason, phase).Inc()
+}
+
+// ReasonForError returns the reason of the api status error as the failure reason. Only the reasons defined in the
+// apimachinery are returned, so the label values are bounded.
+func ReasonForError(err error) string {
+	switch reason := apierrors.ReasonForError(err); reason {
+	case metav1.StatusReasonUnauthorized, metav1.StatusReasonForbidden, metav1.StatusReasonNotFound,
+		metav1.StatusReasonAlreadyExists, metav1.StatusReasonConflict, metav1.StatusReasonGone,
+		metav1.StatusReasonInvalid, metav1.StatusReasonServerTimeout, metav1.StatusReasonTimeout,
+		metav1.StatusReasonTooManyRequests, metav1.StatusReasonBadRequest, metav1.StatusReasonMethodNotAllowed,
+		metav1.StatusReasonNotAcceptable, metav1.StatusReasonRequestEntityTooLarge,
+		metav1.StatusReasonUnsupportedMediaType, metav1.StatusReasonInternalError, metav1.StatusReasonExpired,
+		metav1.StatusReasonServiceUnavailable:
+		return string(reason)
+	default:
+		return UnknownReason
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics/testutil"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+func TestReasonForError(t *testing.T) {
+	cases := []struct {
+		name           string
+		err            error
+		expectedReason string
+	}{
+		{
+			name:           "forbidden",
+			err:            apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "test", fmt.Errorf("denied")),
+			expectedReason: "Forbidden",
+		},
+		{
+			name:           "wrapped conflict",
+			err:            fmt.Errorf("failed: %w", apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, "test", fmt.Errorf("conflict"))),
+			expectedReason: "Conflict",
+		},
+		{
+			name:           "not an api status error",
+			err:            fmt.Errorf("failed"),
+			expectedReason: UnknownReason,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if reason := ReasonForError(c.err); reason != c.expectedReason {
+				t.Errorf("expected reason %q, but got %q", c.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestObserveManifestApply(t *testing.T) {
+	RegisterMetrics()
+
+	failures := manifestApplyFailures.WithLabelValues(helper.DefaultHubMetricsLabel, "Update", "Forbidden")
+	before, err := testutil.GetCounterMetricValue(failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ObserveManifestApply("", "Update", "", time.Second)
+	ObserveManifestApply("", "Update", "Forbidden", time.Second)
+
+	after, err := testutil.GetCounterMetricValue(failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after-before != 1 {
+		t.Errorf("expected the failures are increased by 1, but got %v", after-before)
+	}
+
+	count, err := testutil.GetHistogramMetricCount(manifestApplyDuration.WithLabelValues(helper.DefaultHubMetricsLabel, "Update", ApplyResultSuccess))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 successful apply is observed, but got %d", count)
+	}
+}
+
+func TestRecordEvictionByHub(t *testing.T) {
+	RegisterMetrics()
+
+	counter := appliedManifestWorkEvictions.WithLabelValues("0123abcd", EvictionReasonHubChanged, EvictionPhaseStarted)
+	before, err := testutil.GetCounterMetricValue(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RecordEviction("0123abcd", EvictionReasonHubChanged, EvictionPhaseStarted)
+	RecordEviction("", EvictionReasonHubChanged, EvictionPhaseStarted)
+
+	after, err := testutil.GetCounterMetricValue(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after-before != 1 {
+		t.Errorf("expected the evictions of the hub are increased by 1, but got %v", after-before)
+	}
+}
@@ -2,20 +2,30 @@ package spoke
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
 	"time"
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/spf13/cobra"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	ocmfeature "open-cluster-management.io/api/feature"
+	workapiv1 "open-cluster-management.io/api/work/v1"
 
 	commonoptions "open-cluster-management.io/ocm/pkg/common/options"
 	"open-cluster-management.io/ocm/pkg/features"
@@ -25,6 +35,10 @@ import (
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers/finalizercontroller"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers/manifestcontroller"
 	"open-cluster-management.io/ocm/pkg/work/spoke/controllers/statuscontroller"
+	"open-cluster-management.io/ocm/pkg/work/spoke/debugger"
+	"open-cluster-management.io/ocm/pkg/work/spoke/filesource"
+	"open-cluster-management.io/ocm/pkg/work/spoke/hubcache"
+	"open-cluster-management.io/ocm/pkg/work/spoke/metrics"
 )
 
 const (
@@ -37,8 +51,19 @@ const (
 	appliedManifestWorkFinalizeControllerWorkers = 10
 	manifestWorkFinalizeControllerWorkers        = 10
 	availableStatusControllerWorkers             = 10
+
+	// hubNameLength is the length of the hub hash prefix naming the controllers of an additional hub
+	hubNameLength = 8
+
+	// defaultComponentNamespace is the default namespace in which the work agent is deployed
+	defaultComponentNamespace = "open-cluster-management-agent"
 )
 
+// HubConnectionsHealthzPath is the path of the server of the work agent to check the connections to the hubs, the
+// check fails when the agent is in the degraded mode for any hub. Unlike /healthz, the path is authorized, and it is
+// not probed by the kubelet since the agent keeps reconciling the cached manifestworks in the degraded mode.
+const HubConnectionsHealthzPath = "/healthz/hubs"
+
 // WorkloadAgentOptions defines the flags for workload agent
 type WorkloadAgentOptions struct {
 	AgentOptions                           *commonoptions.AgentOptions
@@ -46,6 +71,27 @@ type WorkloadAgentOptions struct {
 	AgentID                                string
 	StatusSyncInterval                     time.Duration
 	AppliedManifestWorkEvictionGracePeriod time.Duration
+	ExecutorCachesNamespace                string
+	ExecutorCachesPersistInterval          time.Duration
+	ManifestWorkCacheNamespace             string
+	StatusUpdateBatchWindow                time.Duration
+	StatusUpdateQPS                        float32
+	StatusUpdateBurst                      int
+	StatusFeedbackBudget                   int
+	DeletionProtectedResources             []string
+	DeletionProtectionPolicy               string
+	DeletionStuckThreshold                 time.Duration
+	DeletionTimeout                        time.Duration
+	DeletionTimeoutPolicy                  string
+	MaintenanceWindowSchedule              string
+	MaintenanceWindowDuration              time.Duration
+	MaintenanceWindowTimeZone              string
+	AdditionalHubKubeconfigFiles           []string
+	ManifestWorkDir                        string
+	ManifestWorkStatusDir                  string
+
+	// hubConnections reports the connections to the hubs in the health of the agent
+	hubConnections *hubcache.HubConnectionsChecker
 }
 
 // NewWorkloadAgentOptions returns the flags with default value set
@@ -54,6 +100,15 @@ func NewWorkloadAgentOptions() *WorkloadAgentOptions {
 		AgentOptions:                           commonoptions.NewAgentOptions(),
 		StatusSyncInterval:                     10 * time.Second,
 		AppliedManifestWorkEvictionGracePeriod: 10 * time.Minute,
+		ExecutorCachesPersistInterval:          5 * time.Minute,
+		StatusUpdateQPS:                        20,
+		StatusUpdateBurst:                      50,
+		StatusFeedbackBudget:                   64 * 1024,
+		DeletionProtectionPolicy:               string(helper.DeletionProtectionConfirm),
+		DeletionStuckThreshold:                 5 * time.Minute,
+		DeletionTimeoutPolicy:                  string(helper.DeletionTimeoutWait),
+		MaintenanceWindowDuration:              4 * time.Hour,
+		hubConnections:                         hubcache.NewHubConnectionsChecker(),
 	}
 }
 
@@ -68,29 +123,141 @@ func (o *WorkloadAgentOptions) AddFlags(cmd *cobra.Command) {
 	flags.DurationVar(&o.StatusSyncInterval, "status-sync-interval", o.StatusSyncInterval, "Interval to sync resource status to hub.")
 	flags.DurationVar(&o.AppliedManifestWorkEvictionGracePeriod, "appliedmanifestwork-eviction-grace-period",
 		o.AppliedManifestWorkEvictionGracePeriod, "Grace period for appliedmanifestwork eviction")
+	flags.StringVar(&o.ExecutorCachesNamespace, "executor-caches-namespace", o.ExecutorCachesNamespace,
+		"Namespace of the configmap to persist the executor caches on the cluster where the agent runs, which is the "+
+			"namespace of the agent if it is empty.")
+	flags.DurationVar(&o.ExecutorCachesPersistInterval, "executor-caches-persist-interval", o.ExecutorCachesPersistInterval,
+		"Interval to persist the executor caches, the caches are not persisted if it is not positive.")
+	flags.StringVar(&o.ManifestWorkCacheNamespace, "manifestwork-cache-namespace", o.ManifestWorkCacheNamespace,
+		"Namespace on the managed cluster of the secret to persist the manifestworks from the hub, so the agent could keep "+
+			"reconciling the manifestworks after it restarts when the hub is not reachable. The manifestworks are not persisted if it is empty.")
+	flags.DurationVar(&o.StatusUpdateBatchWindow, "status-update-batch-window", o.StatusUpdateBatchWindow,
+		"Window to coalesce the status updates of a manifestwork into one write to the hub, the status updates are written immediately if it is zero.")
+	flags.Float32Var(&o.StatusUpdateQPS, "status-update-qps", o.StatusUpdateQPS,
+		"QPS of the status writes from the agent to the hub, the writes are not limited if it is not positive.")
+	flags.IntVar(&o.StatusUpdateBurst, "status-update-burst", o.StatusUpdateBurst, "Burst of the status writes from the agent to the hub.")
+	flags.IntVar(&o.StatusFeedbackBudget, "status-feedback-budget", o.StatusFeedbackBudget,
+		"Max total size in bytes of the status feedback values of a manifestwork, the values with the lowest priority are "+
+			"dropped once the budget is exceeded. The values are not limited if it is not positive.")
+	flags.StringSliceVar(&o.DeletionProtectedResources, "deletion-protected-resources", o.DeletionProtectedResources,
+		"Resources in the format of <resource>[.<group>] protected from being deleted when they are removed from the manifestworks "+
+			"or the manifestworks are deleted, e.g. persistentvolumeclaims,namespaces. A resource could also be protected with the "+
+			"annotation "+helper.DeletionProtectionAnnotationKey+".")
+	flags.StringVar(&o.DeletionProtectionPolicy, "deletion-protection-policy", o.DeletionProtectionPolicy,
+		"Policy to delete the protected resources, Orphan to orphan them, or Confirm to delete them only after the deletion is "+
+			"confirmed with the annotation "+helper.ConfirmDeletionAnnotationKey+" on the manifestwork.")
+	flags.DurationVar(&o.DeletionStuckThreshold, "deletion-stuck-threshold", o.DeletionStuckThreshold,
+		"Duration after which a deleted resource not gone yet is reported as stuck in deletion with its remaining finalizers "+
+			"in the manifestwork status. The stuck resources are not reported if it is not positive.")
+	flags.DurationVar(&o.DeletionTimeout, "deletion-timeout", o.DeletionTimeout,
+		"Duration after which the deletion of a resource times out and the deletion timeout policy is applied, "+
+			"the deletion never times out if it is not positive.")
+	flags.StringVar(&o.DeletionTimeoutPolicy, "deletion-timeout-policy", o.DeletionTimeoutPolicy,
+		"Policy to handle the resources whose deletion times out, Wait to keep waiting, Orphan to stop waiting and orphan them, "+
+			"or RemoveFinalizers to remove their finalizers.")
+	flags.StringVar(&o.MaintenanceWindowSchedule, "maintenance-window-schedule", o.MaintenanceWindowSchedule,
+		"Cron expression of the maintenance windows in which the new generations of the manifestworks are applied and "+
+			"the resources of the deleted manifestworks are deleted. The changes are applied at any time if it is empty. "+
+			"It is overridden by the annotation "+helper.MaintenanceWindowAnnotationKey+" on the manifestwork.")
+	flags.DurationVar(&o.MaintenanceWindowDuration, "maintenance-window-duration", o.MaintenanceWindowDuration,
+		"Duration of each maintenance window.")
+	flags.StringVar(&o.MaintenanceWindowTimeZone, "maintenance-window-time-zone", o.MaintenanceWindowTimeZone,
+		"IANA time zone of the maintenance window schedule, it is UTC if it is empty.")
+	flags.StringSliceVar(&o.AdditionalHubKubeconfigFiles, "additional-hub-kubeconfigs", o.AdditionalHubKubeconfigFiles,
+		"Locations of kubeconfig files to connect to the additional hub clusters, the manifestworks of each hub are "+
+			"processed by a separate set of controllers. The resources applied by the manifestworks of multiple hubs are "+
+			"reported with the condition "+helper.WorkHubConflict+".")
+	flags.StringVar(&o.ManifestWorkDir, "manifestwork-dir", o.ManifestWorkDir,
+		"Directory of the yaml or json files of the manifestworks, e.g. a mounted configmap. If it is set, the manifestworks "+
+			"are read from the files instead of the hub of --hub-kubeconfig, and the files are read every status sync interval.")
+	flags.StringVar(&o.ManifestWorkStatusDir, "manifestwork-status-dir", o.ManifestWorkStatusDir,
+		"Directory to write the status of the manifestworks read from --manifestwork-dir into, the status of each manifestwork "+
+			"is written into the file <name>.yaml. The status is not written if it is empty.")
+}
+
+// spokeContext holds the clients of the managed cluster and the policies shared by the controllers of all the hubs
+type spokeContext struct {
+	restConfig          *rest.Config
+	dynamicClient       dynamic.Interface
+	kubeClient          kubernetes.Interface
+	apiExtensionClient  apiextensionsclient.Interface
+	workClient          workclientset.Interface
+	workInformerFactory workinformers.SharedInformerFactory
+	restMapper          meta.RESTMapper
+	deletionProtection  *helper.DeletionProtection
+	deletionTimeout     *helper.DeletionTimeout
+	maintenanceWindow   *helper.MaintenanceWindow
+	// executorCachesClient is the client of the configmaps to persist the executor caches, on the cluster where
+	// the agent runs
+	executorCachesClient corev1client.ConfigMapInterface
+}
+
+// hub is a source of the manifestworks, which is either a hub cluster or the file source
+type hub struct {
+	hash       string
+	workClient workv1client.ManifestWorksGetter
 }
 
 // RunWorkloadAgent starts the controllers on agent to process work from hub.
 func (o *WorkloadAgentOptions) RunWorkloadAgent(ctx context.Context, controllerContext *controllercmd.ControllerContext) error {
-	// build hub client and informer
-	hubRestConfig, err := clientcmd.BuildConfigFromFlags("" /* leave masterurl as empty */, o.HubKubeconfigFile)
-	if err != nil {
-		return err
+	// build the work clients of the default hub and the additional hubs, the manifestworks of the default hub are
+	// read from the files if the manifestwork dir is set.
+	hubs := []hub{}
+	hubHashes := sets.NewString()
+	var fileSource *filesource.FileSource
+	if len(o.ManifestWorkDir) > 0 {
+		fileSource = filesource.NewFileSource(o.ManifestWorkDir, o.ManifestWorkStatusDir, o.AgentOptions.SpokeClusterName)
+		hubHashes.Insert(fileSource.Hash())
+		hubs = append(hubs, hub{hash: fileSource.Hash(), workClient: fileSource.WorkClient()})
+	}
+	hubKubeconfigFiles := o.AdditionalHubKubeconfigFiles
+	if fileSource == nil {
+		hubKubeconfigFiles = append([]string{o.HubKubeconfigFile}, hubKubeconfigFiles...)
+	}
+	for _, hubKubeconfigFile := range hubKubeconfigFiles {
+		hubRestConfig, err := clientcmd.BuildConfigFromFlags("" /* leave masterurl as empty */, hubKubeconfigFile)
+		if err != nil {
+			return err
+		}
+		hubhash := helper.HubHash(hubRestConfig.Host)
+		if hubHashes.Has(hubhash) {
+			return fmt.Errorf("the hub %s of the kubeconfig %s is duplicated", hubRestConfig.Host, hubKubeconfigFile)
+		}
+		hubHashes.Insert(hubhash)
+		hubWorkClient, err := workclientset.NewForConfig(hubRestConfig)
+		if err != nil {
+			return err
+		}
+		hubs = append(hubs, hub{hash: hubhash, workClient: hubWorkClient.WorkV1()})
 	}
-	hubhash := helper.HubHash(hubRestConfig.Host)
 
-	agentID := o.AgentID
-	if len(agentID) == 0 {
-		agentID = hubhash
+	// the health checks of the server cannot be added once the server is started by the controller command, so the
+	// connections to the hubs are checked on a separate path of the server
+	if controllerContext.Server != nil {
+		healthz.InstallPathHandler(controllerContext.Server.Handler.NonGoRestfulMux, HubConnectionsHealthzPath, o.hubConnections)
 	}
 
-	hubWorkClient, err := workclientset.NewForConfig(hubRestConfig)
+	deletionProtection, err := helper.NewDeletionProtection(
+		o.DeletionProtectedResources, helper.DeletionProtectionPolicy(o.DeletionProtectionPolicy))
 	if err != nil {
 		return err
 	}
-	// Only watch the cluster namespace on hub
-	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(hubWorkClient, 5*time.Minute,
-		workinformers.WithNamespace(o.AgentOptions.SpokeClusterName))
+	deletionTimeout, err := helper.NewDeletionTimeout(
+		o.DeletionStuckThreshold, o.DeletionTimeout, helper.DeletionTimeoutPolicy(o.DeletionTimeoutPolicy))
+	if err != nil {
+		return err
+	}
+	var maintenanceWindow *helper.MaintenanceWindow
+	if len(o.MaintenanceWindowSchedule) > 0 {
+		maintenanceWindow, err = helper.NewMaintenanceWindow(helper.MaintenanceWindowSpec{
+			Schedule: o.MaintenanceWindowSchedule,
+			Duration: metav1.Duration{Duration: o.MaintenanceWindowDuration},
+			TimeZone: o.MaintenanceWindowTimeZone,
+		})
+		if err != nil {
+			return err
+		}
+	}
 
 	// load spoke client config and create spoke clients,
 	// the work agent may not running in the spoke/managed cluster.
@@ -126,81 +293,191 @@ func (o *WorkloadAgentOptions) RunWorkloadAgent(ctx context.Context, controllerC
 		return err
 	}
 
-	validator := auth.NewFactory(
-		spokeRestConfig,
-		spokeKubeClient,
+	// the executor caches are persisted on the cluster where the agent runs, which is not the managed cluster in
+	// the hosted mode
+	managementKubeClient, err := kubernetes.NewForConfig(controllerContext.KubeConfig)
+	if err != nil {
+		return err
+	}
+	executorCachesNamespace := o.ExecutorCachesNamespace
+	if len(executorCachesNamespace) == 0 {
+		executorCachesNamespace = componentNamespace()
+	}
+
+	hubcache.RegisterMetrics()
+	metrics.RegisterMetrics()
+
+	spoke := &spokeContext{
+		restConfig:           spokeRestConfig,
+		dynamicClient:        spokeDynamicClient,
+		kubeClient:           spokeKubeClient,
+		apiExtensionClient:   spokeAPIExtensionClient,
+		workClient:           spokeWorkClient,
+		workInformerFactory:  spokeWorkInformerFactory,
+		restMapper:           restMapper,
+		deletionProtection:   deletionProtection,
+		deletionTimeout:      deletionTimeout,
+		maintenanceWindow:    maintenanceWindow,
+		executorCachesClient: managementKubeClient.CoreV1().ConfigMaps(executorCachesNamespace),
+	}
+	for i, hub := range hubs {
+		if err := o.startHubControllers(ctx, controllerContext, spoke, hub, i == 0); err != nil {
+			return err
+		}
+	}
+	if fileSource != nil {
+		go fileSource.Run(ctx, o.StatusSyncInterval)
+	}
+
+	go spokeWorkInformerFactory.Start(ctx.Done())
+	<-ctx.Done()
+	return nil
+}
+
+// startHubControllers starts the controllers to process the manifestworks of a hub. The controllers of the
+// additional hubs are named with the hub name, which is the prefix of the hub hash, and use the hub hash as the
+// agent id, so the appliedmanifestworks of each hub are only processed by its own controllers.
+func (o *WorkloadAgentOptions) startHubControllers(ctx context.Context, controllerContext *controllercmd.ControllerContext,
+	spoke *spokeContext, hub hub, isDefaultHub bool) error {
+	hubhash := hub.hash
+	hubWorkClient := hub.workClient
+	recorder := controllerContext.EventRecorder
+	hubName := ""
+	agentID := o.AgentID
+	if len(agentID) == 0 {
+		agentID = hubhash
+	}
+	if !isDefaultHub {
+		hubName = hubhash[:hubNameLength]
+		agentID = hubhash
+		recorder = recorder.WithComponentSuffix(hubName)
+	}
+
+	// Only watch the cluster namespace on hub. The manifestwork informer, which is the only informer of the
+	// factory, is registered below and lists the manifestworks with the hub work client, so the factory has no
+	// clientset.
+	workInformerFactory := workinformers.NewSharedInformerFactoryWithOptions(nil, 5*time.Minute,
+		workinformers.WithNamespace(o.AgentOptions.SpokeClusterName))
+
+	// the hub connection tracks whether the agent is in the degraded mode because the hub is not reachable
+	hubConnection := hubcache.NewHubConnection(recorder)
+	o.hubConnections.Add(hubName, hubConnection)
+
+	var manifestWorkStore *hubcache.ManifestWorkStore
+	if len(o.ManifestWorkCacheNamespace) > 0 {
+		manifestWorkStore = hubcache.NewManifestWorkStore(
+			spoke.kubeClient.CoreV1().Secrets(o.ManifestWorkCacheNamespace), fmt.Sprintf("manifestworks-%s", hubhash))
+	}
+	// register the manifestwork informer which lists the manifestworks from the cache when the hub is not reachable
+	workInformerFactory.InformerFor(&workapiv1.ManifestWork{},
+		hubcache.NewManifestWorkInformerFunc(
+			hubWorkClient.ManifestWorks(o.AgentOptions.SpokeClusterName), manifestWorkStore, hubConnection))
+	manifestWorkLister := workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName)
+	statusUpdater := hubcache.NewStatusUpdater(
+		hubWorkClient.ManifestWorks(o.AgentOptions.SpokeClusterName),
+		manifestWorkLister,
+		hubConnection,
+	).WithBatching(o.StatusUpdateBatchWindow, o.StatusUpdateQPS, o.StatusUpdateBurst).WithHubName(hubName)
+
+	// the executor caches of each hub are persisted into a separate configmap
+	validatorFactory := auth.NewFactory(
+		spoke.restConfig,
+		spoke.kubeClient,
 		workInformerFactory.Work().V1().ManifestWorks(),
 		o.AgentOptions.SpokeClusterName,
-		controllerContext.EventRecorder,
-		restMapper,
-	).NewExecutorValidator(ctx, features.DefaultSpokeWorkMutableFeatureGate.Enabled(ocmfeature.ExecutorValidatingCaches))
+		recorder,
+		spoke.restMapper,
+	).WithHubName(hubName).WithCachesPersistence(spoke.executorCachesClient, o.ExecutorCachesPersistInterval)
+	validator := validatorFactory.NewExecutorValidator(ctx,
+		features.DefaultSpokeWorkMutableFeatureGate.Enabled(ocmfeature.ExecutorValidatingCaches))
+
+	if controllerContext.Server != nil {
+		debug := debugger.NewDebugger(hubName, manifestWorkLister, spoke.restMapper, validator)
+		controllerContext.Server.Handler.NonGoRestfulMux.HandlePrefix(debug.Path(), http.HandlerFunc(debug.Handler))
+	}
 
 	manifestWorkController := manifestcontroller.NewManifestWorkController(
-		controllerContext.EventRecorder,
-		spokeDynamicClient,
-		spokeKubeClient,
-		spokeAPIExtensionClient,
-		hubWorkClient.WorkV1().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		recorder,
+		spoke.restConfig,
+		spoke.dynamicClient,
+		spoke.kubeClient,
+		spoke.apiExtensionClient,
+		statusUpdater,
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
-		spokeWorkClient.WorkV1().AppliedManifestWorks(),
-		spokeWorkInformerFactory.Work().V1().AppliedManifestWorks(),
-		hubhash, agentID,
-		restMapper,
+		manifestWorkLister,
+		spoke.workClient.WorkV1().AppliedManifestWorks(),
+		spoke.workInformerFactory.Work().V1().AppliedManifestWorks(),
+		hubhash, agentID, hubName,
+		spoke.restMapper,
 		validator,
+		spoke.maintenanceWindow,
 	)
 	addFinalizerController := finalizercontroller.NewAddFinalizerController(
-		controllerContext.EventRecorder,
-		hubWorkClient.WorkV1().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		recorder,
+		hubWorkClient.ManifestWorks(o.AgentOptions.SpokeClusterName),
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		manifestWorkLister,
+		hubName,
 	)
 	appliedManifestWorkFinalizeController := finalizercontroller.NewAppliedManifestWorkFinalizeController(
-		controllerContext.EventRecorder,
-		spokeDynamicClient,
-		spokeWorkClient.WorkV1().AppliedManifestWorks(),
-		spokeWorkInformerFactory.Work().V1().AppliedManifestWorks(),
-		agentID,
+		recorder,
+		spoke.dynamicClient,
+		workInformerFactory.Work().V1().ManifestWorks(),
+		manifestWorkLister,
+		statusUpdater,
+		spoke.workClient.WorkV1().AppliedManifestWorks(),
+		spoke.workInformerFactory.Work().V1().AppliedManifestWorks(),
+		spoke.deletionProtection,
+		spoke.deletionTimeout,
+		spoke.maintenanceWindow,
+		hubhash, agentID, hubName,
 	)
 	manifestWorkFinalizeController := finalizercontroller.NewManifestWorkFinalizeController(
-		controllerContext.EventRecorder,
-		hubWorkClient.WorkV1().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		recorder,
+		hubWorkClient.ManifestWorks(o.AgentOptions.SpokeClusterName),
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
-		spokeWorkClient.WorkV1().AppliedManifestWorks(),
-		spokeWorkInformerFactory.Work().V1().AppliedManifestWorks(),
-		hubhash,
+		manifestWorkLister,
+		spoke.workClient.WorkV1().AppliedManifestWorks(),
+		spoke.workInformerFactory.Work().V1().AppliedManifestWorks(),
+		hubConnection,
+		hubhash, hubName,
 	)
 	unmanagedAppliedManifestWorkController := finalizercontroller.NewUnManagedAppliedWorkController(
-		controllerContext.EventRecorder,
+		recorder,
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
-		spokeWorkClient.WorkV1().AppliedManifestWorks(),
-		spokeWorkInformerFactory.Work().V1().AppliedManifestWorks(),
+		manifestWorkLister,
+		spoke.workClient.WorkV1().AppliedManifestWorks(),
+		spoke.workInformerFactory.Work().V1().AppliedManifestWorks(),
 		o.AppliedManifestWorkEvictionGracePeriod,
-		hubhash, agentID,
+		hubConnection,
+		hubhash, agentID, hubName,
 	)
 	appliedManifestWorkController := appliedmanifestcontroller.NewAppliedManifestWorkController(
-		controllerContext.EventRecorder,
-		spokeDynamicClient,
-		hubWorkClient.WorkV1().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		recorder,
+		spoke.dynamicClient,
+		hubWorkClient.ManifestWorks(o.AgentOptions.SpokeClusterName),
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
-		spokeWorkClient.WorkV1().AppliedManifestWorks(),
-		spokeWorkInformerFactory.Work().V1().AppliedManifestWorks(),
-		hubhash,
+		manifestWorkLister,
+		statusUpdater,
+		spoke.workClient.WorkV1().AppliedManifestWorks(),
+		spoke.workInformerFactory.Work().V1().AppliedManifestWorks(),
+		spoke.deletionProtection,
+		spoke.deletionTimeout,
+		hubhash, hubName,
 	)
 	availableStatusController := statuscontroller.NewAvailableStatusController(
-		controllerContext.EventRecorder,
-		spokeDynamicClient,
-		hubWorkClient.WorkV1().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		recorder,
+		spoke.dynamicClient,
+		statusUpdater,
 		workInformerFactory.Work().V1().ManifestWorks(),
-		workInformerFactory.Work().V1().ManifestWorks().Lister().ManifestWorks(o.AgentOptions.SpokeClusterName),
+		manifestWorkLister,
+		validator,
 		o.StatusSyncInterval,
+		o.StatusFeedbackBudget,
+		hubName,
 	)
 
 	go workInformerFactory.Start(ctx.Done())
-	go spokeWorkInformerFactory.Start(ctx.Done())
 	go addFinalizerController.Run(ctx, 1)
 	go appliedManifestWorkFinalizeController.Run(ctx, appliedManifestWorkFinalizeControllerWorkers)
 	go unmanagedAppliedManifestWorkController.Run(ctx, 1)
@@ -208,6 +485,26 @@ func (o *WorkloadAgentOptions) RunWorkloadAgent(ctx context.Context, controllerC
 	go manifestWorkController.Run(ctx, 1)
 	go manifestWorkFinalizeController.Run(ctx, manifestWorkFinalizeControllerWorkers)
 	go availableStatusController.Run(ctx, availableStatusControllerWorkers)
-	<-ctx.Done()
+	go statusUpdater.Run(ctx, o.StatusSyncInterval)
+	if manifestWorkStore != nil {
+		manifestWorkCacheController := hubcache.NewManifestWorkCacheController(
+			recorder,
+			workInformerFactory.Work().V1().ManifestWorks(),
+			manifestWorkLister,
+			manifestWorkStore,
+			hubConnection,
+			hubName,
+		)
+		go manifestWorkCacheController.Run(ctx, 1)
+	}
 	return nil
 }
+
+// componentNamespace returns the namespace in which the work agent is deployed
+func componentNamespace() string {
+	nsBytes, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return defaultComponentNamespace
+	}
+	return string(nsBytes)
+}
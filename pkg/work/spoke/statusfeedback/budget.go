@@ -0,0 +1,94 @@
+package statusfeedback
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+// feedbackValueRef refers to a feedback value of a manifest in the manifestwork status
+type feedbackValueRef struct {
+	manifestIndex int
+	valueIndex    int
+	size          int
+	priority      int
+}
+
+// priority returns the priority of the feedback value, the values with the lower priority are dropped first when
+// the feedback values exceed the budget. The json raw values are usually the largest and the least structured
+// ones, so they have the lowest priority.
+func priority(value workapiv1.FeedbackValue) int {
+	switch value.Value.Type {
+	case workapiv1.JsonRaw:
+		return 0
+	case workapiv1.String:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// TruncateFeedbackValues drops the feedback values of the manifests so the total size of the feedback values is
+// within the budget in bytes, and returns the dropped values in the format of "<resource> <namespace>/<name>: <value name>".
+// The values with the lowest priority are dropped first, and the values with the same priority are dropped in
+// the reverse order of the manifests and the values, so the truncation is deterministic. The values are not
+// truncated if the budget is not positive.
+func TruncateFeedbackValues(manifests []workapiv1.ManifestCondition, budget int) []string {
+	if budget <= 0 {
+		return nil
+	}
+
+	total := 0
+	refs := []feedbackValueRef{}
+	for i, manifest := range manifests {
+		for j, value := range manifest.StatusFeedbacks.Values {
+			raw, _ := json.Marshal(value)
+			total += len(raw)
+			refs = append(refs, feedbackValueRef{manifestIndex: i, valueIndex: j, size: len(raw), priority: priority(value)})
+		}
+	}
+	if total <= budget {
+		return nil
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].priority != refs[j].priority {
+			return refs[i].priority < refs[j].priority
+		}
+		if refs[i].manifestIndex != refs[j].manifestIndex {
+			return refs[i].manifestIndex > refs[j].manifestIndex
+		}
+		return refs[i].valueIndex > refs[j].valueIndex
+	})
+
+	droppedValues := map[int]map[int]bool{}
+	dropped := []string{}
+	for _, ref := range refs {
+		if total <= budget {
+			break
+		}
+		total -= ref.size
+		if droppedValues[ref.manifestIndex] == nil {
+			droppedValues[ref.manifestIndex] = map[int]bool{}
+		}
+		droppedValues[ref.manifestIndex][ref.valueIndex] = true
+
+		resourceMeta := manifests[ref.manifestIndex].ResourceMeta
+		dropped = append(dropped, fmt.Sprintf("%s %s/%s: %s", resourceMeta.Resource, resourceMeta.Namespace, resourceMeta.Name,
+			manifests[ref.manifestIndex].StatusFeedbacks.Values[ref.valueIndex].Name))
+	}
+
+	for i, values := range droppedValues {
+		kept := []workapiv1.FeedbackValue{}
+		for j, value := range manifests[i].StatusFeedbacks.Values {
+			if !values[j] {
+				kept = append(kept, value)
+			}
+		}
+		manifests[i].StatusFeedbacks.Values = kept
+	}
+
+	return dropped
+}
@@ -0,0 +1,125 @@
+package statusfeedback
+
+import (
+	"encoding/json"
+	"testing"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+)
+
+func newStringValue(name, value string) workapiv1.FeedbackValue {
+	return workapiv1.FeedbackValue{Name: name, Value: workapiv1.FieldValue{Type: workapiv1.String, String: pointer.String(value)}}
+}
+
+func newJsonRawValue(name, value string) workapiv1.FeedbackValue {
+	return workapiv1.FeedbackValue{Name: name, Value: workapiv1.FieldValue{Type: workapiv1.JsonRaw, JsonRaw: pointer.String(value)}}
+}
+
+func newManifestCondition(name string, values ...workapiv1.FeedbackValue) workapiv1.ManifestCondition {
+	return workapiv1.ManifestCondition{
+		ResourceMeta: workapiv1.ManifestResourceMeta{
+			Resource:  "deployments",
+			Namespace: "default",
+			Name:      name,
+		},
+		StatusFeedbacks: workapiv1.StatusFeedbackResult{Values: values},
+	}
+}
+
+func sizeOf(values ...workapiv1.FeedbackValue) int {
+	size := 0
+	for _, value := range values {
+		raw, _ := json.Marshal(value)
+		size += len(raw)
+	}
+	return size
+}
+
+func TestTruncateFeedbackValues(t *testing.T) {
+	replicas := newIntegerValue("replicas", 1)
+	status := newStringValue("status", "Available")
+	conditions := newJsonRawValue("conditions", `[{"type":"Available","status":"True"}]`)
+	readyReplicas := newIntegerValue("readyReplicas", 1)
+
+	cases := []struct {
+		name              string
+		manifests         []workapiv1.ManifestCondition
+		budget            int
+		expectedManifests []workapiv1.ManifestCondition
+		expectedDropped   []string
+	}{
+		{
+			name: "no budget",
+			manifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas, conditions),
+			},
+			expectedManifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas, conditions),
+			},
+		},
+		{
+			name: "within the budget",
+			manifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas, conditions),
+			},
+			budget: sizeOf(replicas, conditions),
+			expectedManifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas, conditions),
+			},
+		},
+		{
+			name: "drop json raw values first",
+			manifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", conditions, replicas),
+				newManifestCondition("test2", status, readyReplicas),
+			},
+			budget: sizeOf(replicas, status, readyReplicas),
+			expectedManifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas),
+				newManifestCondition("test2", status, readyReplicas),
+			},
+			expectedDropped: []string{"deployments default/test1: conditions"},
+		},
+		{
+			name: "drop the values of the later manifests first",
+			manifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", conditions, replicas),
+				newManifestCondition("test2", conditions, readyReplicas),
+			},
+			budget: sizeOf(conditions, replicas, readyReplicas),
+			expectedManifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", conditions, replicas),
+				newManifestCondition("test2", readyReplicas),
+			},
+			expectedDropped: []string{"deployments default/test2: conditions"},
+		},
+		{
+			name: "drop values by priority",
+			manifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas, status, conditions),
+				newManifestCondition("test2", readyReplicas),
+			},
+			budget: sizeOf(replicas, readyReplicas),
+			expectedManifests: []workapiv1.ManifestCondition{
+				newManifestCondition("test1", replicas),
+				newManifestCondition("test2", readyReplicas),
+			},
+			expectedDropped: []string{"deployments default/test1: conditions", "deployments default/test1: status"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dropped := TruncateFeedbackValues(c.manifests, c.budget)
+			if !apiequality.Semantic.DeepEqual(dropped, c.expectedDropped) {
+				t.Errorf("expected dropped %v, but got %v", c.expectedDropped, dropped)
+			}
+			if !apiequality.Semantic.DeepEqual(c.manifests, c.expectedManifests) {
+				t.Errorf("expected manifests %v, but got %v", c.expectedManifests, c.manifests)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package statusfeedback
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/pager"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+const (
+	defaultTopN = 3
+
+	// listPageSize is the max number of the related resources returned by one list request
+	listPageSize = 500
+
+	// listCacheTTL is how long the listed related resources are cached. The status feedback is synced every 10s by
+	// default, the related resources are listed at most once in the ttl for all the manifestworks instead.
+	listCacheTTL = time.Minute
+)
+
+// RelatedResourcesReader reads the summarized status of the resources related to a manifest resource.
+type RelatedResourcesReader struct {
+	dynamicClient dynamic.Interface
+	clock         clock.Clock
+
+	lock sync.Mutex
+	// listCache caches the listed related resources by the resource, namespace and label selector
+	listCache map[listKey]*listResult
+}
+
+type listKey struct {
+	gvr           schema.GroupVersionResource
+	namespace     string
+	labelSelector string
+}
+
+type listResult struct {
+	items  []unstructured.Unstructured
+	listed time.Time
+}
+
+func NewRelatedResourcesReader(dynamicClient dynamic.Interface) *RelatedResourcesReader {
+	return &RelatedResourcesReader{
+		dynamicClient: dynamicClient,
+		clock:         clock.RealClock{},
+		listCache:     map[listKey]*listResult{},
+	}
+}
+
+// GetValuesByRule returns the summarized status of the related resources of the obj as feedback values. For each
+// related resource, the values are
+//   - <name>.count: the number of the related resources
+//   - <name>.phase.<phase>: the number of the related resources in each phase
+//   - <name>.restarts: the total restart count of the containers of the related resources
+//   - <name>.failureReasons: the top N failure reasons of the related resources with their counts
+func (r *RelatedResourcesReader) GetValuesByRule(ctx context.Context, obj *unstructured.Unstructured,
+	rule helper.RelatedResourcesFeedbackRule) ([]workapiv1.FeedbackValue, error) {
+	errs := []error{}
+	values := []workapiv1.FeedbackValue{}
+	owners := sets.New[types.UID](obj.GetUID())
+
+	for _, related := range rule.RelatedResources {
+		objs, err := r.listRelatedResources(ctx, obj.GetNamespace(), related, owners)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, relatedObj := range objs {
+			owners.Insert(relatedObj.GetUID())
+		}
+
+		name := related.Name
+		if len(name) == 0 {
+			name = related.Resource
+		}
+		topN := related.TopN
+		if topN == 0 {
+			topN = defaultTopN
+		}
+		values = append(values, summarize(name, objs, topN)...)
+	}
+
+	return values, utilerrors.NewAggregate(errs)
+}
+
+// listRelatedResources lists the related resources by the label selector, or the resources owned by the owners
+// if the label selector is not set.
+func (r *RelatedResourcesReader) listRelatedResources(ctx context.Context, namespace string,
+	related helper.RelatedResource, owners sets.Set[types.UID]) ([]unstructured.Unstructured, error) {
+	gvr := schema.GroupVersionResource{Group: related.Group, Version: related.Version, Resource: related.Resource}
+	items, err := r.list(ctx, listKey{gvr: gvr, namespace: namespace, labelSelector: related.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the related resources %s: %v", gvr.String(), err)
+	}
+
+	if len(related.LabelSelector) > 0 {
+		return items, nil
+	}
+
+	objs := []unstructured.Unstructured{}
+	for _, item := range items {
+		for _, owner := range item.GetOwnerReferences() {
+			if owners.Has(owner.UID) {
+				objs = append(objs, item)
+				break
+			}
+		}
+	}
+	return objs, nil
+}
+
+// list returns the cached resources of the key if they are listed within the listCacheTTL, otherwise it lists the
+// resources page by page and caches them. The expired caches are removed at the same time.
+func (r *RelatedResourcesReader) list(ctx context.Context, key listKey) ([]unstructured.Unstructured, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.clock.Now()
+	for k, result := range r.listCache {
+		if now.Sub(result.listed) >= listCacheTTL {
+			delete(r.listCache, k)
+		}
+	}
+	if result, ok := r.listCache[key]; ok {
+		return result.items, nil
+	}
+
+	client := r.dynamicClient.Resource(key.gvr).Namespace(key.namespace)
+	listPager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return client.List(ctx, opts)
+	}))
+	listPager.PageSize = listPageSize
+
+	items := []unstructured.Unstructured{}
+	err := listPager.EachListItem(ctx, metav1.ListOptions{LabelSelector: key.labelSelector}, func(obj runtime.Object) error {
+		item, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected object type %T", obj)
+		}
+		items = append(items, *item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.listCache[key] = &listResult{items: items, listed: now}
+	return items, nil
+}
+
+func summarize(name string, objs []unstructured.Unstructured, topN int) []workapiv1.FeedbackValue {
+	phases := map[string]int64{}
+	reasons := map[string]int64{}
+	var restarts int64
+	hasContainers := false
+
+	for _, obj := range objs {
+		if phase, found, _ := unstructured.NestedString(obj.Object, "status", "phase"); found && len(phase) > 0 {
+			phases[phase]++
+		}
+
+		for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+			containerStatuses, _, _ := unstructured.NestedSlice(obj.Object, "status", field)
+			for _, containerStatus := range containerStatuses {
+				status, ok := containerStatus.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				hasContainers = true
+				if count, found, _ := unstructured.NestedInt64(status, "restartCount"); found {
+					restarts += count
+				}
+				if reason, _, _ := unstructured.NestedString(status, "state", "waiting", "reason"); len(reason) > 0 {
+					reasons[reason]++
+				}
+				if reason, _, _ := unstructured.NestedString(status, "state", "terminated", "reason"); len(reason) > 0 && reason != "Completed" {
+					reasons[reason]++
+				}
+			}
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, condition := range conditions {
+			c, ok := condition.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			status, _, _ := unstructured.NestedString(c, "status")
+			reason, _, _ := unstructured.NestedString(c, "reason")
+			if status == string(metav1.ConditionFalse) && len(reason) > 0 {
+				reasons[reason]++
+			}
+		}
+	}
+
+	values := []workapiv1.FeedbackValue{newIntegerValue(name+".count", int64(len(objs)))}
+
+	phaseNames := sets.List(sets.KeySet(phases))
+	for _, phase := range phaseNames {
+		values = append(values, newIntegerValue(fmt.Sprintf("%s.phase.%s", name, phase), phases[phase]))
+	}
+
+	if hasContainers {
+		values = append(values, newIntegerValue(name+".restarts", restarts))
+	}
+
+	if len(reasons) > 0 {
+		values = append(values, workapiv1.FeedbackValue{
+			Name: name + ".failureReasons",
+			Value: workapiv1.FieldValue{
+				Type:   workapiv1.String,
+				String: pointer.String(topReasons(reasons, topN)),
+			},
+		})
+	}
+
+	return values
+}
+
+// topReasons returns the top N reasons in the format of "<reason>(<count>)", the reasons are sorted by the count
+// and then the reason.
+func topReasons(reasons map[string]int64, topN int) string {
+	names := sets.List(sets.KeySet(reasons))
+	sort.SliceStable(names, func(i, j int) bool {
+		return reasons[names[i]] > reasons[names[j]]
+	})
+	if len(names) > topN {
+		names = names[:topN]
+	}
+
+	summaries := []string{}
+	for _, name := range names {
+		summaries = append(summaries, fmt.Sprintf("%s(%d)", name, reasons[name]))
+	}
+	return strings.Join(summaries, ", ")
+}
+
+func newIntegerValue(name string, value int64) workapiv1.FeedbackValue {
+	return workapiv1.FeedbackValue{
+		Name: name,
+		Value: workapiv1.FieldValue{
+			Type:    workapiv1.Integer,
+			Integer: pointer.Int64(value),
+		},
+	}
+}
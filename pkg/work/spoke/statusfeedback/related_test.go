@@ -0,0 +1,171 @@
+package statusfeedback
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	workapiv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/ocm/pkg/work/helper"
+)
+
+func newRelatedObject(apiVersion, kind, name, uid, ownerUID string, labels map[string]string,
+	status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"status":     status,
+	}}
+	obj.SetNamespace("ns1")
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	obj.SetLabels(labels)
+	if len(ownerUID) > 0 {
+		obj.SetOwnerReferences([]metav1.OwnerReference{{UID: types.UID(ownerUID)}})
+	}
+	return obj
+}
+
+func newPodStatus(phase string, restarts int64, waitingReason string) map[string]interface{} {
+	containerStatus := map[string]interface{}{"restartCount": restarts}
+	if len(waitingReason) > 0 {
+		containerStatus["state"] = map[string]interface{}{"waiting": map[string]interface{}{"reason": waitingReason}}
+	}
+	return map[string]interface{}{
+		"phase":             phase,
+		"containerStatuses": []interface{}{containerStatus},
+	}
+}
+
+func TestGetRelatedValuesByRule(t *testing.T) {
+	deployment := newRelatedObject("apps/v1", "Deployment", "deploy1", "deploy-uid", "", nil, nil)
+	objs := []runtime.Object{
+		newRelatedObject("apps/v1", "ReplicaSet", "rs1", "rs1-uid", "deploy-uid", nil, map[string]interface{}{}),
+		newRelatedObject("apps/v1", "ReplicaSet", "rs2", "rs2-uid", "other-uid", nil, map[string]interface{}{}),
+		newRelatedObject("v1", "Pod", "pod1", "pod1-uid", "rs1-uid", map[string]string{"app": "test"},
+			newPodStatus("Running", 1, "")),
+		newRelatedObject("v1", "Pod", "pod2", "pod2-uid", "rs1-uid", map[string]string{"app": "test"},
+			newPodStatus("Pending", 3, "CrashLoopBackOff")),
+		newRelatedObject("v1", "Pod", "pod3", "pod3-uid", "rs1-uid", nil,
+			newPodStatus("Pending", 0, "ImagePullBackOff")),
+		newRelatedObject("v1", "Pod", "pod4", "pod4-uid", "rs2-uid", nil,
+			newPodStatus("Pending", 0, "ImagePullBackOff")),
+	}
+
+	cases := []struct {
+		name           string
+		rule           helper.RelatedResourcesFeedbackRule
+		expectedValues []workapiv1.FeedbackValue
+	}{
+		{
+			name: "owned resources",
+			rule: helper.RelatedResourcesFeedbackRule{
+				RelatedResources: []helper.RelatedResource{
+					{Name: "rs", Group: "apps", Version: "v1", Resource: "replicasets"},
+					{Version: "v1", Resource: "pods", TopN: 1},
+				},
+			},
+			expectedValues: []workapiv1.FeedbackValue{
+				newIntegerValue("rs.count", 1),
+				newIntegerValue("pods.count", 3),
+				newIntegerValue("pods.phase.Pending", 2),
+				newIntegerValue("pods.phase.Running", 1),
+				newIntegerValue("pods.restarts", 4),
+				{Name: "pods.failureReasons", Value: workapiv1.FieldValue{Type: workapiv1.String, String: pointer.String("CrashLoopBackOff(1)")}},
+			},
+		},
+		{
+			name: "resources selected by labels",
+			rule: helper.RelatedResourcesFeedbackRule{
+				RelatedResources: []helper.RelatedResource{
+					{Version: "v1", Resource: "pods", LabelSelector: "app=test"},
+				},
+			},
+			expectedValues: []workapiv1.FeedbackValue{
+				newIntegerValue("pods.count", 2),
+				newIntegerValue("pods.phase.Pending", 1),
+				newIntegerValue("pods.phase.Running", 1),
+				newIntegerValue("pods.restarts", 4),
+				{Name: "pods.failureReasons", Value: workapiv1.FieldValue{Type: workapiv1.String, String: pointer.String("CrashLoopBackOff(1)")}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+				{Group: "apps", Version: "v1", Resource: "replicasets"}: "ReplicaSetList",
+				{Version: "v1", Resource: "pods"}:                       "PodList",
+			}, objs...)
+
+			reader := NewRelatedResourcesReader(dynamicClient)
+			values, err := reader.GetValuesByRule(context.TODO(), deployment, c.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !apiequality.Semantic.DeepEqual(values, c.expectedValues) {
+				t.Errorf("expected values %v, but got %v", c.expectedValues, values)
+			}
+		})
+	}
+}
+
+func TestRelatedResourcesListCache(t *testing.T) {
+	deployment := newRelatedObject("apps/v1", "Deployment", "deploy1", "deploy-uid", "", nil, nil)
+	pod := newRelatedObject("v1", "Pod", "pod1", "pod1-uid", "deploy-uid", nil, newPodStatus("Running", 0, ""))
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{{Version: "v1", Resource: "pods"}: "PodList"}, pod)
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	reader := NewRelatedResourcesReader(dynamicClient)
+	reader.clock = fakeClock
+	rule := helper.RelatedResourcesFeedbackRule{
+		RelatedResources: []helper.RelatedResource{{Version: "v1", Resource: "pods"}},
+	}
+
+	listCount := func() int {
+		count := 0
+		for _, action := range dynamicClient.Actions() {
+			if action.GetVerb() == "list" {
+				count++
+			}
+		}
+		return count
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := reader.GetValuesByRule(context.TODO(), deployment, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := listCount(); count != 1 {
+		t.Errorf("expected the pods are listed once within the ttl, but got %d", count)
+	}
+
+	fakeClock.Step(listCacheTTL)
+	if _, err := reader.GetValuesByRule(context.TODO(), deployment, rule); err != nil {
+		t.Fatal(err)
+	}
+	if count := listCount(); count != 2 {
+		t.Errorf("expected the pods are listed again after the ttl, but got %d", count)
+	}
+}
+
+func TestTopReasons(t *testing.T) {
+	reasons := map[string]int64{"ImagePullBackOff": 2, "CrashLoopBackOff": 2, "OOMKilled": 1, "Error": 3}
+	if actual := topReasons(reasons, 3); actual != "Error(3), CrashLoopBackOff(2), ImagePullBackOff(2)" {
+		t.Errorf("unexpected top reasons %q", actual)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/features"
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/webhook/common"
 )
 
@@ -62,28 +63,64 @@ func (r *ManifestWorkWebhook) validateRequest(newWork, oldWork *workv1.ManifestW
 		return apierrors.NewBadRequest(err.Error())
 	}
 
+	if _, err := helper.RelatedResourcesFeedbackRules(newWork); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := helper.StatusReferences(newWork); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if err := helper.ValidateDeletionConfirmation(newWork); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if err := helper.ValidatePaused(newWork); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := helper.MaintenanceWindowOf(newWork, nil); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
 	req, err := admission.RequestFromContext(ctx)
 	if err != nil {
 		return apierrors.NewBadRequest(err.Error())
 	}
 
 	// do not need to check the executor when it is not changed
-	if oldWork != nil && reflect.DeepEqual(oldWork.Spec.Executor, newWork.Spec.Executor) {
+	if oldWork != nil && reflect.DeepEqual(oldWork.Spec.Executor, newWork.Spec.Executor) &&
+		executorAnnotationsEqual(oldWork, newWork) {
 		return nil
 	}
 	return validateExecutor(r.kubeClient, newWork, req.UserInfo)
 }
 
+func executorAnnotationsEqual(oldWork, newWork *workv1.ManifestWork) bool {
+	for _, key := range []string{helper.ExecutorUserAnnotationKey, helper.ExecutorGroupAnnotationKey} {
+		oldValue, oldExists := oldWork.Annotations[key]
+		newValue, newExists := newWork.Annotations[key]
+		if oldExists != newExists || oldValue != newValue {
+			return false
+		}
+	}
+	return true
+}
+
 func validateExecutor(kubeClient kubernetes.Interface, work *workv1.ManifestWork, userInfo authenticationv1.UserInfo) error {
-	executor := work.Spec.Executor
+	executor, err := helper.ManifestWorkExecutor(work)
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
 	if !features.DefaultHubWorkMutableFeatureGate.Enabled(ocmfeature.NilExecutorValidating) {
 		if executor == nil {
 			return nil
 		}
 	}
 
-	if work.Spec.Executor == nil {
-		executor = &workv1.ManifestWorkExecutor{
+	if executor == nil {
+		executor = helper.NewExecutor(&workv1.ManifestWorkExecutor{
 			Subject: workv1.ManifestWorkExecutorSubject{
 				Type: workv1.ExecutorSubjectTypeServiceAccount,
 				ServiceAccount: &workv1.ManifestWorkSubjectServiceAccount{
@@ -92,13 +129,17 @@ func validateExecutor(kubeClient kubernetes.Interface, work *workv1.ManifestWork
 					Name:      "klusterlet-work-sa", // the default sa of the work agent
 				},
 			},
-		}
+		})
 	}
 
 	if executor.Subject.Type == workv1.ExecutorSubjectTypeServiceAccount && executor.Subject.ServiceAccount == nil {
 		return apierrors.NewBadRequest("executor service account can not be nil")
 	}
 
+	if err := executor.Subject.Validate(); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
 	sar := &authorizationv1.SubjectAccessReview{
 		Spec: authorizationv1.SubjectAccessReviewSpec{
 			User:   userInfo.Username,
@@ -109,20 +150,30 @@ func validateExecutor(kubeClient kubernetes.Interface, work *workv1.ManifestWork
 				Resource:  "manifestworks",
 				Verb:      "execute-as",
 				Namespace: work.Namespace,
-				Name: fmt.Sprintf("system:serviceaccount:%s:%s",
-					executor.Subject.ServiceAccount.Namespace, executor.Subject.ServiceAccount.Name),
+				Name:      executor.Subject.String(),
 			},
 		},
 	}
-	sar, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+	sar, err = kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
 	if err != nil {
 		return apierrors.NewBadRequest(err.Error())
 	}
 
 	if !sar.Status.Allowed {
-		return apierrors.NewBadRequest(fmt.Sprintf("user %s cannot manipulate the Manifestwork with executor %s/%s in namespace %s",
-			userInfo.Username, executor.Subject.ServiceAccount.Namespace, executor.Subject.ServiceAccount.Name, work.Namespace))
+		return apierrors.NewBadRequest(fmt.Sprintf("user %s cannot manipulate the Manifestwork with executor %s in namespace %s",
+			userInfo.Username, executorName(&executor.Subject), work.Namespace))
 	}
 
 	return nil
 }
+
+// executorName returns the name of the executor in the error message
+func executorName(subject *helper.ExecutorSubject) string {
+	switch subject.Type {
+	case helper.ExecutorSubjectTypeUser:
+		return fmt.Sprintf("user %s", subject.User.Name)
+	case helper.ExecutorSubjectTypeGroup:
+		return fmt.Sprintf("group %s", subject.Group.Name)
+	}
+	return fmt.Sprintf("%s/%s", subject.ServiceAccount.Namespace, subject.ServiceAccount.Name)
+}
@@ -22,6 +22,7 @@ import (
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	"open-cluster-management.io/ocm/pkg/features"
+	"open-cluster-management.io/ocm/pkg/work/helper"
 	"open-cluster-management.io/ocm/pkg/work/spoke/spoketesting"
 )
 
@@ -54,6 +55,7 @@ func TestManifestWorkExecutorValidate(t *testing.T) {
 		manifests   []*unstructured.Unstructured
 		oldExecutor *workv1.ManifestWorkExecutor
 		executor    *workv1.ManifestWorkExecutor
+		annotations map[string]string
 		expectErr   error
 	}{
 		{
@@ -248,6 +250,244 @@ func TestManifestWorkExecutorValidate(t *testing.T) {
 			},
 			expectErr: apierrors.NewBadRequest(fmt.Sprintf("user test1 cannot manipulate the Manifestwork with executor ns1/executor2 in namespace cluster1")),
 		},
+		{
+			name: "validate user executor success",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{helper.ExecutorUserAnnotationKey: "user1"},
+			expectErr:   nil,
+		},
+		{
+			name: "validate group executor fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{helper.ExecutorGroupAnnotationKey: "group1"},
+			expectErr: apierrors.NewBadRequest(
+				"user test1 cannot manipulate the Manifestwork with executor group group1 in namespace cluster1"),
+		},
+		{
+			name: "validate both user and group executor fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.ExecutorUserAnnotationKey:  "user1",
+				helper.ExecutorGroupAnnotationKey: "group1",
+			},
+			expectErr: apierrors.NewBadRequest(fmt.Sprintf("only one of the annotations %s and %s can be set",
+				helper.ExecutorUserAnnotationKey, helper.ExecutorGroupAnnotationKey)),
+		},
+		{
+			name: "validate reserved group executor fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{helper.ExecutorGroupAnnotationKey: "system:masters"},
+			expectErr:   apierrors.NewBadRequest("the executor group system:masters is reserved"),
+		},
+		{
+			name: "validate invalid related resources feedback fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.RelatedResourcesFeedbackAnnotationKey: "{",
+			},
+			expectErr: apierrors.NewBadRequest(fmt.Sprintf(
+				"the annotation %s is not a valid json list of related resources feedback rules: unexpected end of JSON input",
+				helper.RelatedResourcesFeedbackAnnotationKey)),
+		},
+		{
+			name: "validate invalid status references fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.StatusReferencesAnnotationKey: `[{"version":"v1","resource":"nodes"}]`,
+			},
+			expectErr: apierrors.NewBadRequest(fmt.Sprintf(
+				"the version, resource and name of the status references are required in the annotation %s",
+				helper.StatusReferencesAnnotationKey)),
+		},
+		{
+			name: "validate invalid paused annotation fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Update,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.PausedAnnotationKey: "yes",
+			},
+			expectErr: apierrors.NewBadRequest(fmt.Sprintf(
+				"the annotation %s should be true or false, but got \"yes\"", helper.PausedAnnotationKey)),
+		},
+		{
+			name: "validate invalid maintenance window fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Update,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.MaintenanceWindowAnnotationKey: `{"schedule":"0 2 * * 6"}`,
+			},
+			expectErr: apierrors.NewBadRequest("the duration of the maintenance window should be positive"),
+		},
+		{
+			name: "validate invalid deletion confirmation fail",
+			request: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Resource:  manifestWorkSchema,
+					Operation: admissionv1.Update,
+					UserInfo:  authenticationv1.UserInfo{Username: "test1"},
+				},
+			},
+			manifests: []*unstructured.Unstructured{
+				{
+					Object: map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "kind",
+						"metadata": map[string]interface{}{
+							"namespace": "ns1",
+							"name":      "test",
+						},
+					},
+				},
+			},
+			annotations: map[string]string{
+				helper.ConfirmDeletionAnnotationKey: "persistentvolumeclaims/ns1",
+			},
+			expectErr: apierrors.NewBadRequest(fmt.Sprintf(
+				"the key \"persistentvolumeclaims/ns1\" in the annotation %s is invalid, it should be \"*\" or in the format of "+
+					"<resource>[.<group>]/<namespace>/<name>", helper.ConfirmDeletionAnnotationKey)),
+		},
 	}
 
 	utilruntime.Must(features.DefaultHubWorkMutableFeatureGate.Set(
@@ -289,6 +529,21 @@ func TestManifestWorkExecutorValidate(t *testing.T) {
 				}, nil
 			}
 
+			if obj.Spec.User == "test1" &&
+				reflect.DeepEqual(obj.Spec.ResourceAttributes, &v1.ResourceAttributes{
+					Group:     "work.open-cluster-management.io",
+					Resource:  "manifestworks",
+					Verb:      "execute-as",
+					Namespace: "cluster1",
+					Name:      "user1",
+				}) {
+				return true, &v1.SubjectAccessReview{
+					Status: v1.SubjectAccessReviewStatus{
+						Allowed: true,
+					},
+				}, nil
+			}
+
 			return true, &v1.SubjectAccessReview{
 				Status: v1.SubjectAccessReviewStatus{
 					Allowed: false,
@@ -311,6 +566,7 @@ func TestManifestWorkExecutorValidate(t *testing.T) {
 				oldWork.Spec.Executor = c.oldExecutor
 			}
 			newWork.Spec.Executor = c.executor
+			newWork.Annotations = c.annotations
 			err := mw.validateRequest(newWork, oldWork, ctx)
 			if !reflect.DeepEqual(err, c.expectErr) {
 				t.Errorf("case: %v, expected %v but got: %v", c.name, c.expectErr, err)
@@ -13,6 +13,7 @@ import (
 	ocmfeature "open-cluster-management.io/api/feature"
 	workv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 	"open-cluster-management.io/ocm/pkg/work/webhook/common"
 )
 
@@ -65,6 +66,34 @@ func (r *ManifestWorkReplicaSetWebhook) validateRequest(
 		return apierrors.NewBadRequest(err.Error())
 	}
 
+	if _, err := hubhelper.RolloutStrategyOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.OverridesOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.RollbackOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.RevisionHistoryLimitOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.AnalysisOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.FeedbackAggregationsOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	if _, err := hubhelper.RemovalPolicyOf(newmwrSet); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
 	_, err := admission.RequestFromContext(ctx)
 	if err != nil {
 		return apierrors.NewBadRequest(err.Error())
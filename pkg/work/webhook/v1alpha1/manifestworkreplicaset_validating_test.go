@@ -14,6 +14,7 @@ import (
 	ocmfeature "open-cluster-management.io/api/feature"
 	workv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
 
+	hubhelper "open-cluster-management.io/ocm/pkg/work/hub/helper"
 	helpertest "open-cluster-management.io/ocm/pkg/work/hub/test"
 )
 
@@ -58,6 +59,57 @@ func TestWebHookValidateRequest(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.RolloutStrategyAnnotationKey: `{"type":"Canary"}`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid rollout strategy ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.OverridesAnnotationKey: `[{"name":"patch","patches":[{"kind":"Deployment","name":"test","type":"Replace"}]}]`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid overrides ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.RollbackAnnotationKey: `{"revision":0}`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid rollback ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.RevisionHistoryLimitAnnotationKey: "-1"}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid revision history limit ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.AnalysisAnnotationKey: `{"rules":[{"name":"ready",` +
+		`"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"},` +
+		`"expression":"feedback.ReadyReplicas =="}]}`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid analysis ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.FeedbackAggregationsAnnotationKey: `[{"name":"replicas",` +
+		`"resourceIdentifier":{"group":"apps","resource":"deployments","namespace":"default","name":"test"}}]`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid feedback aggregations ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.RemovalPolicyAnnotationKey: `{"gracePeriod":"-10m"}`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for invalid removal policy ", err)
+	}
+
+	mwrSet.Annotations = map[string]string{hubhelper.OverridesAnnotationKey: `[{"name":"prod","placements":["unknown"],"patches":[]}]`}
+	err = webHook.validateRequest(mwrSet, nil, ctx)
+	if !apierrors.IsBadRequest(err) {
+		t.Fatal("Expecting bad request error for the overrides of an unknown placement ", err)
+	}
 }
 
 func TestWebHookCreateRequest(t *testing.T) {
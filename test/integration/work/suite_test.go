@@ -48,6 +48,7 @@ var CRDPaths = []string{
 	"./vendor/open-cluster-management.io/api/work/v1alpha1/0000_00_work.open-cluster-management.io_manifestworkreplicasets.crd.yaml",
 	"./vendor/open-cluster-management.io/api/cluster/v1beta1/0000_02_clusters.open-cluster-management.io_placements.crd.yaml",
 	"./vendor/open-cluster-management.io/api/cluster/v1beta1/0000_03_clusters.open-cluster-management.io_placementdecisions.crd.yaml",
+	"./vendor/open-cluster-management.io/api/cluster/v1/0000_00_clusters.open-cluster-management.io_managedclusters.crd.yaml",
 	// spoke
 	"./vendor/open-cluster-management.io/api/work/v1/0000_01_work.open-cluster-management.io_appliedmanifestworks.crd.yaml",
 }
@@ -97,7 +98,7 @@ var _ = ginkgo.BeforeSuite(func() {
 
 	// start hub controller
 	go func() {
-		err := hub.RunWorkHubManager(envCtx, &controllercmd.ControllerContext{
+		err := hub.NewWorkHubManagerOptions().RunWorkHubManager(envCtx, &controllercmd.ControllerContext{
 			KubeConfig:    cfg,
 			EventRecorder: util.NewIntegrationTestEventRecorder("hub"),
 		})